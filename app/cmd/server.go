@@ -19,11 +19,15 @@ import (
 	"github.com/go-bumbu/todo-app/app/logger"
 	"github.com/go-bumbu/todo-app/app/metainfo"
 	"github.com/go-bumbu/todo-app/app/router"
+	"github.com/go-bumbu/todo-app/internal/blobstore"
 	"github.com/go-bumbu/todo-app/internal/model/todolist"
 )
 
 const dbFile = "carbon.db"
 
+// trashPurgeInterval is how often deleted tasks past the retention period are removed
+const trashPurgeInterval = time.Hour
+
 func serverCmd() *cobra.Command {
 	var configFile = "./config.yaml"
 	cmd := &cobra.Command{
@@ -94,6 +98,13 @@ func runServer(configFile string) error {
 	if err != nil {
 		return fmt.Errorf("unable to create task manager :%v", err)
 	}
+	blobs, err := blobstore.NewFS(cfg.Attachments.Dir)
+	if err != nil {
+		return fmt.Errorf("unable to create attachment store :%v", err)
+	}
+	todoList.EnableAttachments(blobs, int64(cfg.Attachments.QuotaMb)*1024*1024)
+	go purgeTrash(todoList, cfg, l)
+
 	routerCfg := router.Cfg{
 		Db:          db,
 		SessionAuth: sessionAuth,
@@ -131,6 +142,25 @@ func runServer(configFile string) error {
 
 }
 
+// purgeTrash periodically removes the deleted tasks older than the configured retention
+func purgeTrash(todoList *todolist.Manager, cfg config.AppCfg, l *slog.Logger) {
+	if cfg.Trash.RetentionDays <= 0 {
+		return
+	}
+	ticker := time.NewTicker(trashPurgeInterval)
+	defer ticker.Stop()
+	for {
+		before := time.Now().AddDate(0, 0, -cfg.Trash.RetentionDays)
+		n, err := todoList.PurgeTrash(before)
+		if err != nil {
+			l.Warn("unable to purge trash", slog.String("component", "trash"), slog.String("error", err.Error()))
+		} else if n > 0 {
+			l.Info("purged trash", slog.String("component", "trash"), slog.Int("amount", n))
+		}
+		<-ticker.C
+	}
+}
+
 func getUserStore(cfg config.AppCfg, l *slog.Logger) (userauth.UserGetter, error) {
 	var userGet userauth.UserGetter
 	// load the correct user manager
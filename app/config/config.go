@@ -6,11 +6,13 @@ import (
 )
 
 type AppCfg struct {
-	Server serverCfg
-	Obs    serverCfg `config:"Observability"`
-	Auth   authConfig
-	Env    Env
-	Msgs   []Msg
+	Server      serverCfg
+	Obs         serverCfg `config:"Observability"`
+	Auth        authConfig
+	Trash       trashCfg
+	Attachments attachmentsCfg
+	Env         Env
+	Msgs        []Msg
 }
 
 type Env struct {
@@ -30,6 +32,15 @@ func (c serverCfg) Addr() string {
 	return c.BindIp + ":" + strconv.Itoa(c.Port)
 }
 
+type trashCfg struct {
+	RetentionDays int // deleted tasks older than this are removed permanently, 0 keeps them forever
+}
+
+type attachmentsCfg struct {
+	Dir     string // directory where the uploaded files are stored
+	QuotaMb int    // storage every user can use for attachments, 0 is unlimited
+}
+
 type authConfig struct {
 	SessionPath string
 	HashKey     string
@@ -76,6 +87,13 @@ var defaultCfg = AppCfg{
 			},
 		},
 	},
+	Trash: trashCfg{
+		RetentionDays: 30,
+	},
+	Attachments: attachmentsCfg{
+		Dir:     "attachments",
+		QuotaMb: 100,
+	},
 	Env: Env{
 		LogLevel:   "info",
 		Production: true,
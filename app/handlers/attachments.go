@@ -0,0 +1,285 @@
+package handlrs
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/go-bumbu/todo-app/internal/model/todolist"
+	"github.com/go-bumbu/userauth/handlers/sessionauth"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// AttachmentHandler exposes the files attached to the tasks of a user
+type AttachmentHandler struct {
+	TaskManager *todolist.Manager
+}
+
+// attachmentField is the multipart form field holding the uploaded file
+const attachmentField = "file"
+
+// maxFileName is the maximum length in bytes of the stored file names
+const maxFileName = 255
+
+// inlineTypes are shown by the browser, any other content is downloaded to avoid rendering
+// uploaded html or svg files in the context of the app
+var inlineTypes = map[string]bool{
+	"image/png":       true,
+	"image/jpeg":      true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+	"text/plain":      true,
+}
+
+type localAttachmentList struct {
+	Count       int
+	Attachments []localAttachmentOutput
+}
+
+type localAttachmentOutput struct {
+	Id          string `json:"id"`
+	TaskId      string `json:"taskId"`
+	Name        string `json:"name"`
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"`
+	CreatedAt   string `json:"createdAt"`
+}
+
+type localStorageUsage struct {
+	Used  int64 `json:"used"`
+	Quota int64 `json:"quota"` // 0 is unlimited
+}
+
+func attachmentOutput(a todolist.Attachment) localAttachmentOutput {
+	return localAttachmentOutput{
+		Id:          a.ID,
+		TaskId:      a.TaskId,
+		Name:        a.Name,
+		ContentType: a.ContentType,
+		Size:        a.Size,
+		CreatedAt:   a.CreatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+// Upload attaches the file sent in the "file" field of a multipart request to the task,
+// the content is streamed to the blob store without buffering the whole file
+func (h *AttachmentHandler) Upload() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		taskId, hErr := getTaskId(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to upload attachment: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		mr, err := r.MultipartReader()
+		if err != nil {
+			http.Error(w, "request is not a multipart upload", http.StatusBadRequest)
+			return
+		}
+		var part io.Reader
+		var name, declaredType string
+		for {
+			p, err := mr.NextPart()
+			if err == io.EOF {
+				http.Error(w, fmt.Sprintf("%s field is required", attachmentField), http.StatusBadRequest)
+				return
+			}
+			if err != nil {
+				http.Error(w, fmt.Sprintf("unable to read upload: %s", err.Error()), http.StatusBadRequest)
+				return
+			}
+			if p.FormName() == attachmentField {
+				part, name, declaredType = p, cleanFileName(p.FileName()), p.Header.Get("Content-Type")
+				break
+			}
+		}
+
+		content := bufio.NewReaderSize(part, 512)
+		a := todolist.Attachment{
+			TaskId:      taskId,
+			OwnerId:     uData.UserId,
+			Name:        name,
+			ContentType: uploadType(declaredType, content),
+		}
+		_, err = requestManager(r, h.TaskManager).Attach(r.Context(), &a, content)
+		if err != nil {
+			attachmentErr(w, err)
+			return
+		}
+		writeJson(w, attachmentOutput(a), http.StatusOK)
+	})
+}
+
+// cleanFileName keeps the base name of the uploaded file without control characters
+func cleanFileName(name string) string {
+	name = name[strings.LastIndexAny(name, `/\`)+1:]
+	name = strings.TrimSpace(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name))
+	for len(name) > maxFileName {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	if name == "" || name == "." || name == ".." {
+		return "attachment"
+	}
+	return name
+}
+
+// uploadType returns the content type declared by the client, if it is missing or generic the type
+// is detected from the first bytes of the content
+func uploadType(declared string, content *bufio.Reader) string {
+	mt, params, err := mime.ParseMediaType(declared)
+	if err == nil && mt != "application/octet-stream" {
+		return mime.FormatMediaType(mt, params)
+	}
+	head, _ := content.Peek(512)
+	return http.DetectContentType(head)
+}
+
+func (h *AttachmentHandler) List() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		taskId, hErr := getTaskId(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to list attachments: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		items, err := requestManager(r, h.TaskManager).Attachments(taskId, uData.UserId)
+		if err != nil {
+			attachmentErr(w, err)
+			return
+		}
+		output := localAttachmentList{
+			Count:       len(items),
+			Attachments: make([]localAttachmentOutput, len(items)),
+		}
+		for i := range items {
+			output.Attachments[i] = attachmentOutput(items[i])
+		}
+		writeJson(w, output, http.StatusOK)
+	})
+}
+
+// Download streams the content of the attachment, range requests are supported if the blob store allows seeking
+func (h *AttachmentHandler) Download() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, hErr := getAttachmentId(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to download attachment: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		a, content, err := requestManager(r, h.TaskManager).OpenAttachment(r.Context(), id, uData.UserId)
+		if err != nil {
+			attachmentErr(w, err)
+			return
+		}
+		defer func() { _ = content.Close() }()
+
+		disposition := "attachment"
+		if mt, _, err := mime.ParseMediaType(a.ContentType); err == nil && inlineTypes[mt] {
+			disposition = "inline"
+		}
+		w.Header().Set("Content-Type", a.ContentType)
+		w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": a.Name}))
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+
+		if rs, ok := content.(io.ReadSeeker); ok {
+			http.ServeContent(w, r, a.Name, a.CreatedAt, rs)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.FormatInt(a.Size, 10))
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.Copy(w, content)
+	})
+}
+
+func (h *AttachmentHandler) Delete() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, hErr := getAttachmentId(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to delete attachment: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		err = requestManager(r, h.TaskManager).DeleteAttachment(r.Context(), id, uData.UserId)
+		if err != nil {
+			attachmentErr(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+// Usage returns the storage used by the attachments of the user and the quota
+func (h *AttachmentHandler) Usage() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to get storage usage: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		used, err := requestManager(r, h.TaskManager).StorageUsed(uData.UserId)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to get storage usage: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		writeJson(w, localStorageUsage{Used: used, Quota: requestManager(r, h.TaskManager).Quota()}, http.StatusOK)
+	})
+}
+
+// attachmentErr writes the http error matching an error returned by the attachment methods of the manager
+func attachmentErr(w http.ResponseWriter, err error) {
+	t := &todolist.ItemNotFountErr{}
+	a := &todolist.AttachmentNotFoundErr{}
+	if errors.As(err, &t) || errors.As(err, &a) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+	} else if errors.Is(err, todolist.ErrPermissionDenied) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+	} else if errors.Is(err, todolist.ErrQuotaExceeded) {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+	} else if errors.Is(err, todolist.ErrAttachmentsDisabled) {
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+	} else {
+		http.Error(w, fmt.Sprintf("unable to process attachment: %s", err.Error()), http.StatusInternalServerError)
+	}
+}
+
+func getAttachmentId(r *http.Request) (string, *httpErr) {
+	return getUuidVar(r, "ID", "attachment")
+}
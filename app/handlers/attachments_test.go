@@ -0,0 +1,185 @@
+package handlrs
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/go-bumbu/todo-app/internal/blobstore"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+// uploadReq creates a multipart request with the content in the "file" field
+func uploadReq(t *testing.T, taskId, field, name, contentType, content, user string) *http.Request {
+	t.Helper()
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Disposition", `form-data; name="`+field+`"; filename="`+name+`"`)
+	if contentType != "" {
+		h.Set("Content-Type", contentType)
+	}
+	part, err := mw.CreatePart(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = part.Write([]byte(content))
+	if err = mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	req := userReq(t, "POST", "/api/task/"+taskId+"/attachments", body.String(), user, map[string]string{"ID": taskId})
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestAttachmentHandler(t *testing.T) {
+	mngr := newTestManager(t)
+	store, err := blobstore.NewFS(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mngr.EnableAttachments(store, 20)
+	ah := AttachmentHandler{TaskManager: mngr}
+	taskId := createTask(t, mngr, "report", user1)
+
+	upload := func(t *testing.T, req *http.Request) localAttachmentOutput {
+		t.Helper()
+		recorder := httptest.NewRecorder()
+		ah.Upload().ServeHTTP(recorder, req)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v: %s", recorder.Code, http.StatusOK, recorder.Body.String())
+		}
+		got := localAttachmentOutput{}
+		if err := json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	t.Run("upload and download", func(t *testing.T) {
+		got := upload(t, uploadReq(t, taskId, "file", `C:\docs\notes.txt`, "", "hello", user1))
+		if got.Name != "notes.txt" || got.Size != 5 || got.ContentType != "text/plain; charset=utf-8" {
+			t.Errorf("unexpected attachment: %+v", got)
+		}
+
+		recorder := httptest.NewRecorder()
+		ah.Download().ServeHTTP(recorder, userReq(t, "GET", "/api/attachments/"+got.Id, "", user1, map[string]string{"ID": got.Id}))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", recorder.Code, http.StatusOK)
+		}
+		if recorder.Body.String() != "hello" {
+			t.Errorf("unexpected content: %q", recorder.Body.String())
+		}
+		if d := recorder.Header().Get("Content-Disposition"); d != `inline; filename=notes.txt` {
+			t.Errorf("unexpected disposition: %s", d)
+		}
+		if n := recorder.Header().Get("X-Content-Type-Options"); n != "nosniff" {
+			t.Errorf("expected nosniff header, got: %s", n)
+		}
+
+		recorder = httptest.NewRecorder()
+		ah.Delete().ServeHTTP(recorder, userReq(t, "DELETE", "/api/attachments/"+got.Id, "", user1, map[string]string{"ID": got.Id}))
+		if recorder.Code != http.StatusAccepted {
+			t.Errorf("handler returned wrong status code: got %v want %v", recorder.Code, http.StatusAccepted)
+		}
+	})
+
+	t.Run("html is downloaded", func(t *testing.T) {
+		got := upload(t, uploadReq(t, taskId, "file", "page.html", "text/html", "<p>x</p>", user1))
+		recorder := httptest.NewRecorder()
+		ah.Download().ServeHTTP(recorder, userReq(t, "GET", "/api/attachments/"+got.Id, "", user1, map[string]string{"ID": got.Id}))
+		if d := recorder.Header().Get("Content-Disposition"); d != `attachment; filename=page.html` {
+			t.Errorf("unexpected disposition: %s", d)
+		}
+		if ct := recorder.Header().Get("Content-Type"); ct != "text/html" {
+			t.Errorf("unexpected content type: %s", ct)
+		}
+	})
+
+	t.Run("list and usage", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		ah.List().ServeHTTP(recorder, userReq(t, "GET", "/api/task/"+taskId+"/attachments", "", user1, map[string]string{"ID": taskId}))
+		got := localAttachmentList{}
+		if err := json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Count != 1 || got.Attachments[0].Name != "page.html" {
+			t.Errorf("unexpected attachments: %+v", got)
+		}
+
+		recorder = httptest.NewRecorder()
+		ah.Usage().ServeHTTP(recorder, userReq(t, "GET", "/api/attachments/usage", "", user1, nil))
+		usage := localStorageUsage{}
+		if err := json.NewDecoder(recorder.Body).Decode(&usage); err != nil {
+			t.Fatal(err)
+		}
+		if usage != (localStorageUsage{Used: 8, Quota: 20}) {
+			t.Errorf("unexpected usage: %+v", usage)
+		}
+	})
+
+	tcs := []struct {
+		name       string
+		req        func(t *testing.T) *http.Request
+		expectCode int
+		expectErr  string
+	}{
+		{
+			name: "not multipart",
+			req: func(t *testing.T) *http.Request {
+				return userReq(t, "POST", "/api/task/"+taskId+"/attachments", "hello", user1, map[string]string{"ID": taskId})
+			},
+			expectCode: http.StatusBadRequest,
+			expectErr:  "request is not a multipart upload",
+		},
+		{
+			name: "missing file field",
+			req: func(t *testing.T) *http.Request {
+				return uploadReq(t, taskId, "other", "a.txt", "", "hello", user1)
+			},
+			expectCode: http.StatusBadRequest,
+			expectErr:  "file field is required",
+		},
+		{
+			name: "task of other user",
+			req: func(t *testing.T) *http.Request {
+				return uploadReq(t, taskId, "file", "a.txt", "", "hello", user2)
+			},
+			expectCode: http.StatusNotFound,
+			expectErr:  "task with id: " + taskId + " and owner " + user2 + " not found",
+		},
+		{
+			name: "quota exceeded",
+			req: func(t *testing.T) *http.Request {
+				return uploadReq(t, taskId, "file", "a.txt", "", strings.Repeat("x", 13), user1)
+			},
+			expectCode: http.StatusRequestEntityTooLarge,
+			expectErr:  "attachment storage quota exceeded",
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			ah.Upload().ServeHTTP(recorder, tc.req(t))
+			if recorder.Code != tc.expectCode {
+				t.Errorf("handler returned wrong status code: got %v want %v", recorder.Code, tc.expectCode)
+			}
+			if got := strings.TrimSuffix(recorder.Body.String(), "\n"); got != tc.expectErr {
+				t.Errorf("unexpecter error message: got \"%s\"", got)
+			}
+		})
+	}
+
+	t.Run("attachment of other user", func(t *testing.T) {
+		got := upload(t, uploadReq(t, taskId, "file", "b.txt", "", "x", user1))
+		recorder := httptest.NewRecorder()
+		ah.Download().ServeHTTP(recorder, userReq(t, "GET", "/api/attachments/"+got.Id, "", user2, map[string]string{"ID": got.Id}))
+		if recorder.Code != http.StatusNotFound {
+			t.Errorf("handler returned wrong status code: got %v want %v", recorder.Code, http.StatusNotFound)
+		}
+	})
+}
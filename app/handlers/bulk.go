@@ -0,0 +1,137 @@
+package handlrs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-bumbu/todo-app/internal/model/todolist"
+	"github.com/go-bumbu/userauth/handlers/sessionauth"
+	"github.com/google/uuid"
+	"net/http"
+)
+
+type localBulkRequest struct {
+	Operations []localBulkOperation `json:"operations"`
+}
+
+type localBulkOperation struct {
+	// one of complete, reopen, delete, move or addTag
+	Op string `json:"op"`
+	// the tasks are selected either by ids or by a filter expression, see todolist.ParseFilter,
+	// filters match the tasks as they are before the first operation is applied
+	Ids    []string `json:"ids"`
+	Filter *string  `json:"filter"`
+	// list the tasks are moved to, an empty list is the inbox
+	ListId string `json:"listId"`
+	// name of the added tag
+	Tag string `json:"tag"`
+	// complete tasks that are waiting for blockers
+	Force bool `json:"force"`
+}
+
+type localBulkResponse struct {
+	Count   int
+	Failed  int
+	Results []localBulkResult
+}
+
+type localBulkResult struct {
+	Op string `json:"op"`
+	Id string `json:"id"`
+	// Status is the http status code the change of the single task would have had
+	Status int    `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Bulk applies a list of operations to many tasks in a single transaction and returns the result of every task,
+// tasks that cannot be changed are reported without affecting the others
+func (h *TodoListHandler) Bulk() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to update tasks: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		if r.Body == nil {
+			http.Error(w, "request had empty body", http.StatusBadRequest)
+			return
+		}
+		payload := localBulkRequest{}
+		err = json.NewDecoder(r.Body).Decode(&payload)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to decode json: %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+		if len(payload.Operations) == 0 {
+			http.Error(w, "operations cannot be empty", http.StatusBadRequest)
+			return
+		}
+
+		ops := make([]todolist.BulkOperation, len(payload.Operations))
+		for i, o := range payload.Operations {
+			for _, id := range o.Ids {
+				if _, err := uuid.Parse(id); err != nil {
+					http.Error(w, fmt.Sprintf("task id is not a UUID: %s", id), http.StatusBadRequest)
+					return
+				}
+			}
+			ops[i] = todolist.BulkOperation{
+				Action:         o.Op,
+				Ids:            o.Ids,
+				ListId:         o.ListId,
+				Tag:            o.Tag,
+				IgnoreBlockers: o.Force,
+			}
+			if o.Filter != nil {
+				filter, err := todolist.ParseFilter(*o.Filter)
+				if err != nil {
+					http.Error(w, fmt.Sprintf("invalid %s: %s", filterParam, err.Error()), http.StatusBadRequest)
+					return
+				}
+				ops[i].Scopes = []todolist.Scope{filter}
+			}
+		}
+
+		results, err := requestManager(r, h.TaskManager).WithAudit(requestAudit(r, uData.UserId)).Bulk(uData.UserId, ops)
+		if err != nil {
+			lErr := &todolist.ListNotFoundErr{}
+			if errors.Is(err, todolist.ErrUnknownBulkAction) || errors.Is(err, todolist.ErrNoBulkTasks) ||
+				errors.Is(err, todolist.ErrBulkTooLarge) || errors.Is(err, todolist.ErrEmptyTagName) || errors.As(err, &lErr) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			} else {
+				http.Error(w, fmt.Sprintf("unable to update tasks: %s", err.Error()), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		output := localBulkResponse{
+			Count:   len(results),
+			Results: make([]localBulkResult, len(results)),
+		}
+		for i, res := range results {
+			output.Results[i] = localBulkResult{Op: res.Action, Id: res.TaskId, Status: http.StatusOK}
+			if res.Err != nil {
+				output.Failed++
+				output.Results[i].Status = bulkStatus(res.Err)
+				output.Results[i].Error = res.Err.Error()
+			}
+		}
+		writeJson(w, output, http.StatusOK)
+	})
+}
+
+// bulkStatus maps the error of a single task in a bulk operation to an http status code
+func bulkStatus(err error) int {
+	t := &todolist.ItemNotFountErr{}
+	if errors.As(err, &t) {
+		return http.StatusNotFound
+	}
+	if errors.Is(err, todolist.ErrTaskBlocked) {
+		return http.StatusConflict
+	}
+	if errors.Is(err, todolist.ErrPermissionDenied) {
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,95 @@
+package handlrs
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBulkHandler(t *testing.T) {
+	th := TodoListHandler{TaskManager: newTestManager(t)}
+	milk := createTask(t, th.TaskManager, "milk", user1)
+	bread := createTask(t, th.TaskManager, "bread", user1)
+	other := createTask(t, th.TaskManager, "other", user2)
+
+	body := `{"operations":[
+		{"op":"complete","ids":["` + milk + `","` + other + `"]},
+		{"op":"addTag","filter":"done:false","tag":"shopping"}
+	]}`
+	recorder := httptest.NewRecorder()
+	th.Bulk().ServeHTTP(recorder, userReq(t, "POST", "/api/tasks/bulk", body, user1, nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", recorder.Code, http.StatusOK, recorder.Body.String())
+	}
+	got := localBulkResponse{}
+	if err := json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	want := []localBulkResult{
+		{Op: "complete", Id: milk, Status: http.StatusOK},
+		{Op: "complete", Id: other, Status: http.StatusNotFound, Error: "task with id: " + other + " and owner " + user1 + " not found"},
+		// filters match the tasks as they were before the request
+		{Op: "addTag", Id: milk, Status: http.StatusOK},
+		{Op: "addTag", Id: bread, Status: http.StatusOK},
+	}
+	if got.Count != 4 || got.Failed != 1 || len(got.Results) != len(want) {
+		t.Fatalf("unexpected response: %+v", got)
+	}
+	for i := range want {
+		if got.Results[i] != want[i] {
+			t.Errorf("unexpected result %d: got %+v want %+v", i, got.Results[i], want[i])
+		}
+	}
+
+	tcs := []struct {
+		name       string
+		body       string
+		expectCode int
+		expectErr  string
+	}{
+		{
+			name:       "no operations",
+			body:       `{"operations":[]}`,
+			expectCode: http.StatusBadRequest,
+			expectErr:  "operations cannot be empty",
+		},
+		{
+			name:       "unknown operation",
+			body:       `{"operations":[{"op":"archive","ids":["` + milk + `"]}]}`,
+			expectCode: http.StatusBadRequest,
+			expectErr:  "unknown bulk action: archive",
+		},
+		{
+			name:       "ids and filter",
+			body:       `{"operations":[{"op":"delete","ids":["` + milk + `"],"filter":"done:true"}]}`,
+			expectCode: http.StatusBadRequest,
+			expectErr:  "the tasks of a bulk operation are selected either by ids or by filter",
+		},
+		{
+			name:       "malformed id",
+			body:       `{"operations":[{"op":"delete","ids":["` + milk + `","milk"]}]}`,
+			expectCode: http.StatusBadRequest,
+			expectErr:  "task id is not a UUID: milk",
+		},
+		{
+			name:       "invalid filter",
+			body:       `{"operations":[{"op":"delete","filter":"due:tomorrow"}]}`,
+			expectCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			th.Bulk().ServeHTTP(recorder, userReq(t, "POST", "/api/tasks/bulk", tc.body, user1, nil))
+			if recorder.Code != tc.expectCode {
+				t.Errorf("handler returned wrong status code: got %v want %v", recorder.Code, tc.expectCode)
+			}
+			if got := strings.TrimSuffix(recorder.Body.String(), "\n"); tc.expectErr != "" && got != tc.expectErr {
+				t.Errorf("unexpecter error message: got \"%s\"", got)
+			}
+		})
+	}
+}
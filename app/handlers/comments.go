@@ -0,0 +1,208 @@
+package handlrs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-bumbu/todo-app/internal/markdown"
+	"github.com/go-bumbu/todo-app/internal/model/todolist"
+	"github.com/go-bumbu/userauth/handlers/sessionauth"
+	"net/http"
+	"time"
+)
+
+// CommentHandler exposes the comment threads of the tasks
+type CommentHandler struct {
+	TaskManager *todolist.Manager
+}
+
+type localCommentInput struct {
+	Body string `json:"body"`
+}
+
+type localCommentList struct {
+	Count    int
+	Comments []localCommentOutput
+}
+
+type localCommentOutput struct {
+	Id       string `json:"id"`
+	TaskId   string `json:"taskId"`
+	AuthorId string `json:"authorId"`
+	Body     string `json:"body"`
+	// BodyHtml is the sanitized markdown rendering of the body
+	BodyHtml  string `json:"bodyHtml"`
+	CreatedAt string `json:"createdAt"`
+	UpdatedAt string `json:"updatedAt"`
+	Edited    bool   `json:"edited,omitempty"`
+}
+
+func commentOutput(c todolist.Comment) localCommentOutput {
+	return localCommentOutput{
+		Id:        c.ID,
+		TaskId:    c.TaskId,
+		AuthorId:  c.AuthorId,
+		Body:      c.Body,
+		BodyHtml:  markdown.Render(c.Body),
+		CreatedAt: c.CreatedAt.UTC().Format(time.RFC3339),
+		UpdatedAt: c.UpdatedAt.UTC().Format(time.RFC3339),
+		Edited:    c.UpdatedAt.After(c.CreatedAt),
+	}
+}
+
+func (h *CommentHandler) List() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		taskId, hErr := getTaskId(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to list comments: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		items, err := requestManager(r, h.TaskManager).Comments(taskId, uData.UserId)
+		if err != nil {
+			commentErr(w, err, "unable to list comments")
+			return
+		}
+		output := localCommentList{
+			Count:    len(items),
+			Comments: make([]localCommentOutput, len(items)),
+		}
+		for i := range items {
+			output.Comments[i] = commentOutput(items[i])
+		}
+		writeJson(w, output, http.StatusOK)
+	})
+}
+
+func (h *CommentHandler) Create() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		taskId, hErr := getTaskId(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to create comment: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		payload, hErr := readCommentInput(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		c := todolist.Comment{
+			TaskId:   taskId,
+			AuthorId: uData.UserId,
+			Body:     payload.Body,
+		}
+		_, err = requestManager(r, h.TaskManager).AddComment(&c)
+		if err != nil {
+			commentErr(w, err, "unable to create comment")
+			return
+		}
+		writeJson(w, commentOutput(c), http.StatusOK)
+	})
+}
+
+func (h *CommentHandler) Update() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		taskId, hErr := getTaskId(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+		commentId, hErr := getCommentId(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to update comment: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		payload, hErr := readCommentInput(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		c, err := requestManager(r, h.TaskManager).UpdateComment(taskId, commentId, uData.UserId, payload.Body)
+		if err != nil {
+			commentErr(w, err, "unable to update comment")
+			return
+		}
+		writeJson(w, commentOutput(c), http.StatusOK)
+	})
+}
+
+func (h *CommentHandler) Delete() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		taskId, hErr := getTaskId(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+		commentId, hErr := getCommentId(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to delete comment: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		err = requestManager(r, h.TaskManager).DeleteComment(taskId, commentId, uData.UserId)
+		if err != nil {
+			commentErr(w, err, "unable to delete comment")
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+func readCommentInput(r *http.Request) (localCommentInput, *httpErr) {
+	payload := localCommentInput{}
+	if r.Body == nil {
+		return payload, &httpErr{Error: "request had empty body", Code: http.StatusBadRequest}
+	}
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		return payload, &httpErr{Error: fmt.Sprintf("unable to decode json: %s", err.Error()), Code: http.StatusBadRequest}
+	}
+	return payload, nil
+}
+
+// commentErr writes the http error matching an error returned by the comment methods of the manager
+func commentErr(w http.ResponseWriter, err error, msg string) {
+	t := &todolist.ItemNotFountErr{}
+	c := &todolist.CommentNotFoundErr{}
+	if errors.As(err, &t) || errors.As(err, &c) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+	} else if errors.Is(err, todolist.ErrEmptyComment) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	} else if errors.Is(err, todolist.ErrNotCommentAuthor) || errors.Is(err, todolist.ErrPermissionDenied) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+	} else {
+		http.Error(w, fmt.Sprintf("%s: %s", msg, err.Error()), http.StatusInternalServerError)
+	}
+}
+
+func getCommentId(r *http.Request) (string, *httpErr) {
+	return getUuidVar(r, "CommentID", "comment")
+}
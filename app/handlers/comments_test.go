@@ -0,0 +1,141 @@
+package handlrs
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCommentHandler(t *testing.T) {
+	mngr := newTestManager(t)
+	ch := CommentHandler{TaskManager: mngr}
+	th := TodoListHandler{TaskManager: mngr}
+	taskId := createTask(t, mngr, "report", user1)
+	taskVars := map[string]string{"ID": taskId}
+
+	create := func(t *testing.T, body string) localCommentOutput {
+		t.Helper()
+		recorder := httptest.NewRecorder()
+		ch.Create().ServeHTTP(recorder, userReq(t, "POST", "/api/task/"+taskId+"/comments", body, user1, taskVars))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", recorder.Code, http.StatusOK)
+		}
+		got := localCommentOutput{}
+		if err := json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	first := create(t, `{"body":"looks **good**"}`)
+	if first.AuthorId != user1 || first.BodyHtml != "<p>looks <strong>good</strong></p>\n" {
+		t.Errorf("unexpected comment: %+v", first)
+	}
+	create(t, `{"body":"second"}`)
+
+	t.Run("list", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		ch.List().ServeHTTP(recorder, userReq(t, "GET", "/api/task/"+taskId+"/comments", "", user1, taskVars))
+		got := localCommentList{}
+		if err := json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Count != 2 || got.Comments[0].Body != "looks **good**" {
+			t.Errorf("unexpected comments: %+v", got)
+		}
+	})
+
+	t.Run("count in task list", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		th.List().ServeHTTP(recorder, userReq(t, "GET", "/api/tasks", "", user1, nil))
+		got := localTaskList{}
+		if err := json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Count != 1 || got.Tasks[0].Comments != 2 {
+			t.Errorf("unexpected tasks: %+v", got.Tasks)
+		}
+	})
+
+	commentVars := map[string]string{"ID": taskId, "CommentID": first.Id}
+	tcs := []struct {
+		name       string
+		method     string
+		body       string
+		user       string
+		vars       map[string]string
+		expectCode int
+		expectErr  string
+	}{
+		{
+			name:       "empty body",
+			method:     "PUT",
+			body:       `{"body":" "}`,
+			vars:       commentVars,
+			expectCode: http.StatusBadRequest,
+			expectErr:  "comment body cannot be empty",
+		},
+		{
+			name:       "invalid comment id",
+			method:     "PUT",
+			body:       `{"body":"x"}`,
+			vars:       map[string]string{"ID": taskId, "CommentID": "1"},
+			expectCode: http.StatusBadRequest,
+			expectErr:  "comment id is not a UUID",
+		},
+		{
+			name:       "task of other user",
+			method:     "DELETE",
+			user:       user2,
+			vars:       commentVars,
+			expectCode: http.StatusNotFound,
+			expectErr:  "task with id: " + taskId + " and owner " + user2 + " not found",
+		},
+		{
+			name:       "edit",
+			method:     "PUT",
+			body:       `{"body":"changed"}`,
+			vars:       commentVars,
+			expectCode: http.StatusOK,
+		},
+		{
+			name:       "delete",
+			method:     "DELETE",
+			vars:       commentVars,
+			expectCode: http.StatusAccepted,
+		},
+		{
+			name:       "delete again",
+			method:     "DELETE",
+			vars:       commentVars,
+			expectCode: http.StatusNotFound,
+			expectErr:  "comment with id: " + first.Id + " and user " + user1 + " not found",
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			user := tc.user
+			if user == "" {
+				user = user1
+			}
+			req := userReq(t, tc.method, "/api/task/"+taskId+"/comments/"+first.Id, tc.body, user, tc.vars)
+			recorder := httptest.NewRecorder()
+			if tc.method == "PUT" {
+				ch.Update().ServeHTTP(recorder, req)
+			} else {
+				ch.Delete().ServeHTTP(recorder, req)
+			}
+			if recorder.Code != tc.expectCode {
+				t.Errorf("handler returned wrong status code: got %v want %v", recorder.Code, tc.expectCode)
+			}
+			if tc.expectErr != "" {
+				if got := strings.TrimSuffix(recorder.Body.String(), "\n"); got != tc.expectErr {
+					t.Errorf("unexpecter error message: got \"%s\"", got)
+				}
+			}
+		})
+	}
+}
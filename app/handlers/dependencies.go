@@ -0,0 +1,90 @@
+package handlrs
+
+import (
+	"fmt"
+	"github.com/go-bumbu/userauth/handlers/sessionauth"
+	"net/http"
+	"strconv"
+)
+
+// forceParam allows completing a task while some of its blockers are still open
+const forceParam = "force"
+
+func getForce(r *http.Request) (bool, *httpErr) {
+	v := r.URL.Query().Get(forceParam)
+	if v == "" {
+		return false, nil
+	}
+	force, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, &httpErr{Error: fmt.Sprintf("unable to convert %s value to boolean", forceParam), Code: http.StatusBadRequest}
+	}
+	return force, nil
+}
+
+type localGraphNode struct {
+	Id      string `json:"id"`
+	Text    string `json:"text"`
+	Done    bool   `json:"done"`
+	Blocked bool   `json:"blocked"`
+	ListId  string `json:"listId"`
+}
+
+// localGraphEdge points from the blocker to the task waiting for it
+type localGraphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type localGraph struct {
+	Nodes []localGraphNode `json:"nodes"`
+	Edges []localGraphEdge `json:"edges"`
+}
+
+// Graph returns the dependency graph of the tasks in a list as nodes and edges, tasks of other lists
+// connected to the list are included so that every edge has both of its nodes
+func (h *ListsHandler) Graph() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		listId, hErr := getListId(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to get dependency graph: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		graph, err := requestManager(r, h.TaskManager).DependencyGraph(uData.UserId, listId)
+		if err != nil {
+			listErr(w, err)
+			return
+		}
+
+		blocked := map[string]bool{}
+		done := map[string]bool{}
+		for _, task := range graph.Tasks {
+			done[task.ID] = task.Done
+		}
+		output := localGraph{
+			Nodes: make([]localGraphNode, len(graph.Tasks)),
+			Edges: make([]localGraphEdge, len(graph.Edges)),
+		}
+		for i, e := range graph.Edges {
+			output.Edges[i] = localGraphEdge{From: e.BlockerId, To: e.TaskId}
+			blocked[e.TaskId] = blocked[e.TaskId] || !done[e.BlockerId]
+		}
+		for i, task := range graph.Tasks {
+			output.Nodes[i] = localGraphNode{
+				Id:      task.ID,
+				Text:    task.Text,
+				Done:    task.Done,
+				Blocked: blocked[task.ID],
+				ListId:  task.ListId,
+			}
+		}
+		writeJson(w, output, http.StatusOK)
+	})
+}
@@ -0,0 +1,145 @@
+package handlrs
+
+import (
+	"encoding/json"
+	"github.com/google/go-cmp/cmp"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDependencyHandlers(t *testing.T) {
+	mngr := newTestManager(t)
+	th := TodoListHandler{TaskManager: mngr}
+	lh := ListsHandler{TaskManager: mngr}
+
+	design := createTask(t, mngr, "design", user1)
+	inbox, err := mngr.Inbox(user1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var build localTaskOutput
+	t.Run("create with blockers", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		body := `{"text":"build","blockedBy":["` + design + `"]}`
+		th.Create().ServeHTTP(recorder, userReq(t, "POST", "/api/task", body, user1, nil))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", recorder.Code, http.StatusOK)
+		}
+		err := json.NewDecoder(recorder.Body).Decode(&build)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(build.BlockedBy, []string{design}); diff != "" || !build.Blocked {
+			t.Errorf("unexpected blockers %v (-got +want)\n%s", build.Blocked, diff)
+		}
+	})
+
+	t.Run("graph", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		lh.Graph().ServeHTTP(recorder, userReq(t, "GET", "/api/lists/"+inbox.ID+"/graph", "", user1, map[string]string{"ID": inbox.ID}))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", recorder.Code, http.StatusOK)
+		}
+		got := localGraph{}
+		err := json.NewDecoder(recorder.Body).Decode(&got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := localGraph{
+			Nodes: []localGraphNode{
+				{Id: design, Text: "design", ListId: inbox.ID},
+				{Id: build.Id, Text: "build", Blocked: true, ListId: inbox.ID},
+			},
+			Edges: []localGraphEdge{{From: design, To: build.Id}},
+		}
+		if diff := cmp.Diff(got, want); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+	})
+
+	tcs := []struct {
+		name       string
+		handler    http.Handler
+		target     string
+		body       string
+		vars       map[string]string
+		user       string
+		expectCode int
+		expectErr  string
+	}{
+		{
+			name:       "complete blocked task",
+			handler:    th.Patch(),
+			target:     "/api/task/" + build.Id,
+			body:       `{"Done":true}`,
+			vars:       map[string]string{"ID": build.Id},
+			expectCode: http.StatusConflict,
+			expectErr:  "the task is blocked by tasks that are not done",
+		},
+		{
+			name:       "create a cycle",
+			handler:    th.Patch(),
+			target:     "/api/task/" + design,
+			body:       `{"blockedBy":["` + build.Id + `"]}`,
+			vars:       map[string]string{"ID": design},
+			expectCode: http.StatusBadRequest,
+			expectErr:  "a task cannot depend on itself or on a task that depends on it",
+		},
+		{
+			name:       "unknown blocker",
+			handler:    th.Patch(),
+			target:     "/api/task/" + design,
+			body:       `{"blockedBy":["` + inbox.ID + `"]}`,
+			vars:       map[string]string{"ID": design},
+			expectCode: http.StatusBadRequest,
+			expectErr:  "blocker task not found: " + inbox.ID,
+		},
+		{
+			name:       "invalid force value",
+			handler:    th.Patch(),
+			target:     "/api/task/" + build.Id + "?force=maybe",
+			body:       `{"Done":true}`,
+			vars:       map[string]string{"ID": build.Id},
+			expectCode: http.StatusBadRequest,
+			expectErr:  "unable to convert force value to boolean",
+		},
+		{
+			name:       "graph of other user",
+			handler:    lh.Graph(),
+			target:     "/api/lists/" + inbox.ID + "/graph",
+			vars:       map[string]string{"ID": inbox.ID},
+			user:       user2,
+			expectCode: http.StatusNotFound,
+		},
+		{
+			name:       "force completion",
+			handler:    th.Patch(),
+			target:     "/api/task/" + build.Id + "?force=true",
+			body:       `{"Done":true}`,
+			vars:       map[string]string{"ID": build.Id},
+			expectCode: http.StatusOK,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			user := user1
+			if tc.user != "" {
+				user = tc.user
+			}
+			recorder := httptest.NewRecorder()
+			tc.handler.ServeHTTP(recorder, userReq(t, "PUT", tc.target, tc.body, user, tc.vars))
+			if recorder.Code != tc.expectCode {
+				t.Fatalf("handler returned wrong status code: got %v want %v", recorder.Code, tc.expectCode)
+			}
+			if tc.expectErr != "" {
+				if got := strings.TrimSuffix(recorder.Body.String(), "\n"); got != tc.expectErr {
+					t.Errorf("unexpecter error message: got \"%s\"", got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package handlrs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/go-bumbu/todo-app/internal/model/todolist"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// taskETag is the strong entity tag of a single task, it changes with every change to the task
+func taskETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// bodyETag is a weak entity tag derived from a response body, used for lists that have no version of their own
+func bodyETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `W/"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// entityTags splits the value of an If-Match or If-None-Match header into its entity tags
+func entityTags(header string) []string {
+	tags := []string{}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// ifMatchVersions returns the task versions listed in the If-Match header, nil if the header is
+// missing or "*". Weak tags never match under the strong comparison required by If-Match and are ignored.
+func ifMatchVersions(r *http.Request) ([]int64, *httpErr) {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return nil, nil
+	}
+	versions := []int64{}
+	for _, tag := range entityTags(header) {
+		if tag == "*" {
+			return nil, nil
+		}
+		if !strings.HasPrefix(tag, `"`) || !strings.HasSuffix(tag, `"`) || len(tag) < 2 {
+			continue
+		}
+		v, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, v)
+	}
+	if len(versions) == 0 {
+		return nil, &httpErr{
+			Error: todolist.ErrVersionMismatch.Error(),
+			Code:  http.StatusPreconditionFailed,
+		}
+	}
+	return versions, nil
+}
+
+// expectedVersion picks the version the change has to be applied to out of the versions listed in If-Match,
+// the manager only compares a single version so with several candidates the current one is looked up first
+func expectedVersion(mngr *todolist.Manager, taskId, owner string, versions []int64) (*int64, error) {
+	if versions == nil {
+		return nil, nil
+	}
+	if len(versions) == 1 {
+		return &versions[0], nil
+	}
+	task, err := mngr.Get(taskId, owner)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(versions, task.Version) {
+		return nil, todolist.ErrVersionMismatch
+	}
+	return &task.Version, nil
+}
+
+// noneMatch reports whether the If-None-Match header of the request does not match the entity tag,
+// it uses the weak comparison so W/"1" matches "1"
+func noneMatch(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return true
+	}
+	for _, tag := range entityTags(header) {
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+			return false
+		}
+	}
+	return true
+}
+
+// writeJsonETag works like writeJson but also sets the entity tag of the response, a weak one derived from
+// the body if etag is empty
+func writeJsonETag(w http.ResponseWriter, payload any, code int, etag string) {
+	respJson, err := json.Marshal(payload)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if etag == "" {
+		etag = bodyETag(respJson)
+	}
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_, _ = w.Write(respJson)
+}
@@ -0,0 +1,121 @@
+package handlrs
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTaskETags(t *testing.T) {
+	th := TodoListHandler{TaskManager: newTestManager(t)}
+	taskId := createTask(t, th.TaskManager, "draft", user1)
+	taskVars := map[string]string{"ID": taskId}
+
+	read := func(t *testing.T, ifNoneMatch string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := userReq(t, "GET", "/api/task/"+taskId, "", user1, taskVars)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		recorder := httptest.NewRecorder()
+		th.Read().ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	recorder := read(t, "")
+	if got := recorder.Header().Get("ETag"); got != `"1"` {
+		t.Fatalf("unexpected etag: %s", got)
+	}
+
+	t.Run("not modified", func(t *testing.T) {
+		for _, header := range []string{`"1"`, `W/"1"`, `"7", "1"`, "*"} {
+			recorder := read(t, header)
+			if recorder.Code != http.StatusNotModified {
+				t.Errorf("%s: handler returned wrong status code: got %v want %v", header, recorder.Code, http.StatusNotModified)
+			}
+			if recorder.Body.Len() != 0 {
+				t.Errorf("%s: unexpected body: %s", header, recorder.Body.String())
+			}
+		}
+		if recorder := read(t, `"2"`); recorder.Code != http.StatusOK {
+			t.Errorf("handler returned wrong status code: got %v want %v", recorder.Code, http.StatusOK)
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		th.List().ServeHTTP(recorder, userReq(t, "GET", "/api/tasks", "", user1, nil))
+		if got := recorder.Header().Get("ETag"); !strings.HasPrefix(got, `W/"`) {
+			t.Errorf("unexpected etag: %s", got)
+		}
+	})
+
+	tcs := []struct {
+		name       string
+		method     string
+		ifMatch    string
+		expectCode int
+		expectTag  string
+	}{
+		{
+			name:       "update with stale version",
+			method:     "PATCH",
+			ifMatch:    `"2"`,
+			expectCode: http.StatusPreconditionFailed,
+		},
+		{
+			name:       "weak tags never match",
+			method:     "PATCH",
+			ifMatch:    `W/"1"`,
+			expectCode: http.StatusPreconditionFailed,
+		},
+		{
+			name:       "update with current version",
+			method:     "PATCH",
+			ifMatch:    `"1"`,
+			expectCode: http.StatusOK,
+			expectTag:  `"2"`,
+		},
+		{
+			name:       "any of several versions",
+			method:     "PATCH",
+			ifMatch:    `"1", "2"`,
+			expectCode: http.StatusOK,
+			expectTag:  `"3"`,
+		},
+		{
+			name:       "delete with stale version",
+			method:     "DELETE",
+			ifMatch:    `"1"`,
+			expectCode: http.StatusPreconditionFailed,
+		},
+		{
+			name:       "delete with current version",
+			method:     "DELETE",
+			ifMatch:    `"3"`,
+			expectCode: http.StatusAccepted,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			if tc.method == "PATCH" {
+				req := userReq(t, "PATCH", "/api/task/"+taskId, `{"priority":"high"}`, user1, taskVars)
+				req.Header.Set("If-Match", tc.ifMatch)
+				th.Patch().ServeHTTP(recorder, req)
+			} else {
+				req := userReq(t, "DELETE", "/api/task/"+taskId, "", user1, taskVars)
+				req.Header.Set("If-Match", tc.ifMatch)
+				th.Delete().ServeHTTP(recorder, req)
+			}
+			if recorder.Code != tc.expectCode {
+				t.Errorf("handler returned wrong status code: got %v want %v", recorder.Code, tc.expectCode)
+			}
+			if got := recorder.Header().Get("ETag"); got != tc.expectTag {
+				t.Errorf("unexpected etag: got %s want %s", got, tc.expectTag)
+			}
+		})
+	}
+}
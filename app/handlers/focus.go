@@ -0,0 +1,49 @@
+package handlrs
+
+import (
+	"fmt"
+	"github.com/go-bumbu/todo-app/internal/model/todolist"
+	"github.com/go-bumbu/userauth/handlers/sessionauth"
+	"net/http"
+	"time"
+)
+
+// tzParam is the time zone used to decide which tasks are due today
+const tzParam = "tz"
+
+// Focus lists the pending tasks that need attention ranked by priority and due date,
+// it accepts the same filters as List
+func (h *TodoListHandler) Focus() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to list focus tasks: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		limit, _, hErr := getPaging(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		scopes, hErr := taskFilters(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		loc, err := time.LoadLocation(r.URL.Query().Get(tzParam))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid time zone: %s", r.URL.Query().Get(tzParam)), http.StatusBadRequest)
+			return
+		}
+
+		tasks, err := requestManager(r, h.TaskManager).Focus(uData.UserId, limit, time.Now().In(loc), scopes...)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to list focus tasks: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		writeTaskList(w, requestManager(r, h.TaskManager), todolist.TaskPage{Tasks: tasks, Total: int64(len(tasks))}, subtasksNone)
+	})
+}
@@ -0,0 +1,94 @@
+package handlrs
+
+import (
+	"encoding/json"
+	"github.com/google/go-cmp/cmp"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTodoListHandler_Focus(t *testing.T) {
+	th := TodoListHandler{TaskManager: newTestManager(t)}
+
+	create := func(t *testing.T, body string) (int, string) {
+		recorder := httptest.NewRecorder()
+		th.Create().ServeHTTP(recorder, userReq(t, "POST", "/api/task", body, user1, nil))
+		return recorder.Code, strings.TrimSuffix(recorder.Body.String(), "\n")
+	}
+
+	for _, body := range []string{
+		`{"text":"someday"}`,
+		`{"text":"call the plumber","priority":"high"}`,
+		`{"text":"pay taxes","priority":"medium","important":true,"urgent":true}`,
+		`{"text":"water plants","priority":"low","dueDate":"2000-01-01"}`,
+		`{"text":"done already","priority":"urgent","done":true}`,
+	} {
+		if code, resp := create(t, body); code != http.StatusOK {
+			t.Fatalf("unable to create task: %d %s", code, resp)
+		}
+	}
+
+	t.Run("priority in output", func(t *testing.T) {
+		code, resp := create(t, `{"text":"urgent task","priority":"Urgent","urgent":true}`)
+		if code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", code, http.StatusOK)
+		}
+		got := localTaskOutput{}
+		err := json.Unmarshal([]byte(resp), &got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Priority != "urgent" || !got.Urgent || got.Important {
+			t.Errorf("unexpected priority values: %+v", got)
+		}
+
+		recorder := httptest.NewRecorder()
+		req := userReq(t, "PATCH", "/api/task/"+got.Id, `{"priority":"none","urgent":false}`, user1, map[string]string{"ID": got.Id})
+		th.Patch().ServeHTTP(recorder, req)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", recorder.Code, http.StatusOK)
+		}
+	})
+
+	t.Run("invalid priority", func(t *testing.T) {
+		code, resp := create(t, `{"text":"asap","priority":"asap"}`)
+		if code != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v", code, http.StatusBadRequest)
+		}
+		want := `invalid priority: unknown priority "asap", use one of: none, low, medium, high, urgent`
+		if resp != want {
+			t.Errorf("unexpecter error message: got \"%s\"", resp)
+		}
+	})
+
+	t.Run("focus list", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		th.Focus().ServeHTTP(recorder, userReq(t, "GET", "/api/tasks/focus?tz=Europe/Zurich", "", user1, nil))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", recorder.Code, http.StatusOK)
+		}
+		got := localTaskList{}
+		err := json.NewDecoder(recorder.Body).Decode(&got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		texts := []string{}
+		for _, task := range got.Tasks {
+			texts = append(texts, task.Text)
+		}
+		want := []string{"water plants", "pay taxes", "call the plumber"}
+		if diff := cmp.Diff(texts, want); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+	})
+
+	t.Run("invalid time zone", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		th.Focus().ServeHTTP(recorder, userReq(t, "GET", "/api/tasks/focus?tz=Mars/Base", "", user1, nil))
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v", recorder.Code, http.StatusBadRequest)
+		}
+	})
+}
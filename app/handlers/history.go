@@ -0,0 +1,136 @@
+package handlrs
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-bumbu/todo-app/internal/model/todolist"
+	"github.com/go-bumbu/userauth/handlers/sessionauth"
+	"net/http"
+	"time"
+)
+
+// RequestIdHeader carries the id of a request, it is logged and stored in the history of the changed tasks
+const RequestIdHeader = "Request-Id"
+
+// requestAudit identifies the user and the request that change the tasks
+func requestAudit(r *http.Request, user string) todolist.Audit {
+	return todolist.Audit{Actor: user, RequestId: r.Header.Get(RequestIdHeader)}
+}
+
+type localHistory struct {
+	Count   int
+	Changes []localChange
+}
+
+type localChange struct {
+	Id        string             `json:"id"`
+	Action    string             `json:"action"`
+	Actor     string             `json:"actor"`
+	RequestId string             `json:"requestId,omitempty"`
+	CreatedAt string             `json:"createdAt"`
+	Fields    []localFieldChange `json:"fields"`
+}
+
+type localFieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// History returns the changes made to a task, the most recent first
+func (h *TodoListHandler) History() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		taskId, hErr := getTaskId(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to get history: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		changes, err := requestManager(r, h.TaskManager).History(taskId, uData.UserId)
+		if err != nil {
+			t := &todolist.ItemNotFountErr{}
+			if errors.As(err, &t) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+			} else {
+				http.Error(w, fmt.Sprintf("unable to get history: %s", err.Error()), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		output := localHistory{
+			Count:   len(changes),
+			Changes: make([]localChange, len(changes)),
+		}
+		for i, c := range changes {
+			fields := make([]localFieldChange, len(c.Fields))
+			for j, f := range c.Fields {
+				fields[j] = localFieldChange{Field: f.Field, Old: f.OldValue, New: f.NewValue}
+			}
+			output.Changes[i] = localChange{
+				Id:        c.ID,
+				Action:    c.Action,
+				Actor:     c.Actor,
+				RequestId: c.RequestId,
+				CreatedAt: c.CreatedAt.UTC().Format(time.RFC3339),
+				Fields:    fields,
+			}
+		}
+		writeJson(w, output, http.StatusOK)
+	})
+}
+
+// Revert restores a task to the version it had after a change of its history and returns the reverted task
+func (h *TodoListHandler) Revert() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		taskId, hErr := getTaskId(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+		changeId, hErr := getUuidVar(r, "ChangeID", "change")
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to revert task: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		err = requestManager(r, h.TaskManager).WithAudit(requestAudit(r, uData.UserId)).Revert(taskId, uData.UserId, changeId)
+		if err != nil {
+			t := &todolist.ItemNotFountErr{}
+			c := &todolist.ChangeNotFoundErr{}
+			if errors.Is(err, todolist.ErrRevertConflict) || errors.Is(err, todolist.ErrTaskBlocked) {
+				http.Error(w, err.Error(), http.StatusConflict)
+			} else if errors.As(err, &t) || errors.As(err, &c) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+			} else if errors.Is(err, todolist.ErrPermissionDenied) {
+				http.Error(w, err.Error(), http.StatusForbidden)
+			} else {
+				http.Error(w, fmt.Sprintf("unable to revert task: %s", err.Error()), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		task, err := requestManager(r, h.TaskManager).Get(taskId, uData.UserId)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to read task: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		outputs, err := taskOutputs(requestManager(r, h.TaskManager), task.OwnerId, []todolist.TodoItem{task}, subtasksNone)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to get dependencies: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		writeJson(w, outputs[0], http.StatusOK)
+	})
+}
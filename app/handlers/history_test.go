@@ -0,0 +1,112 @@
+package handlrs
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHistoryHandler(t *testing.T) {
+	th := TodoListHandler{TaskManager: newTestManager(t)}
+	taskId := createTask(t, th.TaskManager, "draft", user1)
+	taskVars := map[string]string{"ID": taskId}
+
+	req := userReq(t, "PATCH", "/api/task/"+taskId, `{"priority":"high"}`, user1, taskVars)
+	req.Header.Set(RequestIdHeader, "req-42")
+	recorder := httptest.NewRecorder()
+	th.Patch().ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", recorder.Code, http.StatusOK)
+	}
+
+	history := func(t *testing.T) localHistory {
+		t.Helper()
+		recorder := httptest.NewRecorder()
+		th.History().ServeHTTP(recorder, userReq(t, "GET", "/api/task/"+taskId+"/history", "", user1, taskVars))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", recorder.Code, http.StatusOK)
+		}
+		got := localHistory{}
+		if err := json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	got := history(t)
+	if got.Count != 2 {
+		t.Fatalf("unexpected history: %+v", got)
+	}
+	update := got.Changes[0]
+	if update.Action != "update" || update.Actor != user1 || update.RequestId != "req-42" {
+		t.Errorf("unexpected change: %+v", update)
+	}
+	if len(update.Fields) != 1 || update.Fields[0] != (localFieldChange{Field: "priority", Old: "none", New: "high"}) {
+		t.Errorf("unexpected fields: %+v", update.Fields)
+	}
+	created := got.Changes[1].Id
+
+	t.Run("revert", func(t *testing.T) {
+		vars := map[string]string{"ID": taskId, "ChangeID": created}
+		recorder := httptest.NewRecorder()
+		th.Revert().ServeHTTP(recorder, userReq(t, "POST", "/api/task/"+taskId+"/history/"+created+"/revert", "", user1, vars))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", recorder.Code, http.StatusOK)
+		}
+		task := localTaskOutput{}
+		if err := json.NewDecoder(recorder.Body).Decode(&task); err != nil {
+			t.Fatal(err)
+		}
+		if task.Priority != "" {
+			t.Errorf("unexpected priority: %s", task.Priority)
+		}
+		if got := history(t); got.Count != 3 || got.Changes[0].Action != "revert" {
+			t.Errorf("unexpected history: %+v", got)
+		}
+	})
+
+	tcs := []struct {
+		name       string
+		user       string
+		vars       map[string]string
+		expectCode int
+		expectErr  string
+	}{
+		{
+			name:       "task of other user",
+			user:       user2,
+			vars:       map[string]string{"ID": taskId, "ChangeID": created},
+			expectCode: http.StatusNotFound,
+			expectErr:  "task with id: " + taskId + " and owner " + user2 + " not found",
+		},
+		{
+			name:       "unknown change",
+			user:       user1,
+			vars:       map[string]string{"ID": taskId, "ChangeID": "b4a5e3a8-0c36-4a53-a0d5-0a8a4b0ddd1e"},
+			expectCode: http.StatusNotFound,
+			expectErr:  "change with id: b4a5e3a8-0c36-4a53-a0d5-0a8a4b0ddd1e not found in the history of task " + taskId,
+		},
+		{
+			name:       "invalid change id",
+			user:       user1,
+			vars:       map[string]string{"ID": taskId, "ChangeID": "1"},
+			expectCode: http.StatusBadRequest,
+			expectErr:  "change id is not a UUID",
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			th.Revert().ServeHTTP(recorder, userReq(t, "POST", "/api/task/"+taskId+"/history/x/revert", "", tc.user, tc.vars))
+			if recorder.Code != tc.expectCode {
+				t.Errorf("handler returned wrong status code: got %v want %v", recorder.Code, tc.expectCode)
+			}
+			if got := strings.TrimSuffix(recorder.Body.String(), "\n"); got != tc.expectErr {
+				t.Errorf("unexpecter error message: got \"%s\"", got)
+			}
+		})
+	}
+}
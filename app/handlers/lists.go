@@ -0,0 +1,338 @@
+package handlrs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-bumbu/todo-app/internal/model/todolist"
+	"github.com/go-bumbu/userauth"
+	"github.com/go-bumbu/userauth/handlers/sessionauth"
+	"net/http"
+	"strconv"
+)
+
+// ListsHandler exposes the todo lists (projects) of a user
+type ListsHandler struct {
+	TaskManager *todolist.Manager
+	// Users is used to check that invited users exist, invitations are not checked if it is nil
+	Users userauth.UserGetter
+}
+
+type localListList struct {
+	Count int
+	Lists []localListOutput
+}
+
+type localListInput struct {
+	Name     *string `json:"name"`
+	Color    *string `json:"color"`
+	Position *int    `json:"position"`
+	Archived *bool   `json:"archived"`
+}
+
+type localListOutput struct {
+	Id       string `json:"id"`
+	Name     string `json:"name"`
+	Color    string `json:"color"`
+	Position int    `json:"position"`
+	Archived bool   `json:"archived"`
+	Inbox    bool   `json:"inbox"`
+	// Owner and Role are only set on lists shared by other users
+	Owner string `json:"owner,omitempty"`
+	Role  string `json:"role,omitempty"`
+}
+
+func listOutput(list todolist.TodoList) localListOutput {
+	return localListOutput{
+		Id:       list.ID,
+		Name:     list.Name,
+		Color:    list.Color,
+		Position: list.Position,
+		Archived: list.Archived,
+		Inbox:    list.IsInbox,
+	}
+}
+
+// sharedListOutput adds the owner and the role of the user to lists that are shared by other users
+func sharedListOutput(list todolist.TodoList, user string, role todolist.Role) localListOutput {
+	out := listOutput(list)
+	if list.OwnerId != user {
+		out.Owner = list.OwnerId
+		out.Role = string(role)
+	}
+	return out
+}
+
+const archivedParam = "archived"
+const cascadeParam = "cascade"
+
+func (h *ListsHandler) List() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to list lists: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		archived := false
+		archivedStr := r.URL.Query().Get(archivedParam)
+		if archivedStr != "" {
+			archived, err = strconv.ParseBool(archivedStr)
+			if err != nil {
+				http.Error(w, "unable to convert archived value to boolean", http.StatusBadRequest)
+				return
+			}
+		}
+
+		items, err := requestManager(r, h.TaskManager).Lists(uData.UserId, archived)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to get lists: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		output := localListList{
+			Count: len(items),
+			Lists: make([]localListOutput, len(items)),
+		}
+		for i := range items {
+			output.Lists[i] = listOutput(items[i])
+		}
+		writeJson(w, output, http.StatusOK)
+	})
+}
+
+func (h *ListsHandler) Create() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to create list: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		if r.Body == nil {
+			http.Error(w, "request had empty body", http.StatusBadRequest)
+			return
+		}
+		payload := localListInput{}
+		err = json.NewDecoder(r.Body).Decode(&payload)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to decode json: %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		if payload.Name == nil || *payload.Name == "" {
+			http.Error(w, "name cannot be empty in list payload", http.StatusBadRequest)
+			return
+		}
+
+		l := todolist.TodoList{
+			OwnerId: uData.UserId,
+			Name:    *payload.Name,
+		}
+		if payload.Color != nil {
+			l.Color = *payload.Color
+		}
+		if payload.Position != nil {
+			l.Position = *payload.Position
+		}
+		if payload.Archived != nil {
+			l.Archived = *payload.Archived
+		}
+
+		_, err = requestManager(r, h.TaskManager).CreateList(&l)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to store list in DB: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		writeJson(w, listOutput(l), http.StatusOK)
+	})
+}
+
+func (h *ListsHandler) Read() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		listId, hErr := getListId(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to read list: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		list, role, err := requestManager(r, h.TaskManager).ListAccess(listId, uData.UserId)
+		if err != nil {
+			listErr(w, err)
+			return
+		}
+		writeJson(w, sharedListOutput(list, uData.UserId, role), http.StatusOK)
+	})
+}
+
+func (h *ListsHandler) Update() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		listId, hErr := getListId(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to update list: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		if r.Body == nil {
+			http.Error(w, "request had empty body", http.StatusBadRequest)
+			return
+		}
+		payload := localListInput{}
+		err = json.NewDecoder(r.Body).Decode(&payload)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to decode json: %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+		if payload.Name != nil && *payload.Name == "" {
+			http.Error(w, "name cannot be empty in list payload", http.StatusBadRequest)
+			return
+		}
+
+		err = requestManager(r, h.TaskManager).UpdateList(listId, uData.UserId, todolist.ListUpdate{
+			Name:     payload.Name,
+			Color:    payload.Color,
+			Position: payload.Position,
+			Archived: payload.Archived,
+		})
+		if err != nil {
+			listErr(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+// Delete removes a list, by default the tasks are moved into the inbox, pass cascade=true to delete them as well
+func (h *ListsHandler) Delete() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		listId, hErr := getListId(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to delete list: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		cascade := false
+		cascadeStr := r.URL.Query().Get(cascadeParam)
+		if cascadeStr != "" {
+			cascade, err = strconv.ParseBool(cascadeStr)
+			if err != nil {
+				http.Error(w, "unable to convert cascade value to boolean", http.StatusBadRequest)
+				return
+			}
+		}
+
+		err = requestManager(r, h.TaskManager).DeleteList(listId, uData.UserId, cascade)
+		if err != nil {
+			listErr(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+// Tasks lists the tasks that belong to a single list, it accepts the same paging and filter parameters as the task list
+func (h *ListsHandler) Tasks() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		listId, hErr := getListId(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to list task: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		pageReq, hErr := getPageRequest(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		scopes, hErr := taskFilters(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		mode, hErr := getSubtaskMode(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+		if mode != subtasksNone {
+			scopes = append(scopes, todolist.RootTasks())
+		}
+
+		// the tasks of a shared list belong to the owner of the list
+		list, _, err := requestManager(r, h.TaskManager).ListAccess(listId, uData.UserId)
+		if err != nil {
+			listErr(w, err)
+			return
+		}
+
+		scopes = append(scopes, todolist.InList(listId))
+		page, err := requestManager(r, h.TaskManager).ListPage(list.OwnerId, pageReq, scopes...)
+		if err != nil {
+			if errors.Is(err, todolist.ErrInvalidCursor) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			} else {
+				http.Error(w, fmt.Sprintf("unable to get task: %s", err.Error()), http.StatusInternalServerError)
+			}
+			return
+		}
+		writeTaskList(w, requestManager(r, h.TaskManager), page, mode)
+	})
+}
+
+// listErr writes the http error matching an error returned by the list methods of the manager
+func listErr(w http.ResponseWriter, err error) {
+	lErr := &todolist.ListNotFoundErr{}
+	mErr := &todolist.MemberNotFoundErr{}
+	if errors.As(err, &lErr) || errors.As(err, &mErr) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+	} else if errors.Is(err, todolist.ErrInboxList) || errors.Is(err, todolist.ErrInvalidRole) ||
+		errors.Is(err, todolist.ErrShareInbox) || errors.Is(err, todolist.ErrShareWithOwner) ||
+		errors.Is(err, todolist.ErrOtherWorkspace) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	} else if errors.Is(err, todolist.ErrPermissionDenied) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+	} else {
+		http.Error(w, fmt.Sprintf("unable to process list: %s", err.Error()), http.StatusInternalServerError)
+	}
+}
+
+func getListId(r *http.Request) (string, *httpErr) {
+	return getUuidVar(r, "ID", "list")
+}
+
+func writeJson(w http.ResponseWriter, payload any, code int) {
+	respJson, err := json.Marshal(payload)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_, _ = w.Write(respJson)
+}
@@ -0,0 +1,201 @@
+package handlrs
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/glebarez/sqlite"
+	"github.com/go-bumbu/todo-app/internal/model/todolist"
+	"github.com/go-bumbu/userauth/handlers/sessionauth"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestManager returns a manager backed by an isolated sqlite DB
+func newTestManager(t *testing.T) *todolist.Manager {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mngr, err := todolist.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mngr
+}
+
+// userReq creates a request with the user data set in the context
+func userReq(t *testing.T, method, target, body, user string, vars map[string]string) *http.Request {
+	t.Helper()
+	var reader io.Reader
+	if body != "" {
+		reader = bytes.NewBufferString(body)
+	}
+	req, err := http.NewRequest(method, target, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user != "" {
+		sessionauth.CtxSetUserData(req, sessionauth.SessionData{
+			UserData: sessionauth.UserData{
+				UserId:          user,
+				IsAuthenticated: true,
+			},
+		})
+	}
+	if vars != nil {
+		req = mux.SetURLVars(req, vars)
+	}
+	return req
+}
+
+func TestListsHandler(t *testing.T) {
+	lh := ListsHandler{TaskManager: newTestManager(t)}
+
+	// create a list
+	recorder := httptest.NewRecorder()
+	lh.Create().ServeHTTP(recorder, userReq(t, "POST", "/api/lists", `{"name":"work","color":"red"}`, user1, nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", recorder.Code, http.StatusOK)
+	}
+	work := localListOutput{}
+	err := json.NewDecoder(recorder.Body).Decode(&work)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsValidUUID(work.Id) {
+		t.Error("returned list ID is not a valid UUID")
+	}
+
+	// list the lists
+	recorder = httptest.NewRecorder()
+	lh.List().ServeHTTP(recorder, userReq(t, "GET", "/api/lists", "", user1, nil))
+	got := localListList{}
+	err = json.NewDecoder(recorder.Body).Decode(&got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := localListList{
+		Count: 2,
+		Lists: []localListOutput{
+			{Name: todolist.InboxName, Inbox: true},
+			{Name: "work", Color: "red", Position: 1},
+		},
+	}
+	if diff := cmp.Diff(got, want, cmpopts.IgnoreFields(localListOutput{}, "Id")); diff != "" {
+		t.Errorf("unexpected value (-got +want)\n%s", diff)
+	}
+
+	tcs := []struct {
+		name       string
+		handler    http.Handler
+		req        *http.Request
+		expecErr   string
+		expectCode int
+	}{
+		{
+			name:       "read list",
+			handler:    lh.Read(),
+			req:        userReq(t, "GET", "/api/lists/"+work.Id, "", user1, map[string]string{"ID": work.Id}),
+			expectCode: http.StatusOK,
+		},
+		{
+			name:       "read list of other user",
+			handler:    lh.Read(),
+			req:        userReq(t, "GET", "/api/lists/"+work.Id, "", user2, map[string]string{"ID": work.Id}),
+			expecErr:   "list with id: " + work.Id + " and owner user2 not found",
+			expectCode: http.StatusNotFound,
+		},
+		{
+			name:       "empty name on create",
+			handler:    lh.Create(),
+			req:        userReq(t, "POST", "/api/lists", `{"color":"red"}`, user1, nil),
+			expecErr:   "name cannot be empty in list payload",
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			name:       "malformed list id",
+			handler:    lh.Tasks(),
+			req:        userReq(t, "GET", "/api/lists/ddd/tasks", "", user1, map[string]string{"ID": "ddd"}),
+			expecErr:   "list id is not a UUID",
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			name:       "delete inbox",
+			handler:    lh.Delete(),
+			req:        userReq(t, "DELETE", "/api/lists/"+got.Lists[0].Id, "", user1, map[string]string{"ID": got.Lists[0].Id}),
+			expecErr:   todolist.ErrInboxList.Error(),
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			name:       "delete list",
+			handler:    lh.Delete(),
+			req:        userReq(t, "DELETE", "/api/lists/"+work.Id+"?cascade=true", "", user1, map[string]string{"ID": work.Id}),
+			expectCode: http.StatusAccepted,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			tc.handler.ServeHTTP(recorder, tc.req)
+
+			if status := recorder.Code; status != tc.expectCode {
+				t.Errorf("handler returned wrong status code: got %v want %v",
+					status, tc.expectCode)
+			}
+			if tc.expecErr != "" {
+				got := strings.TrimSuffix(recorder.Body.String(), "\n")
+				if got != tc.expecErr {
+					t.Errorf("unexpecter error message: got \"%s\" want \"%v\"",
+						got, tc.expecErr)
+				}
+			}
+		})
+	}
+}
+
+func TestListsHandler_Tasks(t *testing.T) {
+	lh := ListsHandler{TaskManager: newTestManager(t)}
+	th := TodoListHandler{TaskManager: lh.TaskManager}
+
+	list := todolist.TodoList{Name: "groceries", OwnerId: user1}
+	listId, err := lh.TaskManager.CreateList(&list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = createTask(t, lh.TaskManager, "in inbox", user1)
+
+	recorder := httptest.NewRecorder()
+	th.Create().ServeHTTP(recorder, userReq(t, "POST", "/api/task", `{"text":"milk","listId":"`+listId+`"}`, user1, nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", recorder.Code, http.StatusOK)
+	}
+
+	recorder = httptest.NewRecorder()
+	lh.Tasks().ServeHTTP(recorder, userReq(t, "GET", "/api/lists/"+listId+"/tasks", "", user1, map[string]string{"ID": listId}))
+	got := localTaskList{}
+	err = json.NewDecoder(recorder.Body).Decode(&got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := localTaskList{
+		Count: 1,
+		Total: 1,
+		Tasks: []localTaskOutput{{Text: "milk", ListId: listId, Version: 1}},
+	}
+	if diff := cmp.Diff(got, want, cmpopts.IgnoreFields(localTaskOutput{}, "Id", "Position", "StatusId")); diff != "" {
+		t.Errorf("unexpected value (-got +want)\n%s", diff)
+	}
+}
@@ -0,0 +1,66 @@
+package handlrs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-bumbu/todo-app/internal/markdown"
+	"github.com/go-bumbu/todo-app/internal/model/todolist"
+	"github.com/go-bumbu/userauth/handlers/sessionauth"
+	"net/http"
+)
+
+type localCheckbox struct {
+	// Index is the data-index attribute of the checkbox in the rendered notes
+	Index   *int `json:"index"`
+	Checked bool `json:"checked"`
+}
+
+// Checkbox checks or unchecks a task list item in the notes of a task
+func (h *TodoListHandler) Checkbox() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		taskId, hErr := getTaskId(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to update notes: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		if r.Body == nil {
+			http.Error(w, "request had empty body", http.StatusBadRequest)
+			return
+		}
+		payload := localCheckbox{}
+		err = json.NewDecoder(r.Body).Decode(&payload)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to decode json: %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+		if payload.Index == nil {
+			http.Error(w, "index is required", http.StatusBadRequest)
+			return
+		}
+
+		mngr := requestManager(r, h.TaskManager).WithAudit(requestAudit(r, uData.UserId))
+		err = mngr.SetCheckbox(taskId, uData.UserId, *payload.Index, payload.Checked)
+		if err != nil {
+			t := &todolist.ItemNotFountErr{}
+			if errors.As(err, &t) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+			} else if errors.Is(err, markdown.ErrNoCheckbox) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			} else if errors.Is(err, todolist.ErrPermissionDenied) {
+				http.Error(w, err.Error(), http.StatusForbidden)
+			} else {
+				http.Error(w, fmt.Sprintf("unable to update notes: %s", err.Error()), http.StatusInternalServerError)
+			}
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
@@ -0,0 +1,104 @@
+package handlrs
+
+import (
+	"encoding/json"
+	"github.com/go-bumbu/todo-app/internal/model/todolist"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNotesHandlers(t *testing.T) {
+	mngr := newTestManager(t)
+	th := TodoListHandler{TaskManager: mngr}
+
+	task := todolist.TodoItem{Text: "trip", OwnerId: user1, Notes: "- [ ] passport\n- [ ] <b>tickets</b>"}
+	_, err := mngr.Create(&task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vars := map[string]string{"ID": task.ID}
+
+	read := func(t *testing.T) localTaskOutput {
+		t.Helper()
+		recorder := httptest.NewRecorder()
+		th.Read().ServeHTTP(recorder, userReq(t, "GET", "/api/task/"+task.ID, "", user1, vars))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", recorder.Code, http.StatusOK)
+		}
+		got := localTaskOutput{}
+		err := json.NewDecoder(recorder.Body).Decode(&got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	t.Run("read rendered notes", func(t *testing.T) {
+		got := read(t)
+		want := "<ul>\n<li class=\"task\"><input type=\"checkbox\" data-index=\"0\"> passport</li>\n" +
+			"<li class=\"task\"><input type=\"checkbox\" data-index=\"1\"> &lt;b&gt;tickets&lt;/b&gt;</li>\n</ul>\n"
+		if got.NotesHtml != want {
+			t.Errorf("unexpected html: %q", got.NotesHtml)
+		}
+	})
+
+	tcs := []struct {
+		name       string
+		body       string
+		user       string
+		expectCode int
+		expectErr  string
+	}{
+		{
+			name:       "missing index",
+			body:       `{"checked":true}`,
+			expectCode: http.StatusBadRequest,
+			expectErr:  "index is required",
+		},
+		{
+			name:       "unknown checkbox",
+			body:       `{"index":2,"checked":true}`,
+			expectCode: http.StatusBadRequest,
+			expectErr:  "checkbox not found",
+		},
+		{
+			name:       "task of other user",
+			body:       `{"index":0,"checked":true}`,
+			user:       user2,
+			expectCode: http.StatusNotFound,
+			expectErr:  "task with id: " + task.ID + " and owner " + user2 + " not found",
+		},
+		{
+			name:       "check item",
+			body:       `{"index":1,"checked":true}`,
+			expectCode: http.StatusAccepted,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			user := user1
+			if tc.user != "" {
+				user = tc.user
+			}
+			recorder := httptest.NewRecorder()
+			th.Checkbox().ServeHTTP(recorder, userReq(t, "POST", "/api/task/"+task.ID+"/checkbox", tc.body, user, vars))
+			if recorder.Code != tc.expectCode {
+				t.Fatalf("handler returned wrong status code: got %v want %v", recorder.Code, tc.expectCode)
+			}
+			if tc.expectErr != "" {
+				if got := strings.TrimSuffix(recorder.Body.String(), "\n"); got != tc.expectErr {
+					t.Errorf("unexpecter error message: got \"%s\"", got)
+				}
+			}
+		})
+	}
+
+	t.Run("notes after toggle", func(t *testing.T) {
+		if got := read(t).Notes; got != "- [ ] passport\n- [x] <b>tickets</b>" {
+			t.Errorf("unexpected notes: %q", got)
+		}
+	})
+}
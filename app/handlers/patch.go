@@ -0,0 +1,188 @@
+package handlrs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/go-bumbu/todo-app/internal/model/todolist"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// mergePatchType is the media type of JSON merge patch documents, see RFC 7396
+const mergePatchType = "application/merge-patch+json"
+
+// taskChanges is a decoded update payload, unlike the other fields the text and the list are plain strings
+// in the payload, so they are only changed when text and listId are set
+type taskChanges struct {
+	localTaskInput
+	text   *string
+	listId *string
+}
+
+// taskDefaults resets a field of the payload to the value of a new task unless the field is set,
+// the keys are the lower case json names of the fields
+var taskDefaults = map[string]func(p *localTaskInput){
+	"done": func(p *localTaskInput) {
+		if p.Done == nil {
+			p.Done = new(bool)
+		}
+	},
+	"notes": func(p *localTaskInput) {
+		if p.Notes == nil {
+			p.Notes = new(string)
+		}
+	},
+	// an empty status moves the task into the first open or done status
+	"statusid": func(p *localTaskInput) {
+		if p.StatusId == nil {
+			p.StatusId = new(string)
+		}
+	},
+	"duedate": func(p *localTaskInput) {
+		if p.DueDate == nil {
+			p.DueDate = new(string)
+		}
+	},
+	"startdate": func(p *localTaskInput) {
+		if p.StartDate == nil {
+			p.StartDate = new(string)
+		}
+	},
+	"timezone": func(p *localTaskInput) {
+		if p.TimeZone == nil {
+			p.TimeZone = new(string)
+		}
+	},
+	"tags": func(p *localTaskInput) {
+		if p.Tags == nil {
+			p.Tags = &[]string{}
+		}
+	},
+	"parentid": func(p *localTaskInput) {
+		if p.ParentId == nil {
+			p.ParentId = new(string)
+		}
+	},
+	"recurrence": func(p *localTaskInput) {
+		if p.Recurrence == nil {
+			p.Recurrence = new(string)
+		}
+	},
+	"priority": func(p *localTaskInput) {
+		if p.Priority == nil {
+			none := todolist.PriorityNone.String()
+			p.Priority = &none
+		}
+	},
+	"important": func(p *localTaskInput) {
+		if p.Important == nil {
+			p.Important = new(bool)
+		}
+	},
+	"urgent": func(p *localTaskInput) {
+		if p.Urgent == nil {
+			p.Urgent = new(bool)
+		}
+	},
+	"blockedby": func(p *localTaskInput) {
+		if p.BlockedBy == nil {
+			p.BlockedBy = &[]string{}
+		}
+	},
+	"assignee": func(p *localTaskInput) {
+		if p.Assignee == nil {
+			p.Assignee = new(string)
+		}
+	},
+}
+
+// placementFields are the fields of a replacement that are left untouched when missing, as they place the task
+// rather than describe it
+var placementFields = map[string]bool{"listid": true, "parentid": true}
+
+// decodeReplacement decodes the full representation of a task, fields missing in the payload are reset
+// to the values of a new task, except for the list and the parent that keep the task where it is
+func decodeReplacement(r *http.Request) (taskChanges, *httpErr) {
+	c := taskChanges{}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return c, &httpErr{Error: fmt.Sprintf("unable to read body: %s", err.Error()), Code: http.StatusBadRequest}
+	}
+	err = json.NewDecoder(bytes.NewReader(body)).Decode(&c.localTaskInput)
+	members := map[string]json.RawMessage{}
+	if err == nil {
+		err = json.Unmarshal(body, &members)
+	}
+	if err != nil {
+		return c, &httpErr{Error: fmt.Sprintf("unable to decode json: %s", err.Error()), Code: http.StatusBadRequest}
+	}
+	if c.Text == "" {
+		return c, &httpErr{Error: "text cannot be empty req task payload", Code: http.StatusBadRequest}
+	}
+	c.text = &c.Text
+
+	// member names are matched case-insensitively like encoding/json does
+	present := map[string]bool{}
+	for name := range members {
+		present[strings.ToLower(name)] = true
+	}
+	if present["listid"] {
+		c.listId = &c.ListId
+	}
+	for name, reset := range taskDefaults {
+		if placementFields[name] && !present[name] {
+			continue
+		}
+		reset(&c.localTaskInput)
+	}
+	return c, nil
+}
+
+// decodeMergePatch decodes a JSON merge patch of a task: only the members of the patch are changed and
+// members set to null are reset to the values of a new task, the text cannot be removed
+func decodeMergePatch(r *http.Request) (taskChanges, *httpErr) {
+	c := taskChanges{}
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		mediaType, _, err := mime.ParseMediaType(ct)
+		if err != nil || (mediaType != mergePatchType && mediaType != "application/json") {
+			return c, &httpErr{
+				Error: fmt.Sprintf("content type must be %s", mergePatchType),
+				Code:  http.StatusUnsupportedMediaType,
+			}
+		}
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return c, &httpErr{Error: fmt.Sprintf("unable to read body: %s", err.Error()), Code: http.StatusBadRequest}
+	}
+	members := map[string]json.RawMessage{}
+	err = json.Unmarshal(body, &members)
+	if err == nil {
+		err = json.Unmarshal(body, &c.localTaskInput)
+	}
+	if err != nil {
+		return c, &httpErr{Error: fmt.Sprintf("unable to decode json: %s", err.Error()), Code: http.StatusBadRequest}
+	}
+
+	// member names are matched case-insensitively like encoding/json does
+	for name, value := range members {
+		name = strings.ToLower(name)
+		switch name {
+		case "text":
+			if c.Text == "" {
+				return c, &httpErr{Error: "text cannot be empty req task payload", Code: http.StatusBadRequest}
+			}
+			c.text = &c.Text
+		case "listid":
+			c.listId = &c.ListId
+		}
+		if reset, ok := taskDefaults[name]; ok && string(value) == "null" {
+			reset(&c.localTaskInput)
+		}
+	}
+	return c, nil
+}
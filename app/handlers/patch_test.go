@@ -0,0 +1,210 @@
+package handlrs
+
+import (
+	"encoding/json"
+	"github.com/glebarez/sqlite"
+	"github.com/go-bumbu/todo-app/internal/model/todolist"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTaskHandler_Patch(t *testing.T) {
+	th := TodoListHandler{TaskManager: newTestManager(t)}
+
+	save := func(t *testing.T, handler http.Handler, method, taskId, body string) localTaskOutput {
+		t.Helper()
+		recorder := httptest.NewRecorder()
+		req := userReq(t, method, "/api/task/"+taskId, body, user1, map[string]string{"ID": taskId})
+		handler.ServeHTTP(recorder, req)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v: %s", recorder.Code, http.StatusOK, recorder.Body.String())
+		}
+		got := localTaskOutput{}
+		if err := json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if etag := recorder.Header().Get("ETag"); etag != taskETag(got.Version) {
+			t.Errorf("unexpected etag: %s", etag)
+		}
+		return got
+	}
+	ignore := cmpopts.IgnoreFields(localTaskOutput{}, "Id", "ListId", "StatusId", "Position", "Version")
+
+	recorder := httptest.NewRecorder()
+	body := `{"text":"report","notes":"*draft*","dueDate":"2024-05-06","tags":["work"],"priority":"high","important":true}`
+	th.Create().ServeHTTP(recorder, userReq(t, "POST", "/api/task", body, user1, nil))
+	task := localTaskOutput{}
+	if err := json.NewDecoder(recorder.Body).Decode(&task); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("merge patch", func(t *testing.T) {
+		got := save(t, th.Patch(), "PATCH", task.Id, `{"text":"final report","notes":null,"Tags":null,"urgent":true}`)
+		want := localTaskOutput{
+			Text:      "final report",
+			DueDate:   "2024-05-06",
+			Priority:  "high",
+			Important: true,
+			Urgent:    true,
+		}
+		if diff := cmp.Diff(got, want, ignore); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+	})
+
+	t.Run("full replacement", func(t *testing.T) {
+		got := save(t, th.Update(), "PUT", task.Id, `{"text":"summary","priority":"low"}`)
+		want := localTaskOutput{
+			Text:     "summary",
+			Priority: "low",
+		}
+		if diff := cmp.Diff(got, want, ignore); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+	})
+
+	t.Run("replacement keeps the list and the parent", func(t *testing.T) {
+		list := todolist.TodoList{Name: "work", OwnerId: user1}
+		listId, err := th.TaskManager.CreateList(&list)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parent := todolist.TodoItem{Text: "project", OwnerId: user1, ListId: listId}
+		if _, err = th.TaskManager.Create(&parent); err != nil {
+			t.Fatal(err)
+		}
+		sub := todolist.TodoItem{Text: "draft", OwnerId: user1, ParentId: parent.ID}
+		if _, err = th.TaskManager.Create(&sub); err != nil {
+			t.Fatal(err)
+		}
+
+		got := save(t, th.Update(), "PUT", parent.ID, `{"text":"project v2"}`)
+		if got.ListId != listId {
+			t.Errorf("expected the task to stay in the list, got: %s", got.ListId)
+		}
+		got = save(t, th.Update(), "PUT", sub.ID, `{"text":"draft v2"}`)
+		if got.ParentId != parent.ID || got.ListId != listId {
+			t.Errorf("expected the subtask to keep its parent, got: %+v", got)
+		}
+		got = save(t, th.Update(), "PUT", sub.ID, `{"text":"draft v3","parentId":null}`)
+		if got.ParentId != "" || got.ListId != listId {
+			t.Errorf("expected the subtask to be detached, got: %+v", got)
+		}
+		got = save(t, th.Update(), "PUT", parent.ID, `{"text":"project v3","listId":""}`)
+		if got.ListId == listId {
+			t.Errorf("expected an empty list to move the task into the inbox, got: %s", got.ListId)
+		}
+	})
+
+	tcs := []struct {
+		name        string
+		handler     http.Handler
+		body        string
+		contentType string
+		expectCode  int
+		expectErr   string
+	}{
+		{
+			name:       "remove the text",
+			handler:    th.Patch(),
+			body:       `{"text":null}`,
+			expectCode: http.StatusBadRequest,
+			expectErr:  "text cannot be empty req task payload",
+		},
+		{
+			name:       "replace without text",
+			handler:    th.Update(),
+			body:       `{"done":true}`,
+			expectCode: http.StatusBadRequest,
+			expectErr:  "text cannot be empty req task payload",
+		},
+		{
+			name:       "malformed patch",
+			handler:    th.Patch(),
+			body:       `{"text":`,
+			expectCode: http.StatusBadRequest,
+			expectErr:  "unable to decode json: unexpected end of JSON input",
+		},
+		{
+			name:        "unsupported content type",
+			handler:     th.Patch(),
+			body:        `{"text":"x"}`,
+			contentType: "application/json-patch+json",
+			expectCode:  http.StatusUnsupportedMediaType,
+			expectErr:   "content type must be application/merge-patch+json",
+		},
+		{
+			name:       "list of other user",
+			handler:    th.Patch(),
+			body:       `{"listId":"b4a5e3a8-0c36-4a53-a0d5-0a8a4b0ddd1e"}`,
+			expectCode: http.StatusBadRequest,
+			expectErr:  "list with id: b4a5e3a8-0c36-4a53-a0d5-0a8a4b0ddd1e and owner " + user1 + " not found",
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			req := userReq(t, "PATCH", "/api/task/"+task.Id, tc.body, user1, map[string]string{"ID": task.Id})
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+			tc.handler.ServeHTTP(recorder, req)
+			if recorder.Code != tc.expectCode {
+				t.Errorf("handler returned wrong status code: got %v want %v", recorder.Code, tc.expectCode)
+			}
+			if got := strings.TrimSuffix(recorder.Body.String(), "\n"); got != tc.expectErr {
+				t.Errorf("unexpecter error message: got \"%s\"", got)
+			}
+		})
+	}
+}
+
+// dates without time zone read the stored task first, its errors are reported like the ones of the update
+func TestTaskHandler_PatchDateErrors(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mngr, err := todolist.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	th := TodoListHandler{TaskManager: mngr}
+	task := todolist.TodoItem{Text: "report", OwnerId: user1}
+	if _, err = mngr.Create(&task); err != nil {
+		t.Fatal(err)
+	}
+	body := `{"dueDate":"2024-05-06"}`
+
+	recorder := httptest.NewRecorder()
+	th.Patch().ServeHTTP(recorder, userReq(t, "PATCH", "/api/task/"+task.ID, body, user2, map[string]string{"ID": task.ID}))
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("handler returned wrong status code: got %v want %v: %s", recorder.Code, http.StatusNotFound, recorder.Body.String())
+	}
+
+	sqlDb, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = sqlDb.Close(); err != nil {
+		t.Fatal(err)
+	}
+	recorder = httptest.NewRecorder()
+	th.Patch().ServeHTTP(recorder, userReq(t, "PATCH", "/api/task/"+task.ID, body, user1, map[string]string{"ID": task.ID}))
+	if recorder.Code != http.StatusInternalServerError {
+		t.Errorf("handler returned wrong status code: got %v want %v: %s", recorder.Code, http.StatusInternalServerError, recorder.Body.String())
+	}
+	if got := recorder.Body.String(); !strings.HasPrefix(got, "unable to read task: ") {
+		t.Errorf("unexpecter error message: got \"%s\"", got)
+	}
+}
@@ -0,0 +1,312 @@
+package handlrs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-bumbu/todo-app/internal/model/todolist"
+	"github.com/go-bumbu/userauth/handlers/sessionauth"
+	"net/http"
+	"time"
+)
+
+// SavedFilterHandler exposes the saved filters of a user, they are shown like lists in the sidebar
+type SavedFilterHandler struct {
+	TaskManager *todolist.Manager
+}
+
+type localFilterInput struct {
+	Name *string `json:"name"`
+	// Filter is the structured filter definition, see todolist.FilterDefinition
+	Filter json.RawMessage `json:"filter"`
+}
+
+type localFilterOutput struct {
+	Id     string          `json:"id"`
+	Name   string          `json:"name"`
+	Filter json.RawMessage `json:"filter"`
+	// Count is the amount of tasks matching the filter, it is only included in the sidebar
+	Count *int64 `json:"count,omitempty"`
+	// Invalid explains why a stored filter can no longer be evaluated, e.g. because its list was deleted
+	Invalid string `json:"invalid,omitempty"`
+}
+
+type localFilterList struct {
+	Count   int
+	Filters []localFilterOutput
+}
+
+func filterOutput(f todolist.SavedFilter) localFilterOutput {
+	out := localFilterOutput{Id: f.ID, Name: f.Name, Filter: json.RawMessage(f.Definition)}
+	// stored definitions of older schema versions are returned migrated
+	def, err := f.Filter()
+	if err != nil {
+		out.Invalid = err.Error()
+		return out
+	}
+	if data, err := json.Marshal(def); err == nil {
+		out.Filter = data
+	}
+	return out
+}
+
+// filterDefinition validates the filter of the payload, a missing filter is returned as nil
+func (p localFilterInput) filterDefinition() (*todolist.FilterDefinition, *httpErr) {
+	if len(p.Filter) == 0 || string(p.Filter) == "null" {
+		return nil, nil
+	}
+	def, err := todolist.ParseFilterDefinition(p.Filter)
+	if err != nil {
+		return nil, &httpErr{Error: err.Error(), Code: http.StatusBadRequest}
+	}
+	return &def, nil
+}
+
+// filterNow returns the current time in the time zone of the request, it decides which tasks are due today
+func filterNow(r *http.Request) (time.Time, *httpErr) {
+	loc, err := time.LoadLocation(r.URL.Query().Get(tzParam))
+	if err != nil {
+		return time.Time{}, &httpErr{Error: fmt.Sprintf("invalid time zone: %s", r.URL.Query().Get(tzParam)), Code: http.StatusBadRequest}
+	}
+	return time.Now().In(loc), nil
+}
+
+// List returns the saved filters of the user with the amount of tasks each one matches
+func (h *SavedFilterHandler) List() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to list saved filters: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		now, hErr := filterNow(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		counts, err := requestManager(r, h.TaskManager).SavedFilterCounts(uData.UserId, now)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to list saved filters: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		output := localFilterList{
+			Count:   len(counts),
+			Filters: make([]localFilterOutput, len(counts)),
+		}
+		for i, c := range counts {
+			output.Filters[i] = filterOutput(c.Filter)
+			if c.Err != nil {
+				output.Filters[i].Invalid = c.Err.Error()
+				continue
+			}
+			count := c.Count
+			output.Filters[i].Count = &count
+		}
+		writeJson(w, output, http.StatusOK)
+	})
+}
+
+func (h *SavedFilterHandler) Create() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to create saved filter: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		if r.Body == nil {
+			http.Error(w, "request had empty body", http.StatusBadRequest)
+			return
+		}
+		payload := localFilterInput{}
+		err = json.NewDecoder(r.Body).Decode(&payload)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to decode json: %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+		if payload.Name == nil {
+			http.Error(w, "name cannot be empty in filter payload", http.StatusBadRequest)
+			return
+		}
+		def, hErr := payload.filterDefinition()
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+		if def == nil {
+			http.Error(w, "filter cannot be empty in filter payload", http.StatusBadRequest)
+			return
+		}
+
+		f, err := requestManager(r, h.TaskManager).CreateSavedFilter(uData.UserId, *payload.Name, *def)
+		if err != nil {
+			savedFilterErr(w, err)
+			return
+		}
+		writeJson(w, filterOutput(f), http.StatusOK)
+	})
+}
+
+func (h *SavedFilterHandler) Read() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		filterId, hErr := getFilterId(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to read saved filter: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		f, err := requestManager(r, h.TaskManager).GetSavedFilter(filterId, uData.UserId)
+		if err != nil {
+			savedFilterErr(w, err)
+			return
+		}
+		writeJson(w, filterOutput(f), http.StatusOK)
+	})
+}
+
+// Update changes the name or replaces the definition of a saved filter
+func (h *SavedFilterHandler) Update() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		filterId, hErr := getFilterId(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to update saved filter: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		if r.Body == nil {
+			http.Error(w, "request had empty body", http.StatusBadRequest)
+			return
+		}
+		payload := localFilterInput{}
+		err = json.NewDecoder(r.Body).Decode(&payload)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to decode json: %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+		def, hErr := payload.filterDefinition()
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		err = requestManager(r, h.TaskManager).UpdateSavedFilter(filterId, uData.UserId, todolist.SavedFilterUpdate{Name: payload.Name, Definition: def})
+		if err != nil {
+			savedFilterErr(w, err)
+			return
+		}
+		f, err := requestManager(r, h.TaskManager).GetSavedFilter(filterId, uData.UserId)
+		if err != nil {
+			savedFilterErr(w, err)
+			return
+		}
+		writeJson(w, filterOutput(f), http.StatusOK)
+	})
+}
+
+func (h *SavedFilterHandler) Delete() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		filterId, hErr := getFilterId(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to delete saved filter: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		err = requestManager(r, h.TaskManager).DeleteSavedFilter(filterId, uData.UserId)
+		if err != nil {
+			savedFilterErr(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+// Tasks returns a page of the tasks matching the saved filter, it accepts the same paging, filter and subtask
+// parameters as the task list
+func (h *SavedFilterHandler) Tasks() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		filterId, hErr := getFilterId(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to list task: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		pageReq, hErr := getPageRequest(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+		scopes, hErr := taskFilters(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+		mode, hErr := getSubtaskMode(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+		if mode != subtasksNone {
+			scopes = append(scopes, todolist.RootTasks())
+		}
+		now, hErr := filterNow(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		page, err := requestManager(r, h.TaskManager).SavedFilterPage(filterId, uData.UserId, now, pageReq, scopes...)
+		if err != nil {
+			if errors.Is(err, todolist.ErrInvalidFilter) {
+				// the stored definition is no longer valid, it has to be updated
+				http.Error(w, err.Error(), http.StatusConflict)
+			} else if errors.Is(err, todolist.ErrInvalidCursor) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			} else {
+				savedFilterErr(w, err)
+			}
+			return
+		}
+		writeTaskList(w, requestManager(r, h.TaskManager), page, mode)
+	})
+}
+
+// savedFilterErr writes the http error matching an error returned by the saved filter methods of the manager
+func savedFilterErr(w http.ResponseWriter, err error) {
+	fErr := &todolist.SavedFilterNotFoundErr{}
+	if errors.As(err, &fErr) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+	} else if errors.Is(err, todolist.ErrInvalidFilter) || errors.Is(err, todolist.ErrEmptyFilterName) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	} else {
+		http.Error(w, fmt.Sprintf("unable to process saved filter: %s", err.Error()), http.StatusInternalServerError)
+	}
+}
+
+func getFilterId(r *http.Request) (string, *httpErr) {
+	return getUuidVar(r, "ID", "saved filter")
+}
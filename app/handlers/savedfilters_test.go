@@ -0,0 +1,153 @@
+package handlrs
+
+import (
+	"encoding/json"
+	"github.com/go-bumbu/todo-app/internal/model/todolist"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSavedFilters(t *testing.T) {
+	mngr := newTestManager(t)
+	fh := SavedFilterHandler{TaskManager: mngr}
+
+	list := todolist.TodoList{Name: "work", OwnerId: user1}
+	listId, err := mngr.CreateList(&list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, task := range []todolist.TodoItem{
+		{Text: "report", OwnerId: user1, ListId: listId, Priority: todolist.PriorityHigh},
+		{Text: "groceries", OwnerId: user1, Priority: todolist.PriorityLow},
+	} {
+		if _, err = mngr.Create(&task); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	recorder := httptest.NewRecorder()
+	fh.Create().ServeHTTP(recorder, userReq(t, "POST", "/api/filters",
+		`{"name":"High priority not done","filter":{"done":false,"minPriority":"high"}}`, user1, nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", recorder.Code, http.StatusOK, recorder.Body.String())
+	}
+	created := localFilterOutput{}
+	if err = json.NewDecoder(recorder.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+	recorder = httptest.NewRecorder()
+	fh.Create().ServeHTTP(recorder, userReq(t, "POST", "/api/filters", `{"name":"Work","filter":{"listId":"`+listId+`"}}`, user1, nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", recorder.Code, http.StatusOK, recorder.Body.String())
+	}
+	work := localFilterOutput{}
+	if err = json.NewDecoder(recorder.Body).Decode(&work); err != nil {
+		t.Fatal(err)
+	}
+	filterVars := map[string]string{"ID": created.Id}
+	workVars := map[string]string{"ID": work.Id}
+
+	tcs := []struct {
+		name       string
+		handler    http.Handler
+		req        *http.Request
+		expectCode int
+		expectErr  string
+	}{
+		{
+			name:       "unknown field",
+			handler:    fh.Create(),
+			req:        userReq(t, "POST", "/api/filters", `{"name":"old","filter":{"dueBefore":"2024-01-01"}}`, user1, nil),
+			expectCode: http.StatusBadRequest,
+			expectErr:  `invalid filter definition: json: unknown field "dueBefore"`,
+		},
+		{
+			name:       "missing filter",
+			handler:    fh.Create(),
+			req:        userReq(t, "POST", "/api/filters", `{"name":"empty"}`, user1, nil),
+			expectCode: http.StatusBadRequest,
+			expectErr:  "filter cannot be empty in filter payload",
+		},
+		{
+			name:       "other users cannot read",
+			handler:    fh.Read(),
+			req:        userReq(t, "GET", "/api/filters/"+created.Id, "", user2, filterVars),
+			expectCode: http.StatusNotFound,
+		},
+		{
+			name:       "rename",
+			handler:    fh.Update(),
+			req:        userReq(t, "PUT", "/api/filters/"+created.Id, `{"name":"Important"}`, user1, filterVars),
+			expectCode: http.StatusOK,
+		},
+		{
+			name:       "invalid due",
+			handler:    fh.Update(),
+			req:        userReq(t, "PUT", "/api/filters/"+created.Id, `{"filter":{"due":"later"}}`, user1, filterVars),
+			expectCode: http.StatusBadRequest,
+			expectErr:  "invalid filter definition: due must be one of: overdue, today, week, none",
+		},
+		{
+			name:       "invalid time zone",
+			handler:    fh.Tasks(),
+			req:        userReq(t, "GET", "/api/filters/"+created.Id+"/tasks?tz=Mars/Base", "", user1, filterVars),
+			expectCode: http.StatusBadRequest,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			tc.handler.ServeHTTP(recorder, tc.req)
+			if recorder.Code != tc.expectCode {
+				t.Errorf("handler returned wrong status code: got %v want %v: %s", recorder.Code, tc.expectCode, recorder.Body.String())
+			}
+			if got := strings.TrimSuffix(recorder.Body.String(), "\n"); tc.expectErr != "" && got != tc.expectErr {
+				t.Errorf("unexpecter error message: got \"%s\"", got)
+			}
+		})
+	}
+
+	t.Run("tasks", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		fh.Tasks().ServeHTTP(recorder, userReq(t, "GET", "/api/filters/"+created.Id+"/tasks", "", user1, filterVars))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v: %s", recorder.Code, http.StatusOK, recorder.Body.String())
+		}
+		got := localTaskList{}
+		if err := json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Count != 1 || got.Tasks[0].Text != "report" {
+			t.Errorf("unexpected tasks: %+v", got)
+		}
+	})
+
+	t.Run("sidebar reports invalid filters", func(t *testing.T) {
+		if err := mngr.DeleteList(listId, user1, false); err != nil {
+			t.Fatal(err)
+		}
+		recorder := httptest.NewRecorder()
+		fh.List().ServeHTTP(recorder, userReq(t, "GET", "/api/filters", "", user1, nil))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v: %s", recorder.Code, http.StatusOK, recorder.Body.String())
+		}
+		got := localFilterList{}
+		if err := json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Count != 2 || got.Filters[0].Name != "Important" || got.Filters[0].Count == nil || *got.Filters[0].Count != 1 {
+			t.Errorf("unexpected filters: %+v", got)
+		}
+		if got.Filters[1].Count != nil || !strings.Contains(got.Filters[1].Invalid, "not found") {
+			t.Errorf("expected the filter of the deleted list to be invalid: %+v", got.Filters[1])
+		}
+
+		recorder = httptest.NewRecorder()
+		fh.Tasks().ServeHTTP(recorder, userReq(t, "GET", "/api/filters/"+work.Id+"/tasks", "", user1, workVars))
+		if recorder.Code != http.StatusConflict {
+			t.Errorf("handler returned wrong status code: got %v want %v", recorder.Code, http.StatusConflict)
+		}
+	})
+}
@@ -0,0 +1,80 @@
+package handlrs
+
+import (
+	"fmt"
+	"github.com/go-bumbu/todo-app/internal/model/todolist"
+	"github.com/go-bumbu/userauth/handlers/sessionauth"
+	"html"
+	"net/http"
+	"strings"
+)
+
+const searchQueryParam = "q"
+
+type localSearchList struct {
+	Count   int
+	Results []localSearchResult
+}
+
+type localSearchResult struct {
+	localTaskOutput
+	// Snippet is the html escaped text around the matches, matched terms are wrapped in <mark>
+	Snippet string `json:"snippet"`
+}
+
+var snippetMarks = strings.NewReplacer(todolist.SnippetStart, "<mark>", todolist.SnippetEnd, "</mark>")
+
+// highlight escapes the snippet and replaces the match delimiters with html marks
+func highlight(snippet string) string {
+	return snippetMarks.Replace(html.EscapeString(snippet))
+}
+
+func (h *TodoListHandler) Search() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to search tasks: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		query := strings.TrimSpace(r.URL.Query().Get(searchQueryParam))
+		if query == "" {
+			http.Error(w, fmt.Sprintf("%s cannot be empty", searchQueryParam), http.StatusBadRequest)
+			return
+		}
+
+		limit, page, hErr := getPaging(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		results, err := requestManager(r, h.TaskManager).Search(uData.UserId, query, limit, page)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to search tasks: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		items := make([]todolist.TodoItem, len(results))
+		for i := range results {
+			items[i] = results[i].TodoItem
+		}
+		outputs, err := ownerTaskOutputs(requestManager(r, h.TaskManager), items, subtasksNone)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to search tasks: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		output := localSearchList{
+			Count:   len(results),
+			Results: make([]localSearchResult, len(results)),
+		}
+		for i, res := range results {
+			output.Results[i] = localSearchResult{
+				localTaskOutput: outputs[i],
+				Snippet:         highlight(res.Snippet),
+			}
+		}
+		writeJson(w, output, http.StatusOK)
+	})
+}
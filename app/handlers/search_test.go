@@ -0,0 +1,73 @@
+package handlrs
+
+import (
+	"encoding/json"
+	"github.com/google/go-cmp/cmp"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTodoListHandler_Search(t *testing.T) {
+	th := TodoListHandler{TaskManager: newTestManager(t)}
+
+	for _, item := range []struct{ body, user string }{
+		{body: `{"text":"buy <b>groceries</b>"}`, user: user1},
+		{body: `{"text":"groom the dog"}`, user: user1},
+		{body: `{"text":"buy groceries"}`, user: user2},
+	} {
+		recorder := httptest.NewRecorder()
+		th.Create().ServeHTTP(recorder, userReq(t, "POST", "/api/task", item.body, item.user, nil))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", recorder.Code, http.StatusOK)
+		}
+	}
+
+	t.Run("search own tasks", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		th.Search().ServeHTTP(recorder, userReq(t, "GET", "/api/tasks/search?q=groceries", "", user1, nil))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", recorder.Code, http.StatusOK)
+		}
+		got := localSearchList{}
+		err := json.NewDecoder(recorder.Body).Decode(&got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Count != 1 {
+			t.Fatalf("expected 1 result, got %d", got.Count)
+		}
+		want := "buy &lt;b&gt;<mark>groceries</mark>&lt;/b&gt;"
+		if diff := cmp.Diff(got.Results[0].Snippet, want); diff != "" {
+			t.Errorf("unexpected snippet (-got +want)\n%s", diff)
+		}
+		if got.Results[0].Text != "buy <b>groceries</b>" {
+			t.Errorf("unexpected task text: %s", got.Results[0].Text)
+		}
+	})
+
+	t.Run("prefix match", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		th.Search().ServeHTTP(recorder, userReq(t, "GET", "/api/tasks/search?q=gro", "", user1, nil))
+		got := localSearchList{}
+		err := json.NewDecoder(recorder.Body).Decode(&got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Count != 2 {
+			t.Errorf("expected 2 results, got %d", got.Count)
+		}
+	})
+
+	t.Run("empty query", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		th.Search().ServeHTTP(recorder, userReq(t, "GET", "/api/tasks/search?q=", "", user1, nil))
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v", recorder.Code, http.StatusBadRequest)
+		}
+		if got := strings.TrimSuffix(recorder.Body.String(), "\n"); got != "q cannot be empty" {
+			t.Errorf("unexpecter error message: got \"%s\"", got)
+		}
+	})
+}
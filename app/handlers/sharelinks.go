@@ -0,0 +1,309 @@
+package handlrs
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-bumbu/todo-app/internal/markdown"
+	"github.com/go-bumbu/todo-app/internal/model/todolist"
+	"github.com/go-bumbu/userauth/handlers/sessionauth"
+	"github.com/gorilla/mux"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SharePath is the path prefix of the public share links, it is served without authentication
+const SharePath = "/share"
+
+type localLinkInput struct {
+	// ExpiresAt is optional, links without expiry are valid until they are revoked
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+type localLinkOutput struct {
+	Token     string     `json:"token"`
+	Path      string     `json:"path"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	Expired   bool       `json:"expired"`
+	CreatedBy string     `json:"createdBy"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+type localLinkList struct {
+	Count int
+	Links []localLinkOutput
+}
+
+func linkOutput(l todolist.ShareLink) localLinkOutput {
+	return localLinkOutput{
+		Token:     l.Token,
+		Path:      SharePath + "/" + l.Token,
+		ExpiresAt: l.ExpiresAt,
+		Expired:   l.ExpiresAt != nil && !time.Now().Before(*l.ExpiresAt),
+		CreatedBy: l.CreatedBy,
+		CreatedAt: l.CreatedAt,
+	}
+}
+
+// Links returns the public share links of a list
+func (h *ListsHandler) Links() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		listId, hErr := getListId(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to list share links: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		links, err := requestManager(r, h.TaskManager).ShareLinks(listId, uData.UserId)
+		if err != nil {
+			linkErr(w, err)
+			return
+		}
+		output := localLinkList{
+			Count: len(links),
+			Links: make([]localLinkOutput, len(links)),
+		}
+		for i, l := range links {
+			output.Links[i] = linkOutput(l)
+		}
+		writeJson(w, output, http.StatusOK)
+	})
+}
+
+// CreateLink creates a read only public share link for a list
+func (h *ListsHandler) CreateLink() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		listId, hErr := getListId(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to create share link: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		// the body is optional
+		payload := localLinkInput{}
+		if r.Body != nil && r.ContentLength != 0 {
+			err = json.NewDecoder(r.Body).Decode(&payload)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("unable to decode json: %s", err.Error()), http.StatusBadRequest)
+				return
+			}
+		}
+
+		link, err := requestManager(r, h.TaskManager).CreateShareLink(listId, uData.UserId, payload.ExpiresAt)
+		if err != nil {
+			linkErr(w, err)
+			return
+		}
+		writeJson(w, linkOutput(link), http.StatusOK)
+	})
+}
+
+// RevokeLink deletes a public share link of a list, the link stops working immediately
+func (h *ListsHandler) RevokeLink() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		listId, hErr := getListId(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+		token := mux.Vars(r)["Token"]
+
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to revoke share link: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		err = requestManager(r, h.TaskManager).RevokeShareLink(listId, uData.UserId, token)
+		if err != nil {
+			linkErr(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+// linkErr writes the http error matching an error returned by the share link methods of the manager
+func linkErr(w http.ResponseWriter, err error) {
+	sErr := &todolist.ShareLinkNotFoundErr{}
+	if errors.As(err, &sErr) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+	} else if errors.Is(err, todolist.ErrLinkExpired) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	} else {
+		listErr(w, err)
+	}
+}
+
+// PublicListHandler serves the lists of public share links, it must not be behind the authenticator:
+// the token is the only credential and only gives read access to the tasks of one list.
+type PublicListHandler struct {
+	TaskManager *todolist.Manager
+}
+
+type localPublicTask struct {
+	Text    string `json:"text"`
+	Done    bool   `json:"done"`
+	DueDate string `json:"dueDate,omitempty"`
+	Notes   string `json:"notes,omitempty"`
+	// NotesHtml is the sanitized rendering of the notes, see the markdown package
+	NotesHtml template.HTML     `json:"notesHtml,omitempty"`
+	Subtasks  []localPublicTask `json:"subtasks,omitempty"`
+}
+
+type localPublicList struct {
+	Name  string `json:"name"`
+	Color string `json:"color,omitempty"`
+	Count int    // tasks in this page
+	Total int64  // tasks in all pages
+	Next  string
+	Prev  string
+	Tasks []localPublicTask
+}
+
+// localPublicPage is the shared list rendered as html, the links lead to the other pages of the html view
+type localPublicPage struct {
+	localPublicList
+	NextLink string
+	PrevLink string
+}
+
+// publicPageLink returns the link to the page of the html view at the cursor, it keeps the other query parameters
+// of the request, e.g. the page size and the order
+func publicPageLink(r *http.Request, cursor string) string {
+	if cursor == "" {
+		return ""
+	}
+	q := r.URL.Query()
+	q.Del(pageParam)
+	q.Set(cursorParam, cursor)
+	q.Set(formatParam, "html")
+	return "?" + q.Encode()
+}
+
+// publicTasks keeps the fields of the tasks that are safe to show to anyone, e.g. assignees and tags are left out
+func publicTasks(items []localTaskOutput) []localPublicTask {
+	out := make([]localPublicTask, len(items))
+	for i, item := range items {
+		out[i] = localPublicTask{
+			Text:     item.Text,
+			Done:     item.Done,
+			DueDate:  item.DueDate,
+			Notes:    item.Notes,
+			Subtasks: publicTasks(item.Subtasks),
+		}
+		if item.Notes != "" {
+			// the renderer escapes raw html in the notes, its output can be inserted as is
+			out[i].NotesHtml = template.HTML(markdown.Render(item.Notes))
+		}
+	}
+	return out
+}
+
+const formatParam = "format"
+
+//go:embed tmpl/sharedList.html
+var sharedListTmpl string
+
+var sharedListHtml = template.Must(template.New("sharedList").Parse(sharedListTmpl))
+
+// Read returns the list of a share link with its tasks and nested subtasks, as json or rendered as html.
+// The format query parameter selects the format, otherwise html is returned to browsers. The html view links to
+// the previous and next pages of the list.
+func (h *PublicListHandler) Read() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the token is part of the url, it must not leak to other sites or caches
+		w.Header().Set("Referrer-Policy", "no-referrer")
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("X-Robots-Tag", "noindex")
+
+		html, hErr := publicFormat(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+		pageReq, hErr := getPageRequest(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		list, err := h.TaskManager.SharedLinkList(mux.Vars(r)["Token"])
+		if err != nil {
+			linkErr(w, err)
+			return
+		}
+
+		mngr := h.TaskManager.InWorkspace(list.WorkspaceId)
+		page, err := mngr.ListPage(list.OwnerId, pageReq, todolist.InList(list.ID), todolist.RootTasks())
+		if err != nil {
+			if errors.Is(err, todolist.ErrInvalidCursor) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			} else {
+				http.Error(w, fmt.Sprintf("unable to get tasks: %s", err.Error()), http.StatusInternalServerError)
+			}
+			return
+		}
+		items, err := taskOutputs(&mngr, list.OwnerId, page.Tasks, subtasksNested)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to get subtasks: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		output := localPublicList{
+			Name:  list.Name,
+			Color: list.Color,
+			Count: len(items),
+			Total: page.Total,
+			Next:  page.Next,
+			Prev:  page.Prev,
+			Tasks: publicTasks(items),
+		}
+
+		if !html {
+			writeJson(w, output, http.StatusOK)
+			return
+		}
+		var buf bytes.Buffer
+		err = sharedListHtml.Execute(&buf, localPublicPage{
+			localPublicList: output,
+			NextLink:        publicPageLink(r, page.Next),
+			PrevLink:        publicPageLink(r, page.Prev),
+		})
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to render list: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write(buf.Bytes())
+	})
+}
+
+// publicFormat reports whether the shared list is rendered as html
+func publicFormat(r *http.Request) (bool, *httpErr) {
+	switch r.URL.Query().Get(formatParam) {
+	case "html":
+		return true, nil
+	case "json":
+		return false, nil
+	case "":
+		return strings.Contains(r.Header.Get("Accept"), "text/html"), nil
+	default:
+		return false, &httpErr{Error: fmt.Sprintf("%s must be one of: json, html", formatParam), Code: http.StatusBadRequest}
+	}
+}
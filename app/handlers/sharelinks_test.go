@@ -0,0 +1,209 @@
+package handlrs
+
+import (
+	"encoding/json"
+	"github.com/go-bumbu/todo-app/internal/model/todolist"
+	"github.com/google/go-cmp/cmp"
+	"github.com/gorilla/mux"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestShareLinks(t *testing.T) {
+	mngr := newTestManager(t)
+	lh := ListsHandler{TaskManager: mngr}
+	ph := PublicListHandler{TaskManager: mngr}
+
+	list := todolist.TodoList{Name: "groceries", OwnerId: user1}
+	listId, err := mngr.CreateList(&list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	task := todolist.TodoItem{Text: "milk <b>", OwnerId: user1, ListId: listId}
+	if _, err = mngr.Create(&task); err != nil {
+		t.Fatal(err)
+	}
+	sub := todolist.TodoItem{Text: "oat", OwnerId: user1, ListId: listId, ParentId: task.ID}
+	if _, err = mngr.Create(&sub); err != nil {
+		t.Fatal(err)
+	}
+	listVars := map[string]string{"ID": listId}
+
+	recorder := httptest.NewRecorder()
+	lh.CreateLink().ServeHTTP(recorder, userReq(t, "POST", "/api/lists/"+listId+"/links", "", user1, listVars))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", recorder.Code, http.StatusOK, recorder.Body.String())
+	}
+	link := localLinkOutput{}
+	if err = json.NewDecoder(recorder.Body).Decode(&link); err != nil {
+		t.Fatal(err)
+	}
+	if link.Path != SharePath+"/"+link.Token || link.Expired {
+		t.Errorf("unexpected link: %+v", link)
+	}
+
+	// publicReq is a request without user data, as served outside the authenticator
+	publicReq := func(token, format, accept string) *http.Request {
+		req, err := http.NewRequest("GET", SharePath+"/"+token+"?format="+format, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Accept", accept)
+		return mux.SetURLVars(req, map[string]string{"Token": token})
+	}
+
+	t.Run("read json", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		ph.Read().ServeHTTP(recorder, publicReq(link.Token, "", "application/json"))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v: %s", recorder.Code, http.StatusOK, recorder.Body.String())
+		}
+		got := localPublicList{}
+		if err := json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		want := localPublicList{
+			Name:  "groceries",
+			Count: 1,
+			Total: 1,
+			Tasks: []localPublicTask{{Text: "milk <b>", Subtasks: []localPublicTask{{Text: "oat"}}}},
+		}
+		if diff := cmp.Diff(got, want); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+		if recorder.Header().Get("Referrer-Policy") != "no-referrer" {
+			t.Errorf("expected the referrer to be hidden")
+		}
+	})
+
+	t.Run("read html", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		ph.Read().ServeHTTP(recorder, publicReq(link.Token, "", "text/html,application/xhtml+xml"))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v: %s", recorder.Code, http.StatusOK, recorder.Body.String())
+		}
+		body := recorder.Body.String()
+		if !strings.Contains(body, "<h2>groceries</h2>") || !strings.Contains(body, "milk &lt;b&gt;") || !strings.Contains(body, "oat") {
+			t.Errorf("unexpected html: %s", body)
+		}
+	})
+
+	t.Run("notes are rendered as markdown", func(t *testing.T) {
+		notes := "**buy** oat milk <script>alert(1)</script>"
+		if err := mngr.Update(task.ID, user1, todolist.TaskUpdate{Notes: &notes}); err != nil {
+			t.Fatal(err)
+		}
+		recorder := httptest.NewRecorder()
+		ph.Read().ServeHTTP(recorder, publicReq(link.Token, "html", ""))
+		body := recorder.Body.String()
+		if !strings.Contains(body, "<strong>buy</strong>") || strings.Contains(body, "<script>") ||
+			!strings.Contains(body, "&lt;script&gt;") {
+			t.Errorf("unexpected html: %s", body)
+		}
+	})
+
+	t.Run("html pages", func(t *testing.T) {
+		pages := todolist.TodoList{Name: "chores", OwnerId: user1}
+		pagesId, err := mngr.CreateList(&pages)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, text := range []string{"dishes", "laundry"} {
+			if _, err = mngr.Create(&todolist.TodoItem{Text: text, OwnerId: user1, ListId: pagesId}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		pagesLink, err := mngr.CreateShareLink(pagesId, user1, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// read follows the links of the html view, they keep the page size and the html format
+		read := func(query, want, rel string) string {
+			t.Helper()
+			req := publicReq(pagesLink.Token, "html", "")
+			req.URL.RawQuery = strings.TrimPrefix(query, "?")
+			recorder := httptest.NewRecorder()
+			ph.Read().ServeHTTP(recorder, req)
+			body := recorder.Body.String()
+			if recorder.Code != http.StatusOK || !strings.Contains(body, want) {
+				t.Fatalf("unexpected response %d: %s", recorder.Code, body)
+			}
+			match := regexp.MustCompile(`href="([^"]+)">` + rel).FindStringSubmatch(body)
+			if match == nil {
+				t.Fatalf("expected a %s link: %s", rel, body)
+			}
+			return html.UnescapeString(match[1])
+		}
+		next := read("?limit=1&format=html", "dishes", "next")
+		if !strings.Contains(next, "cursor=") || !strings.Contains(next, "format=html") || !strings.Contains(next, "limit=1") {
+			t.Errorf("unexpected next link: %s", next)
+		}
+		prev := read(next, "laundry", "previous")
+		read(prev, "dishes", "next")
+	})
+
+	tcs := []struct {
+		name       string
+		handler    http.Handler
+		req        *http.Request
+		expectCode int
+		expectErr  string
+	}{
+		{
+			name:       "unknown format",
+			handler:    ph.Read(),
+			req:        publicReq(link.Token, "xml", ""),
+			expectCode: http.StatusBadRequest,
+			expectErr:  "format must be one of: json, html",
+		},
+		{
+			name:       "unknown token",
+			handler:    ph.Read(),
+			req:        publicReq("unknown", "", ""),
+			expectCode: http.StatusNotFound,
+			expectErr:  "share link not found",
+		},
+		{
+			name:       "expiry in the past",
+			handler:    lh.CreateLink(),
+			req:        userReq(t, "POST", "/api/lists/"+listId+"/links", `{"expiresAt":"2020-01-01T00:00:00Z"}`, user1, listVars),
+			expectCode: http.StatusBadRequest,
+			expectErr:  "the expiry date of the share link must be in the future",
+		},
+		{
+			name:       "other users cannot list the links",
+			handler:    lh.Links(),
+			req:        userReq(t, "GET", "/api/lists/"+listId+"/links", "", user2, listVars),
+			expectCode: http.StatusNotFound,
+		},
+		{
+			name:       "revoke",
+			handler:    lh.RevokeLink(),
+			req:        userReq(t, "DELETE", "/api/lists/"+listId+"/links/"+link.Token, "", user1, map[string]string{"ID": listId, "Token": link.Token}),
+			expectCode: http.StatusAccepted,
+		},
+		{
+			name:       "revoked token",
+			handler:    ph.Read(),
+			req:        publicReq(link.Token, "json", ""),
+			expectCode: http.StatusNotFound,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			tc.handler.ServeHTTP(recorder, tc.req)
+			if recorder.Code != tc.expectCode {
+				t.Errorf("handler returned wrong status code: got %v want %v: %s", recorder.Code, tc.expectCode, recorder.Body.String())
+			}
+			if got := strings.TrimSuffix(recorder.Body.String(), "\n"); tc.expectErr != "" && got != tc.expectErr {
+				t.Errorf("unexpecter error message: got \"%s\"", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,274 @@
+package handlrs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-bumbu/todo-app/internal/model/todolist"
+	"github.com/go-bumbu/userauth"
+	"github.com/go-bumbu/userauth/handlers/sessionauth"
+	"github.com/gorilla/mux"
+	"net/http"
+	"time"
+)
+
+type localMemberInput struct {
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+type localMemberOutput struct {
+	Username  string    `json:"username"`
+	Role      string    `json:"role"`
+	InvitedBy string    `json:"invitedBy"`
+	Accepted  bool      `json:"accepted"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type localMemberList struct {
+	Count   int
+	Members []localMemberOutput
+}
+
+type localSharedList struct {
+	localListOutput
+	Accepted bool `json:"accepted"`
+}
+
+type localSharedLists struct {
+	Count int
+	Lists []localSharedList
+}
+
+func memberOutput(m todolist.ListMember) localMemberOutput {
+	return localMemberOutput{
+		Username:  m.UserId,
+		Role:      string(m.Role),
+		InvitedBy: m.InvitedBy,
+		Accepted:  m.Accepted,
+		CreatedAt: m.CreatedAt,
+	}
+}
+
+// Members returns the members of a list and the pending invitations
+func (h *ListsHandler) Members() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		listId, hErr := getListId(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to list members: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		members, err := requestManager(r, h.TaskManager).Members(listId, uData.UserId)
+		if err != nil {
+			listErr(w, err)
+			return
+		}
+		output := localMemberList{
+			Count:   len(members),
+			Members: make([]localMemberOutput, len(members)),
+		}
+		for i := range members {
+			output.Members[i] = memberOutput(members[i])
+		}
+		writeJson(w, output, http.StatusOK)
+	})
+}
+
+// Invite shares the list with another user, the user gets access once the invitation is accepted
+func (h *ListsHandler) Invite() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		listId, hErr := getListId(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to share list: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		if r.Body == nil {
+			http.Error(w, "request had empty body", http.StatusBadRequest)
+			return
+		}
+		payload := localMemberInput{}
+		err = json.NewDecoder(r.Body).Decode(&payload)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to decode json: %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+		if payload.Username == "" {
+			http.Error(w, "username cannot be empty in member payload", http.StatusBadRequest)
+			return
+		}
+		if hErr = userExists(h.Users, payload.Username); hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+		err = requestManager(r, h.TaskManager).Share(listId, uData.UserId, payload.Username, todolist.Role(payload.Role))
+		if err != nil {
+			listErr(w, err)
+			return
+		}
+		h.writeMember(w, r, listId, uData.UserId, payload.Username)
+	})
+}
+
+// UpdateMember changes the role of a member of the list
+func (h *ListsHandler) UpdateMember() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		listId, hErr := getListId(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+		member := mux.Vars(r)["User"]
+
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to update member: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		if r.Body == nil {
+			http.Error(w, "request had empty body", http.StatusBadRequest)
+			return
+		}
+		payload := localMemberInput{}
+		err = json.NewDecoder(r.Body).Decode(&payload)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to decode json: %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		err = requestManager(r, h.TaskManager).SetRole(listId, uData.UserId, member, todolist.Role(payload.Role))
+		if err != nil {
+			listErr(w, err)
+			return
+		}
+		h.writeMember(w, r, listId, uData.UserId, member)
+	})
+}
+
+// writeMember writes a single member of the list
+func (h *ListsHandler) writeMember(w http.ResponseWriter, r *http.Request, listId, user, member string) {
+	members, err := requestManager(r, h.TaskManager).Members(listId, user)
+	if err != nil {
+		listErr(w, err)
+		return
+	}
+	for _, m := range members {
+		if m.UserId == member {
+			writeJson(w, memberOutput(m), http.StatusOK)
+			return
+		}
+	}
+	http.Error(w, fmt.Sprintf("unable to read member %s", member), http.StatusInternalServerError)
+}
+
+// userExists checks that the invited user has an account, no check is done if no user store is configured
+func userExists(users userauth.UserGetter, user string) *httpErr {
+	if users == nil {
+		return nil
+	}
+	_, err := users.GetUser(user)
+	if errors.Is(err, userauth.NotFoundErr) {
+		return &httpErr{Error: fmt.Sprintf("user %s not found", user), Code: http.StatusBadRequest}
+	}
+	if err != nil {
+		return &httpErr{Error: fmt.Sprintf("unable to check user: %s", err.Error()), Code: http.StatusInternalServerError}
+	}
+	return nil
+}
+
+// RemoveMember revokes the access of a member, members can also use it to leave a list or decline an invitation.
+// The access is removed immediately.
+func (h *ListsHandler) RemoveMember() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		listId, hErr := getListId(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+		member := mux.Vars(r)["User"]
+
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to remove member: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		err = requestManager(r, h.TaskManager).WithAudit(requestAudit(r, uData.UserId)).Revoke(listId, uData.UserId, member)
+		if err != nil {
+			listErr(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+// Accept accepts the invitation of the user to a list
+func (h *ListsHandler) Accept() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		listId, hErr := getListId(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to accept invitation: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		err = requestManager(r, h.TaskManager).AcceptInvitation(listId, uData.UserId)
+		if err != nil {
+			listErr(w, err)
+			return
+		}
+		list, role, err := requestManager(r, h.TaskManager).ListAccess(listId, uData.UserId)
+		if err != nil {
+			listErr(w, err)
+			return
+		}
+		writeJson(w, sharedListOutput(list, uData.UserId, role), http.StatusOK)
+	})
+}
+
+// SharedWithMe returns the lists of other users the user is a member of, including pending invitations
+func (h *ListsHandler) SharedWithMe() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to get shared lists: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		shared, err := requestManager(r, h.TaskManager).SharedWithMe(uData.UserId)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to get shared lists: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		output := localSharedLists{
+			Count: len(shared),
+			Lists: make([]localSharedList, len(shared)),
+		}
+		for i, s := range shared {
+			output.Lists[i] = localSharedList{
+				localListOutput: sharedListOutput(s.List, uData.UserId, s.Role),
+				Accepted:        s.Accepted,
+			}
+		}
+		writeJson(w, output, http.StatusOK)
+	})
+}
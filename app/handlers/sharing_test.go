@@ -0,0 +1,238 @@
+package handlrs
+
+import (
+	"encoding/json"
+	"github.com/go-bumbu/todo-app/internal/model/todolist"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestListSharing(t *testing.T) {
+	mngr := newTestManager(t)
+	lh := ListsHandler{TaskManager: mngr}
+	th := TodoListHandler{TaskManager: mngr}
+
+	list := todolist.TodoList{Name: "work", OwnerId: user1}
+	listId, err := mngr.CreateList(&list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	task := todolist.TodoItem{Text: "report", OwnerId: user1, ListId: listId}
+	if _, err = mngr.Create(&task); err != nil {
+		t.Fatal(err)
+	}
+	listVars := map[string]string{"ID": listId}
+	memberVars := map[string]string{"ID": listId, "User": user2}
+	taskVars := map[string]string{"ID": task.ID}
+
+	tcs := []struct {
+		name       string
+		handler    http.Handler
+		req        *http.Request
+		expectCode int
+		expectErr  string
+	}{
+		{
+			name:       "invite with unknown role",
+			handler:    lh.Invite(),
+			req:        userReq(t, "POST", "/api/lists/"+listId+"/members", `{"username":"user2","role":"admin"}`, user1, listVars),
+			expectCode: http.StatusBadRequest,
+			expectErr:  "role must be one of: viewer, editor, owner: admin",
+		},
+		{
+			name:       "invite as viewer",
+			handler:    lh.Invite(),
+			req:        userReq(t, "POST", "/api/lists/"+listId+"/members", `{"username":"user2","role":"viewer"}`, user1, listVars),
+			expectCode: http.StatusOK,
+		},
+		{
+			name:       "pending invitation has no access",
+			handler:    lh.Read(),
+			req:        userReq(t, "GET", "/api/lists/"+listId, "", user2, listVars),
+			expectCode: http.StatusNotFound,
+		},
+		{
+			name:       "accept invitation",
+			handler:    lh.Accept(),
+			req:        userReq(t, "POST", "/api/lists/"+listId+"/accept", "", user2, listVars),
+			expectCode: http.StatusOK,
+		},
+		{
+			name:       "viewer reads the tasks",
+			handler:    lh.Tasks(),
+			req:        userReq(t, "GET", "/api/lists/"+listId+"/tasks", "", user2, listVars),
+			expectCode: http.StatusOK,
+		},
+		{
+			name:       "viewer cannot change tasks",
+			handler:    th.Patch(),
+			req:        userReq(t, "PATCH", "/api/task/"+task.ID, `{"text":"changed"}`, user2, taskVars),
+			expectCode: http.StatusForbidden,
+			expectErr:  "the role on the list does not allow this action",
+		},
+		{
+			name:       "viewer cannot invite",
+			handler:    lh.UpdateMember(),
+			req:        userReq(t, "PUT", "/api/lists/"+listId+"/members/user2", `{"role":"owner"}`, user2, memberVars),
+			expectCode: http.StatusForbidden,
+		},
+		{
+			name:       "owner promotes to editor",
+			handler:    lh.UpdateMember(),
+			req:        userReq(t, "PUT", "/api/lists/"+listId+"/members/user2", `{"role":"editor"}`, user1, memberVars),
+			expectCode: http.StatusOK,
+		},
+		{
+			name:       "editor changes tasks",
+			handler:    th.Patch(),
+			req:        userReq(t, "PATCH", "/api/task/"+task.ID, `{"text":"changed"}`, user2, taskVars),
+			expectCode: http.StatusOK,
+		},
+		{
+			name:       "revoke",
+			handler:    lh.RemoveMember(),
+			req:        userReq(t, "DELETE", "/api/lists/"+listId+"/members/user2", "", user1, memberVars),
+			expectCode: http.StatusAccepted,
+		},
+		{
+			name:       "revoked member has no access",
+			handler:    th.Read(),
+			req:        userReq(t, "GET", "/api/task/"+task.ID, "", user2, taskVars),
+			expectCode: http.StatusNotFound,
+		},
+		{
+			name:       "revoke unknown member",
+			handler:    lh.RemoveMember(),
+			req:        userReq(t, "DELETE", "/api/lists/"+listId+"/members/user2", "", user1, memberVars),
+			expectCode: http.StatusNotFound,
+			expectErr:  "user user2 is not a member of the list with id: " + listId,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			tc.handler.ServeHTTP(recorder, tc.req)
+			if recorder.Code != tc.expectCode {
+				t.Errorf("handler returned wrong status code: got %v want %v: %s", recorder.Code, tc.expectCode, recorder.Body.String())
+			}
+			if got := strings.TrimSuffix(recorder.Body.String(), "\n"); tc.expectErr != "" && got != tc.expectErr {
+				t.Errorf("unexpecter error message: got \"%s\"", got)
+			}
+		})
+	}
+}
+
+func TestListSharedWithMe(t *testing.T) {
+	mngr := newTestManager(t)
+	lh := ListsHandler{TaskManager: mngr}
+
+	list := todolist.TodoList{Name: "work", OwnerId: user1}
+	listId, err := mngr.CreateList(&list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = mngr.Share(listId, user1, user2, todolist.RoleEditor); err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := httptest.NewRecorder()
+	lh.SharedWithMe().ServeHTTP(recorder, userReq(t, "GET", "/api/lists/shared", "", user2, nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", recorder.Code, http.StatusOK)
+	}
+	got := localSharedLists{}
+	if err = json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	want := localSharedList{
+		localListOutput: localListOutput{Id: listId, Name: "work", Position: 1, Owner: user1, Role: "editor"},
+		Accepted:        false,
+	}
+	if got.Count != 1 || got.Lists[0] != want {
+		t.Errorf("unexpected shared lists: %+v", got)
+	}
+}
+
+func TestTaskAssignment(t *testing.T) {
+	mngr := newTestManager(t)
+	th := TodoListHandler{TaskManager: mngr}
+
+	list := todolist.TodoList{Name: "work", OwnerId: user1}
+	listId, err := mngr.CreateList(&list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	task := todolist.TodoItem{Text: "report", OwnerId: user1, ListId: listId}
+	if _, err = mngr.Create(&task); err != nil {
+		t.Fatal(err)
+	}
+	if err = mngr.Share(listId, user1, user2, todolist.RoleViewer); err != nil {
+		t.Fatal(err)
+	}
+	if err = mngr.AcceptInvitation(listId, user2); err != nil {
+		t.Fatal(err)
+	}
+	taskVars := map[string]string{"ID": task.ID}
+
+	tcs := []struct {
+		name       string
+		user       string
+		body       string
+		expectCode int
+		expectErr  string
+	}{
+		{
+			name:       "assignee is not a collaborator",
+			user:       user1,
+			body:       `{"assignee":"user3"}`,
+			expectCode: http.StatusBadRequest,
+			expectErr:  "the assignee is not a collaborator of the list",
+		},
+		{
+			name:       "assign to member",
+			user:       user1,
+			body:       `{"assignee":"user2"}`,
+			expectCode: http.StatusOK,
+		},
+		{
+			name:       "assignee cannot change the text",
+			user:       user2,
+			body:       `{"text":"changed"}`,
+			expectCode: http.StatusForbidden,
+		},
+		{
+			name:       "assignee completes the task",
+			user:       user2,
+			body:       `{"done":true}`,
+			expectCode: http.StatusOK,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			th.Patch().ServeHTTP(recorder, userReq(t, "PATCH", "/api/task/"+task.ID, tc.body, tc.user, taskVars))
+			if recorder.Code != tc.expectCode {
+				t.Errorf("handler returned wrong status code: got %v want %v: %s", recorder.Code, tc.expectCode, recorder.Body.String())
+			}
+			if got := strings.TrimSuffix(recorder.Body.String(), "\n"); tc.expectErr != "" && got != tc.expectErr {
+				t.Errorf("unexpecter error message: got \"%s\"", got)
+			}
+		})
+	}
+
+	recorder := httptest.NewRecorder()
+	th.List().ServeHTTP(recorder, userReq(t, "GET", "/api/tasks?assignee=me", "", user2, nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", recorder.Code, http.StatusOK)
+	}
+	got := localTaskList{}
+	if err = json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Count != 1 || got.Tasks[0].Id != task.ID || got.Tasks[0].Assignee != user2 || !got.Tasks[0].Done {
+		t.Errorf("unexpected assigned tasks: %+v", got)
+	}
+}
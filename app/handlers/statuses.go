@@ -0,0 +1,251 @@
+package handlrs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-bumbu/todo-app/internal/model/todolist"
+	"github.com/go-bumbu/userauth/handlers/sessionauth"
+	"github.com/google/uuid"
+	"net/http"
+)
+
+// StatusHandler exposes the board statuses of a user
+type StatusHandler struct {
+	TaskManager *todolist.Manager
+}
+
+type localStatusList struct {
+	Count    int
+	Statuses []localStatusOutput
+}
+
+type localStatusInput struct {
+	Name     *string `json:"name"`
+	Position *int    `json:"position"`
+	Done     *bool   `json:"done"`
+}
+
+type localStatusOutput struct {
+	Id       string `json:"id"`
+	Name     string `json:"name"`
+	Position int    `json:"position"`
+	Done     bool   `json:"done"`
+}
+
+func statusOutput(s todolist.Status) localStatusOutput {
+	return localStatusOutput{
+		Id:       s.ID,
+		Name:     s.Name,
+		Position: s.Position,
+		Done:     s.Done,
+	}
+}
+
+const moveToParam = "move_to"
+
+func (h *StatusHandler) List() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to list statuses: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		items, err := requestManager(r, h.TaskManager).Statuses(uData.UserId)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to get statuses: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		output := localStatusList{
+			Count:    len(items),
+			Statuses: make([]localStatusOutput, len(items)),
+		}
+		for i := range items {
+			output.Statuses[i] = statusOutput(items[i])
+		}
+		writeJson(w, output, http.StatusOK)
+	})
+}
+
+func (h *StatusHandler) Create() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to create status: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		if r.Body == nil {
+			http.Error(w, "request had empty body", http.StatusBadRequest)
+			return
+		}
+		payload := localStatusInput{}
+		err = json.NewDecoder(r.Body).Decode(&payload)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to decode json: %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		s := todolist.Status{OwnerId: uData.UserId}
+		if payload.Name != nil {
+			s.Name = *payload.Name
+		}
+		if payload.Position != nil {
+			s.Position = *payload.Position
+		}
+		if payload.Done != nil {
+			s.Done = *payload.Done
+		}
+
+		_, err = requestManager(r, h.TaskManager).CreateStatus(&s)
+		if err != nil {
+			statusErr(w, err)
+			return
+		}
+		writeJson(w, statusOutput(s), http.StatusOK)
+	})
+}
+
+func (h *StatusHandler) Update() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		statusId, hErr := getStatusId(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to update status: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		if r.Body == nil {
+			http.Error(w, "request had empty body", http.StatusBadRequest)
+			return
+		}
+		payload := localStatusInput{}
+		err = json.NewDecoder(r.Body).Decode(&payload)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to decode json: %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		err = requestManager(r, h.TaskManager).UpdateStatus(statusId, uData.UserId, todolist.StatusUpdate{
+			Name:     payload.Name,
+			Position: payload.Position,
+			Done:     payload.Done,
+		})
+		if err != nil {
+			statusErr(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+// Delete removes a status, its tasks are moved into the status passed as move_to or by default
+// into the first status with the same done value
+func (h *StatusHandler) Delete() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		statusId, hErr := getStatusId(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to delete status: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		moveTo := r.URL.Query().Get(moveToParam)
+		if moveTo != "" {
+			if _, err = uuid.Parse(moveTo); err != nil {
+				http.Error(w, fmt.Sprintf("%s is not a UUID", moveToParam), http.StatusBadRequest)
+				return
+			}
+		}
+
+		err = requestManager(r, h.TaskManager).DeleteStatus(statusId, uData.UserId, moveTo)
+		if err != nil {
+			statusErr(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+// statusErr writes the http error matching an error returned by the status methods of the manager
+func statusErr(w http.ResponseWriter, err error) {
+	sErr := &todolist.StatusNotFoundErr{}
+	if errors.As(err, &sErr) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+	} else if errors.Is(err, todolist.ErrLastStatus) || errors.Is(err, todolist.ErrEmptyStatusName) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	} else {
+		http.Error(w, fmt.Sprintf("unable to process status: %s", err.Error()), http.StatusInternalServerError)
+	}
+}
+
+func getStatusId(r *http.Request) (string, *httpErr) {
+	return getUuidVar(r, "ID", "status")
+}
+
+type localBoard struct {
+	Columns []localBoardColumn `json:"columns"`
+}
+
+type localBoardColumn struct {
+	Status localStatusOutput `json:"status"`
+	Total  int64             `json:"total"`
+	Tasks  []localTaskOutput `json:"tasks"`
+}
+
+// Board returns the tasks grouped by status, limit applies to every column. It accepts the same
+// filters as the task list, e.g. filter=list:<id> shows the board of a single list.
+func (h *TodoListHandler) Board() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to get board: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		limit, _, hErr := getPaging(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		scopes, hErr := taskFilters(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		columns, err := requestManager(r, h.TaskManager).Board(uData.UserId, limit, scopes...)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to get board: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		output := localBoard{Columns: make([]localBoardColumn, len(columns))}
+		for i, c := range columns {
+			tasks, err := taskOutputs(requestManager(r, h.TaskManager), uData.UserId, c.Tasks, subtasksNone)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("unable to get subtasks: %s", err.Error()), http.StatusInternalServerError)
+				return
+			}
+			output.Columns[i] = localBoardColumn{
+				Status: statusOutput(c.Status),
+				Total:  c.Total,
+				Tasks:  tasks,
+			}
+		}
+		writeJson(w, output, http.StatusOK)
+	})
+}
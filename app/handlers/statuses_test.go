@@ -0,0 +1,185 @@
+package handlrs
+
+import (
+	"encoding/json"
+	"github.com/google/go-cmp/cmp"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestStatusHandler(t *testing.T) {
+	mngr := newTestManager(t)
+	th := TodoListHandler{TaskManager: mngr}
+	sh := StatusHandler{TaskManager: mngr}
+
+	statuses := func(t *testing.T) map[string]localStatusOutput {
+		t.Helper()
+		recorder := httptest.NewRecorder()
+		sh.List().ServeHTTP(recorder, userReq(t, "GET", "/api/statuses", "", user1, nil))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", recorder.Code, http.StatusOK)
+		}
+		got := localStatusList{}
+		err := json.NewDecoder(recorder.Body).Decode(&got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		byName := map[string]localStatusOutput{}
+		for _, s := range got.Statuses {
+			byName[s.Name] = s
+		}
+		return byName
+	}
+	board := func(t *testing.T) map[string][]string {
+		t.Helper()
+		recorder := httptest.NewRecorder()
+		th.Board().ServeHTTP(recorder, userReq(t, "GET", "/api/board", "", user1, nil))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", recorder.Code, http.StatusOK)
+		}
+		got := localBoard{}
+		err := json.NewDecoder(recorder.Body).Decode(&got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		columns := map[string][]string{}
+		for _, c := range got.Columns {
+			texts := []string{}
+			for _, task := range c.Tasks {
+				texts = append(texts, task.Text)
+			}
+			columns[c.Status.Name] = texts
+		}
+		return columns
+	}
+
+	taskId := createTask(t, mngr, "write docs", user1)
+	_ = createTask(t, mngr, "fix bug", user1)
+	byName := statuses(t)
+
+	t.Run("create status", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		sh.Create().ServeHTTP(recorder, userReq(t, "POST", "/api/statuses", `{"name":"Review"}`, user1, nil))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", recorder.Code, http.StatusOK)
+		}
+		got := localStatusOutput{}
+		err := json.NewDecoder(recorder.Body).Decode(&got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Name != "Review" || got.Position != 4 || got.Done {
+			t.Errorf("unexpected status: %+v", got)
+		}
+	})
+
+	t.Run("transition", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		body := `{"statusId":"` + byName["Done"].Id + `"}`
+		th.Transition().ServeHTTP(recorder, userReq(t, "POST", "/api/task/"+taskId+"/status", body, user1, map[string]string{"ID": taskId}))
+		if recorder.Code != http.StatusAccepted {
+			t.Fatalf("handler returned wrong status code: got %v want %v", recorder.Code, http.StatusAccepted)
+		}
+		want := map[string][]string{"To do": {"fix bug"}, "In progress": {}, "Blocked": {}, "Done": {"write docs"}, "Review": {}}
+		if diff := cmp.Diff(board(t), want); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+
+		recorder = httptest.NewRecorder()
+		th.Read().ServeHTTP(recorder, userReq(t, "GET", "/api/task/"+taskId, "", user1, map[string]string{"ID": taskId}))
+		got := localTaskOutput{}
+		err := json.NewDecoder(recorder.Body).Decode(&got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Done || got.StatusId != byName["Done"].Id {
+			t.Errorf("expected task to be done, got %+v", got)
+		}
+	})
+
+	tcs := []struct {
+		name       string
+		handler    http.Handler
+		body       string
+		vars       map[string]string
+		expectCode int
+		expectErr  string
+	}{
+		{
+			name:       "transition to unknown status",
+			handler:    th.Transition(),
+			body:       `{"statusId":"` + taskId + `"}`,
+			vars:       map[string]string{"ID": taskId},
+			expectCode: http.StatusBadRequest,
+			expectErr:  "status with id: " + taskId + " and owner " + user1 + " not found",
+		},
+		{
+			name:       "transition without status",
+			handler:    th.Transition(),
+			body:       `{}`,
+			vars:       map[string]string{"ID": taskId},
+			expectCode: http.StatusBadRequest,
+			expectErr:  "status id is not a UUID",
+		},
+		{
+			name:       "create without name",
+			handler:    sh.Create(),
+			body:       `{"done":true}`,
+			expectCode: http.StatusBadRequest,
+			expectErr:  "status name cannot be empty",
+		},
+		{
+			name:       "delete last done status",
+			handler:    sh.Delete(),
+			vars:       map[string]string{"ID": byName["Done"].Id},
+			expectCode: http.StatusBadRequest,
+			expectErr:  "at least one open and one done status are required",
+		},
+		{
+			name:       "rename status",
+			handler:    sh.Update(),
+			body:       `{"name":"Waiting"}`,
+			vars:       map[string]string{"ID": byName["Blocked"].Id},
+			expectCode: http.StatusAccepted,
+		},
+		{
+			name:       "delete status",
+			handler:    sh.Delete(),
+			vars:       map[string]string{"ID": byName["In progress"].Id},
+			expectCode: http.StatusAccepted,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			tc.handler.ServeHTTP(recorder, userReq(t, "POST", "/api/statuses", tc.body, user1, tc.vars))
+			if recorder.Code != tc.expectCode {
+				t.Fatalf("handler returned wrong status code: got %v want %v", recorder.Code, tc.expectCode)
+			}
+			if tc.expectErr != "" {
+				if got := strings.TrimSuffix(recorder.Body.String(), "\n"); got != tc.expectErr {
+					t.Errorf("unexpecter error message: got \"%s\"", got)
+				}
+			}
+		})
+	}
+
+	t.Run("statuses after changes", func(t *testing.T) {
+		got := []string{}
+		for name := range statuses(t) {
+			got = append(got, name)
+		}
+		want := map[string]bool{"To do": true, "Waiting": true, "Done": true, "Review": true}
+		if len(got) != len(want) {
+			t.Errorf("unexpected statuses: %v", got)
+		}
+		for _, name := range got {
+			if !want[name] {
+				t.Errorf("unexpected status %s", name)
+			}
+		}
+	})
+}
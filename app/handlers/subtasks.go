@@ -0,0 +1,142 @@
+package handlrs
+
+import (
+	"fmt"
+	"github.com/go-bumbu/todo-app/internal/model/todolist"
+	"net/http"
+)
+
+const subtasksParam = "subtasks"
+
+const (
+	subtasksNone   = ""
+	subtasksNested = "nested"
+	subtasksFlat   = "flat"
+)
+
+type localProgress struct {
+	Done  int `json:"done"`
+	Total int `json:"total"`
+}
+
+// getSubtaskMode reads how subtasks should be included in the response: nested, flat or not at all
+func getSubtaskMode(r *http.Request) (string, *httpErr) {
+	mode := r.URL.Query().Get(subtasksParam)
+	switch mode {
+	case subtasksNone, subtasksNested, subtasksFlat:
+		return mode, nil
+	default:
+		return "", &httpErr{
+			Error: fmt.Sprintf("%s must be one of: %s, %s", subtasksParam, subtasksNested, subtasksFlat),
+			Code:  http.StatusBadRequest,
+		}
+	}
+}
+
+// taskOutputs converts the tasks into their json representation including the subtask progress,
+// depending on mode the descendants of every task are attached nested or as a flat list.
+func taskOutputs(mngr *todolist.Manager, owner string, items []todolist.TodoItem, mode string) ([]localTaskOutput, error) {
+	ids := make([]string, len(items))
+	for i := range items {
+		ids[i] = items[i].ID
+	}
+
+	var subtasks []todolist.TodoItem
+	var err error
+	if mode != subtasksNone {
+		subtasks, err = mngr.Subtasks(owner, ids...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for i := range subtasks {
+		ids = append(ids, subtasks[i].ID)
+	}
+
+	progress, err := mngr.Progress(owner, ids...)
+	if err != nil {
+		return nil, err
+	}
+	deps, err := mngr.Dependencies(owner, ids...)
+	if err != nil {
+		return nil, err
+	}
+	comments, err := mngr.CommentCounts(owner, ids...)
+	if err != nil {
+		return nil, err
+	}
+	children := map[string][]localTaskOutput{}
+	for i := range subtasks {
+		children[subtasks[i].ParentId] = append(children[subtasks[i].ParentId], detailedOutput(subtasks[i], progress, deps, comments))
+	}
+
+	out := make([]localTaskOutput, len(items))
+	for i := range items {
+		out[i] = detailedOutput(items[i], progress, deps, comments)
+		switch mode {
+		case subtasksNested:
+			out[i].Subtasks = nestSubtasks(out[i].Id, children)
+		case subtasksFlat:
+			out[i].Subtasks = flattenSubtasks(out[i].Id, children, nil)
+		}
+	}
+	return out, nil
+}
+
+// ownerTaskOutputs is like taskOutputs for tasks that can belong to different owners, the order of the tasks is kept
+func ownerTaskOutputs(mngr *todolist.Manager, tasks []todolist.TodoItem, mode string) ([]localTaskOutput, error) {
+	owners := []string{}
+	byOwner := map[string][]todolist.TodoItem{}
+	for _, task := range tasks {
+		if _, ok := byOwner[task.OwnerId]; !ok {
+			owners = append(owners, task.OwnerId)
+		}
+		byOwner[task.OwnerId] = append(byOwner[task.OwnerId], task)
+	}
+	outputs := map[string]localTaskOutput{}
+	for _, owner := range owners {
+		items, err := taskOutputs(mngr, owner, byOwner[owner], mode)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			outputs[item.Id] = item
+		}
+	}
+	out := make([]localTaskOutput, len(tasks))
+	for i, task := range tasks {
+		out[i] = outputs[task.ID]
+	}
+	return out, nil
+}
+
+// detailedOutput adds the computed subtask progress, blockers and comment count to the json representation of the task
+func detailedOutput(item todolist.TodoItem, progress map[string]todolist.Progress, deps map[string]todolist.TaskDependencies,
+	comments map[string]int) localTaskOutput {
+	out := taskOutput(item)
+	if p, ok := progress[item.ID]; ok {
+		out.Progress = &localProgress{Done: p.Done, Total: p.Total}
+	}
+	if d, ok := deps[item.ID]; ok {
+		out.BlockedBy, out.Blocked = d.BlockedBy, d.Blocked
+	}
+	out.Comments = comments[item.ID]
+	return out
+}
+
+func nestSubtasks(id string, children map[string][]localTaskOutput) []localTaskOutput {
+	items := children[id]
+	for i := range items {
+		items[i].Subtasks = nestSubtasks(items[i].Id, children)
+	}
+	return items
+}
+
+// flattenSubtasks returns the descendants depth first, every subtask is followed by its own subtasks
+func flattenSubtasks(id string, children map[string][]localTaskOutput, acc []localTaskOutput) []localTaskOutput {
+	for _, child := range children[id] {
+		acc = append(acc, child)
+		acc = flattenSubtasks(child.Id, children, acc)
+	}
+	return acc
+}
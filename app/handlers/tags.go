@@ -0,0 +1,244 @@
+package handlrs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-bumbu/todo-app/internal/model/todolist"
+	"github.com/go-bumbu/userauth/handlers/sessionauth"
+	"github.com/google/uuid"
+	"net/http"
+)
+
+// TagsHandler exposes the tags of a user
+type TagsHandler struct {
+	TaskManager *todolist.Manager
+}
+
+type localTagList struct {
+	Count int
+	Tags  []localTagOutput
+}
+
+type localTagInput struct {
+	Name  *string `json:"name"`
+	Color *string `json:"color"`
+}
+
+type localTagOutput struct {
+	Id    string `json:"id"`
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+type localTagMerge struct {
+	Into string `json:"into"`
+}
+
+func tagOutput(tag todolist.Tag) localTagOutput {
+	return localTagOutput{
+		Id:    tag.ID,
+		Name:  tag.Name,
+		Color: tag.Color,
+	}
+}
+
+func (h *TagsHandler) List() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to list tags: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		items, err := requestManager(r, h.TaskManager).Tags(uData.UserId)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to get tags: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		output := localTagList{
+			Count: len(items),
+			Tags:  make([]localTagOutput, len(items)),
+		}
+		for i := range items {
+			output.Tags[i] = tagOutput(items[i])
+		}
+		writeJson(w, output, http.StatusOK)
+	})
+}
+
+func (h *TagsHandler) Create() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to create tag: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		if r.Body == nil {
+			http.Error(w, "request had empty body", http.StatusBadRequest)
+			return
+		}
+		payload := localTagInput{}
+		err = json.NewDecoder(r.Body).Decode(&payload)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to decode json: %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		tag := todolist.Tag{OwnerId: uData.UserId}
+		if payload.Name != nil {
+			tag.Name = *payload.Name
+		}
+		if payload.Color != nil {
+			tag.Color = *payload.Color
+		}
+
+		_, err = requestManager(r, h.TaskManager).CreateTag(&tag)
+		if err != nil {
+			tagErr(w, err)
+			return
+		}
+		writeJson(w, tagOutput(tag), http.StatusOK)
+	})
+}
+
+func (h *TagsHandler) Read() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tagId, hErr := getTagId(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to read tag: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		tag, err := requestManager(r, h.TaskManager).GetTag(tagId, uData.UserId)
+		if err != nil {
+			tagErr(w, err)
+			return
+		}
+		writeJson(w, tagOutput(tag), http.StatusOK)
+	})
+}
+
+// Update renames a tag or changes its color, renaming to a name already in use returns a 409
+func (h *TagsHandler) Update() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tagId, hErr := getTagId(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to update tag: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		if r.Body == nil {
+			http.Error(w, "request had empty body", http.StatusBadRequest)
+			return
+		}
+		payload := localTagInput{}
+		err = json.NewDecoder(r.Body).Decode(&payload)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to decode json: %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		err = requestManager(r, h.TaskManager).UpdateTag(tagId, uData.UserId, payload.Name, payload.Color)
+		if err != nil {
+			tagErr(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+func (h *TagsHandler) Delete() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tagId, hErr := getTagId(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to delete tag: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		err = requestManager(r, h.TaskManager).DeleteTag(tagId, uData.UserId)
+		if err != nil {
+			tagErr(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+// Merge moves all tasks of the tag in the path into the tag passed as "into" in the payload
+// and deletes the first one.
+func (h *TagsHandler) Merge() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tagId, hErr := getTagId(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to merge tag: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		if r.Body == nil {
+			http.Error(w, "request had empty body", http.StatusBadRequest)
+			return
+		}
+		payload := localTagMerge{}
+		err = json.NewDecoder(r.Body).Decode(&payload)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to decode json: %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+		if _, err = uuid.Parse(payload.Into); err != nil {
+			http.Error(w, "target tag id is not a UUID", http.StatusBadRequest)
+			return
+		}
+
+		err = requestManager(r, h.TaskManager).MergeTags(tagId, payload.Into, uData.UserId)
+		if err != nil {
+			tagErr(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+// tagErr writes the http error matching an error returned by the tag methods of the manager
+func tagErr(w http.ResponseWriter, err error) {
+	tErr := &todolist.TagNotFoundErr{}
+	if errors.As(err, &tErr) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+	} else if errors.Is(err, todolist.ErrTagExists) {
+		http.Error(w, err.Error(), http.StatusConflict)
+	} else if errors.Is(err, todolist.ErrEmptyTagName) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	} else {
+		http.Error(w, fmt.Sprintf("unable to process tag: %s", err.Error()), http.StatusInternalServerError)
+	}
+}
+
+func getTagId(r *http.Request) (string, *httpErr) {
+	return getUuidVar(r, "ID", "tag")
+}
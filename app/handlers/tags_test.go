@@ -0,0 +1,128 @@
+package handlrs
+
+import (
+	"encoding/json"
+	"github.com/google/go-cmp/cmp"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTagsHandler(t *testing.T) {
+	tgh := TagsHandler{TaskManager: newTestManager(t)}
+	th := TodoListHandler{TaskManager: tgh.TaskManager}
+
+	for _, body := range []string{
+		`{"text":"report","tags":["work","urgent"]}`,
+		`{"text":"groceries","tags":["errand"]}`,
+		`{"text":"call boss","tags":["work"]}`,
+	} {
+		recorder := httptest.NewRecorder()
+		th.Create().ServeHTTP(recorder, userReq(t, "POST", "/api/task", body, user1, nil))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", recorder.Code, http.StatusOK)
+		}
+	}
+
+	recorder := httptest.NewRecorder()
+	tgh.List().ServeHTTP(recorder, userReq(t, "GET", "/api/tags", "", user1, nil))
+	tags := localTagList{}
+	err := json.NewDecoder(recorder.Body).Decode(&tags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tags.Count != 3 {
+		t.Fatalf("expected 3 tags, got %d", tags.Count)
+	}
+	errand, urgent, work := tags.Tags[0], tags.Tags[1], tags.Tags[2]
+
+	t.Run("filter tasks", func(t *testing.T) {
+		tcs := []struct {
+			query  string
+			expect []string
+		}{
+			{query: "tag=work", expect: []string{"report", "call boss"}},
+			{query: "tag=work&tag=errand", expect: []string{"report", "groceries", "call boss"}},
+			{query: "tag=work&tag=urgent&tag_mode=and", expect: []string{"report"}},
+		}
+		for _, tc := range tcs {
+			recorder := httptest.NewRecorder()
+			th.List().ServeHTTP(recorder, userReq(t, "GET", "/api/tasks?"+tc.query, "", user1, nil))
+			got := localTaskList{}
+			err := json.NewDecoder(recorder.Body).Decode(&got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			texts := []string{}
+			for _, task := range got.Tasks {
+				texts = append(texts, task.Text)
+			}
+			if diff := cmp.Diff(texts, tc.expect); diff != "" {
+				t.Errorf("%s: unexpected value (-got +want)\n%s", tc.query, diff)
+			}
+		}
+	})
+
+	tcs := []struct {
+		name       string
+		handler    http.Handler
+		req        *http.Request
+		expecErr   string
+		expectCode int
+	}{
+		{
+			name:       "invalid tag mode",
+			handler:    th.List(),
+			req:        userReq(t, "GET", "/api/tasks?tag=work&tag_mode=xor", "", user1, nil),
+			expecErr:   "tag_mode must be one of: and, or",
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			name:       "create duplicated tag",
+			handler:    tgh.Create(),
+			req:        userReq(t, "POST", "/api/tags", `{"name":"work"}`, user1, nil),
+			expecErr:   "a tag with the same name already exists",
+			expectCode: http.StatusConflict,
+		},
+		{
+			name:       "rename to existing tag",
+			handler:    tgh.Update(),
+			req:        userReq(t, "PUT", "/api/tags/"+urgent.Id, `{"name":"work"}`, user1, map[string]string{"ID": urgent.Id}),
+			expecErr:   "a tag with the same name already exists",
+			expectCode: http.StatusConflict,
+		},
+		{
+			name:       "merge tag of other user",
+			handler:    tgh.Merge(),
+			req:        userReq(t, "POST", "/api/tags/"+urgent.Id+"/merge", `{"into":"`+work.Id+`"}`, user2, map[string]string{"ID": urgent.Id}),
+			expecErr:   "tag with id: " + urgent.Id + " and owner user2 not found",
+			expectCode: http.StatusNotFound,
+		},
+		{
+			name:       "merge tags",
+			handler:    tgh.Merge(),
+			req:        userReq(t, "POST", "/api/tags/"+errand.Id+"/merge", `{"into":"`+work.Id+`"}`, user1, map[string]string{"ID": errand.Id}),
+			expectCode: http.StatusAccepted,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			tc.handler.ServeHTTP(recorder, tc.req)
+
+			if status := recorder.Code; status != tc.expectCode {
+				t.Errorf("handler returned wrong status code: got %v want %v",
+					status, tc.expectCode)
+			}
+			if tc.expecErr != "" {
+				got := strings.TrimSuffix(recorder.Body.String(), "\n")
+				if got != tc.expecErr {
+					t.Errorf("unexpecter error message: got \"%s\" want \"%v\"",
+						got, tc.expecErr)
+				}
+			}
+		})
+	}
+}
@@ -5,13 +5,15 @@ import (
 	"errors"
 	"fmt"
 	"github.com/davecgh/go-spew/spew"
+	"github.com/go-bumbu/todo-app/internal/markdown"
 	"github.com/go-bumbu/todo-app/internal/model/todolist"
-	"github.com/go-bumbu/userauth/handlers/sessionauth"
 
+	"github.com/go-bumbu/userauth/handlers/sessionauth"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"net/http"
 	"strconv"
+	"time"
 )
 
 var _ = spew.Dump // prevent IDE from removing dependency
@@ -21,12 +23,34 @@ type TodoListHandler struct {
 }
 
 type localTaskList struct {
-	Count int
+	Count int   // tasks in this page
+	Total int64 // tasks in all pages
+	Next  string
+	Prev  string
 	Tasks []localTaskOutput
 }
 
 const limitParam = "limit"
 const pageParam = "page"
+const dueBeforeParam = "due_before"
+const dueAfterParam = "due_after"
+const tagParam = "tag"
+const tagModeParam = "tag_mode"
+const completeSubtasksParam = "complete_subtasks"
+const doneParam = "done"
+const statusParam = "status"
+const blockedParam = "blocked"
+const createdAfterParam = "created_after"
+const updatedAfterParam = "updated_after"
+const sortParam = "sort"
+const orderParam = "order"
+const filterParam = "filter"
+const cursorParam = "cursor"
+
+// assigneeParam filters the tasks by assignee, the value "me" lists the tasks assigned to the user
+// including the ones of lists shared with the user
+const assigneeParam = "assignee"
+const assigneeMe = "me"
 
 func (h *TodoListHandler) List() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -36,72 +60,350 @@ func (h *TodoListHandler) List() http.Handler {
 			return
 		}
 
-		limitStr := r.URL.Query().Get(limitParam)
-		limit := 0
-		if limitStr != "" {
-			limit, err = strconv.Atoi(limitStr)
-			if err != nil {
-				http.Error(w, "unable to convert limit value to number", http.StatusBadRequest)
-				return
-			}
+		pageReq, hErr := getPageRequest(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
 		}
 
-		pageStr := r.URL.Query().Get(pageParam)
-		page := 0
-		if pageStr != "" {
-			page, err = strconv.Atoi(pageStr)
-			if err != nil {
-				http.Error(w, "unable to convert page value to number", http.StatusBadRequest)
-				return
-			}
+		scopes, hErr := taskFilters(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		mode, hErr := getSubtaskMode(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+		if mode != subtasksNone {
+			// subtasks are attached to their parents instead of being listed individually
+			scopes = append(scopes, todolist.RootTasks())
 		}
 
-		items, err := h.TaskManager.List(uData.UserId, limit, page)
+		var page todolist.TaskPage
+		switch assignee := r.URL.Query().Get(assigneeParam); assignee {
+		case "":
+			page, err = requestManager(r, h.TaskManager).ListPage(uData.UserId, pageReq, scopes...)
+		case assigneeMe, uData.UserId:
+			page, err = requestManager(r, h.TaskManager).AssignedPage(uData.UserId, pageReq, scopes...)
+		default:
+			scopes = append(scopes, todolist.AssignedTo(assignee))
+			page, err = requestManager(r, h.TaskManager).ListPage(uData.UserId, pageReq, scopes...)
+		}
 		if err != nil {
 			t := &todolist.ItemNotFountErr{}
 			if errors.As(err, &t) {
 				http.Error(w, err.Error(), http.StatusNotFound)
+			} else if errors.Is(err, todolist.ErrInvalidCursor) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
 			} else {
 				http.Error(w, fmt.Sprintf("unable to get task: %s", err.Error()), http.StatusInternalServerError)
 			}
 			return
 		}
 
-		taskItems := make([]localTaskOutput, len(items))
-		for i := 0; i < len(items); i++ {
+		writeTaskList(w, requestManager(r, h.TaskManager), page, mode)
+	})
+}
 
-			taskItems[i] = localTaskOutput{
-				Id:   items[i].ID,
-				Text: items[i].Text,
-				Done: items[i].Done,
-			}
+// writeTaskList writes a page of tasks, the tasks can belong to different owners when they are
+// assigned to the user in lists shared by others
+func writeTaskList(w http.ResponseWriter, mngr *todolist.Manager, page todolist.TaskPage, mode string) {
+	taskItems, err := ownerTaskOutputs(mngr, page.Tasks, mode)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("unable to get subtasks: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	output := localTaskList{
+		Count: len(taskItems),
+		Total: page.Total,
+		Next:  page.Next,
+		Prev:  page.Prev,
+		Tasks: taskItems,
+	}
+	writeJsonETag(w, output, http.StatusOK, "")
+}
+
+// taskFilters translates the filter query parameters of a task list request into manager scopes
+func taskFilters(r *http.Request) ([]todolist.Scope, *httpErr) {
+	scopes := []todolist.Scope{}
+	q := r.URL.Query()
+
+	if v := q.Get(dueBeforeParam); v != "" {
+		d, err := todolist.ParseDate(v, time.UTC)
+		if err != nil || d.Time == nil {
+			return nil, &httpErr{Error: fmt.Sprintf("unable to convert %s value to date", dueBeforeParam), Code: http.StatusBadRequest}
 		}
+		scopes = append(scopes, todolist.DueBefore(*d.Time))
+	}
+	if v := q.Get(dueAfterParam); v != "" {
+		d, err := todolist.ParseDate(v, time.UTC)
+		if err != nil || d.Time == nil {
+			return nil, &httpErr{Error: fmt.Sprintf("unable to convert %s value to date", dueAfterParam), Code: http.StatusBadRequest}
+		}
+		scopes = append(scopes, todolist.DueAfter(*d.Time))
+	}
 
-		output := localTaskList{
-			Count: len(taskItems),
-			Tasks: taskItems,
+	if tags, ok := q[tagParam]; ok {
+		all := false
+		switch q.Get(tagModeParam) {
+		case "", "or":
+		case "and":
+			all = true
+		default:
+			return nil, &httpErr{Error: fmt.Sprintf("%s must be one of: and, or", tagModeParam), Code: http.StatusBadRequest}
 		}
+		scopes = append(scopes, todolist.WithTags(tags, all))
+	}
 
-		respJson, err := json.Marshal(output)
+	if v := q.Get(doneParam); v != "" {
+		done, err := strconv.ParseBool(v)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
+			return nil, &httpErr{Error: fmt.Sprintf("unable to convert %s value to boolean", doneParam), Code: http.StatusBadRequest}
 		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write(respJson)
+		scopes = append(scopes, todolist.WithDone(done))
+	}
+	if v := q.Get(blockedParam); v != "" {
+		blocked, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, &httpErr{Error: fmt.Sprintf("unable to convert %s value to boolean", blockedParam), Code: http.StatusBadRequest}
+		}
+		scopes = append(scopes, todolist.Blocked(blocked))
+	}
+	if v := q.Get(statusParam); v != "" {
+		if _, err := uuid.Parse(v); err != nil {
+			return nil, &httpErr{Error: fmt.Sprintf("%s is not a UUID", statusParam), Code: http.StatusBadRequest}
+		}
+		scopes = append(scopes, todolist.InStatus(v))
+	}
+	if v := q.Get(createdAfterParam); v != "" {
+		d, err := todolist.ParseDate(v, time.UTC)
+		if err != nil || d.Time == nil {
+			return nil, &httpErr{Error: fmt.Sprintf("unable to convert %s value to date", createdAfterParam), Code: http.StatusBadRequest}
+		}
+		scopes = append(scopes, todolist.CreatedAfter(*d.Time))
+	}
+	if v := q.Get(updatedAfterParam); v != "" {
+		d, err := todolist.ParseDate(v, time.UTC)
+		if err != nil || d.Time == nil {
+			return nil, &httpErr{Error: fmt.Sprintf("unable to convert %s value to date", updatedAfterParam), Code: http.StatusBadRequest}
+		}
+		scopes = append(scopes, todolist.UpdatedAfter(*d.Time))
+	}
 
-	})
+	if v := q.Get(filterParam); v != "" {
+		filter, err := todolist.ParseFilter(v)
+		if err != nil {
+			return nil, &httpErr{Error: fmt.Sprintf("invalid %s: %s", filterParam, err.Error()), Code: http.StatusBadRequest}
+		}
+		scopes = append(scopes, filter)
+	}
+
+	return scopes, nil
+}
+
+// getPageRequest reads the paging and sorting query parameters, a cursor takes precedence over the page number
+func getPageRequest(r *http.Request) (todolist.PageRequest, *httpErr) {
+	limit, page, hErr := getPaging(r)
+	if hErr != nil {
+		return todolist.PageRequest{}, hErr
+	}
+	q := r.URL.Query()
+	req := todolist.PageRequest{Size: limit, Page: page, Cursor: q.Get(cursorParam), Sort: q.Get(sortParam)}
+
+	switch q.Get(orderParam) {
+	case "", "asc":
+	case "desc":
+		req.Desc = true
+	default:
+		return req, &httpErr{Error: fmt.Sprintf("%s must be one of: asc, desc", orderParam), Code: http.StatusBadRequest}
+	}
+	if req.Sort != "" {
+		_, err := todolist.SortBy(req.Sort, req.Desc)
+		if err != nil {
+			return req, &httpErr{Error: fmt.Sprintf("invalid %s: %s", sortParam, err.Error()), Code: http.StatusBadRequest}
+		}
+	}
+	return req, nil
+}
+
+// getPaging reads the limit and page query parameters, missing values are returned as 0
+func getPaging(r *http.Request) (limit, page int, hErr *httpErr) {
+	var err error
+	limitStr := r.URL.Query().Get(limitParam)
+	if limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil {
+			return 0, 0, &httpErr{Error: "unable to convert limit value to number", Code: http.StatusBadRequest}
+		}
+	}
+
+	pageStr := r.URL.Query().Get(pageParam)
+	if pageStr != "" {
+		page, err = strconv.Atoi(pageStr)
+		if err != nil {
+			return 0, 0, &httpErr{Error: "unable to convert page value to number", Code: http.StatusBadRequest}
+		}
+	}
+	return limit, page, nil
 }
 
 type localTaskInput struct {
 	Text string `json:"text"`
 	Done *bool
+	// an empty list moves the task into the inbox, subtasks always stay in the list of their parent
+	ListId string `json:"listId"`
+	// markdown description of the task
+	Notes *string `json:"notes"`
+	// the done value is derived from the status when both are set
+	StatusId *string `json:"statusId"`
+	// dates are either "YYYY-MM-DD" or RFC3339, an empty string removes the date
+	DueDate   *string `json:"dueDate"`
+	StartDate *string `json:"startDate"`
+	TimeZone  *string `json:"timeZone"`
+	// tags are referenced by name, missing tags are created
+	Tags     *[]string `json:"tags"`
+	ParentId *string   `json:"parentId"`
+	// recurrence rule e.g. "FREQ=WEEKLY;BYDAY=MO,FR", an empty string stops the recurrence
+	Recurrence *string `json:"recurrence"`
+	// one of none, low, medium, high or urgent
+	Priority  *string `json:"priority"`
+	Important *bool   `json:"important"`
+	Urgent    *bool   `json:"urgent"`
+	// ids of the tasks that have to be done first, replaces all the blockers
+	BlockedBy *[]string `json:"blockedBy"`
+	// username of the owner or a member of the list, an empty string removes the assignee
+	Assignee *string `json:"assignee"`
 }
 type localTaskOutput struct {
-	Id   string `json:"id"`
-	Text string `json:"text"`
-	Done bool   `json:"done"`
+	Id        string   `json:"id"`
+	Text      string   `json:"text"`
+	Done      bool     `json:"done"`
+	StatusId  string   `json:"statusId,omitempty"`
+	ListId    string   `json:"listId"`
+	DueDate   string   `json:"dueDate,omitempty"`
+	StartDate string   `json:"startDate,omitempty"`
+	TimeZone  string   `json:"timeZone,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+	ParentId  string   `json:"parentId,omitempty"`
+	Position  string   `json:"position"`
+	Priority  string   `json:"priority,omitempty"`
+	Important bool     `json:"important,omitempty"`
+	Urgent    bool     `json:"urgent,omitempty"`
+	Assignee  string   `json:"assignee,omitempty"`
+	BlockedBy []string `json:"blockedBy,omitempty"`
+	// Blocked is true while at least one of the blockers is not done
+	Blocked bool `json:"blocked,omitempty"`
+
+	Notes string `json:"notes,omitempty"`
+	// NotesHtml is the sanitized rendering of the notes, it is only included when reading a single task
+	NotesHtml string `json:"notesHtml,omitempty"`
+
+	Recurrence string `json:"recurrence,omitempty"`
+	SeriesId   string `json:"seriesId,omitempty"`
+
+	// Comments is the amount of comments in the thread of the task
+	Comments int `json:"comments,omitempty"`
+
+	// Version changes with every change to the task, it is the value of the ETag header of the task
+	Version int64 `json:"version"`
+
+	Progress *localProgress    `json:"progress,omitempty"`
+	Subtasks []localTaskOutput `json:"subtasks,omitempty"`
+}
+
+func taskOutput(item todolist.TodoItem) localTaskOutput {
+	loc, err := time.LoadLocation(item.TimeZone)
+	if err != nil {
+		loc = time.UTC
+	}
+	return localTaskOutput{
+		Id:        item.ID,
+		Text:      item.Text,
+		Notes:     item.Notes,
+		Done:      item.Done,
+		StatusId:  item.StatusId,
+		ListId:    item.ListId,
+		DueDate:   todolist.FormatDate(item.DueDate, item.DueHasTime, loc),
+		StartDate: todolist.FormatDate(item.StartDate, item.StartHasTime, loc),
+		TimeZone:  item.TimeZone,
+		Tags:      item.TagNames(),
+		ParentId:  item.ParentId,
+		Position:  item.Position,
+		Priority:  priorityOutput(item.Priority),
+		Important: item.Important,
+		Urgent:    item.Urgent,
+		Assignee:  item.AssigneeId,
+
+		Recurrence: item.Recurrence,
+		SeriesId:   item.SeriesId,
+
+		Version: item.Version,
+	}
+}
+
+// recurrence validates the recurrence rule of the payload
+func (p localTaskInput) recurrence() (*string, *httpErr) {
+	if p.Recurrence == nil || *p.Recurrence == "" {
+		return p.Recurrence, nil
+	}
+	r, err := todolist.ParseRecurrence(*p.Recurrence)
+	if err != nil {
+		return nil, &httpErr{Error: fmt.Sprintf("invalid recurrence: %s", err.Error()), Code: http.StatusBadRequest}
+	}
+	rule := r.String()
+	return &rule, nil
+}
+
+// priority validates the priority of the payload
+func (p localTaskInput) priority() (*todolist.Priority, *httpErr) {
+	if p.Priority == nil {
+		return nil, nil
+	}
+	v, err := todolist.ParsePriority(*p.Priority)
+	if err != nil {
+		return nil, &httpErr{Error: fmt.Sprintf("invalid priority: %s", err.Error()), Code: http.StatusBadRequest}
+	}
+	return &v, nil
+}
+
+// priorityOutput omits the default priority from the json output
+func priorityOutput(p todolist.Priority) string {
+	if p == todolist.PriorityNone {
+		return ""
+	}
+	return p.String()
+}
+
+// schedule parses the date fields of the payload, timestamps without offset are interpreted
+// in the time zone of the payload, falling back to tz.
+func (p localTaskInput) schedule(tz string) (due, start *todolist.Date, hErr *httpErr) {
+	if p.TimeZone != nil {
+		tz = *p.TimeZone
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, nil, &httpErr{Error: fmt.Sprintf("invalid time zone: %s", tz), Code: http.StatusBadRequest}
+	}
+
+	if p.DueDate != nil {
+		d, err := todolist.ParseDate(*p.DueDate, loc)
+		if err != nil {
+			return nil, nil, &httpErr{Error: fmt.Sprintf("invalid due date: %s", err.Error()), Code: http.StatusBadRequest}
+		}
+		due = &d
+	}
+	if p.StartDate != nil {
+		d, err := todolist.ParseDate(*p.StartDate, loc)
+		if err != nil {
+			return nil, nil, &httpErr{Error: fmt.Sprintf("invalid start date: %s", err.Error()), Code: http.StatusBadRequest}
+		}
+		start = &d
+	}
+	return due, start, nil
 }
 
 func (h *TodoListHandler) Create() http.Handler {
@@ -133,30 +435,91 @@ func (h *TodoListHandler) Create() http.Handler {
 			payload.Done = &f
 		}
 
+		due, start, hErr := payload.schedule("")
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+		recurrence, hErr := payload.recurrence()
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+		priority, hErr := payload.priority()
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
 		t := todolist.TodoItem{
 			Text:    payload.Text,
 			Done:    *payload.Done,
 			OwnerId: uData.UserId,
+			ListId:  payload.ListId,
+		}
+		if payload.Notes != nil {
+			t.Notes = *payload.Notes
+		}
+		if payload.TimeZone != nil {
+			t.TimeZone = *payload.TimeZone
+		}
+		if due != nil {
+			t.DueDate, t.DueHasTime = due.Time, due.HasTime
+		}
+		if start != nil {
+			t.StartDate, t.StartHasTime = start.Time, start.HasTime
+		}
+		if payload.Tags != nil {
+			for _, name := range *payload.Tags {
+				t.Tags = append(t.Tags, todolist.Tag{Name: name})
+			}
+		}
+		if payload.ParentId != nil {
+			t.ParentId = *payload.ParentId
+		}
+		if payload.StatusId != nil {
+			t.StatusId = *payload.StatusId
+		}
+		if recurrence != nil {
+			t.Recurrence = *recurrence
+		}
+		if priority != nil {
+			t.Priority = *priority
+		}
+		if payload.Important != nil {
+			t.Important = *payload.Important
+		}
+		if payload.Urgent != nil {
+			t.Urgent = *payload.Urgent
+		}
+		if payload.BlockedBy != nil {
+			t.BlockedBy = *payload.BlockedBy
+		}
+		if payload.Assignee != nil {
+			t.AssigneeId = *payload.Assignee
 		}
-		Id, err := h.TaskManager.Create(&t)
+		_, err = requestManager(r, h.TaskManager).WithAudit(requestAudit(r, uData.UserId)).Create(&t)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("unable to store task in DB: %s", err.Error()), http.StatusInternalServerError)
+			lErr := &todolist.ListNotFoundErr{}
+			tErr := &todolist.ItemNotFountErr{}
+			sErr := &todolist.StatusNotFoundErr{}
+			if errors.As(err, &lErr) || errors.As(err, &tErr) || errors.As(err, &sErr) || errors.Is(err, todolist.ErrEmptyTagName) ||
+				errors.Is(err, todolist.ErrUnknownBlocker) || errors.Is(err, todolist.ErrInvalidAssignee) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			} else if errors.Is(err, todolist.ErrPermissionDenied) {
+				http.Error(w, err.Error(), http.StatusForbidden)
+			} else {
+				http.Error(w, fmt.Sprintf("unable to store task in DB: %s", err.Error()), http.StatusInternalServerError)
+			}
 			return
 		}
 
-		output := localTaskOutput{
-			Id:   Id,
-			Text: payload.Text,
-			Done: *payload.Done,
-		}
-		respJson, err := json.Marshal(output)
+		outputs, err := taskOutputs(requestManager(r, h.TaskManager), t.OwnerId, []todolist.TodoItem{t}, subtasksNone)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			http.Error(w, fmt.Sprintf("unable to get dependencies: %s", err.Error()), http.StatusInternalServerError)
 			return
 		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write(respJson)
+		writeJsonETag(w, outputs[0], http.StatusOK, taskETag(t.Version))
 	})
 }
 
@@ -174,7 +537,13 @@ func (h *TodoListHandler) Read() http.Handler {
 			return
 		}
 
-		Task, err := h.TaskManager.Get(taskId, uData.UserId)
+		mode, hErr := getSubtaskMode(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		Task, err := requestManager(r, h.TaskManager).Get(taskId, uData.UserId)
 		if err != nil {
 			t := &todolist.ItemNotFountErr{}
 			if errors.As(err, &t) {
@@ -184,23 +553,36 @@ func (h *TodoListHandler) Read() http.Handler {
 			}
 			return
 		}
-		output := localTaskOutput{
-			Id:   Task.ID,
-			Text: Task.Text,
-			Done: Task.Done,
+		etag := taskETag(Task.Version)
+		if !noneMatch(r, etag) {
+			w.Header().Set("ETag", etag)
+			w.WriteHeader(http.StatusNotModified)
+			return
 		}
-		respJson, err := json.Marshal(output)
+		outputs, err := taskOutputs(requestManager(r, h.TaskManager), Task.OwnerId, []todolist.TodoItem{Task}, mode)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			http.Error(w, fmt.Sprintf("unable to get subtasks: %s", err.Error()), http.StatusInternalServerError)
 			return
 		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write(respJson)
+		output := outputs[0]
+		output.NotesHtml = markdown.Render(Task.Notes)
+		writeJsonETag(w, output, http.StatusOK, etag)
 	})
 }
 
+// Update replaces the task with the payload, fields missing in the payload are reset to the values of a new task.
+// It returns the updated task.
 func (h *TodoListHandler) Update() http.Handler {
+	return h.saveTask(false)
+}
+
+// Patch applies a JSON merge patch (RFC 7396) to the task, only the fields in the payload are changed
+// and fields set to null are reset. It returns the updated task.
+func (h *TodoListHandler) Patch() http.Handler {
+	return h.saveTask(true)
+}
+
+func (h *TodoListHandler) saveTask(patch bool) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		taskId, hErr := getTaskId(r)
 		if hErr != nil {
@@ -218,32 +600,256 @@ func (h *TodoListHandler) Update() http.Handler {
 			http.Error(w, "request had empty body", http.StatusBadRequest)
 			return
 		}
-		payload := localTaskInput{}
+		var payload taskChanges
+		if patch {
+			payload, hErr = decodeMergePatch(r)
+		} else {
+			payload, hErr = decodeReplacement(r)
+		}
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		upd := todolist.TaskUpdate{
+			Text:       payload.text,
+			ListId:     payload.listId,
+			Notes:      payload.Notes,
+			Done:       payload.Done,
+			StatusId:   payload.StatusId,
+			Important:  payload.Important,
+			Urgent:     payload.Urgent,
+			TimeZone:   payload.TimeZone,
+			Tags:       payload.Tags,
+			ParentId:   payload.ParentId,
+			BlockedBy:  payload.BlockedBy,
+			AssigneeId: payload.Assignee,
+		}
+		if r.URL.Query().Get(completeSubtasksParam) != "" {
+			upd.CompleteSubtasks, err = strconv.ParseBool(r.URL.Query().Get(completeSubtasksParam))
+			if err != nil {
+				http.Error(w, "unable to convert complete_subtasks value to boolean", http.StatusBadRequest)
+				return
+			}
+		}
+		upd.IgnoreBlockers, hErr = getForce(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		tz := ""
+		if payload.TimeZone == nil && (payload.DueDate != nil || payload.StartDate != nil) {
+			// dates without offset are interpreted in the time zone already stored in the task
+			task, err := requestManager(r, h.TaskManager).Get(taskId, uData.UserId)
+			tErr := &todolist.ItemNotFountErr{}
+			if errors.As(err, &tErr) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			} else if errors.Is(err, todolist.ErrPermissionDenied) {
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			} else if err != nil {
+				http.Error(w, fmt.Sprintf("unable to read task: %s", err.Error()), http.StatusInternalServerError)
+				return
+			}
+			tz = task.TimeZone
+		}
+		upd.Due, upd.Start, hErr = payload.schedule(tz)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+		upd.Recurrence, hErr = payload.recurrence()
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+		upd.Priority, hErr = payload.priority()
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+		versions, hErr := ifMatchVersions(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		upd.IfVersion, err = expectedVersion(requestManager(r, h.TaskManager), taskId, uData.UserId, versions)
+		if err == nil {
+			err = requestManager(r, h.TaskManager).WithAudit(requestAudit(r, uData.UserId)).Update(taskId, uData.UserId, upd)
+		}
+		sErr := &todolist.StatusNotFoundErr{}
+		lErr := &todolist.ListNotFoundErr{}
+		tErr := &todolist.ItemNotFountErr{}
+		if errors.Is(err, todolist.ErrEmptyTagName) || errors.Is(err, todolist.ErrTaskCycle) || errors.As(err, &sErr) ||
+			errors.Is(err, todolist.ErrDependencyCycle) || errors.Is(err, todolist.ErrUnknownBlocker) || errors.As(err, &lErr) ||
+			errors.Is(err, todolist.ErrInvalidAssignee) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, todolist.ErrTaskBlocked) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if errors.Is(err, todolist.ErrVersionMismatch) {
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
+			return
+		}
+		if errors.Is(err, todolist.ErrPermissionDenied) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if errors.As(err, &tErr) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to store task in DB: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		task, err := requestManager(r, h.TaskManager).Get(taskId, uData.UserId)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to read task: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		outputs, err := taskOutputs(requestManager(r, h.TaskManager), task.OwnerId, []todolist.TodoItem{task}, subtasksNone)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to get dependencies: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		output := outputs[0]
+		output.NotesHtml = markdown.Render(task.Notes)
+		writeJsonETag(w, output, http.StatusOK, taskETag(task.Version))
+	})
+}
+
+type localTaskMove struct {
+	// id of the task to place the moved task before or after, exactly one has to be set
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// Move changes the manual position of a task placing it right before or after another task
+func (h *TodoListHandler) Move() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		taskId, hErr := getTaskId(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to move task: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		if r.Body == nil {
+			http.Error(w, "request had empty body", http.StatusBadRequest)
+			return
+		}
+		payload := localTaskMove{}
 		err = json.NewDecoder(r.Body).Decode(&payload)
 		if err != nil {
 			http.Error(w, fmt.Sprintf("unable to decode json: %s", err.Error()), http.StatusBadRequest)
 			return
 		}
+		if (payload.Before == "") == (payload.After == "") {
+			http.Error(w, "exactly one of before or after is required", http.StatusBadRequest)
+			return
+		}
+		anchor, after := payload.Before, false
+		if payload.After != "" {
+			anchor, after = payload.After, true
+		}
+		if _, err = uuid.Parse(anchor); err != nil {
+			http.Error(w, "anchor task id is not a UUID", http.StatusBadRequest)
+			return
+		}
 
-		taskText := ""
-		if payload.Text == "" {
-			taskText = payload.Text
+		err = requestManager(r, h.TaskManager).WithAudit(requestAudit(r, uData.UserId)).Move(taskId, uData.UserId, anchor, after)
+		if err != nil {
+			t := &todolist.ItemNotFountErr{}
+			if errors.As(err, &t) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+			} else if errors.Is(err, todolist.ErrMoveToSelf) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			} else if errors.Is(err, todolist.ErrPermissionDenied) {
+				http.Error(w, err.Error(), http.StatusForbidden)
+			} else {
+				http.Error(w, fmt.Sprintf("unable to move task: %s", err.Error()), http.StatusInternalServerError)
+			}
+			return
 		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+type localTaskTransition struct {
+	StatusId string `json:"statusId"`
+}
 
-		var taskDone *bool
-		if payload.Done != nil {
-			taskDone = payload.Done
+// Transition moves a task into another status of the board, completing or reopening it if needed
+func (h *TodoListHandler) Transition() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		taskId, hErr := getTaskId(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
 		}
 
-		err = h.TaskManager.Update(taskId, uData.UserId, taskText, taskDone)
+		uData, err := sessionauth.CtxGetUserData(r)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("unable to store task in DB: %s", err.Error()), http.StatusInternalServerError)
+			http.Error(w, fmt.Sprintf("unable to change task status: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		if r.Body == nil {
+			http.Error(w, "request had empty body", http.StatusBadRequest)
+			return
+		}
+		payload := localTaskTransition{}
+		err = json.NewDecoder(r.Body).Decode(&payload)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to decode json: %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+		if _, err = uuid.Parse(payload.StatusId); err != nil {
+			http.Error(w, "status id is not a UUID", http.StatusBadRequest)
+			return
+		}
+		force, hErr := getForce(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		upd := todolist.TaskUpdate{StatusId: &payload.StatusId, IgnoreBlockers: force}
+		err = requestManager(r, h.TaskManager).WithAudit(requestAudit(r, uData.UserId)).Update(taskId, uData.UserId, upd)
+		if err != nil {
+			t := &todolist.ItemNotFountErr{}
+			sErr := &todolist.StatusNotFoundErr{}
+			if errors.As(err, &t) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+			} else if errors.As(err, &sErr) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			} else if errors.Is(err, todolist.ErrTaskBlocked) {
+				http.Error(w, err.Error(), http.StatusConflict)
+			} else if errors.Is(err, todolist.ErrPermissionDenied) {
+				http.Error(w, err.Error(), http.StatusForbidden)
+			} else {
+				http.Error(w, fmt.Sprintf("unable to change task status: %s", err.Error()), http.StatusInternalServerError)
+			}
 			return
 		}
 		w.WriteHeader(http.StatusAccepted)
 	})
 }
 
+// Delete removes a task and all its subtasks, pass subtasks=keep to move the subtasks one level up instead
 func (h *TodoListHandler) Delete() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		taskId, hErr := getTaskId(r)
@@ -258,11 +864,41 @@ func (h *TodoListHandler) Delete() http.Handler {
 			return
 		}
 
-		err = h.TaskManager.Delete(taskId, uData.UserId)
+		keep := false
+		switch r.URL.Query().Get(subtasksParam) {
+		case "", "delete":
+		case "keep":
+			keep = true
+		default:
+			http.Error(w, fmt.Sprintf("%s must be one of: delete, keep", subtasksParam), http.StatusBadRequest)
+			return
+		}
+		versions, hErr := ifMatchVersions(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		mngr := requestManager(r, h.TaskManager).WithAudit(requestAudit(r, uData.UserId))
+		version, err := expectedVersion(requestManager(r, h.TaskManager), taskId, uData.UserId, versions)
+		if err == nil {
+			switch {
+			case version != nil:
+				err = mngr.DeleteVersion(taskId, uData.UserId, *version, keep)
+			case keep:
+				err = mngr.DeleteKeepSubtasks(taskId, uData.UserId)
+			default:
+				err = mngr.Delete(taskId, uData.UserId)
+			}
+		}
 		if err != nil {
 			t := &todolist.ItemNotFountErr{}
 			if errors.As(err, &t) {
 				http.Error(w, err.Error(), http.StatusNotFound)
+			} else if errors.Is(err, todolist.ErrVersionMismatch) {
+				http.Error(w, err.Error(), http.StatusPreconditionFailed)
+			} else if errors.Is(err, todolist.ErrPermissionDenied) {
+				http.Error(w, err.Error(), http.StatusForbidden)
 			} else {
 				http.Error(w, fmt.Sprintf("unable to get task: %s", err.Error()), http.StatusInternalServerError)
 			}
@@ -278,26 +914,32 @@ type httpErr struct {
 }
 
 func getTaskId(r *http.Request) (string, *httpErr) {
+	return getUuidVar(r, "ID", "task")
+}
+
+// getUuidVar extracts the path variable "name" from the request and verifies it is a UUID,
+// kind is used to describe the item in the error messages
+func getUuidVar(r *http.Request, name, kind string) (string, *httpErr) {
 	vars := mux.Vars(r)
-	taskId, ok := vars["ID"]
+	id, ok := vars[name]
 	if !ok {
 		return "", &httpErr{
 			Error: "could not extract id to read from request context",
 			Code:  http.StatusInternalServerError,
 		}
 	}
-	if taskId == "" {
+	if id == "" {
 		return "", &httpErr{
-			Error: "no task id provided",
+			Error: fmt.Sprintf("no %s id provided", kind),
 			Code:  http.StatusBadRequest,
 		}
 	}
-	_, err := uuid.Parse(taskId)
+	_, err := uuid.Parse(id)
 	if err != nil {
 		return "", &httpErr{
-			Error: "task id is not a UUID",
+			Error: fmt.Sprintf("%s id is not a UUID", kind),
 			Code:  http.StatusBadRequest,
 		}
 	}
-	return taskId, nil
+	return id, nil
 }
@@ -17,6 +17,7 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strconv"
 	"strings"
 	"testing"
@@ -61,8 +62,8 @@ func TestTaskHandler_List(t *testing.T) {
 			expect: localTaskList{
 				Count: 2,
 				Tasks: []localTaskOutput{
-					{Text: "task1_user1"},
-					{Text: "task2_user1"},
+					{Text: "task1_user1", Version: 1},
+					{Text: "task2_user1", Version: 1},
 				},
 			},
 		},
@@ -92,9 +93,9 @@ func TestTaskHandler_List(t *testing.T) {
 			expect: localTaskList{
 				Count: 3,
 				Tasks: []localTaskOutput{
-					{Text: "task4_user2"},
-					{Text: "task5_user2"},
-					{Text: "task6_user2"},
+					{Text: "task4_user2", Version: 1},
+					{Text: "task5_user2", Version: 1},
+					{Text: "task6_user2", Version: 1},
 				},
 			},
 		},
@@ -168,7 +169,12 @@ func TestTaskHandler_List(t *testing.T) {
 				if err != nil {
 					t.Fatal(err)
 				}
-				if diff := cmp.Diff(got, tc.expect, cmpopts.IgnoreFields(localTaskOutput{}, "Id")); diff != "" {
+				// paging details are covered in TestTaskHandler_Paging, the shared DB accumulates tasks between cases
+				ignore := []cmp.Option{
+					cmpopts.IgnoreFields(localTaskOutput{}, "Id", "ListId", "Position", "StatusId"),
+					cmpopts.IgnoreFields(localTaskList{}, "Total", "Next", "Prev"),
+				}
+				if diff := cmp.Diff(got, tc.expect, ignore...); diff != "" {
 					t.Errorf("unexpected value (-got +want)\n%s", diff)
 				}
 
@@ -480,11 +486,18 @@ func TestTaskHandler_Read(t *testing.T) {
 				if err != nil {
 					t.Fatal(err)
 				}
+				inbox, err := th.TaskManager.Inbox(user1)
+				if err != nil {
+					t.Fatal(err)
+				}
 				want := todolist.TodoItem{
-					ID:   taskId,
-					Text: "sample",
+					ID:      taskId,
+					ListId:  inbox.ID,
+					Text:    "sample",
+					Version: 1,
 				}
-				if diff := cmp.Diff(got, want); diff != "" {
+				// the position depends on the tasks created by other tests and the status id is generated
+				if diff := cmp.Diff(got, want, cmpopts.IgnoreFields(todolist.TodoItem{}, "Position", "StatusId")); diff != "" {
 					t.Errorf("unexpected value (-got +want)\n%s", diff)
 				}
 			}
@@ -519,7 +532,7 @@ func TestTaskHandler_Update(t *testing.T) {
 				})
 				return req, nil
 			},
-			expectCode: http.StatusAccepted,
+			expectCode: http.StatusOK,
 		},
 		{
 			name: "missing user in context",
@@ -840,3 +853,477 @@ func createTask(t *testing.T, mngr *todolist.Manager, content, owner string) str
 	}
 	return id
 }
+
+func TestTaskHandler_DueDates(t *testing.T) {
+	th := TodoListHandler{TaskManager: newTestManager(t)}
+
+	create := func(body string) localTaskOutput {
+		recorder := httptest.NewRecorder()
+		th.Create().ServeHTTP(recorder, userReq(t, "POST", "/api/task", body, user1, nil))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", recorder.Code, http.StatusOK)
+		}
+		got := localTaskOutput{}
+		err := json.NewDecoder(recorder.Body).Decode(&got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	allDay := create(`{"text":"all day","dueDate":"2024-05-10"}`)
+	timed := create(`{"text":"timed","dueDate":"2024-05-12T09:00","startDate":"2024-05-11","timeZone":"Europe/Zurich"}`)
+	_ = create(`{"text":"no date"}`)
+
+	if allDay.DueDate != "2024-05-10" {
+		t.Errorf("unexpected due date: %s", allDay.DueDate)
+	}
+	if timed.DueDate != "2024-05-12T09:00:00+02:00" || timed.StartDate != "2024-05-11" {
+		t.Errorf("unexpected dates: due %s, start %s", timed.DueDate, timed.StartDate)
+	}
+
+	tcs := []struct {
+		name       string
+		query      string
+		expecErr   string
+		expectCode int
+		expect     []string
+	}{
+		{
+			name:       "due before",
+			query:      "due_before=2024-05-11",
+			expectCode: http.StatusOK,
+			expect:     []string{"all day"},
+		},
+		{
+			name:       "due after with time",
+			query:      "due_after=2024-05-12T08:00:00%2B02:00",
+			expectCode: http.StatusOK,
+			expect:     []string{"timed"},
+		},
+		{
+			name:       "invalid date",
+			query:      "due_before=tomorrow",
+			expecErr:   "unable to convert due_before value to date",
+			expectCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			th.List().ServeHTTP(recorder, userReq(t, "GET", "/api/tasks?"+tc.query, "", user1, nil))
+			if status := recorder.Code; status != tc.expectCode {
+				t.Errorf("handler returned wrong status code: got %v want %v", status, tc.expectCode)
+			}
+			if tc.expecErr != "" {
+				got := strings.TrimSuffix(recorder.Body.String(), "\n")
+				if got != tc.expecErr {
+					t.Errorf("unexpecter error message: got \"%s\" want \"%v\"", got, tc.expecErr)
+				}
+				return
+			}
+			got := localTaskList{}
+			err := json.NewDecoder(recorder.Body).Decode(&got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			texts := []string{}
+			for _, task := range got.Tasks {
+				texts = append(texts, task.Text)
+			}
+			if diff := cmp.Diff(texts, tc.expect); diff != "" {
+				t.Errorf("unexpected value (-got +want)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestTaskHandler_Subtasks(t *testing.T) {
+	th := TodoListHandler{TaskManager: newTestManager(t)}
+
+	create := func(body string) string {
+		recorder := httptest.NewRecorder()
+		th.Create().ServeHTTP(recorder, userReq(t, "POST", "/api/task", body, user1, nil))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", recorder.Code, http.StatusOK)
+		}
+		got := localTaskOutput{}
+		err := json.NewDecoder(recorder.Body).Decode(&got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got.Id
+	}
+
+	trip := create(`{"text":"trip"}`)
+	pack := create(`{"text":"pack","parentId":"` + trip + `"}`)
+	_ = create(`{"text":"passport","parentId":"` + pack + `","done":true}`)
+	_ = create(`{"text":"hotel","parentId":"` + trip + `"}`)
+
+	list := func(t *testing.T, query string) localTaskList {
+		recorder := httptest.NewRecorder()
+		th.List().ServeHTTP(recorder, userReq(t, "GET", "/api/tasks?"+query, "", user1, nil))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", recorder.Code, http.StatusOK)
+		}
+		got := localTaskList{}
+		err := json.NewDecoder(recorder.Body).Decode(&got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	t.Run("nested", func(t *testing.T) {
+		got := list(t, "subtasks=nested")
+		if got.Count != 1 {
+			t.Fatalf("expected only the root task, got %d", got.Count)
+		}
+		root := got.Tasks[0]
+		if diff := cmp.Diff(root.Progress, &localProgress{Done: 1, Total: 3}); diff != "" {
+			t.Errorf("unexpected progress (-got +want)\n%s", diff)
+		}
+		if len(root.Subtasks) != 2 || root.Subtasks[0].Text != "pack" || len(root.Subtasks[0].Subtasks) != 1 {
+			t.Errorf("unexpected subtask tree: %+v", root.Subtasks)
+		}
+	})
+
+	t.Run("flat", func(t *testing.T) {
+		got := list(t, "subtasks=flat")
+		texts := []string{}
+		for _, task := range got.Tasks[0].Subtasks {
+			texts = append(texts, task.Text)
+		}
+		if diff := cmp.Diff(texts, []string{"pack", "passport", "hotel"}); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+	})
+
+	t.Run("invalid mode", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		th.List().ServeHTTP(recorder, userReq(t, "GET", "/api/tasks?subtasks=tree", "", user1, nil))
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v", recorder.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("parent cycle", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		req := userReq(t, "PATCH", "/api/task/"+trip, `{"parentId":"`+pack+`"}`, user1, map[string]string{"ID": trip})
+		th.Patch().ServeHTTP(recorder, req)
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v", recorder.Code, http.StatusBadRequest)
+		}
+	})
+}
+
+func TestTaskHandler_Recurrence(t *testing.T) {
+	th := TodoListHandler{TaskManager: newTestManager(t)}
+
+	recorder := httptest.NewRecorder()
+	th.Create().ServeHTTP(recorder, userReq(t, "POST", "/api/task", `{"text":"gym","recurrence":"FREQ=HOURLY"}`, user1, nil))
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", recorder.Code, http.StatusBadRequest)
+	}
+	want := "invalid recurrence: FREQ must be one of: DAILY, WEEKLY, MONTHLY"
+	if got := strings.TrimSuffix(recorder.Body.String(), "\n"); got != want {
+		t.Errorf("unexpecter error message: got \"%s\" want \"%v\"", got, want)
+	}
+
+	recorder = httptest.NewRecorder()
+	body := `{"text":"gym","dueDate":"2024-05-06","recurrence":"freq=weekly;byday=mo,we"}`
+	th.Create().ServeHTTP(recorder, userReq(t, "POST", "/api/task", body, user1, nil))
+	task := localTaskOutput{}
+	err := json.NewDecoder(recorder.Body).Decode(&task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.Recurrence != "FREQ=WEEKLY;BYDAY=MO,WE" {
+		t.Errorf("unexpected recurrence: %s", task.Recurrence)
+	}
+
+	recorder = httptest.NewRecorder()
+	th.Patch().ServeHTTP(recorder, userReq(t, "PATCH", "/api/task/"+task.Id, `{"Done":true}`, user1, map[string]string{"ID": task.Id}))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", recorder.Code, http.StatusOK)
+	}
+
+	recorder = httptest.NewRecorder()
+	th.List().ServeHTTP(recorder, userReq(t, "GET", "/api/tasks", "", user1, nil))
+	got := localTaskList{}
+	err = json.NewDecoder(recorder.Body).Decode(&got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Count != 2 {
+		t.Fatalf("expected the next occurrence to be created, got %d tasks", got.Count)
+	}
+	for _, occurrence := range got.Tasks {
+		if occurrence.Id != task.Id && (occurrence.DueDate != "2024-05-08" || occurrence.SeriesId != task.Id) {
+			t.Errorf("unexpected next occurrence: %+v", occurrence)
+		}
+	}
+}
+
+func TestTaskHandler_Filters(t *testing.T) {
+	th := TodoListHandler{TaskManager: newTestManager(t)}
+
+	for _, body := range []string{
+		`{"text":"report","dueDate":"2024-05-10","tags":["work"]}`,
+		`{"text":"call boss","done":true,"dueDate":"2024-05-03","tags":["work"]}`,
+		`{"text":"groceries","dueDate":"2024-05-12"}`,
+	} {
+		recorder := httptest.NewRecorder()
+		th.Create().ServeHTTP(recorder, userReq(t, "POST", "/api/task", body, user1, nil))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", recorder.Code, http.StatusOK)
+		}
+	}
+
+	tcs := []struct {
+		name       string
+		query      string
+		expecErr   string
+		expectCode int
+		expect     []string
+	}{
+		{
+			name:       "pending tasks",
+			query:      "done=false",
+			expectCode: http.StatusOK,
+			expect:     []string{"report", "groceries"},
+		},
+		{
+			name:       "sort by due descending",
+			query:      "sort=due&order=desc",
+			expectCode: http.StatusOK,
+			expect:     []string{"groceries", "report", "call boss"},
+		},
+		{
+			name:       "newest first",
+			query:      "order=desc&created_after=2024-01-01",
+			expectCode: http.StatusOK,
+			expect:     []string{"groceries", "call boss", "report"},
+		},
+		{
+			name:       "filter expression",
+			query:      "filter=" + url.QueryEscape("tag:work AND (done:false OR due<2024-05-04)") + "&sort=due",
+			expectCode: http.StatusOK,
+			expect:     []string{"call boss", "report"},
+		},
+		{
+			name:       "invalid done",
+			query:      "done=maybe",
+			expecErr:   "unable to convert done value to boolean",
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid sort",
+			query:      "sort=color",
+			expecErr:   "invalid sort: unknown sort key \"color\", use one of: position, created, updated, due, priority",
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid order",
+			query:      "sort=due&order=up",
+			expecErr:   "order must be one of: asc, desc",
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid updated after",
+			query:      "updated_after=yesterday",
+			expecErr:   "unable to convert updated_after value to date",
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid filter",
+			query:      "filter=" + url.QueryEscape("tag:work OR"),
+			expecErr:   "invalid filter: unexpected end of filter at position 11",
+			expectCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			th.List().ServeHTTP(recorder, userReq(t, "GET", "/api/tasks?"+tc.query, "", user1, nil))
+			if status := recorder.Code; status != tc.expectCode {
+				t.Errorf("handler returned wrong status code: got %v want %v", status, tc.expectCode)
+			}
+			if tc.expecErr != "" {
+				got := strings.TrimSuffix(recorder.Body.String(), "\n")
+				if got != tc.expecErr {
+					t.Errorf("unexpecter error message: got \"%s\" want \"%v\"", got, tc.expecErr)
+				}
+				return
+			}
+			got := localTaskList{}
+			err := json.NewDecoder(recorder.Body).Decode(&got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			texts := []string{}
+			for _, task := range got.Tasks {
+				texts = append(texts, task.Text)
+			}
+			if diff := cmp.Diff(texts, tc.expect); diff != "" {
+				t.Errorf("unexpected value (-got +want)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestTaskHandler_Paging(t *testing.T) {
+	th := TodoListHandler{TaskManager: newTestManager(t)}
+	for i := 1; i <= 5; i++ {
+		_ = createTask(t, th.TaskManager, "task"+strconv.Itoa(i), user1)
+	}
+
+	list := func(t *testing.T, query string) localTaskList {
+		recorder := httptest.NewRecorder()
+		th.List().ServeHTTP(recorder, userReq(t, "GET", "/api/tasks?"+query, "", user1, nil))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", recorder.Code, http.StatusOK)
+		}
+		got := localTaskList{}
+		err := json.NewDecoder(recorder.Body).Decode(&got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+	texts := func(l localTaskList) []string {
+		out := []string{}
+		for _, task := range l.Tasks {
+			out = append(out, task.Text)
+		}
+		return out
+	}
+
+	first := list(t, "limit=2&order=desc")
+	if first.Total != 5 || first.Count != 2 || first.Prev != "" || first.Next == "" {
+		t.Fatalf("unexpected first page: %+v", first)
+	}
+	_ = createTask(t, th.TaskManager, "task6", user1)
+
+	second := list(t, "limit=2&cursor="+first.Next)
+	if diff := cmp.Diff(texts(second), []string{"task3", "task2"}); diff != "" {
+		t.Errorf("unexpected value (-got +want)\n%s", diff)
+	}
+	if second.Total != 6 {
+		t.Errorf("expected total of 6, got %d", second.Total)
+	}
+
+	back := list(t, "limit=2&cursor="+second.Prev)
+	if diff := cmp.Diff(texts(back), []string{"task5", "task4"}); diff != "" {
+		t.Errorf("unexpected value (-got +want)\n%s", diff)
+	}
+
+	recorder := httptest.NewRecorder()
+	th.List().ServeHTTP(recorder, userReq(t, "GET", "/api/tasks?cursor=invalid", "", user1, nil))
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", recorder.Code, http.StatusBadRequest)
+	}
+}
+
+func TestTaskHandler_Move(t *testing.T) {
+	mngr := newTestManager(t)
+	th := TodoListHandler{TaskManager: mngr}
+
+	a := createTask(t, mngr, "a", user1)
+	b := createTask(t, mngr, "b", user1)
+	c := createTask(t, mngr, "c", user1)
+	other := createTask(t, mngr, "other", user2)
+
+	tcs := []struct {
+		name       string
+		id         string
+		body       string
+		expectCode int
+		expectErr  string
+		want       []string
+	}{
+		{
+			name:       "move before",
+			id:         c,
+			body:       `{"before":"` + a + `"}`,
+			expectCode: http.StatusAccepted,
+			want:       []string{"c", "a", "b"},
+		},
+		{
+			name:       "move after",
+			id:         c,
+			body:       `{"after":"` + b + `"}`,
+			expectCode: http.StatusAccepted,
+			want:       []string{"a", "b", "c"},
+		},
+		{
+			name:       "missing anchor",
+			id:         c,
+			body:       `{}`,
+			expectCode: http.StatusBadRequest,
+			expectErr:  "exactly one of before or after is required",
+		},
+		{
+			name:       "both anchors",
+			id:         c,
+			body:       `{"before":"` + a + `","after":"` + b + `"}`,
+			expectCode: http.StatusBadRequest,
+			expectErr:  "exactly one of before or after is required",
+		},
+		{
+			name:       "invalid anchor",
+			id:         c,
+			body:       `{"before":"a"}`,
+			expectCode: http.StatusBadRequest,
+			expectErr:  "anchor task id is not a UUID",
+		},
+		{
+			name:       "move relative to itself",
+			id:         c,
+			body:       `{"before":"` + c + `"}`,
+			expectCode: http.StatusBadRequest,
+			expectErr:  "a task cannot be moved relative to itself",
+		},
+		{
+			name:       "anchor of other user",
+			id:         c,
+			body:       `{"before":"` + other + `"}`,
+			expectCode: http.StatusNotFound,
+			expectErr:  "task with id: " + other + " and owner " + user1 + " not found",
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			req := userReq(t, "POST", "/api/task/"+tc.id+"/move", tc.body, user1, map[string]string{"ID": tc.id})
+			th.Move().ServeHTTP(recorder, req)
+			if recorder.Code != tc.expectCode {
+				t.Fatalf("handler returned wrong status code: got %v want %v", recorder.Code, tc.expectCode)
+			}
+			if tc.expectErr != "" {
+				if got := strings.TrimSuffix(recorder.Body.String(), "\n"); got != tc.expectErr {
+					t.Errorf("unexpecter error message: got \"%s\"", got)
+				}
+				return
+			}
+
+			recorder = httptest.NewRecorder()
+			th.List().ServeHTTP(recorder, userReq(t, "GET", "/api/tasks", "", user1, nil))
+			got := localTaskList{}
+			err := json.NewDecoder(recorder.Body).Decode(&got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			texts := []string{}
+			for _, task := range got.Tasks {
+				texts = append(texts, task.Text)
+			}
+			if diff := cmp.Diff(texts, tc.want); diff != "" {
+				t.Errorf("unexpected value (-got +want)\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package handlrs
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-bumbu/todo-app/internal/model/todolist"
+	"github.com/go-bumbu/userauth/handlers/sessionauth"
+	"net/http"
+	"time"
+)
+
+// TrashHandler exposes the deleted tasks of a user
+type TrashHandler struct {
+	TaskManager *todolist.Manager
+}
+
+type localTrashList struct {
+	Count int
+	Tasks []localTrashOutput
+}
+
+type localTrashOutput struct {
+	localTaskOutput
+	DeletedAt string `json:"deletedAt"`
+}
+
+func (h *TrashHandler) List() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to list trash: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		limit, page, hErr := getPaging(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		items, err := requestManager(r, h.TaskManager).Trash(uData.UserId, limit, page)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to get trash: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		output := localTrashList{
+			Count: len(items),
+			Tasks: make([]localTrashOutput, len(items)),
+		}
+		for i := range items {
+			output.Tasks[i] = localTrashOutput{
+				localTaskOutput: taskOutput(items[i]),
+				DeletedAt:       items[i].DeletedAt.Time.UTC().Format(time.RFC3339),
+			}
+		}
+		writeJson(w, output, http.StatusOK)
+	})
+}
+
+// Restore recovers a deleted task together with the subtasks deleted with it
+func (h *TrashHandler) Restore() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		taskId, hErr := getTaskId(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to restore task: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		err = requestManager(r, h.TaskManager).WithAudit(requestAudit(r, uData.UserId)).Restore(taskId, uData.UserId)
+		if err != nil {
+			trashErr(w, err, "unable to restore task")
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+// RestoreAll recovers all the deleted tasks
+func (h *TrashHandler) RestoreAll() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to restore tasks: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		err = requestManager(r, h.TaskManager).WithAudit(requestAudit(r, uData.UserId)).RestoreAll(uData.UserId)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to restore tasks: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+// Purge permanently deletes a task from the trash
+func (h *TrashHandler) Purge() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		taskId, hErr := getTaskId(r)
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to purge task: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		err = requestManager(r, h.TaskManager).Purge(taskId, uData.UserId)
+		if err != nil {
+			trashErr(w, err, "unable to purge task")
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+// Empty permanently deletes all the tasks in the trash
+func (h *TrashHandler) Empty() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to purge tasks: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		err = requestManager(r, h.TaskManager).EmptyTrash(uData.UserId)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to purge tasks: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+func trashErr(w http.ResponseWriter, err error, msg string) {
+	t := &todolist.ItemNotFountErr{}
+	if errors.As(err, &t) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+	} else {
+		http.Error(w, fmt.Sprintf("%s: %s", msg, err.Error()), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,142 @@
+package handlrs
+
+import (
+	"encoding/json"
+	"github.com/google/go-cmp/cmp"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTrashHandler(t *testing.T) {
+	mngr := newTestManager(t)
+	th := TodoListHandler{TaskManager: mngr}
+	trh := TrashHandler{TaskManager: mngr}
+
+	del := func(t *testing.T, id, user string) {
+		t.Helper()
+		recorder := httptest.NewRecorder()
+		th.Delete().ServeHTTP(recorder, userReq(t, "DELETE", "/api/task/"+id, "", user, map[string]string{"ID": id}))
+		if recorder.Code != http.StatusAccepted {
+			t.Fatalf("handler returned wrong status code: got %v want %v", recorder.Code, http.StatusAccepted)
+		}
+	}
+	trash := func(t *testing.T, user string) []string {
+		t.Helper()
+		recorder := httptest.NewRecorder()
+		trh.List().ServeHTTP(recorder, userReq(t, "GET", "/api/trash", "", user, nil))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", recorder.Code, http.StatusOK)
+		}
+		got := localTrashList{}
+		err := json.NewDecoder(recorder.Body).Decode(&got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		texts := []string{}
+		for _, task := range got.Tasks {
+			if task.DeletedAt == "" {
+				t.Errorf("expected deletion time for task %s", task.Text)
+			}
+			texts = append(texts, task.Text)
+		}
+		return texts
+	}
+
+	milk := createTask(t, mngr, "buy milk", user1)
+	bread := createTask(t, mngr, "buy bread", user1)
+	eggs := createTask(t, mngr, "buy eggs", user1)
+	other := createTask(t, mngr, "other user", user2)
+	for _, id := range []string{milk, bread, eggs} {
+		del(t, id, user1)
+	}
+	del(t, other, user2)
+
+	t.Run("list trash", func(t *testing.T) {
+		if diff := cmp.Diff(trash(t, user1), []string{"buy eggs", "buy bread", "buy milk"}); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+	})
+
+	tcs := []struct {
+		name       string
+		handler    http.Handler
+		user       string
+		vars       map[string]string
+		expectCode int
+		expectErr  string
+		trash      []string
+	}{
+		{
+			name:       "restore task of other user",
+			handler:    trh.Restore(),
+			user:       user1,
+			vars:       map[string]string{"ID": other},
+			expectCode: http.StatusNotFound,
+			expectErr:  "task with id: " + other + " and owner " + user1 + " not found",
+			trash:      []string{"buy eggs", "buy bread", "buy milk"},
+		},
+		{
+			name:       "restore one task",
+			handler:    trh.Restore(),
+			user:       user1,
+			vars:       map[string]string{"ID": milk},
+			expectCode: http.StatusAccepted,
+			trash:      []string{"buy eggs", "buy bread"},
+		},
+		{
+			name:       "purge one task",
+			handler:    trh.Purge(),
+			user:       user1,
+			vars:       map[string]string{"ID": bread},
+			expectCode: http.StatusAccepted,
+			trash:      []string{"buy eggs"},
+		},
+		{
+			name:       "invalid id",
+			handler:    trh.Purge(),
+			user:       user1,
+			vars:       map[string]string{"ID": "not-a-uuid"},
+			expectCode: http.StatusBadRequest,
+			expectErr:  "task id is not a UUID",
+			trash:      []string{"buy eggs"},
+		},
+		{
+			name:       "empty trash",
+			handler:    trh.Empty(),
+			user:       user1,
+			expectCode: http.StatusAccepted,
+			trash:      []string{},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			tc.handler.ServeHTTP(recorder, userReq(t, "POST", "/api/trash", "", tc.user, tc.vars))
+			if recorder.Code != tc.expectCode {
+				t.Fatalf("handler returned wrong status code: got %v want %v", recorder.Code, tc.expectCode)
+			}
+			if tc.expectErr != "" {
+				if got := strings.TrimSuffix(recorder.Body.String(), "\n"); got != tc.expectErr {
+					t.Errorf("unexpecter error message: got \"%s\"", got)
+				}
+			}
+			if diff := cmp.Diff(trash(t, tc.user), tc.trash); diff != "" {
+				t.Errorf("unexpected trash (-got +want)\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("restore all", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		trh.RestoreAll().ServeHTTP(recorder, userReq(t, "POST", "/api/trash/restore", "", user2, nil))
+		if recorder.Code != http.StatusAccepted {
+			t.Fatalf("handler returned wrong status code: got %v want %v", recorder.Code, http.StatusAccepted)
+		}
+		if got := trash(t, user2); len(got) != 0 {
+			t.Errorf("expected empty trash, got %v", got)
+		}
+	})
+}
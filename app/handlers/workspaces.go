@@ -0,0 +1,351 @@
+package handlrs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-bumbu/todo-app/internal/model/todolist"
+	"github.com/go-bumbu/userauth"
+	"github.com/go-bumbu/userauth/handlers/sessionauth"
+	"github.com/gorilla/mux"
+	"net/http"
+	"time"
+)
+
+// WorkspaceHandler exposes the team workspaces of a user and keeps the active workspace in the session
+type WorkspaceHandler struct {
+	TaskManager *todolist.Manager
+	// Session stores the active workspace, without it the personal workspace is always active
+	Session *sessionauth.Manager
+	// Users is used to check that invited users exist, invitations are not checked if it is nil
+	Users userauth.UserGetter
+}
+
+const (
+	workspaceSession = "_c_workspace"
+	workspaceKey     = "active"
+)
+
+type workspaceCtxKey struct{}
+
+// requestWorkspace returns the id of the active workspace of the request, empty for the personal workspace
+func requestWorkspace(r *http.Request) string {
+	ws, _ := r.Context().Value(workspaceCtxKey{}).(string)
+	return ws
+}
+
+// requestManager returns the task manager working in the active workspace of the request
+func requestManager(r *http.Request, m *todolist.Manager) *todolist.Manager {
+	scoped := m.InWorkspace(requestWorkspace(r))
+	return &scoped
+}
+
+// Middleware adds the active workspace stored in the session to the request, it needs to run after the
+// authentication. The membership is checked on every request, users that were removed from the active
+// workspace are back in their personal workspace.
+func (h *WorkspaceHandler) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws := h.activeWorkspace(r)
+		if ws != "" {
+			uData, err := sessionauth.CtxGetUserData(r)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("unable to read workspace: %s", err.Error()), http.StatusInternalServerError)
+				return
+			}
+			_, _, err = h.TaskManager.GetWorkspace(ws, uData.UserId)
+			wErr := &todolist.WorkspaceNotFoundErr{}
+			if errors.As(err, &wErr) {
+				ws = ""
+			} else if err != nil {
+				http.Error(w, fmt.Sprintf("unable to read workspace: %s", err.Error()), http.StatusInternalServerError)
+				return
+			}
+		}
+		if ws != "" {
+			r = r.WithContext(context.WithValue(r.Context(), workspaceCtxKey{}, ws))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// activeWorkspace reads the active workspace from the session
+func (h *WorkspaceHandler) activeWorkspace(r *http.Request) string {
+	if h.Session == nil {
+		return ""
+	}
+	session, err := h.Session.Get(r, workspaceSession)
+	if err != nil {
+		return ""
+	}
+	ws, _ := session.Values[workspaceKey].(string)
+	return ws
+}
+
+type localWorkspaceOutput struct {
+	Id        string    `json:"id"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type localWorkspaceList struct {
+	// Active is the id of the active workspace, it is empty for the personal workspace
+	Active     string `json:"active"`
+	Count      int
+	Workspaces []localWorkspaceOutput
+}
+
+type localWorkspaceInput struct {
+	Name string `json:"name"`
+}
+
+type localActiveWorkspace struct {
+	// id of the workspace, an empty id switches back to the personal workspace
+	Id string `json:"id"`
+}
+
+type localWorkspaceMember struct {
+	Username  string    `json:"username"`
+	Role      string    `json:"role"`
+	InvitedBy string    `json:"invitedBy"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type localWorkspaceMembers struct {
+	Count   int
+	Members []localWorkspaceMember
+}
+
+func workspaceOutput(w todolist.Workspace, role todolist.WorkspaceRole) localWorkspaceOutput {
+	return localWorkspaceOutput{Id: w.ID, Name: w.Name, Role: string(role), CreatedAt: w.CreatedAt}
+}
+
+// List returns the workspaces of the user together with the active one
+func (h *WorkspaceHandler) List() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to list workspaces: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		items, err := h.TaskManager.Workspaces(uData.UserId)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to list workspaces: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		output := localWorkspaceList{
+			Active:     requestWorkspace(r),
+			Count:      len(items),
+			Workspaces: make([]localWorkspaceOutput, len(items)),
+		}
+		for i, item := range items {
+			output.Workspaces[i] = workspaceOutput(item.Workspace, item.Role)
+		}
+		writeJson(w, output, http.StatusOK)
+	})
+}
+
+// Create adds a workspace, the user creating it becomes its admin
+func (h *WorkspaceHandler) Create() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to create workspace: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		if r.Body == nil {
+			http.Error(w, "request had empty body", http.StatusBadRequest)
+			return
+		}
+		payload := localWorkspaceInput{}
+		err = json.NewDecoder(r.Body).Decode(&payload)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to decode json: %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		ws, err := h.TaskManager.CreateWorkspace(payload.Name, uData.UserId)
+		if err != nil {
+			workspaceErr(w, err)
+			return
+		}
+		writeJson(w, workspaceOutput(ws, todolist.RoleWorkspaceAdmin), http.StatusOK)
+	})
+}
+
+// Switch changes the active workspace stored in the session
+func (h *WorkspaceHandler) Switch() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to switch workspace: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		if r.Body == nil {
+			http.Error(w, "request had empty body", http.StatusBadRequest)
+			return
+		}
+		payload := localActiveWorkspace{}
+		err = json.NewDecoder(r.Body).Decode(&payload)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to decode json: %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+		if h.Session == nil {
+			http.Error(w, "workspaces need a session", http.StatusNotImplemented)
+			return
+		}
+
+		output := localWorkspaceOutput{}
+		if payload.Id != "" {
+			ws, role, err := h.TaskManager.GetWorkspace(payload.Id, uData.UserId)
+			if err != nil {
+				workspaceErr(w, err)
+				return
+			}
+			output = workspaceOutput(ws, role)
+		}
+
+		session, err := h.Session.Get(r, workspaceSession)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to switch workspace: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		session.Values[workspaceKey] = payload.Id
+		err = session.Save(r, w)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to switch workspace: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		writeJson(w, output, http.StatusOK)
+	})
+}
+
+// Members returns the members of a workspace
+func (h *WorkspaceHandler) Members() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wsId, hErr := getUuidVar(r, "ID", "workspace")
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to list members: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		members, err := h.TaskManager.WorkspaceMembers(wsId, uData.UserId)
+		if err != nil {
+			workspaceErr(w, err)
+			return
+		}
+		output := localWorkspaceMembers{
+			Count:   len(members),
+			Members: make([]localWorkspaceMember, len(members)),
+		}
+		for i, m := range members {
+			output.Members[i] = localWorkspaceMember{
+				Username:  m.UserId,
+				Role:      string(m.Role),
+				InvitedBy: m.InvitedBy,
+				CreatedAt: m.CreatedAt,
+			}
+		}
+		writeJson(w, output, http.StatusOK)
+	})
+}
+
+// Invite adds a user to the workspace or changes the role of a member, only admins can invite
+func (h *WorkspaceHandler) Invite() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wsId, hErr := getUuidVar(r, "ID", "workspace")
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to invite member: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		if r.Body == nil {
+			http.Error(w, "request had empty body", http.StatusBadRequest)
+			return
+		}
+		payload := localMemberInput{}
+		err = json.NewDecoder(r.Body).Decode(&payload)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to decode json: %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+		if payload.Username == "" {
+			http.Error(w, "username cannot be empty in member payload", http.StatusBadRequest)
+			return
+		}
+		if payload.Role == "" {
+			payload.Role = string(todolist.RoleWorkspaceMember)
+		}
+		if hErr = userExists(h.Users, payload.Username); hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+
+		err = h.TaskManager.AddWorkspaceMember(wsId, uData.UserId, payload.Username, todolist.WorkspaceRole(payload.Role))
+		if err != nil {
+			workspaceErr(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+// RemoveMember removes a member from the workspace, members can also use it to leave the workspace.
+// The access is removed immediately.
+func (h *WorkspaceHandler) RemoveMember() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wsId, hErr := getUuidVar(r, "ID", "workspace")
+		if hErr != nil {
+			http.Error(w, hErr.Error, hErr.Code)
+			return
+		}
+		member := mux.Vars(r)["User"]
+
+		uData, err := sessionauth.CtxGetUserData(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to remove member: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		err = h.TaskManager.WithAudit(requestAudit(r, uData.UserId)).RemoveWorkspaceMember(wsId, uData.UserId, member)
+		if err != nil {
+			workspaceErr(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+// workspaceErr writes the http error matching an error returned by the workspace methods of the manager
+func workspaceErr(w http.ResponseWriter, err error) {
+	wErr := &todolist.WorkspaceNotFoundErr{}
+	mErr := &todolist.WorkspaceMemberNotFoundErr{}
+	if errors.As(err, &wErr) || errors.As(err, &mErr) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+	} else if errors.Is(err, todolist.ErrInvalidWorkspaceRole) || errors.Is(err, todolist.ErrEmptyWorkspaceName) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	} else if errors.Is(err, todolist.ErrNotWorkspaceAdmin) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+	} else if errors.Is(err, todolist.ErrLastWorkspaceAdmin) {
+		http.Error(w, err.Error(), http.StatusConflict)
+	} else {
+		http.Error(w, fmt.Sprintf("unable to process workspace: %s", err.Error()), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,189 @@
+package handlrs
+
+import (
+	"encoding/json"
+	"github.com/go-bumbu/todo-app/internal/model/todolist"
+	"github.com/go-bumbu/userauth/handlers/sessionauth"
+	"github.com/gorilla/securecookie"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWorkspaces(t *testing.T) {
+	mngr := newTestManager(t)
+	store, err := sessionauth.NewCookieStore(securecookie.GenerateRandomKey(64), securecookie.GenerateRandomKey(32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := sessionauth.New(sessionauth.Cfg{Store: store})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wh := WorkspaceHandler{TaskManager: mngr, Session: session}
+	th := TodoListHandler{TaskManager: mngr}
+
+	personal := todolist.TodoItem{Text: "personal", OwnerId: user1}
+	if _, err = mngr.Create(&personal); err != nil {
+		t.Fatal(err)
+	}
+
+	// serve runs the handler behind the workspace middleware, the cookies of the user are kept between requests
+	cookies := map[string][]*http.Cookie{}
+	serve := func(t *testing.T, h http.Handler, req *http.Request, user string) *httptest.ResponseRecorder {
+		t.Helper()
+		for _, c := range cookies[user] {
+			req.AddCookie(c)
+		}
+		recorder := httptest.NewRecorder()
+		wh.Middleware(h).ServeHTTP(recorder, req)
+		if c := recorder.Result().Cookies(); len(c) > 0 {
+			cookies[user] = c
+		}
+		return recorder
+	}
+	taskTexts := func(t *testing.T, user string) []string {
+		t.Helper()
+		recorder := serve(t, th.List(), userReq(t, "GET", "/api/tasks", "", user, nil), user)
+		got := localTaskList{}
+		if err := json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		texts := []string{}
+		for _, task := range got.Tasks {
+			texts = append(texts, task.Text)
+		}
+		return texts
+	}
+
+	recorder := serve(t, wh.Create(), userReq(t, "POST", "/api/workspaces", `{"name":"acme"}`, user1, nil), user1)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", recorder.Code, http.StatusOK, recorder.Body.String())
+	}
+	ws := localWorkspaceOutput{}
+	if err = json.NewDecoder(recorder.Body).Decode(&ws); err != nil {
+		t.Fatal(err)
+	}
+	wsVars := map[string]string{"ID": ws.Id}
+	memberVars := map[string]string{"ID": ws.Id, "User": user2}
+
+	tcs := []struct {
+		name       string
+		handler    http.Handler
+		req        *http.Request
+		user       string
+		expectCode int
+		expectErr  string
+	}{
+		{
+			name:       "empty name",
+			handler:    wh.Create(),
+			req:        userReq(t, "POST", "/api/workspaces", `{"name":" "}`, user1, nil),
+			user:       user1,
+			expectCode: http.StatusBadRequest,
+			expectErr:  "workspace name cannot be empty",
+		},
+		{
+			name:       "switch to a workspace of another team",
+			handler:    wh.Switch(),
+			req:        userReq(t, "PUT", "/api/workspaces/active", `{"id":"`+ws.Id+`"}`, user2, nil),
+			user:       user2,
+			expectCode: http.StatusNotFound,
+		},
+		{
+			name:       "switch to the workspace",
+			handler:    wh.Switch(),
+			req:        userReq(t, "PUT", "/api/workspaces/active", `{"id":"`+ws.Id+`"}`, user1, nil),
+			user:       user1,
+			expectCode: http.StatusOK,
+		},
+		{
+			name:       "create task in the workspace",
+			handler:    th.Create(),
+			req:        userReq(t, "POST", "/api/task", `{"text":"team"}`, user1, nil),
+			user:       user1,
+			expectCode: http.StatusOK,
+		},
+		{
+			name:       "invite member",
+			handler:    wh.Invite(),
+			req:        userReq(t, "POST", "/api/workspaces/"+ws.Id+"/members", `{"username":"user2"}`, user1, wsVars),
+			user:       user1,
+			expectCode: http.StatusAccepted,
+		},
+		{
+			name:       "member cannot invite",
+			handler:    wh.Invite(),
+			req:        userReq(t, "POST", "/api/workspaces/"+ws.Id+"/members", `{"username":"user3"}`, user2, wsVars),
+			user:       user2,
+			expectCode: http.StatusForbidden,
+			expectErr:  "only workspace admins can manage members",
+		},
+		{
+			name:       "member switches to the workspace",
+			handler:    wh.Switch(),
+			req:        userReq(t, "PUT", "/api/workspaces/active", `{"id":"`+ws.Id+`"}`, user2, nil),
+			user:       user2,
+			expectCode: http.StatusOK,
+		},
+		{
+			name:       "last admin cannot leave",
+			handler:    wh.RemoveMember(),
+			req:        userReq(t, "DELETE", "/api/workspaces/"+ws.Id+"/members/user1", "", user1, map[string]string{"ID": ws.Id, "User": user1}),
+			user:       user1,
+			expectCode: http.StatusConflict,
+		},
+		{
+			name:       "remove member",
+			handler:    wh.RemoveMember(),
+			req:        userReq(t, "DELETE", "/api/workspaces/"+ws.Id+"/members/user2", "", user1, memberVars),
+			user:       user1,
+			expectCode: http.StatusAccepted,
+		},
+		{
+			name:       "removed member cannot list members",
+			handler:    wh.Members(),
+			req:        userReq(t, "GET", "/api/workspaces/"+ws.Id+"/members", "", user2, wsVars),
+			user:       user2,
+			expectCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := serve(t, tc.handler, tc.req, tc.user)
+			if recorder.Code != tc.expectCode {
+				t.Errorf("handler returned wrong status code: got %v want %v: %s", recorder.Code, tc.expectCode, recorder.Body.String())
+			}
+			if got := strings.TrimSuffix(recorder.Body.String(), "\n"); tc.expectErr != "" && got != tc.expectErr {
+				t.Errorf("unexpecter error message: got \"%s\"", got)
+			}
+		})
+	}
+
+	t.Run("tasks are scoped by the active workspace", func(t *testing.T) {
+		if got := taskTexts(t, user1); len(got) != 1 || got[0] != "team" {
+			t.Errorf("unexpected tasks in the workspace: %v", got)
+		}
+		// the removed member is back in the personal workspace
+		if got := taskTexts(t, user2); len(got) != 0 {
+			t.Errorf("unexpected tasks of a removed member: %v", got)
+		}
+		serve(t, wh.Switch(), userReq(t, "PUT", "/api/workspaces/active", `{"id":""}`, user1, nil), user1)
+		if got := taskTexts(t, user1); len(got) != 1 || got[0] != "personal" {
+			t.Errorf("unexpected tasks in the personal workspace: %v", got)
+		}
+	})
+
+	t.Run("list workspaces", func(t *testing.T) {
+		recorder := serve(t, wh.List(), userReq(t, "GET", "/api/workspaces", "", user1, nil), user1)
+		got := localWorkspaceList{}
+		if err := json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Active != "" || got.Count != 1 || got.Workspaces[0].Id != ws.Id || got.Workspaces[0].Role != "admin" {
+			t.Errorf("unexpected workspaces: %+v", got)
+		}
+	})
+}
@@ -13,15 +13,127 @@ func (h *MainAppHandler) attachApiV0(r *mux.Router) {
 	auth := authenticator.New(authHandlers, h.logger, nil, nil)
 
 	r.Use(auth.Middleware)
+	// the active workspace is read after the user is authenticated
+	wh := handlrs.WorkspaceHandler{TaskManager: h.todoListMngr, Session: h.SessionAuth, Users: h.userMngr.UserStore}
+	r.Use(wh.Middleware)
+	h.attachApiWorkspace(r, &wh)
 	h.attachApiTask(r)
+	h.attachApiList(r)
+	h.attachApiTag(r)
+	h.attachApiTrash(r)
+	h.attachApiStatus(r)
+	h.attachApiAttachment(r)
+	h.attachApiComment(r)
+	h.attachApiFilter(r)
 }
 
 func (h *MainAppHandler) attachApiTask(r *mux.Router) {
 	// add tasks api
 	th := handlrs.TodoListHandler{TaskManager: h.todoListMngr}
 	r.Path("/tasks").Methods(http.MethodGet).Handler(th.List())
+	r.Path("/tasks/search").Methods(http.MethodGet).Handler(th.Search())
+	r.Path("/tasks/focus").Methods(http.MethodGet).Handler(th.Focus())
+	r.Path("/tasks/bulk").Methods(http.MethodPost).Handler(th.Bulk())
 	r.Path("/task").Methods(http.MethodPost).Handler(th.Create())
 	r.Path("/task/{ID}").Methods(http.MethodGet).Handler(th.Read())
 	r.Path("/task/{ID}").Methods(http.MethodDelete).Handler(th.Delete())
 	r.Path("/task/{ID}").Methods(http.MethodPut).Handler(th.Update())
+	r.Path("/task/{ID}").Methods(http.MethodPatch).Handler(th.Patch())
+	r.Path("/task/{ID}/move").Methods(http.MethodPost).Handler(th.Move())
+	r.Path("/task/{ID}/status").Methods(http.MethodPost).Handler(th.Transition())
+	r.Path("/task/{ID}/checkbox").Methods(http.MethodPost).Handler(th.Checkbox())
+	r.Path("/task/{ID}/history").Methods(http.MethodGet).Handler(th.History())
+	r.Path("/task/{ID}/history/{ChangeID}/revert").Methods(http.MethodPost).Handler(th.Revert())
+	r.Path("/board").Methods(http.MethodGet).Handler(th.Board())
+}
+
+func (h *MainAppHandler) attachApiList(r *mux.Router) {
+	// add lists api
+	lh := handlrs.ListsHandler{TaskManager: h.todoListMngr, Users: h.userMngr.UserStore}
+	r.Path("/lists").Methods(http.MethodGet).Handler(lh.List())
+	r.Path("/lists").Methods(http.MethodPost).Handler(lh.Create())
+	r.Path("/lists/shared").Methods(http.MethodGet).Handler(lh.SharedWithMe())
+	r.Path("/lists/{ID}").Methods(http.MethodGet).Handler(lh.Read())
+	r.Path("/lists/{ID}").Methods(http.MethodDelete).Handler(lh.Delete())
+	r.Path("/lists/{ID}").Methods(http.MethodPut).Handler(lh.Update())
+	r.Path("/lists/{ID}/tasks").Methods(http.MethodGet).Handler(lh.Tasks())
+	r.Path("/lists/{ID}/graph").Methods(http.MethodGet).Handler(lh.Graph())
+	r.Path("/lists/{ID}/members").Methods(http.MethodGet).Handler(lh.Members())
+	r.Path("/lists/{ID}/members").Methods(http.MethodPost).Handler(lh.Invite())
+	r.Path("/lists/{ID}/members/{User}").Methods(http.MethodPut).Handler(lh.UpdateMember())
+	r.Path("/lists/{ID}/members/{User}").Methods(http.MethodDelete).Handler(lh.RemoveMember())
+	r.Path("/lists/{ID}/accept").Methods(http.MethodPost).Handler(lh.Accept())
+	r.Path("/lists/{ID}/links").Methods(http.MethodGet).Handler(lh.Links())
+	r.Path("/lists/{ID}/links").Methods(http.MethodPost).Handler(lh.CreateLink())
+	r.Path("/lists/{ID}/links/{Token}").Methods(http.MethodDelete).Handler(lh.RevokeLink())
+}
+
+func (h *MainAppHandler) attachApiTag(r *mux.Router) {
+	// add tags api
+	tgh := handlrs.TagsHandler{TaskManager: h.todoListMngr}
+	r.Path("/tags").Methods(http.MethodGet).Handler(tgh.List())
+	r.Path("/tags").Methods(http.MethodPost).Handler(tgh.Create())
+	r.Path("/tags/{ID}").Methods(http.MethodGet).Handler(tgh.Read())
+	r.Path("/tags/{ID}").Methods(http.MethodDelete).Handler(tgh.Delete())
+	r.Path("/tags/{ID}").Methods(http.MethodPut).Handler(tgh.Update())
+	r.Path("/tags/{ID}/merge").Methods(http.MethodPost).Handler(tgh.Merge())
+}
+
+func (h *MainAppHandler) attachApiTrash(r *mux.Router) {
+	// add trash api
+	trh := handlrs.TrashHandler{TaskManager: h.todoListMngr}
+	r.Path("/trash").Methods(http.MethodGet).Handler(trh.List())
+	r.Path("/trash").Methods(http.MethodDelete).Handler(trh.Empty())
+	r.Path("/trash/restore").Methods(http.MethodPost).Handler(trh.RestoreAll())
+	r.Path("/trash/{ID}").Methods(http.MethodDelete).Handler(trh.Purge())
+	r.Path("/trash/{ID}/restore").Methods(http.MethodPost).Handler(trh.Restore())
+}
+
+func (h *MainAppHandler) attachApiStatus(r *mux.Router) {
+	// add board statuses api
+	sh := handlrs.StatusHandler{TaskManager: h.todoListMngr}
+	r.Path("/statuses").Methods(http.MethodGet).Handler(sh.List())
+	r.Path("/statuses").Methods(http.MethodPost).Handler(sh.Create())
+	r.Path("/statuses/{ID}").Methods(http.MethodPut).Handler(sh.Update())
+	r.Path("/statuses/{ID}").Methods(http.MethodDelete).Handler(sh.Delete())
+}
+
+func (h *MainAppHandler) attachApiAttachment(r *mux.Router) {
+	// add attachments api
+	ah := handlrs.AttachmentHandler{TaskManager: h.todoListMngr}
+	r.Path("/task/{ID}/attachments").Methods(http.MethodGet).Handler(ah.List())
+	r.Path("/task/{ID}/attachments").Methods(http.MethodPost).Handler(ah.Upload())
+	r.Path("/attachments/usage").Methods(http.MethodGet).Handler(ah.Usage())
+	r.Path("/attachments/{ID}").Methods(http.MethodGet).Handler(ah.Download())
+	r.Path("/attachments/{ID}").Methods(http.MethodDelete).Handler(ah.Delete())
+}
+
+func (h *MainAppHandler) attachApiComment(r *mux.Router) {
+	// add comments api
+	ch := handlrs.CommentHandler{TaskManager: h.todoListMngr}
+	r.Path("/task/{ID}/comments").Methods(http.MethodGet).Handler(ch.List())
+	r.Path("/task/{ID}/comments").Methods(http.MethodPost).Handler(ch.Create())
+	r.Path("/task/{ID}/comments/{CommentID}").Methods(http.MethodPut).Handler(ch.Update())
+	r.Path("/task/{ID}/comments/{CommentID}").Methods(http.MethodDelete).Handler(ch.Delete())
+}
+
+func (h *MainAppHandler) attachApiWorkspace(r *mux.Router, wh *handlrs.WorkspaceHandler) {
+	// add workspaces api
+	r.Path("/workspaces").Methods(http.MethodGet).Handler(wh.List())
+	r.Path("/workspaces").Methods(http.MethodPost).Handler(wh.Create())
+	r.Path("/workspaces/active").Methods(http.MethodPut).Handler(wh.Switch())
+	r.Path("/workspaces/{ID}/members").Methods(http.MethodGet).Handler(wh.Members())
+	r.Path("/workspaces/{ID}/members").Methods(http.MethodPost).Handler(wh.Invite())
+	r.Path("/workspaces/{ID}/members/{User}").Methods(http.MethodDelete).Handler(wh.RemoveMember())
+}
+
+func (h *MainAppHandler) attachApiFilter(r *mux.Router) {
+	// add saved filters api
+	fh := handlrs.SavedFilterHandler{TaskManager: h.todoListMngr}
+	r.Path("/filters").Methods(http.MethodGet).Handler(fh.List())
+	r.Path("/filters").Methods(http.MethodPost).Handler(fh.Create())
+	r.Path("/filters/{ID}").Methods(http.MethodGet).Handler(fh.Read())
+	r.Path("/filters/{ID}").Methods(http.MethodPut).Handler(fh.Update())
+	r.Path("/filters/{ID}").Methods(http.MethodDelete).Handler(fh.Delete())
+	r.Path("/filters/{ID}/tasks").Methods(http.MethodGet).Handler(fh.Tasks())
 }
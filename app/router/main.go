@@ -11,6 +11,7 @@ import (
 	handlrs "github.com/go-bumbu/todo-app/app/handlers"
 	"github.com/go-bumbu/userauth"
 	"github.com/go-bumbu/userauth/handlers/sessionauth"
+	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 
 	"github.com/go-bumbu/todo-app/app/spa"
@@ -56,6 +57,9 @@ func New(cfg Cfg) (*MainAppHandler, error) {
 	// normally on real world project you would never add this middleware
 	app.addDelayMiddleware(app.router)
 
+	// the request id has to be set before the logging middleware reads it
+	r.Use(requestId)
+
 	prodMid := middleware.New(middleware.Cfg{
 		JsonErrors:  false,
 		GenericErrs: cfg.ProductionMode,
@@ -69,6 +73,9 @@ func New(cfg Cfg) (*MainAppHandler, error) {
 	// add a handler for /api/v0, this includes authentication on tasks
 	app.attachApiV0(app.router.PathPrefix("/api/v0").Subrouter())
 
+	// public share links are served without authentication, the token only grants read access to one list
+	app.attachPublicShare(app.router.PathPrefix(handlrs.SharePath).Subrouter())
+
 	// attach another handler to /demo to showcase other use-cases
 	app.attachDemo(app.router.PathPrefix("/demo").Subrouter())
 
@@ -95,6 +102,11 @@ func (h *MainAppHandler) attachTodolistSpa(r *mux.Router, path string) error {
 	return nil
 }
 
+func (h *MainAppHandler) attachPublicShare(r *mux.Router) {
+	ph := handlrs.PublicListHandler{TaskManager: h.todoListMngr}
+	r.Path("/{Token}").Methods(http.MethodGet).Handler(ph.Read())
+}
+
 func (h *MainAppHandler) attachUserAuth(r *mux.Router) {
 
 	//  LOGIN
@@ -127,6 +139,23 @@ func (h *MainAppHandler) addDelayMiddleware(r *mux.Router) {
 	}
 }
 
+// maxRequestIdLen limits the size of the request ids sent by clients
+const maxRequestIdLen = 128
+
+// requestId makes sure every request has an id, ids sent by the client are kept to correlate the logs
+// and the task history with the client, the id is returned in the response
+func requestId(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(handlrs.RequestIdHeader)
+		if id == "" || len(id) > maxRequestIdLen {
+			id = uuid.NewString()
+			r.Header.Set(handlrs.RequestIdHeader, id)
+		}
+		w.Header().Set(handlrs.RequestIdHeader, id)
+		next.ServeHTTP(w, r)
+	})
+}
+
 func StatusErr(status int) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, http.StatusText(status), status)
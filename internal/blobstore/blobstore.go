@@ -0,0 +1,54 @@
+// Package blobstore keeps the content of files uploaded by the users, like task attachments.
+// The Store interface only relies on streaming reads and writes addressed by key so that it can be
+// backed by the local file system or by an S3 compatible object storage.
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrNotFound is returned when reading a key that does not exist
+var ErrNotFound = errors.New("blob not found")
+
+// ErrInvalidKey is returned for keys that are empty or contain characters other than letters,
+// digits, dots, dashes and underscores
+var ErrInvalidKey = errors.New("invalid blob key")
+
+// Store saves and reads blobs by key, keys are chosen by the caller and are never reused
+type Store interface {
+	// Put stores the content read from r until EOF under key and returns the amount of bytes written,
+	// the blob is only visible once the whole content has been written
+	Put(ctx context.Context, key string, r io.Reader) (int64, error)
+	// Get opens the blob stored under key, the caller has to close it. Implementations may return
+	// an io.ReadSeeker to allow range requests.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob, deleting a key that does not exist is not an error
+	Delete(ctx context.Context, key string) error
+}
+
+func validKey(key string) bool {
+	if key == "" || key == "." || key == ".." {
+		return false
+	}
+	for _, c := range key {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '.' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// ctxReader stops reading once the context is done, e.g. when the upload request is canceled
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c ctxReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
@@ -0,0 +1,87 @@
+package blobstore_test
+
+import (
+	"context"
+	"errors"
+	"github.com/go-bumbu/todo-app/internal/blobstore"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFS(t *testing.T) {
+	dir := t.TempDir()
+	store, err := blobstore.NewFS(filepath.Join(dir, "blobs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	t.Run("put, get and delete", func(t *testing.T) {
+		n, err := store.Put(ctx, "abc-123", strings.NewReader("hello"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 5 {
+			t.Errorf("unexpected size %d", n)
+		}
+
+		f, err := store.Get(ctx, "abc-123")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := f.(io.ReadSeeker); !ok {
+			t.Errorf("expected the file to be seekable")
+		}
+		content, err := io.ReadAll(f)
+		_ = f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != "hello" {
+			t.Errorf("unexpected content %q", content)
+		}
+
+		err = store.Delete(ctx, "abc-123")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = store.Get(ctx, "abc-123")
+		if !errors.Is(err, blobstore.ErrNotFound) {
+			t.Errorf("expected not found error, got: %v", err)
+		}
+		if err = store.Delete(ctx, "abc-123"); err != nil {
+			t.Errorf("deleting a missing blob should not fail: %v", err)
+		}
+	})
+
+	t.Run("invalid keys", func(t *testing.T) {
+		for _, key := range []string{"", "..", "../escape", "a/b", `a\b`} {
+			_, err := store.Put(ctx, key, strings.NewReader("x"))
+			if !errors.Is(err, blobstore.ErrInvalidKey) {
+				t.Errorf("%q: expected invalid key error, got: %v", key, err)
+			}
+		}
+		if _, err := os.Stat(filepath.Join(dir, "escape")); err == nil {
+			t.Errorf("blob was written outside of the store")
+		}
+	})
+
+	t.Run("canceled upload leaves nothing behind", func(t *testing.T) {
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err := store.Put(canceled, "canceled", strings.NewReader("data"))
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected canceled error, got: %v", err)
+		}
+		entries, err := os.ReadDir(filepath.Join(dir, "blobs", "ca"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 0 {
+			t.Errorf("unexpected files: %v", entries)
+		}
+	})
+}
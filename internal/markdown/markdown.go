@@ -0,0 +1,589 @@
+// Package markdown renders the notes of the tasks. It supports a subset of CommonMark together with
+// GitHub style task lists and strikethrough: headings, paragraphs, block quotes, lists, fenced code,
+// rules, emphasis, code spans and links.
+//
+// The output is safe to insert in a page as is: raw HTML in the source is escaped, only the tags written
+// by the renderer are emitted and links are limited to http, https and mailto. Images are rendered as
+// links so that opening the notes never loads remote content.
+package markdown
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// ErrNoCheckbox is returned when toggling a task list item that does not exist
+var ErrNoCheckbox = errors.New("checkbox not found")
+
+// Render converts the markdown source into HTML
+func Render(src string) string {
+	r := newRenderer(src)
+	r.blocks(r.lines(), false)
+	return r.out.String()
+}
+
+// SetCheckbox checks or unchecks the task list item with the given index, items are counted in
+// document order starting at 0, which is the data-index attribute of the rendered checkbox.
+// The rest of the source is returned unchanged.
+func SetCheckbox(src string, index int, checked bool) (string, error) {
+	r := newRenderer(src)
+	r.blocks(r.lines(), false)
+	if index < 0 || index >= len(r.checkboxes) {
+		return src, ErrNoCheckbox
+	}
+	cb := r.checkboxes[index]
+	mark := " "
+	if checked {
+		mark = "x"
+	}
+	raw := strings.Split(src, "\n")
+	raw[cb.line] = raw[cb.line][:cb.col] + mark + raw[cb.line][cb.col+1:]
+	return strings.Join(raw, "\n"), nil
+}
+
+// ClearCheckboxes unchecks all the task list items
+func ClearCheckboxes(src string) string {
+	r := newRenderer(src)
+	r.blocks(r.lines(), false)
+	raw := strings.Split(src, "\n")
+	for _, cb := range r.checkboxes {
+		raw[cb.line] = raw[cb.line][:cb.col] + " " + raw[cb.line][cb.col+1:]
+	}
+	return strings.Join(raw, "\n")
+}
+
+// line is a line of the source without the prefixes of the blocks containing it, text is always
+// a suffix of the source line so that positions can be mapped back to the source
+type line struct {
+	text string
+	no   int
+}
+
+// checkbox is the position of the mark between the brackets of a task list item
+type checkbox struct {
+	line int
+	col  int
+}
+
+type renderer struct {
+	raw        []string
+	out        bytes.Buffer
+	checkboxes []checkbox
+	inLink     bool
+}
+
+func newRenderer(src string) *renderer {
+	raw := strings.Split(src, "\n")
+	for i := range raw {
+		raw[i] = strings.TrimSuffix(raw[i], "\r")
+	}
+	return &renderer{raw: raw}
+}
+
+func (r *renderer) lines() []line {
+	lines := make([]line, len(r.raw))
+	for i, text := range r.raw {
+		lines[i] = line{text: text, no: i}
+	}
+	return lines
+}
+
+var (
+	headingRe = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	fenceRe   = regexp.MustCompile("^( {0,3})(`{3,}|~{3,})[ \t]*([^`]*)$")
+	ruleRe    = regexp.MustCompile(`^ {0,3}(?:(?:\*[ \t]*){3,}|(?:-[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	quoteRe   = regexp.MustCompile(`^ {0,3}> ?`)
+	markerRe  = regexp.MustCompile(`^( {0,3})([-*+]|(\d{1,9})[.)])(?:[ \t]+|$)`)
+	langRe    = regexp.MustCompile(`^[A-Za-z0-9_+#.-]+$`)
+)
+
+func isBlank(text string) bool {
+	return strings.TrimSpace(text) == ""
+}
+
+// indent returns the width of the leading white space, tabs advance to the next multiple of 4
+func indent(text string) int {
+	w := 0
+	for _, c := range text {
+		switch c {
+		case ' ':
+			w++
+		case '\t':
+			w += 4 - w%4
+		default:
+			return w
+		}
+	}
+	return w
+}
+
+// dedent removes up to n columns of leading white space
+func dedent(text string, n int) string {
+	w := 0
+	for i, c := range text {
+		if w >= n || (c != ' ' && c != '\t') {
+			return text[i:]
+		}
+		if c == ' ' {
+			w++
+		} else {
+			w += 4 - w%4
+		}
+	}
+	return ""
+}
+
+type listMarker struct {
+	ordered bool
+	delim   byte // last character of the marker, items with another delimiter start a new list
+	start   string
+	content int // offset of the item content
+}
+
+func parseMarker(text string) (listMarker, bool) {
+	m := markerRe.FindStringSubmatch(text)
+	if m == nil {
+		return listMarker{}, false
+	}
+	return listMarker{
+		ordered: m[3] != "",
+		delim:   m[2][len(m[2])-1],
+		start:   strings.TrimLeft(m[3], "0"),
+		content: len(m[0]),
+	}, true
+}
+
+func isBlockStart(text string) bool {
+	if headingRe.MatchString(text) || fenceRe.MatchString(text) || ruleRe.MatchString(text) || quoteRe.MatchString(text) {
+		return true
+	}
+	_, ok := parseMarker(text)
+	return ok
+}
+
+// blocks renders a sequence of lines, in tight lists paragraphs are written without <p>
+func (r *renderer) blocks(lines []line, tight bool) {
+	for i := 0; i < len(lines); {
+		text := lines[i].text
+		switch {
+		case isBlank(text):
+			i++
+		case fenceRe.MatchString(text):
+			i = r.code(lines, i)
+		case headingRe.MatchString(text):
+			m := headingRe.FindStringSubmatch(text)
+			fmt.Fprintf(&r.out, "<h%d>", len(m[1]))
+			r.inline(m[2])
+			fmt.Fprintf(&r.out, "</h%d>\n", len(m[1]))
+			i++
+		case ruleRe.MatchString(text):
+			r.out.WriteString("<hr>\n")
+			i++
+		case quoteRe.MatchString(text):
+			i = r.quote(lines, i)
+		default:
+			if _, ok := parseMarker(text); ok {
+				i = r.list(lines, i)
+			} else {
+				i = r.paragraph(lines, i, tight)
+			}
+		}
+	}
+}
+
+func (r *renderer) code(lines []line, i int) int {
+	m := fenceRe.FindStringSubmatch(lines[i].text)
+	open, fence := len(m[1]), m[2]
+	lang := strings.Fields(m[3])
+	if len(lang) > 0 && langRe.MatchString(lang[0]) {
+		fmt.Fprintf(&r.out, "<pre><code class=\"language-%s\">", html.EscapeString(lang[0]))
+	} else {
+		r.out.WriteString("<pre><code>")
+	}
+	for i++; i < len(lines); i++ {
+		text := lines[i].text
+		trimmed := strings.TrimSpace(text)
+		if indent(text) < 4 && strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+			i++
+			break
+		}
+		r.out.WriteString(html.EscapeString(dedent(text, open)))
+		r.out.WriteString("\n")
+	}
+	r.out.WriteString("</code></pre>\n")
+	return i
+}
+
+func (r *renderer) quote(lines []line, i int) int {
+	inner := []line{}
+	for ; i < len(lines); i++ {
+		loc := quoteRe.FindStringIndex(lines[i].text)
+		if loc == nil {
+			break
+		}
+		inner = append(inner, line{text: lines[i].text[loc[1]:], no: lines[i].no})
+	}
+	r.out.WriteString("<blockquote>\n")
+	r.blocks(inner, false)
+	r.out.WriteString("</blockquote>\n")
+	return i
+}
+
+func (r *renderer) paragraph(lines []line, i int, tight bool) int {
+	texts := []string{}
+	for ; i < len(lines); i++ {
+		text := lines[i].text
+		if isBlank(text) || (len(texts) > 0 && isBlockStart(text)) {
+			break
+		}
+		texts = append(texts, strings.TrimLeft(text, " \t"))
+	}
+	if !tight {
+		r.out.WriteString("<p>")
+	}
+	for j, text := range texts {
+		if j > 0 {
+			r.out.WriteString("\n")
+		}
+		last := j == len(texts)-1
+		switch {
+		case !last && strings.HasSuffix(text, "  "):
+			r.inline(strings.TrimRight(text, " "))
+			r.out.WriteString("<br>")
+		case !last && strings.HasSuffix(text, "\\"):
+			r.inline(strings.TrimSuffix(text, "\\"))
+			r.out.WriteString("<br>")
+		default:
+			r.inline(strings.TrimRight(text, " \t"))
+		}
+	}
+	if !tight {
+		r.out.WriteString("</p>")
+	}
+	r.out.WriteString("\n")
+	return i
+}
+
+func sameList(a, b listMarker) bool {
+	return a.ordered == b.ordered && a.delim == b.delim
+}
+
+func (r *renderer) list(lines []line, i int) int {
+	first, _ := parseMarker(lines[i].text)
+	items := [][]line{}
+	loose := false
+	for i < len(lines) {
+		m, ok := parseMarker(lines[i].text)
+		if !ok || !sameList(m, first) {
+			break
+		}
+		item := []line{{text: lines[i].text[m.content:], no: lines[i].no}}
+		for i++; i < len(lines); i++ {
+			text := lines[i].text
+			if isBlank(text) {
+				// blank lines belong to the item if the item goes on after them
+				j := i
+				for j < len(lines) && isBlank(lines[j].text) {
+					j++
+				}
+				if j == len(lines) || indent(lines[j].text) < m.content {
+					break
+				}
+				item = append(item, lines[i:j]...)
+				loose = true
+				i = j - 1
+				continue
+			}
+			if indent(text) >= m.content {
+				item = append(item, line{text: dedent(text, m.content), no: lines[i].no})
+				continue
+			}
+			if isBlockStart(text) {
+				break
+			}
+			// lazy continuation of the paragraph
+			item = append(item, line{text: strings.TrimLeft(text, " \t"), no: lines[i].no})
+		}
+		items = append(items, item)
+
+		j := i
+		for j < len(lines) && isBlank(lines[j].text) {
+			j++
+		}
+		if j > i && j < len(lines) {
+			if next, ok := parseMarker(lines[j].text); ok && sameList(next, first) {
+				loose = true
+				i = j
+			}
+		}
+	}
+
+	tag := "ul"
+	if first.ordered {
+		tag = "ol"
+	}
+	if first.ordered && first.start != "1" {
+		start := first.start
+		if start == "" {
+			start = "0"
+		}
+		fmt.Fprintf(&r.out, "<ol start=\"%s\">\n", start)
+	} else {
+		fmt.Fprintf(&r.out, "<%s>\n", tag)
+	}
+	for _, item := range items {
+		r.item(item, !loose)
+	}
+	fmt.Fprintf(&r.out, "</%s>\n", tag)
+	return i
+}
+
+func (r *renderer) item(item []line, tight bool) {
+	text := item[0].text
+	if len(text) >= 3 && text[0] == '[' && text[2] == ']' && strings.ContainsRune(" xX", rune(text[1])) &&
+		(len(text) == 3 || text[3] == ' ' || text[3] == '\t') {
+		r.checkboxes = append(r.checkboxes, checkbox{
+			line: item[0].no,
+			col:  len(r.raw[item[0].no]) - len(text) + 1,
+		})
+		checked := ""
+		if text[1] != ' ' {
+			checked = " checked"
+		}
+		fmt.Fprintf(&r.out, "<li class=\"task\"><input type=\"checkbox\" data-index=\"%d\"%s> ", len(r.checkboxes)-1, checked)
+		item[0].text = strings.TrimLeft(text[3:], " \t")
+	} else {
+		r.out.WriteString("<li>")
+	}
+	if !tight {
+		r.out.WriteString("\n")
+	}
+	r.blocks(item, tight)
+	if b := r.out.Bytes(); b[len(b)-1] == '\n' {
+		r.out.Truncate(len(b) - 1)
+	}
+	r.out.WriteString("</li>\n")
+}
+
+func isPunct(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n'
+}
+
+func isAlnum(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
+
+// inlineSpecial are the characters that can start an inline element
+const inlineSpecial = "\\`*_~[!<h"
+
+var autolinkRe = regexp.MustCompile(`^<((?:https?://|mailto:)[^\s<>]+)>`)
+var bareLinkRe = regexp.MustCompile(`^https?://[^\s<>]+`)
+
+func (r *renderer) inline(s string) {
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && isPunct(s[i+1]):
+			r.out.WriteString(html.EscapeString(s[i+1 : i+2]))
+			i += 2
+			continue
+		case c == '`':
+			i += r.codeSpan(s, i)
+			continue
+		case c == '*' || c == '_' || c == '~':
+			if n := r.emphasis(s, i); n > 0 {
+				i += n
+				continue
+			}
+		case c == '[' || (c == '!' && i+1 < len(s) && s[i+1] == '['):
+			if n := r.link(s, i); n > 0 {
+				i += n
+				continue
+			}
+		case c == '<' && !r.inLink:
+			if m := autolinkRe.FindStringSubmatch(s[i:]); m != nil {
+				r.anchor(m[1], func() { r.out.WriteString(html.EscapeString(strings.TrimPrefix(m[1], "mailto:"))) })
+				i += len(m[0])
+				continue
+			}
+		case c == 'h' && !r.inLink && (i == 0 || !isAlnum(s[i-1])):
+			if m := bareLinkRe.FindString(s[i:]); m != "" {
+				m = strings.TrimRight(m, ".,:;!?)'\"")
+				r.anchor(m, func() { r.out.WriteString(html.EscapeString(m)) })
+				i += len(m)
+				continue
+			}
+		}
+		// plain text up to the next character that could start an inline element
+		j := i + 1
+		for j < len(s) && strings.IndexByte(inlineSpecial, s[j]) < 0 {
+			j++
+		}
+		r.out.WriteString(html.EscapeString(s[i:j]))
+		i = j
+	}
+}
+
+// runLength counts the repetitions of the character at i
+func runLength(s string, i int) int {
+	n := 1
+	for i+n < len(s) && s[i+n] == s[i] {
+		n++
+	}
+	return n
+}
+
+// codeSpan renders the code span starting at i, a run of backticks without closing run is written as text
+func (r *renderer) codeSpan(s string, i int) int {
+	n := runLength(s, i)
+	for j := i + n; j < len(s); {
+		if s[j] != '`' {
+			j++
+			continue
+		}
+		m := runLength(s, j)
+		if m == n {
+			code := strings.ReplaceAll(s[i+n:j], "\n", " ")
+			if len(code) > 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.Trim(code, " ") != "" {
+				code = code[1 : len(code)-1]
+			}
+			r.out.WriteString("<code>" + html.EscapeString(code) + "</code>")
+			return j + m - i
+		}
+		j += m
+	}
+	r.out.WriteString(s[i : i+n])
+	return n
+}
+
+// emphasis renders *em*, **strong**, ***both*** and ~~strikethrough~~, the closing delimiter needs
+// to have the same length as the opening one
+func (r *renderer) emphasis(s string, i int) int {
+	c := s[i]
+	n := runLength(s, i)
+	if n > 3 || (c == '~' && n != 2) {
+		return 0
+	}
+	start := i + n
+	if start >= len(s) || isSpace(s[start]) || (c == '_' && i > 0 && isAlnum(s[i-1])) {
+		return 0
+	}
+	end := -1
+	for j := start; j < len(s); {
+		if s[j] == '\\' {
+			j += 2
+			continue
+		}
+		if s[j] != c {
+			j++
+			continue
+		}
+		m := runLength(s, j)
+		if m == n && !isSpace(s[j-1]) && !(c == '_' && j+m < len(s) && isAlnum(s[j+m])) {
+			end = j
+			break
+		}
+		j += m
+	}
+	if end < 0 {
+		return 0
+	}
+
+	open, close := "<em>", "</em>"
+	switch {
+	case c == '~':
+		open, close = "<del>", "</del>"
+	case n == 2:
+		open, close = "<strong>", "</strong>"
+	case n == 3:
+		open, close = "<em><strong>", "</strong></em>"
+	}
+	r.out.WriteString(open)
+	r.inline(s[start:end])
+	r.out.WriteString(close)
+	return end + n - i
+}
+
+// link renders [text](url) and ![alt](url), it returns 0 if the text at i is not a link
+func (r *renderer) link(s string, i int) int {
+	open := i
+	if s[i] == '!' {
+		open++
+	}
+	depth := 0
+	closeText := -1
+	for j := open; j < len(s) && closeText < 0; j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				closeText = j
+			}
+		}
+	}
+	if closeText < 0 || closeText+1 >= len(s) || s[closeText+1] != '(' {
+		return 0
+	}
+	closeDest := strings.IndexByte(s[closeText+2:], ')')
+	if closeDest < 0 {
+		return 0
+	}
+	dest := strings.TrimSpace(s[closeText+2 : closeText+2+closeDest])
+	if strings.HasPrefix(dest, "<") {
+		if end := strings.IndexByte(dest, '>'); end > 0 {
+			dest = dest[1:end]
+		}
+	} else if fields := strings.Fields(dest); len(fields) > 0 {
+		// the optional title is ignored
+		dest = fields[0]
+	}
+
+	label := s[open+1 : closeText]
+	if r.inLink {
+		r.inline(label)
+	} else {
+		r.anchor(dest, func() { r.inline(label) })
+	}
+	return closeText + 2 + closeDest + 1 - i
+}
+
+// anchor writes a link to dest, links with a scheme that is not allowed are written as plain text
+func (r *renderer) anchor(dest string, text func()) {
+	if !safeURL(dest) {
+		text()
+		return
+	}
+	fmt.Fprintf(&r.out, "<a href=\"%s\" rel=\"nofollow noopener noreferrer\" target=\"_blank\">", html.EscapeString(dest))
+	r.inLink = true
+	text()
+	r.inLink = false
+	r.out.WriteString("</a>")
+}
+
+func safeURL(dest string) bool {
+	u, err := url.Parse(dest)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		return u.Host != ""
+	case "mailto":
+		return u.Opaque != ""
+	default:
+		return false
+	}
+}
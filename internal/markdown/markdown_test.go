@@ -0,0 +1,196 @@
+package markdown_test
+
+import (
+	"errors"
+	"github.com/go-bumbu/todo-app/internal/markdown"
+	"github.com/google/go-cmp/cmp"
+	"html"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tcs := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "paragraphs and line breaks",
+			in:   "first line\nsame paragraph  \nafter break\n\nsecond",
+			want: "<p>first line\nsame paragraph<br>\nafter break</p>\n<p>second</p>\n",
+		},
+		{
+			name: "headings and rule",
+			in:   "# Title #\n### Sub\n---\n#hashtag",
+			want: "<h1>Title</h1>\n<h3>Sub</h3>\n<hr>\n<p>#hashtag</p>\n",
+		},
+		{
+			name: "emphasis",
+			in:   "*em* **strong** ***both*** ~~gone~~ snake_case_name 2 * 3",
+			want: "<p><em>em</em> <strong>strong</strong> <em><strong>both</strong></em> <del>gone</del> snake_case_name 2 * 3</p>\n",
+		},
+		{
+			name: "code",
+			in:   "use `a < b` here\n\n```go\nif a < b {\n```\n``unclosed",
+			want: "<p>use <code>a &lt; b</code> here</p>\n<pre><code class=\"language-go\">if a &lt; b {\n</code></pre>\n<p>``unclosed</p>\n",
+		},
+		{
+			name: "raw html is escaped",
+			in:   "<script>alert('x')</script> <b onclick=\"x\">bold</b>",
+			want: "<p>&lt;script&gt;alert(&#39;x&#39;)&lt;/script&gt; &lt;b onclick=&#34;x&#34;&gt;bold&lt;/b&gt;</p>\n",
+		},
+		{
+			name: "links",
+			in:   "[docs](https://example.com/a?b=1&c=2 \"title\") <mailto:me@example.com> see https://example.com/x.",
+			want: "<p><a href=\"https://example.com/a?b=1&amp;c=2\" rel=\"nofollow noopener noreferrer\" target=\"_blank\">docs</a> " +
+				"<a href=\"mailto:me@example.com\" rel=\"nofollow noopener noreferrer\" target=\"_blank\">me@example.com</a> " +
+				"see <a href=\"https://example.com/x\" rel=\"nofollow noopener noreferrer\" target=\"_blank\">https://example.com/x</a>.</p>\n",
+		},
+		{
+			name: "unsafe links are written as text",
+			in:   "[click](javascript:alert(1)) [data](data:text/html;base64,xx) ![img](https://example.com/i.png)",
+			want: "<p>click)" + " data " +
+				"<a href=\"https://example.com/i.png\" rel=\"nofollow noopener noreferrer\" target=\"_blank\">img</a></p>\n",
+		},
+		{
+			name: "quote",
+			in:   "> quoted\n> **text**\n\nafter",
+			want: "<blockquote>\n<p>quoted\n<strong>text</strong></p>\n</blockquote>\n<p>after</p>\n",
+		},
+		{
+			name: "nested lists",
+			in:   "- one\n- two\n  1. a\n  2. b\n- three\n\n3) x",
+			want: "<ul>\n<li>one</li>\n<li>two\n<ol>\n<li>a</li>\n<li>b</li>\n</ol></li>\n<li>three</li>\n</ul>\n<ol start=\"3\">\n<li>x</li>\n</ol>\n",
+		},
+		{
+			name: "loose list",
+			in:   "- one\n\n- two",
+			want: "<ul>\n<li>\n<p>one</p></li>\n<li>\n<p>two</p></li>\n</ul>\n",
+		},
+		{
+			name: "task list",
+			in:   "- [ ] todo\n- [x] done\n- [link](https://example.com)\n```\n- [ ] in code\n```\n> * [X] quoted",
+			want: "<ul>\n<li class=\"task\"><input type=\"checkbox\" data-index=\"0\"> todo</li>\n" +
+				"<li class=\"task\"><input type=\"checkbox\" data-index=\"1\" checked> done</li>\n" +
+				"<li><a href=\"https://example.com\" rel=\"nofollow noopener noreferrer\" target=\"_blank\">link</a></li>\n</ul>\n" +
+				"<pre><code>- [ ] in code\n</code></pre>\n" +
+				"<blockquote>\n<ul>\n<li class=\"task\"><input type=\"checkbox\" data-index=\"2\" checked> quoted</li>\n</ul>\n</blockquote>\n",
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			got := markdown.Render(tc.in)
+			if diff := cmp.Diff(got, tc.want); diff != "" {
+				t.Errorf("unexpected value (-got +want)\n%s", diff)
+			}
+		})
+	}
+}
+
+// renderedTag matches the tags written by the renderer together with the attributes it sets, attribute values
+// are escaped so they cannot contain quotes or angle brackets
+var renderedTag = regexp.MustCompile(`^</?(?:p|h[1-6]|hr|br|pre|code|blockquote|ul|ol|li|em|strong|del|a|input)` +
+	`(?: (?:class|start|href|rel|target|type|data-index|checked)(?:="[^"<>]*")?)*>`)
+
+var eventAttr = regexp.MustCompile(`(?i)\son[a-z]*\s*=`)
+var hrefAttr = regexp.MustCompile(` href="([^"]*)"`)
+
+// the rendered notes are shown on the public page of shared lists, no input may produce markup other than the
+// tags of the renderer, event handler attributes or links with other schemes than http, https and mailto
+func FuzzRender(f *testing.F) {
+	seeds := []string{
+		"# Title\n\n*em* **strong** ~~gone~~ `a < b`\n\n```go\nif a < b {\n```",
+		"- [x] done\n- [ ] open\n  1. nested\n\n> quote <b>",
+		"<script>alert('x')</script> <b onclick=\"x\">bold</b> <img src=x onerror=alert(1)>",
+		"[x](javascript:alert(1)) [y](JaVaScRiPt:alert(1)) [z](java\tscript:alert(1)) ![i](data:text/html,<b>)",
+		"[a](<http://x.y/\"onmouseover=\"alert(1)>) [b](http://x.y/ \"title\") <http://x.y/<b>> http://x.y/?a=<b>",
+		"<mailto:a@b.c> [m](mailto:a@b.c?subject=<b>) [[nested](http://a.b)](http://c.d)",
+		"**[bold link](http://a.b)** _x_y_ \\<b\\> &lt;b&gt; &#60;b&#62;",
+		"``unclosed `code` ***a** b* ~~~x~~~ <b",
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, src string) {
+		out := markdown.Render(src)
+		for i := strings.IndexByte(out, '<'); i >= 0; {
+			tag := renderedTag.FindString(out[i:])
+			if tag == "" {
+				t.Fatalf("unescaped < at %d of %q rendered from %q", i, out, src)
+			}
+			if eventAttr.MatchString(tag) {
+				t.Fatalf("event handler in %q rendered from %q", tag, src)
+			}
+			if m := hrefAttr.FindStringSubmatch(tag); m != nil {
+				href := strings.ToLower(html.UnescapeString(m[1]))
+				if !strings.HasPrefix(href, "http://") && !strings.HasPrefix(href, "https://") && !strings.HasPrefix(href, "mailto:") {
+					t.Fatalf("unsafe link %q rendered from %q", m[1], src)
+				}
+			}
+			next := strings.IndexByte(out[i+len(tag):], '<')
+			if next < 0 {
+				break
+			}
+			i += len(tag) + next
+		}
+	})
+}
+
+func TestSetCheckbox(t *testing.T) {
+	src := "# Plan\r\n- [ ] buy\r\n  - [x] milk\r\n```\r\n- [ ] not a task\r\n```\r\n> 1. [ ] call"
+
+	tcs := []struct {
+		name    string
+		index   int
+		checked bool
+		want    string
+		wantErr error
+	}{
+		{
+			name:    "check first",
+			index:   0,
+			checked: true,
+			want:    "# Plan\r\n- [x] buy\r\n  - [x] milk\r\n```\r\n- [ ] not a task\r\n```\r\n> 1. [ ] call",
+		},
+		{
+			name:  "uncheck nested",
+			index: 1,
+			want:  "# Plan\r\n- [ ] buy\r\n  - [ ] milk\r\n```\r\n- [ ] not a task\r\n```\r\n> 1. [ ] call",
+		},
+		{
+			name:    "skip code blocks",
+			index:   2,
+			checked: true,
+			want:    "# Plan\r\n- [ ] buy\r\n  - [x] milk\r\n```\r\n- [ ] not a task\r\n```\r\n> 1. [x] call",
+		},
+		{
+			name:    "out of range",
+			index:   3,
+			want:    src,
+			wantErr: markdown.ErrNoCheckbox,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := markdown.SetCheckbox(src, tc.index, tc.checked)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(got, tc.want); diff != "" {
+				t.Errorf("unexpected value (-got +want)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestClearCheckboxes(t *testing.T) {
+	got := markdown.ClearCheckboxes("- [x] a\n- [X] b\n```\n- [x] code\n```\n[x] text")
+	want := "- [ ] a\n- [ ] b\n```\n- [x] code\n```\n[x] text"
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("unexpected value (-got +want)\n%s", diff)
+	}
+}
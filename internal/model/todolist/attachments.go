@@ -0,0 +1,223 @@
+package todolist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-bumbu/todo-app/internal/blobstore"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"io"
+	"time"
+)
+
+// Attachment is a file attached to a task, the content is kept in the blob store under the attachment id
+type Attachment struct {
+	ID     string `gorm:"primaryKey,index"`
+	TaskId string `gorm:"index"`
+	// OwnerId is the owner of the task, the size of the attachment counts towards the quota of the owner
+	OwnerId string `gorm:"index"`
+	// UploadedBy is the user that attached the file, it differs from the owner on shared lists
+	UploadedBy  string
+	Name        string
+	ContentType string
+	Size        int64
+
+	CreatedAt time.Time
+}
+
+func (a *Attachment) BeforeCreate(db *gorm.DB) (err error) {
+	if a.ID == "" {
+		// UUID version 4
+		a.ID = uuid.NewString()
+	}
+	return
+}
+
+type AttachmentNotFoundErr struct {
+	id    string
+	owner string
+}
+
+func (m *AttachmentNotFoundErr) Error() string {
+	return fmt.Sprintf("attachment with id: %s and owner %s not found", m.id, m.owner)
+}
+
+// ErrAttachmentsDisabled is returned by the attachment methods if the manager has no blob store
+var ErrAttachmentsDisabled = errors.New("attachments are not enabled")
+
+// ErrQuotaExceeded is returned when an upload would exceed the storage quota of the owner
+var ErrQuotaExceeded = errors.New("attachment storage quota exceeded")
+
+// EnableAttachments sets the store for the content of the attachments, quota is the amount of bytes
+// every owner can store, 0 means unlimited. It has to be called before the manager is used.
+func (m *Manager) EnableAttachments(store blobstore.Store, quota int64) {
+	m.blobs = store
+	m.quota = quota
+}
+
+// Quota returns the amount of bytes every owner can store in attachments, 0 means unlimited
+func (m Manager) Quota() int64 {
+	return m.quota
+}
+
+// StorageUsed returns the size of all the attachments of the owner, including the ones of deleted tasks
+func (m Manager) StorageUsed(owner string) (int64, error) {
+	return storageUsed(m.db, owner)
+}
+
+func storageUsed(db *gorm.DB, owner string) (int64, error) {
+	var used int64
+	err := db.Model(&Attachment{}).Where("owner_id = ?", owner).Select("COALESCE(SUM(size), 0)").Scan(&used).Error
+	return used, err
+}
+
+// Attach stores the content as a new attachment of the task, the size of the attachment is set from the
+// written content. The OwnerId of the attachment is the user uploading it, it needs the editor role on shared
+// lists; the attachment is stored for the owner of the task. Uploads that exceed the quota of the task owner
+// fail with ErrQuotaExceeded.
+func (m Manager) Attach(ctx context.Context, a *Attachment, content io.Reader) (string, error) {
+	if m.blobs == nil {
+		return "", ErrAttachmentsDisabled
+	}
+	owner, err := authorize(m.db, a.TaskId, a.OwnerId, RoleEditor)
+	if err != nil {
+		return "", err
+	}
+	a.UploadedBy = a.OwnerId
+	a.OwnerId = owner
+
+	if m.quota > 0 {
+		used, err := storageUsed(m.db, a.OwnerId)
+		if err != nil {
+			return "", err
+		}
+		if used >= m.quota {
+			return "", ErrQuotaExceeded
+		}
+		// read one byte more than allowed to detect uploads over the quota without storing them completely
+		content = io.LimitReader(content, m.quota-used+1)
+	}
+
+	a.ID = uuid.NewString()
+	a.Size, err = m.blobs.Put(ctx, a.ID, content)
+	if err != nil {
+		return "", err
+	}
+
+	err = m.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Create(a).Error
+		if err != nil {
+			return err
+		}
+		if m.quota == 0 {
+			return nil
+		}
+		// check again after the insert to account for concurrent uploads
+		used, err := storageUsed(tx, a.OwnerId)
+		if err != nil {
+			return err
+		}
+		if used > m.quota {
+			return ErrQuotaExceeded
+		}
+		return nil
+	})
+	if err != nil {
+		_ = m.blobs.Delete(ctx, a.ID)
+		return "", err
+	}
+	return a.ID, nil
+}
+
+// Attachments returns the attachments of the task ordered by upload time, every member of a shared list can
+// see them
+func (m Manager) Attachments(taskId, user string) ([]Attachment, error) {
+	_, err := authorize(m.db, taskId, user, RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+	attachments := []Attachment{}
+	err = m.db.Where("task_id = ?", taskId).Order("created_at").Order("id").Find(&attachments).Error
+	if err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+// GetAttachment returns an attachment of a task that is not deleted, every member of a shared list can read it
+func (m Manager) GetAttachment(id, user string) (Attachment, error) {
+	return m.attachmentAccess(id, user, RoleViewer)
+}
+
+// attachmentAccess returns an attachment of a task that is not deleted if the user has at least the role on
+// the task, attachments of tasks the user cannot see are reported as not found
+func (m Manager) attachmentAccess(id, user string, need Role) (Attachment, error) {
+	a := Attachment{}
+	result := m.db.Where("ID = ?", id).Limit(1).Find(&a)
+	if result.Error != nil {
+		return a, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return Attachment{}, &AttachmentNotFoundErr{id: id, owner: user}
+	}
+	_, err := authorize(m.db, a.TaskId, user, need)
+	tErr := &ItemNotFountErr{}
+	if errors.As(err, &tErr) {
+		return Attachment{}, &AttachmentNotFoundErr{id: id, owner: user}
+	}
+	if err != nil {
+		return Attachment{}, err
+	}
+	return a, nil
+}
+
+// OpenAttachment returns the attachment together with its content, the caller has to close the content
+func (m Manager) OpenAttachment(ctx context.Context, id, user string) (Attachment, io.ReadCloser, error) {
+	if m.blobs == nil {
+		return Attachment{}, nil, ErrAttachmentsDisabled
+	}
+	a, err := m.GetAttachment(id, user)
+	if err != nil {
+		return a, nil, err
+	}
+	content, err := m.blobs.Get(ctx, a.ID)
+	if err != nil {
+		return a, nil, err
+	}
+	return a, content, nil
+}
+
+// DeleteAttachment removes the attachment and its content, it needs the editor role on shared lists
+func (m Manager) DeleteAttachment(ctx context.Context, id, user string) error {
+	if m.blobs == nil {
+		return ErrAttachmentsDisabled
+	}
+	a, err := m.attachmentAccess(id, user, RoleEditor)
+	if err != nil {
+		return err
+	}
+	err = m.db.Delete(&a).Error
+	if err != nil {
+		return err
+	}
+	return m.blobs.Delete(ctx, a.ID)
+}
+
+// deleteBlobs removes the content of attachments whose rows were already deleted
+func (m Manager) deleteBlobs(ids []string) error {
+	if m.blobs == nil {
+		return nil
+	}
+	errs := []error{}
+	for _, id := range ids {
+		err := m.blobs.Delete(context.Background(), id)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("unable to delete attachment content: %w", errors.Join(errs...))
+	}
+	return nil
+}
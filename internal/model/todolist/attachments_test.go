@@ -0,0 +1,287 @@
+package todolist_test
+
+import (
+	"context"
+	"errors"
+	"github.com/go-bumbu/todo-app/internal/blobstore"
+	"github.com/go-bumbu/todo-app/internal/model/todolist"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// attachmentManager returns a manager storing the attachment content in a temporary directory
+func attachmentManager(t *testing.T, quota int64) (*todolist.Manager, string) {
+	t.Helper()
+	mngr := testManager(t)
+	dir := filepath.Join(t.TempDir(), "blobs")
+	store, err := blobstore.NewFS(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mngr.EnableAttachments(store, quota)
+	return mngr, dir
+}
+
+func attach(t *testing.T, mngr *todolist.Manager, taskId, owner, name, content string) (todolist.Attachment, error) {
+	t.Helper()
+	a := todolist.Attachment{TaskId: taskId, OwnerId: owner, Name: name, ContentType: "text/plain"}
+	_, err := mngr.Attach(context.Background(), &a, strings.NewReader(content))
+	return a, err
+}
+
+// blobCount returns the amount of files in the blob store
+func blobCount(t *testing.T, dir string) int {
+	t.Helper()
+	n := 0
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			n++
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestAttachments(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("attach, read and delete", func(t *testing.T) {
+		mngr, dir := attachmentManager(t, 0)
+		taskId := createTask(t, mngr, "report", "u1")
+		a, err := attach(t, mngr, taskId, "u1", "notes.txt", "hello")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a.Size != 5 {
+			t.Errorf("unexpected size %d", a.Size)
+		}
+
+		list, err := mngr.Attachments(taskId, "u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != 1 || list[0].Name != "notes.txt" {
+			t.Errorf("unexpected attachments: %+v", list)
+		}
+
+		got, content, err := mngr.OpenAttachment(ctx, a.ID, "u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(content)
+		_ = content.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "hello" || got.ContentType != "text/plain" {
+			t.Errorf("unexpected attachment %+v with content %q", got, data)
+		}
+
+		_, _, err = mngr.OpenAttachment(ctx, a.ID, "u2")
+		target := &todolist.AttachmentNotFoundErr{}
+		if !errors.As(err, &target) {
+			t.Errorf("expected not found error, got: %v", err)
+		}
+
+		err = mngr.DeleteAttachment(ctx, a.ID, "u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := blobCount(t, dir); n != 0 {
+			t.Errorf("expected content to be removed, found %d files", n)
+		}
+	})
+
+	t.Run("task of other owner", func(t *testing.T) {
+		mngr, _ := attachmentManager(t, 0)
+		taskId := createTask(t, mngr, "report", "u1")
+		_, err := attach(t, mngr, taskId, "u2", "notes.txt", "hello")
+		target := &todolist.ItemNotFountErr{}
+		if !errors.As(err, &target) {
+			t.Errorf("expected not found error, got: %v", err)
+		}
+	})
+
+	t.Run("quota", func(t *testing.T) {
+		mngr, dir := attachmentManager(t, 10)
+		taskId := createTask(t, mngr, "report", "u1")
+		_, err := attach(t, mngr, taskId, "u1", "a.txt", "123456")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = attach(t, mngr, taskId, "u1", "b.txt", "12345")
+		if !errors.Is(err, todolist.ErrQuotaExceeded) {
+			t.Errorf("expected quota error, got: %v", err)
+		}
+		_, err = attach(t, mngr, taskId, "u1", "c.txt", "1234")
+		if err != nil {
+			t.Errorf("expected upload filling the quota to succeed: %v", err)
+		}
+		_, err = attach(t, mngr, taskId, "u1", "d.txt", "1")
+		if !errors.Is(err, todolist.ErrQuotaExceeded) {
+			t.Errorf("expected quota error, got: %v", err)
+		}
+
+		// the quota is per owner
+		other := createTask(t, mngr, "other", "u2")
+		if _, err = attach(t, mngr, other, "u2", "a.txt", "1234567890"); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		used, err := mngr.StorageUsed("u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if used != 10 || blobCount(t, dir) != 3 {
+			t.Errorf("unexpected usage %d with %d files", used, blobCount(t, dir))
+		}
+	})
+
+	t.Run("content is removed when the task is purged", func(t *testing.T) {
+		mngr, dir := attachmentManager(t, 0)
+		parent := createTask(t, mngr, "parent", "u1")
+		child := createSubtask(t, mngr, "child", "u1", parent)
+		for _, id := range []string{parent, child} {
+			if _, err := attach(t, mngr, id, "u1", "file.txt", "data"); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// deleted tasks keep their attachments until they are purged
+		deleteTask(t, mngr, parent, "u1", "")
+		if n := blobCount(t, dir); n != 2 {
+			t.Errorf("expected content of deleted tasks to be kept, found %d files", n)
+		}
+		err := mngr.Purge(parent, "u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := blobCount(t, dir); n != 0 {
+			t.Errorf("expected content to be removed, found %d files", n)
+		}
+		used, err := mngr.StorageUsed("u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if used != 0 {
+			t.Errorf("unexpected usage %d", used)
+		}
+	})
+
+	t.Run("shared list", func(t *testing.T) {
+		mngr, _ := attachmentManager(t, 10)
+		work := createList(t, mngr, "work", "u1")
+		task := todolist.TodoItem{Text: "report", OwnerId: "u1", ListId: work}
+		if _, err := mngr.Create(&task); err != nil {
+			t.Fatal(err)
+		}
+		for user, role := range map[string]todolist.Role{"u2": todolist.RoleViewer, "u3": todolist.RoleEditor} {
+			if err := mngr.Share(work, "u1", user, role); err != nil {
+				t.Fatal(err)
+			}
+			if err := mngr.AcceptInvitation(work, user); err != nil {
+				t.Fatal(err)
+			}
+		}
+		owned, err := attach(t, mngr, task.ID, "u1", "owner.txt", "1234")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		t.Run("viewer can read but not change", func(t *testing.T) {
+			_, err := attach(t, mngr, task.ID, "u2", "viewer.txt", "1")
+			if !errors.Is(err, todolist.ErrPermissionDenied) {
+				t.Errorf("expected permission denied, got: %v", err)
+			}
+			list, err := mngr.Attachments(task.ID, "u2")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(list) != 1 || list[0].ID != owned.ID {
+				t.Errorf("unexpected attachments: %+v", list)
+			}
+			_, content, err := mngr.OpenAttachment(ctx, owned.ID, "u2")
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = content.Close()
+			err = mngr.DeleteAttachment(ctx, owned.ID, "u2")
+			if !errors.Is(err, todolist.ErrPermissionDenied) {
+				t.Errorf("expected permission denied, got: %v", err)
+			}
+		})
+
+		t.Run("editor attaches for the owner", func(t *testing.T) {
+			a, err := attach(t, mngr, task.ID, "u3", "editor.txt", "12345")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if a.OwnerId != "u1" || a.UploadedBy != "u3" {
+				t.Errorf("unexpected owner %s and uploader %s", a.OwnerId, a.UploadedBy)
+			}
+			list, err := mngr.Attachments(task.ID, "u1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(list) != 2 {
+				t.Errorf("expected the owner to see the attachment of the editor, got: %+v", list)
+			}
+			// the upload counts towards the quota of the owner of the task
+			used, err := mngr.StorageUsed("u1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if used != 9 {
+				t.Errorf("unexpected usage %d", used)
+			}
+			_, err = attach(t, mngr, task.ID, "u3", "big.txt", "12")
+			if !errors.Is(err, todolist.ErrQuotaExceeded) {
+				t.Errorf("expected quota error, got: %v", err)
+			}
+			err = mngr.DeleteAttachment(ctx, owned.ID, "u3")
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		t.Run("owner deletes attachments of the editor", func(t *testing.T) {
+			list, err := mngr.Attachments(task.ID, "u1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(list) != 1 || list[0].UploadedBy != "u3" {
+				t.Fatalf("unexpected attachments: %+v", list)
+			}
+			err = mngr.DeleteAttachment(ctx, list[0].ID, "u1")
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		t.Run("no access after leaving the list", func(t *testing.T) {
+			if err := mngr.Revoke(work, "u2", "u2"); err != nil {
+				t.Fatal(err)
+			}
+			_, err := mngr.Attachments(task.ID, "u2")
+			target := &todolist.ItemNotFountErr{}
+			if !errors.As(err, &target) {
+				t.Errorf("expected not found error, got: %v", err)
+			}
+		})
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		mngr := testManager(t)
+		taskId := createTask(t, mngr, "report", "u1")
+		_, err := attach(t, mngr, taskId, "u1", "a.txt", "1")
+		if !errors.Is(err, todolist.ErrAttachmentsDisabled) {
+			t.Errorf("expected disabled error, got: %v", err)
+		}
+	})
+}
@@ -0,0 +1,189 @@
+package todolist
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"strings"
+)
+
+// actions of a bulk operation
+const (
+	BulkComplete = "complete"
+	BulkReopen   = "reopen"
+	BulkDelete   = "delete"
+	BulkMove     = "move"
+	BulkAddTag   = "addTag"
+)
+
+// MaxBulkTasks limits the amount of tasks changed by a single call to Bulk
+const MaxBulkTasks = 1000
+
+// ErrUnknownBulkAction is returned when an operation has an action that is not one of the Bulk* constants
+var ErrUnknownBulkAction = errors.New("unknown bulk action")
+
+// ErrNoBulkTasks is returned when an operation selects the tasks both by id and by filter or by neither
+var ErrNoBulkTasks = errors.New("the tasks of a bulk operation are selected either by ids or by filter")
+
+// ErrBulkTooLarge is returned when the operations of a call to Bulk select more than MaxBulkTasks tasks
+var ErrBulkTooLarge = fmt.Errorf("a bulk request can change at most %d tasks", MaxBulkTasks)
+
+// BulkOperation applies an action to a set of tasks, selected either by Ids or by the Scopes they match
+type BulkOperation struct {
+	Action string
+	Ids    []string
+	Scopes []Scope
+	// ListId is the list the tasks are moved to by BulkMove, an empty id is the inbox
+	ListId string
+	// Tag is the name of the tag added by BulkAddTag, it is created if missing
+	Tag string
+	// IgnoreBlockers allows BulkComplete to complete tasks that are waiting for blockers
+	IgnoreBlockers bool
+}
+
+// BulkResult is the outcome of an operation on a single task, Err is nil if the task was changed
+type BulkResult struct {
+	Action string
+	TaskId string
+	Err    error
+}
+
+// Bulk applies the operations in order in a single transaction. Every task is changed in its own savepoint:
+// a task that cannot be changed is left untouched and its error is reported in its result, while the changes
+// to the other tasks are kept. The scopes of all operations are matched against the tasks as they are before
+// the first operation is applied. The returned error is only set if the operations themselves are invalid or
+// the transaction failed.
+//
+// Tasks of shared lists selected by id can be completed, reopened and deleted by editors, assignees can also
+// complete and reopen them. Only the owner of the tasks can move them or tag them, as lists and tags are not shared.
+func (m Manager) Bulk(user string, ops []BulkOperation) ([]BulkResult, error) {
+	// tasks and lists are resolved before the transaction, that only writes, to avoid lock upgrades in sqlite.
+	// Tags are created within the savepoint of every task, so that they are not left behind if nothing is tagged.
+	type resolved struct {
+		op     BulkOperation
+		ids    []string
+		owners map[string]string
+		errs   map[string]error
+		listId string
+	}
+	plan := make([]resolved, len(ops))
+	total := 0
+	for i, op := range ops {
+		if (len(op.Ids) > 0) == (op.Scopes != nil) {
+			return nil, ErrNoBulkTasks
+		}
+		r := resolved{op: op, ids: op.Ids, owners: map[string]string{}, errs: map[string]error{}}
+		switch op.Action {
+		case BulkComplete, BulkReopen, BulkDelete:
+		case BulkMove:
+			listId, err := m.resolveList(op.ListId, user)
+			if err != nil {
+				return nil, err
+			}
+			r.listId = listId
+		case BulkAddTag:
+			if strings.TrimSpace(op.Tag) == "" {
+				return nil, ErrEmptyTagName
+			}
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrUnknownBulkAction, op.Action)
+		}
+
+		if op.Scopes != nil {
+			ids := []string{}
+			db := m.db.Model(&TodoItem{}).Where("owner_id = ?", user)
+			for _, scope := range op.Scopes {
+				db = db.Scopes(scope)
+			}
+			err := db.Scopes(defaultOrder).Limit(MaxBulkTasks+1).Pluck("todo_items.id", &ids).Error
+			if err != nil {
+				return nil, err
+			}
+			r.ids = ids
+		}
+		total += len(r.ids)
+		if total > MaxBulkTasks {
+			return nil, ErrBulkTooLarge
+		}
+
+		for _, id := range r.ids {
+			if op.Scopes != nil {
+				r.owners[id] = user
+				continue
+			}
+			var owner string
+			var err error
+			if op.Action == BulkComplete || op.Action == BulkReopen {
+				done := op.Action == BulkComplete
+				owner, err = authorizeUpdate(m.db, id, user, TaskUpdate{Done: &done})
+			} else {
+				owner, err = authorize(m.db, id, user, RoleEditor)
+			}
+			if err == nil && owner != user && (op.Action == BulkMove || op.Action == BulkAddTag) {
+				err = ErrPermissionDenied
+			}
+			r.owners[id], r.errs[id] = owner, err
+		}
+		plan[i] = r
+	}
+
+	results := []BulkResult{}
+	err := m.db.Transaction(func(tx *gorm.DB) error {
+		// subtasks are deleted together with their parent, deleting them again is not an error
+		removed := map[string]bool{}
+		for _, r := range plan {
+			for _, id := range r.ids {
+				if r.op.Action == BulkDelete && removed[id] {
+					results = append(results, BulkResult{Action: r.op.Action, TaskId: id})
+					continue
+				}
+				if r.errs[id] != nil {
+					results = append(results, BulkResult{Action: r.op.Action, TaskId: id, Err: r.errs[id]})
+					continue
+				}
+				owner := r.owners[id]
+				err := tx.Transaction(func(tx *gorm.DB) error {
+					switch r.op.Action {
+					case BulkComplete, BulkReopen:
+						done := r.op.Action == BulkComplete
+						upd := TaskUpdate{Done: &done, IgnoreBlockers: r.op.IgnoreBlockers}
+						return m.updateTx(tx, id, owner, upd, map[string]any{}, ActionUpdate)
+					case BulkMove:
+						upd := TaskUpdate{ListId: &r.listId}
+						return m.updateTx(tx, id, owner, upd, map[string]any{}, ActionUpdate)
+					case BulkAddTag:
+						tags, err := resolveTags(tx, owner, []string{r.op.Tag})
+						if err != nil {
+							return err
+						}
+						return m.addTags(tx, id, owner, tags)
+					default:
+						ids, err := m.deleteTx(tx, id, owner, nil)
+						for _, deleted := range ids {
+							removed[deleted] = true
+						}
+						return err
+					}
+				})
+				results = append(results, BulkResult{Action: r.op.Action, TaskId: id, Err: err})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// addTags attaches the tags to the task keeping the tags it already has
+func (m Manager) addTags(tx *gorm.DB, id, owner string, tags []Tag) error {
+	err := bumpVersion(tx, id, owner, nil)
+	if err != nil {
+		return err
+	}
+	return m.track(tx, ActionUpdate, []string{id}, func() ([]string, error) {
+		t := TodoItem{ID: id}
+		return nil, tx.Model(&t).Omit("Tags.*").Association("Tags").Append(tags)
+	})
+}
@@ -0,0 +1,132 @@
+package todolist_test
+
+import (
+	"errors"
+	"github.com/go-bumbu/todo-app/internal/model/todolist"
+	"github.com/google/go-cmp/cmp"
+	"testing"
+)
+
+// bulkSummary returns the action, task and error of every result
+func bulkSummary(results []todolist.BulkResult, names map[string]string) []string {
+	got := []string{}
+	for _, r := range results {
+		s := r.Action + " " + names[r.TaskId]
+		if r.Err != nil {
+			s += ": " + r.Err.Error()
+		}
+		got = append(got, s)
+	}
+	return got
+}
+
+func TestBulk(t *testing.T) {
+	mngr := testManager(t)
+	work := createList(t, mngr, "work", "u1")
+	report := createTask(t, mngr, "report", "u1")
+	slides := createTask(t, mngr, "slides", "u1")
+	charts := createSubtask(t, mngr, "charts", "u1", slides)
+	blocked := createTask(t, mngr, "review", "u1")
+	err := mngr.Update(blocked, "u1", todolist.TaskUpdate{BlockedBy: &[]string{report}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := createTask(t, mngr, "other", "u2")
+	names := map[string]string{report: "report", slides: "slides", charts: "charts", blocked: "review", other: "other"}
+
+	t.Run("complete, tag and move", func(t *testing.T) {
+		results, err := mngr.Bulk("u1", []todolist.BulkOperation{
+			{Action: todolist.BulkComplete, Ids: []string{blocked, report, other}},
+			{Action: todolist.BulkAddTag, Ids: []string{report, slides}, Tag: "q3"},
+			{Action: todolist.BulkMove, Ids: []string{slides}, ListId: work},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []string{
+			"complete review: the task is blocked by tasks that are not done",
+			"complete report",
+			"complete other: task with id: " + other + " and owner u1 not found",
+			"addTag report",
+			"addTag slides",
+			"move slides",
+		}
+		if diff := cmp.Diff(bulkSummary(results, names), want); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+
+		if diff := cmp.Diff(taskTexts(t, mngr, "u1", todolist.WithDone(true)), []string{"report"}); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+		if diff := cmp.Diff(taskTexts(t, mngr, "u1", todolist.WithTags([]string{"q3"}, false)), []string{"report", "slides"}); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+		if diff := cmp.Diff(taskTexts(t, mngr, "u1", todolist.InList(work)), []string{"slides", "charts"}); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+		if summary := changeSummary(t, mngr, report, "u1"); summary[0] != "update: tags" {
+			t.Errorf("unexpected history: %v", summary)
+		}
+	})
+
+	t.Run("delete by filter", func(t *testing.T) {
+		filter, err := todolist.ParseFilter("tag:q3")
+		if err != nil {
+			t.Fatal(err)
+		}
+		results, err := mngr.Bulk("u1", []todolist.BulkOperation{
+			{Action: todolist.BulkDelete, Scopes: []todolist.Scope{filter}},
+			{Action: todolist.BulkDelete, Ids: []string{charts}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []string{"delete report", "delete slides", "delete charts"}
+		if diff := cmp.Diff(bulkSummary(results, names), want); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+		if diff := cmp.Diff(taskTexts(t, mngr, "u1"), []string{"review"}); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+	})
+
+	t.Run("invalid operations", func(t *testing.T) {
+		_, err := mngr.Bulk("u1", []todolist.BulkOperation{{Action: "archive", Ids: []string{blocked}}})
+		if !errors.Is(err, todolist.ErrUnknownBulkAction) {
+			t.Errorf("expected unknown action error, got: %v", err)
+		}
+		_, err = mngr.Bulk("u1", []todolist.BulkOperation{{Action: todolist.BulkReopen}})
+		if !errors.Is(err, todolist.ErrNoBulkTasks) {
+			t.Errorf("expected no tasks error, got: %v", err)
+		}
+		_, err = mngr.Bulk("u1", []todolist.BulkOperation{{Action: todolist.BulkAddTag, Ids: []string{blocked}}})
+		if !errors.Is(err, todolist.ErrEmptyTagName) {
+			t.Errorf("expected empty tag error, got: %v", err)
+		}
+		other := createList(t, mngr, "other", "u2")
+		_, err = mngr.Bulk("u1", []todolist.BulkOperation{{Action: todolist.BulkMove, Ids: []string{blocked}, ListId: other}})
+		target := &todolist.ListNotFoundErr{}
+		if !errors.As(err, &target) {
+			t.Errorf("expected list not found error, got: %v", err)
+		}
+	})
+
+	t.Run("tags are not created if no task is tagged", func(t *testing.T) {
+		results, err := mngr.Bulk("u1", []todolist.BulkOperation{{Action: todolist.BulkAddTag, Ids: []string{other}, Tag: "unused"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 1 || results[0].Err == nil {
+			t.Errorf("expected the task of the other user to fail, got: %+v", results)
+		}
+		tags, err := mngr.Tags("u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, tag := range tags {
+			if tag.Name == "unused" {
+				t.Errorf("expected the tag not to be created")
+			}
+		}
+	})
+}
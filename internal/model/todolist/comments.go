@@ -0,0 +1,145 @@
+package todolist
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
+
+// Comment is a message in the discussion thread of a task
+type Comment struct {
+	ID       string `gorm:"primaryKey,index"`
+	TaskId   string `gorm:"index"`
+	AuthorId string `gorm:"index"`
+	Body     string
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (c *Comment) BeforeCreate(db *gorm.DB) (err error) {
+	// UUID version 4
+	c.ID = uuid.NewString()
+	return
+}
+
+type CommentNotFoundErr struct {
+	id   string
+	user string
+}
+
+func (m *CommentNotFoundErr) Error() string {
+	return fmt.Sprintf("comment with id: %s and user %s not found", m.id, m.user)
+}
+
+// ErrEmptyComment is returned when a comment has no text
+var ErrEmptyComment = errors.New("comment body cannot be empty")
+
+// ErrNotCommentAuthor is returned when a user changes a comment written by someone else
+var ErrNotCommentAuthor = errors.New("only the author can change the comment")
+
+// AddComment adds the comment to the thread of the task, the author needs access to the task
+func (m Manager) AddComment(c *Comment) (string, error) {
+	c.Body = strings.TrimSpace(c.Body)
+	if c.Body == "" {
+		return "", ErrEmptyComment
+	}
+	_, err := authorize(m.db, c.TaskId, c.AuthorId, RoleEditor)
+	if err != nil {
+		return "", err
+	}
+	err = m.db.Create(c).Error
+	if err != nil {
+		return "", err
+	}
+	return c.ID, nil
+}
+
+// Comments returns the comments of the task, the oldest first
+func (m Manager) Comments(taskId, user string) ([]Comment, error) {
+	_, err := m.Get(taskId, user)
+	if err != nil {
+		return nil, err
+	}
+	comments := []Comment{}
+	err = m.db.Where("task_id = ?", taskId).Order("created_at").Order("id").Find(&comments).Error
+	if err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+// getComment returns the comment of the task if the user has at least the role on the task
+func (m Manager) getComment(taskId, id, user string, need Role) (Comment, error) {
+	_, err := authorize(m.db, taskId, user, need)
+	if err != nil {
+		return Comment{}, err
+	}
+	c := Comment{}
+	result := m.db.Where("ID = ? AND task_id = ?", id, taskId).Limit(1).Find(&c)
+	if result.Error != nil {
+		return c, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return c, &CommentNotFoundErr{id: id, user: user}
+	}
+	return c, nil
+}
+
+// UpdateComment changes the text of a comment, only the author can edit it while having the editor role
+// on shared lists
+func (m Manager) UpdateComment(taskId, id, user, body string) (Comment, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return Comment{}, ErrEmptyComment
+	}
+	c, err := m.getComment(taskId, id, user, RoleEditor)
+	if err != nil {
+		return c, err
+	}
+	if c.AuthorId != user {
+		return c, ErrNotCommentAuthor
+	}
+	err = m.db.Model(&c).Update("body", body).Error
+	return c, err
+}
+
+// DeleteComment removes the comment, only the author can delete it while having the editor role on shared lists
+func (m Manager) DeleteComment(taskId, id, user string) error {
+	c, err := m.getComment(taskId, id, user, RoleEditor)
+	if err != nil {
+		return err
+	}
+	if c.AuthorId != user {
+		return ErrNotCommentAuthor
+	}
+	return m.db.Delete(&c).Error
+}
+
+// CommentCounts returns the amount of comments of the given tasks, tasks without comments are not included
+func (m Manager) CommentCounts(owner string, ids ...string) (map[string]int, error) {
+	counts := map[string]int{}
+	if len(ids) == 0 {
+		return counts, nil
+	}
+	rows := []struct {
+		TaskId string
+		Count  int
+	}{}
+	err := m.db.Model(&Comment{}).
+		Select("comments.task_id, COUNT(*) AS count").
+		Joins("JOIN todo_items ON todo_items.id = comments.task_id").
+		Where("todo_items.owner_id = ? AND comments.task_id IN ?", owner, ids).
+		Group("comments.task_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		counts[r.TaskId] = r.Count
+	}
+	return counts, nil
+}
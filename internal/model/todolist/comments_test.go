@@ -0,0 +1,142 @@
+package todolist_test
+
+import (
+	"errors"
+	"github.com/go-bumbu/todo-app/internal/model/todolist"
+	"github.com/google/go-cmp/cmp"
+	"testing"
+)
+
+func addComment(t *testing.T, mngr *todolist.Manager, taskId, author, body string) string {
+	t.Helper()
+	id, err := mngr.AddComment(&todolist.Comment{TaskId: taskId, AuthorId: author, Body: body})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func commentBodies(comments []todolist.Comment) []string {
+	bodies := make([]string, len(comments))
+	for i, c := range comments {
+		bodies[i] = c.Body
+	}
+	return bodies
+}
+
+func TestComments(t *testing.T) {
+	t.Run("thread of a task", func(t *testing.T) {
+		mngr := testManager(t)
+		taskId := createTask(t, mngr, "report", "u1")
+		first := addComment(t, mngr, taskId, "u1", " first ")
+		addComment(t, mngr, taskId, "u1", "second")
+
+		got, err := mngr.Comments(taskId, "u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(commentBodies(got), []string{"first", "second"}); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+
+		c, err := mngr.UpdateComment(taskId, first, "u1", "edited")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Body != "edited" {
+			t.Errorf("unexpected body %q", c.Body)
+		}
+
+		err = mngr.DeleteComment(taskId, first, "u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err = mngr.Comments(taskId, "u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(commentBodies(got), []string{"second"}); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+	})
+
+	t.Run("access", func(t *testing.T) {
+		mngr := testManager(t)
+		taskId := createTask(t, mngr, "report", "u1")
+		other := createTask(t, mngr, "other", "u1")
+		id := addComment(t, mngr, taskId, "u1", "hello")
+
+		_, err := mngr.AddComment(&todolist.Comment{TaskId: taskId, AuthorId: "u2", Body: "hi"})
+		taskErr := &todolist.ItemNotFountErr{}
+		if !errors.As(err, &taskErr) {
+			t.Errorf("expected task not found error, got: %v", err)
+		}
+		_, err = mngr.Comments(taskId, "u2")
+		if !errors.As(err, &taskErr) {
+			t.Errorf("expected task not found error, got: %v", err)
+		}
+		err = mngr.DeleteComment(taskId, id, "u2")
+		if !errors.As(err, &taskErr) {
+			t.Errorf("expected task not found error, got: %v", err)
+		}
+
+		// the comment has to belong to the task in the path
+		_, err = mngr.UpdateComment(other, id, "u1", "moved")
+		commentErr := &todolist.CommentNotFoundErr{}
+		if !errors.As(err, &commentErr) {
+			t.Errorf("expected comment not found error, got: %v", err)
+		}
+	})
+
+	t.Run("empty body", func(t *testing.T) {
+		mngr := testManager(t)
+		taskId := createTask(t, mngr, "report", "u1")
+		_, err := mngr.AddComment(&todolist.Comment{TaskId: taskId, AuthorId: "u1", Body: "  \n"})
+		if !errors.Is(err, todolist.ErrEmptyComment) {
+			t.Errorf("expected empty comment error, got: %v", err)
+		}
+		id := addComment(t, mngr, taskId, "u1", "hello")
+		_, err = mngr.UpdateComment(taskId, id, "u1", "")
+		if !errors.Is(err, todolist.ErrEmptyComment) {
+			t.Errorf("expected empty comment error, got: %v", err)
+		}
+	})
+
+	t.Run("counts and purge", func(t *testing.T) {
+		mngr := testManager(t)
+		t1 := createTask(t, mngr, "one", "u1")
+		t2 := createTask(t, mngr, "two", "u1")
+		t3 := createTask(t, mngr, "three", "u1")
+		addComment(t, mngr, t1, "u1", "a")
+		addComment(t, mngr, t1, "u1", "b")
+		addComment(t, mngr, t2, "u1", "c")
+
+		got, err := mngr.CommentCounts("u1", t1, t2, t3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(got, map[string]int{t1: 2, t2: 1}); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+		got, err = mngr.CommentCounts("u2", t1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 0 {
+			t.Errorf("expected no counts for other owner, got: %v", got)
+		}
+
+		deleteTask(t, mngr, t1, "u1", "")
+		err = mngr.Purge(t1, "u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err = mngr.CommentCounts("u1", t1, t2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(got, map[string]int{t2: 1}); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+	})
+}
@@ -0,0 +1,144 @@
+package todolist
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+	"time"
+)
+
+// Date is a point in time used for due and start dates, if HasTime is false only the calendar
+// date is relevant and the task is considered due during the whole day in the user's time zone.
+// A nil Time removes the date from the task.
+type Date struct {
+	Time    *time.Time
+	HasTime bool
+}
+
+const dateLayout = "2006-01-02"
+
+var localLayouts = []string{"2006-01-02T15:04:05", "2006-01-02T15:04"}
+
+// ParseDate reads a date in the format "2006-01-02" (without time) or RFC3339 (with time),
+// timestamps without offset are interpreted in loc, an empty string returns an empty Date.
+func ParseDate(in string, loc *time.Location) (Date, error) {
+	if in == "" {
+		return Date{}, nil
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	if t, err := time.Parse(dateLayout, in); err == nil {
+		return Date{Time: &t}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, in); err == nil {
+		return Date{Time: &t, HasTime: true}, nil
+	}
+	for _, layout := range localLayouts {
+		if t, err := time.ParseInLocation(layout, in, loc); err == nil {
+			return Date{Time: &t, HasTime: true}, nil
+		}
+	}
+	return Date{}, fmt.Errorf("unable to parse date \"%s\", use YYYY-MM-DD or RFC3339", in)
+}
+
+// FormatDate is the counterpart of ParseDate, dates with time are printed in loc
+func FormatDate(t *time.Time, hasTime bool, loc *time.Location) string {
+	if t == nil {
+		return ""
+	}
+	if !hasTime {
+		return t.UTC().Format(dateLayout)
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	return t.In(loc).Format(time.RFC3339)
+}
+
+// value returns the date as it is stored in the DB
+func (d Date) value() any {
+	if d.Time == nil {
+		return nil
+	}
+	return normalizeDate(d.Time, d.HasTime)
+}
+
+func (d Date) hasTime() bool {
+	return d.Time != nil && d.HasTime
+}
+
+// normalizeDate converts timestamps to UTC and dates without time to midnight UTC of the same calendar day
+func normalizeDate(t *time.Time, hasTime bool) *time.Time {
+	if t == nil {
+		return nil
+	}
+	if hasTime {
+		u := t.UTC()
+		return &u
+	}
+	f := floatingDate(*t)
+	return &f
+}
+
+// floatingDate returns the calendar day of t in its own location as midnight UTC
+func floatingDate(t time.Time) time.Time {
+	y, mo, d := t.Date()
+	return time.Date(y, mo, d, 0, 0, 0, 0, time.UTC)
+}
+
+// DueBefore limits the tasks to the ones due before t (exclusive), tasks without time are compared by
+// calendar date in the location of t
+func DueBefore(t time.Time) Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		c := dateBefore("due", t)
+		return db.Where(c.sql, c.args...)
+	}
+}
+
+// dateBefore compares the date column "prefix"_date taking into account if it has a time of day
+func dateBefore(prefix string, t time.Time) cond {
+	return cond{sql: fmt.Sprintf("((%[1]s_has_time = ? AND %[1]s_date < ?) OR (%[1]s_has_time = ? AND %[1]s_date < ?))", prefix),
+		args: []any{true, t.UTC(), false, floatingDate(t)}}
+}
+
+// DueAfter limits the tasks to the ones due at or after t, tasks without time are compared by
+// calendar date in the location of t
+func DueAfter(t time.Time) Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		c := dateAfter("due", t)
+		return db.Where(c.sql, c.args...)
+	}
+}
+
+// dateAfter is the counterpart of dateBefore, t is inclusive
+func dateAfter(prefix string, t time.Time) cond {
+	return cond{sql: fmt.Sprintf("((%[1]s_has_time = ? AND %[1]s_date >= ?) OR (%[1]s_has_time = ? AND %[1]s_date >= ?))", prefix),
+		args: []any{true, t.UTC(), false, floatingDate(t)}}
+}
+
+// DueToday limits the tasks to the ones due on the calendar day of now, in the location of now
+func DueToday(now time.Time) Scope {
+	return DueWithin(now, 0)
+}
+
+// DueWithin limits the tasks to the ones due from the start of the day of now until the end of the
+// day "days" later, e.g. DueWithin(now, 7) returns the tasks due today and in the next 7 days.
+func DueWithin(now time.Time, days int) Scope {
+	y, mo, d := now.Date()
+	start := time.Date(y, mo, d, 0, 0, 0, 0, now.Location())
+	end := start.AddDate(0, 0, days+1)
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("((due_has_time = ? AND due_date >= ? AND due_date < ?) OR (due_has_time = ? AND due_date >= ? AND due_date < ?))",
+			true, start.UTC(), end.UTC(), false, floatingDate(start), floatingDate(end))
+	}
+}
+
+// Overdue limits the tasks to the pending ones which due date has passed, tasks without time
+// are overdue once the calendar day has passed in the location of now
+func Overdue(now time.Time) Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("done = ?", false).
+			Where("((due_has_time = ? AND due_date < ?) OR (due_has_time = ? AND due_date < ?))",
+				true, now.UTC(), false, floatingDate(now))
+	}
+}
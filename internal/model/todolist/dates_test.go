@@ -0,0 +1,123 @@
+package todolist_test
+
+import (
+	"github.com/go-bumbu/todo-app/internal/model/todolist"
+	"github.com/google/go-cmp/cmp"
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	zurich, err := time.LoadLocation("Europe/Zurich")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tcs := []struct {
+		name     string
+		in       string
+		want     string
+		wantTime bool
+		wantErr  bool
+	}{
+		{name: "date only", in: "2024-05-10", want: "2024-05-10"},
+		{name: "rfc3339", in: "2024-05-10T10:30:00+02:00", want: "2024-05-10T10:30:00+02:00", wantTime: true},
+		{name: "local time", in: "2024-05-10T10:30", want: "2024-05-10T10:30:00+02:00", wantTime: true},
+		{name: "empty", in: "", want: ""},
+		{name: "invalid", in: "10.05.2024", wantErr: true},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := todolist.ParseDate(tc.in, zurich)
+			if tc.wantErr {
+				if err == nil {
+					t.Error("expected an error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.HasTime != tc.wantTime {
+				t.Errorf("expected HasTime to be %t", tc.wantTime)
+			}
+			if s := todolist.FormatDate(got.Time, got.HasTime, zurich); s != tc.want {
+				t.Errorf("expected date \"%s\", but got \"%s\"", tc.want, s)
+			}
+		})
+	}
+}
+
+func TestDueDateQueries(t *testing.T) {
+	mngr := testManager(t)
+	loc := time.FixedZone("UTC+2", 2*60*60)
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, loc)
+
+	createDue := func(text string, due time.Time, hasTime bool) {
+		task := todolist.TodoItem{Text: text, OwnerId: "u1", DueDate: &due, DueHasTime: hasTime}
+		_, err := mngr.Create(&task)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	createDue("yesterday", time.Date(2024, 5, 9, 0, 0, 0, 0, time.UTC), false)
+	createDue("this morning", time.Date(2024, 5, 10, 8, 0, 0, 0, loc), true)
+	createDue("today", time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC), false)
+	createDue("tonight", time.Date(2024, 5, 10, 23, 30, 0, 0, loc), true)
+	createDue("in three days", time.Date(2024, 5, 13, 0, 0, 0, 0, time.UTC), false)
+	createDue("next month", time.Date(2024, 6, 10, 9, 0, 0, 0, loc), true)
+	_ = createTask(t, mngr, "no due date", "u1")
+
+	tcs := []struct {
+		name  string
+		scope todolist.Scope
+		want  []string
+	}{
+		{name: "overdue", scope: todolist.Overdue(now), want: []string{"yesterday", "this morning"}},
+		{name: "due today", scope: todolist.DueToday(now), want: []string{"this morning", "today", "tonight"}},
+		{name: "due in the next 3 days", scope: todolist.DueWithin(now, 3), want: []string{"this morning", "today", "tonight", "in three days"}},
+		{name: "due before", scope: todolist.DueBefore(time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC)), want: []string{"yesterday"}},
+		{name: "due after", scope: todolist.DueAfter(time.Date(2024, 5, 11, 0, 0, 0, 0, time.UTC)), want: []string{"in three days", "next month"}},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(taskTexts(t, mngr, "u1", tc.scope), tc.want); diff != "" {
+				t.Errorf("unexpected value (-got +want)\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("done tasks are not overdue", func(t *testing.T) {
+		items, err := mngr.List("u1", 1, 0, todolist.Overdue(now))
+		if err != nil {
+			t.Fatal(err)
+		}
+		done := true
+		err = mngr.Update(items[0].ID, "u1", todolist.TaskUpdate{Done: &done})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(taskTexts(t, mngr, "u1", todolist.Overdue(now)), []string{"this morning"}); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+	})
+
+	t.Run("remove due date", func(t *testing.T) {
+		items, err := mngr.List("u1", 1, 0, todolist.DueAfter(now.AddDate(0, 0, 7)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = mngr.Update(items[0].ID, "u1", todolist.TaskUpdate{Due: &todolist.Date{}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := mngr.Get(items[0].ID, "u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.DueDate != nil {
+			t.Errorf("expected due date to be removed, got %v", got.DueDate)
+		}
+	})
+}
@@ -0,0 +1,185 @@
+package todolist
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"time"
+)
+
+// Dependency records that a task is blocked by another task until the blocker is done
+type Dependency struct {
+	TaskId    string `gorm:"primaryKey"`
+	BlockerId string `gorm:"primaryKey;index"`
+	OwnerId   string `gorm:"index"`
+	CreatedAt time.Time
+}
+
+// ErrDependencyCycle is returned when a dependency would make a task wait, directly or indirectly, for itself
+var ErrDependencyCycle = errors.New("a task cannot depend on itself or on a task that depends on it")
+
+// ErrUnknownBlocker is returned when a blocker does not exist or belongs to another owner
+var ErrUnknownBlocker = errors.New("blocker task not found")
+
+// ErrTaskBlocked is returned when completing a task that still has open blockers
+var ErrTaskBlocked = errors.New("the task is blocked by tasks that are not done")
+
+// blockersCte is a recursive query returning all the tasks the task passed as parameter waits for,
+// directly or through other tasks
+const blockersCte = `WITH RECURSIVE blockers(id) AS (
+	SELECT blocker_id FROM dependencies WHERE task_id = ?
+	UNION
+	SELECT d.blocker_id FROM dependencies d JOIN blockers ON d.task_id = blockers.id
+) `
+
+// openBlockersSql selects the dependencies which blocker is neither done nor deleted
+const openBlockersSql = `SELECT dependencies.task_id, dependencies.blocker_id FROM dependencies
+	JOIN todo_items ON todo_items.id = dependencies.blocker_id
+	WHERE todo_items.done = ? AND todo_items.deleted_at IS NULL`
+
+// setDependencies replaces the blockers of the task, all of them have to belong to the owner
+func setDependencies(tx *gorm.DB, id, owner string, blockerIds []string) error {
+	err := tx.Where("task_id = ?", id).Delete(&Dependency{}).Error
+	if err != nil {
+		return err
+	}
+	for _, blockerId := range blockerIds {
+		if blockerId == id {
+			return ErrDependencyCycle
+		}
+		var count int64
+		err = tx.Model(&TodoItem{}).Where("ID = ? AND owner_id = ?", blockerId, owner).Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return fmt.Errorf("%w: %s", ErrUnknownBlocker, blockerId)
+		}
+
+		var cycles int64
+		err = tx.Raw(blockersCte+"SELECT COUNT(*) FROM blockers WHERE id = ?", blockerId, id).Scan(&cycles).Error
+		if err != nil {
+			return err
+		}
+		if cycles > 0 {
+			return ErrDependencyCycle
+		}
+
+		err = tx.Create(&Dependency{TaskId: id, BlockerId: blockerId, OwnerId: owner}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkBlockers returns ErrTaskBlocked if the task has blockers that are not done
+func checkBlockers(tx *gorm.DB, id string) error {
+	var open int64
+	err := tx.Raw("SELECT COUNT(*) FROM ("+openBlockersSql+" AND dependencies.task_id = ?)", false, id).
+		Scan(&open).Error
+	if err != nil {
+		return err
+	}
+	if open > 0 {
+		return ErrTaskBlocked
+	}
+	return nil
+}
+
+// TaskDependencies are the blockers of a task
+type TaskDependencies struct {
+	BlockedBy []string // ids of all the non deleted blockers
+	Blocked   bool     // at least one blocker is not done
+}
+
+// Dependencies returns the blockers of the given tasks, tasks without blockers are not included
+func (m Manager) Dependencies(owner string, ids ...string) (map[string]TaskDependencies, error) {
+	deps := map[string]TaskDependencies{}
+	if len(ids) == 0 {
+		return deps, nil
+	}
+	rows := []struct {
+		TaskId    string
+		BlockerId string
+		Done      bool
+	}{}
+	err := m.db.Model(&Dependency{}).
+		Select("dependencies.task_id, dependencies.blocker_id, todo_items.done").
+		Joins("JOIN todo_items ON todo_items.id = dependencies.blocker_id AND todo_items.deleted_at IS NULL").
+		Where("dependencies.owner_id = ? AND dependencies.task_id IN ?", owner, ids).
+		Order("dependencies.created_at").Order("dependencies.blocker_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		d := deps[r.TaskId]
+		d.BlockedBy = append(d.BlockedBy, r.BlockerId)
+		d.Blocked = d.Blocked || !r.Done
+		deps[r.TaskId] = d
+	}
+	return deps, nil
+}
+
+// Blocked limits the tasks to the ones waiting for blockers that are not done, or to the ones that
+// can be worked on if blocked is false
+func Blocked(blocked bool) Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		c := blockedCond(blocked)
+		return db.Where(c.sql, c.args...)
+	}
+}
+
+func blockedCond(blocked bool) cond {
+	op := "IN"
+	if !blocked {
+		op = "NOT IN"
+	}
+	return cond{sql: "todo_items.id " + op + " (SELECT task_id FROM (" + openBlockersSql + "))", args: []any{false}}
+}
+
+// DependencyGraph contains the tasks of a list and the dependencies between them, tasks of other
+// lists are included if they are connected to a task of the list
+type DependencyGraph struct {
+	Tasks []TodoItem
+	Edges []Dependency
+}
+
+// DependencyGraph returns the dependencies of the tasks in the list, deleted tasks are left out.
+// Every member of a shared list can read its graph, the connected tasks of other lists are only included
+// if the member can see them.
+func (m Manager) DependencyGraph(user, listId string) (DependencyGraph, error) {
+	graph := DependencyGraph{Tasks: []TodoItem{}, Edges: []Dependency{}}
+	l, _, err := listRole(m.db, listId, user)
+	if err != nil {
+		return graph, err
+	}
+	owner := l.OwnerId
+
+	inList := m.db.Model(&TodoItem{}).Select("id").Where("owner_id = ? AND list_id = ?", owner, listId)
+	active := m.db.Model(&TodoItem{}).Select("id").Where("owner_id = ?", owner)
+	if owner != user {
+		shared := m.db.Model(&ListMember{}).Select("list_id").Where("user_id = ? AND accepted = ?", user, true)
+		active = active.Where("list_id IN (?)", shared)
+	}
+	err = m.db.Where("owner_id = ? AND (task_id IN (?) OR blocker_id IN (?))", owner, inList, inList).
+		Where("task_id IN (?) AND blocker_id IN (?)", active, active).
+		Order("created_at").Order("task_id").Order("blocker_id").
+		Find(&graph.Edges).Error
+	if err != nil {
+		return graph, err
+	}
+
+	ids := []string{}
+	for _, e := range graph.Edges {
+		ids = append(ids, e.TaskId, e.BlockerId)
+	}
+	err = m.db.Scopes(preloadTags, defaultOrder).
+		Where("owner_id = ? AND (list_id = ? OR id IN ?)", owner, listId, ids).
+		Find(&graph.Tasks).Error
+	if err != nil {
+		return graph, err
+	}
+	return graph, nil
+}
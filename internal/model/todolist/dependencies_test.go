@@ -0,0 +1,140 @@
+package todolist_test
+
+import (
+	"errors"
+	"github.com/go-bumbu/todo-app/internal/model/todolist"
+	"github.com/google/go-cmp/cmp"
+	"testing"
+)
+
+func setBlockers(t *testing.T, mngr *todolist.Manager, id, owner string, blockers ...string) error {
+	t.Helper()
+	return mngr.Update(id, owner, todolist.TaskUpdate{BlockedBy: &blockers})
+}
+
+func TestDependencies(t *testing.T) {
+	t.Run("blocked is computed from the blockers", func(t *testing.T) {
+		mngr := testManager(t)
+		design := createTask(t, mngr, "design", "u1")
+		build := createTask(t, mngr, "build", "u1")
+		release := todolist.TodoItem{Text: "release", OwnerId: "u1", BlockedBy: []string{design, build}}
+		_, err := mngr.Create(&release)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		deps, err := mngr.Dependencies("u1", design, release.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := map[string]todolist.TaskDependencies{release.ID: {BlockedBy: []string{design, build}, Blocked: true}}
+		if diff := cmp.Diff(deps, want); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+
+		filter, err := todolist.ParseFilter("blocked:true")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(taskTexts(t, mngr, "u1", filter), []string{"release"}); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+
+		setDone(t, mngr, design, "u1", true, "")
+		setDone(t, mngr, release.ID, "u1", true, todolist.ErrTaskBlocked.Error())
+
+		// deleted blockers don't block the task anymore
+		deleteTask(t, mngr, build, "u1", "")
+		if diff := cmp.Diff(taskTexts(t, mngr, "u1", todolist.Blocked(false)), []string{"design", "release"}); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+		setDone(t, mngr, release.ID, "u1", true, "")
+	})
+
+	t.Run("ignore blockers", func(t *testing.T) {
+		mngr := testManager(t)
+		a := createTask(t, mngr, "a", "u1")
+		b := createTask(t, mngr, "b", "u1")
+		if err := setBlockers(t, mngr, b, "u1", a); err != nil {
+			t.Fatal(err)
+		}
+		done := true
+		err := mngr.Update(b, "u1", todolist.TaskUpdate{Done: &done, IgnoreBlockers: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("reject cycles", func(t *testing.T) {
+		mngr := testManager(t)
+		a := createTask(t, mngr, "a", "u1")
+		b := createTask(t, mngr, "b", "u1")
+		c := createTask(t, mngr, "c", "u1")
+		if err := setBlockers(t, mngr, b, "u1", a); err != nil {
+			t.Fatal(err)
+		}
+		if err := setBlockers(t, mngr, c, "u1", b); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, blocker := range []string{a, c} {
+			err := setBlockers(t, mngr, a, "u1", blocker)
+			if !errors.Is(err, todolist.ErrDependencyCycle) {
+				t.Errorf("expected cycle error, got: %v", err)
+			}
+		}
+	})
+
+	t.Run("blockers of other owner", func(t *testing.T) {
+		mngr := testManager(t)
+		a := createTask(t, mngr, "a", "u1")
+		other := createTask(t, mngr, "other", "u2")
+		err := setBlockers(t, mngr, a, "u1", other)
+		if !errors.Is(err, todolist.ErrUnknownBlocker) {
+			t.Errorf("expected unknown blocker error, got: %v", err)
+		}
+	})
+
+	t.Run("graph", func(t *testing.T) {
+		mngr := testManager(t)
+		work := createList(t, mngr, "work", "u1")
+		a := todolist.TodoItem{Text: "a", OwnerId: "u1", ListId: work}
+		if _, err := mngr.Create(&a); err != nil {
+			t.Fatal(err)
+		}
+		b := todolist.TodoItem{Text: "b", OwnerId: "u1", ListId: work, BlockedBy: []string{a.ID}}
+		if _, err := mngr.Create(&b); err != nil {
+			t.Fatal(err)
+		}
+		_ = createTask(t, mngr, "unrelated", "u1")
+		outside := createTask(t, mngr, "outside", "u1")
+		if err := setBlockers(t, mngr, a.ID, "u1", outside); err != nil {
+			t.Fatal(err)
+		}
+
+		graph, err := mngr.DependencyGraph("u1", work)
+		if err != nil {
+			t.Fatal(err)
+		}
+		texts := []string{}
+		for _, task := range graph.Tasks {
+			texts = append(texts, task.Text)
+		}
+		if diff := cmp.Diff(texts, []string{"a", "b", "outside"}); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+		edges := map[string]string{}
+		for _, e := range graph.Edges {
+			edges[e.TaskId] = e.BlockerId
+		}
+		if diff := cmp.Diff(edges, map[string]string{b.ID: a.ID, a.ID: outside}); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+
+		_, err = mngr.DependencyGraph("u2", work)
+		target := &todolist.ListNotFoundErr{}
+		if !errors.As(err, &target) {
+			t.Errorf("expected list not found error, got: %v", err)
+		}
+	})
+}
@@ -0,0 +1,364 @@
+package todolist
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WithDone limits the tasks to the completed or to the pending ones
+func WithDone(done bool) Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("done = ?", done)
+	}
+}
+
+// CreatedAfter limits the tasks to the ones created at or after t
+func CreatedAfter(t time.Time) Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("created_at >= ?", t.UTC())
+	}
+}
+
+// UpdatedAfter limits the tasks to the ones modified at or after t
+func UpdatedAfter(t time.Time) Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("updated_at >= ?", t.UTC())
+	}
+}
+
+// cond is a sql condition with its arguments
+type cond struct {
+	sql  string
+	args []any
+}
+
+// FilterErr is returned by ParseFilter when the expression is not valid
+type FilterErr struct {
+	pos int
+	msg string
+}
+
+func (e *FilterErr) Error() string {
+	return fmt.Sprintf("%s at position %d", e.msg, e.pos)
+}
+
+// ParseFilter compiles a filter expression into a scope. An expression is a list of terms in the form
+// field:value combined with AND, OR, NOT and parentheses, terms next to each other are combined with AND:
+//
+//	tag:work (due<2024-06-01 OR due:none) NOT done:true
+//
+// Supported fields are text, done, status, due, start, created, updated, priority, important, urgent, blocked, tag
+// and list.
+// Dates and priorities accept the operators :, <, <=, > and >=, due:none and start:none match tasks without
+// the date. Words without field search the text.
+func ParseFilter(expr string) (Scope, error) {
+	tokens, err := lexFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return func(db *gorm.DB) *gorm.DB { return db }, nil
+	}
+	p := filterParser{tokens: tokens, end: len(expr)}
+	c, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t != nil {
+		return nil, &FilterErr{pos: t.pos, msg: fmt.Sprintf("unexpected \"%s\"", t.raw)}
+	}
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(c.sql, c.args...)
+	}, nil
+}
+
+type tokenKind int
+
+const (
+	tokTerm tokenKind = iota
+	tokOpen
+	tokClose
+	tokAnd
+	tokOr
+	tokNot
+)
+
+type filterToken struct {
+	kind  tokenKind
+	pos   int
+	raw   string
+	field string
+	op    string
+	value string
+}
+
+func isFilterDelim(c byte) bool {
+	return c == ' ' || c == '\t' || c == '(' || c == ')' || c == '"'
+}
+
+func lexFilter(in string) ([]filterToken, error) {
+	tokens := []filterToken{}
+	i := 0
+	for i < len(in) {
+		c := in[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '(':
+			tokens = append(tokens, filterToken{kind: tokOpen, pos: i, raw: "("})
+			i++
+		case c == ')':
+			tokens = append(tokens, filterToken{kind: tokClose, pos: i, raw: ")"})
+			i++
+		case c == '"':
+			value, next, err := readQuoted(in, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, filterToken{kind: tokTerm, pos: i, raw: in[i:next], op: ":", value: value})
+			i = next
+		default:
+			start := i
+			for i < len(in) && !isFilterDelim(in[i]) && !strings.ContainsRune(":<>", rune(in[i])) {
+				i++
+			}
+			word := in[start:i]
+			if i >= len(in) || !strings.ContainsRune(":<>", rune(in[i])) {
+				t := filterToken{kind: tokTerm, pos: start, raw: word, op: ":", value: word}
+				switch strings.ToUpper(word) {
+				case "AND":
+					t.kind = tokAnd
+				case "OR":
+					t.kind = tokOr
+				case "NOT":
+					t.kind = tokNot
+				}
+				tokens = append(tokens, t)
+				continue
+			}
+
+			op := string(in[i])
+			i++
+			if op != ":" && i < len(in) && in[i] == '=' {
+				op += "="
+				i++
+			}
+			value := ""
+			if i < len(in) && in[i] == '"' {
+				v, next, err := readQuoted(in, i)
+				if err != nil {
+					return nil, err
+				}
+				value, i = v, next
+			} else {
+				vStart := i
+				for i < len(in) && !isFilterDelim(in[i]) {
+					i++
+				}
+				value = in[vStart:i]
+			}
+			if value == "" {
+				return nil, &FilterErr{pos: i, msg: fmt.Sprintf("missing value for \"%s\"", word)}
+			}
+			tokens = append(tokens, filterToken{kind: tokTerm, pos: start, raw: in[start:i], field: strings.ToLower(word), op: op, value: value})
+		}
+	}
+	return tokens, nil
+}
+
+// readQuoted reads the string starting with the quote at position i, it returns the unquoted value
+// and the position after the closing quote
+func readQuoted(in string, i int) (string, int, error) {
+	end := strings.IndexByte(in[i+1:], '"')
+	if end < 0 {
+		return "", 0, &FilterErr{pos: i, msg: "unterminated quote"}
+	}
+	return in[i+1 : i+1+end], i + end + 2, nil
+}
+
+type filterParser struct {
+	tokens []filterToken
+	i      int
+	end    int
+}
+
+func (p *filterParser) peek() *filterToken {
+	if p.i >= len(p.tokens) {
+		return nil
+	}
+	return &p.tokens[p.i]
+}
+
+func (p *filterParser) parseOr() (cond, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return left, err
+	}
+	for t := p.peek(); t != nil && t.kind == tokOr; t = p.peek() {
+		p.i++
+		right, err := p.parseAnd()
+		if err != nil {
+			return right, err
+		}
+		left = cond{sql: "(" + left.sql + " OR " + right.sql + ")", args: append(left.args, right.args...)}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (cond, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return left, err
+	}
+	for t := p.peek(); t != nil && t.kind != tokOr && t.kind != tokClose; t = p.peek() {
+		if t.kind == tokAnd {
+			p.i++
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return right, err
+		}
+		left = cond{sql: "(" + left.sql + " AND " + right.sql + ")", args: append(left.args, right.args...)}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseUnary() (cond, error) {
+	t := p.peek()
+	if t == nil {
+		return cond{}, &FilterErr{pos: p.end, msg: "unexpected end of filter"}
+	}
+	p.i++
+	switch t.kind {
+	case tokNot:
+		c, err := p.parseUnary()
+		if err != nil {
+			return c, err
+		}
+		return cond{sql: "NOT " + c.sql, args: c.args}, nil
+	case tokOpen:
+		c, err := p.parseOr()
+		if err != nil {
+			return c, err
+		}
+		if next := p.peek(); next == nil || next.kind != tokClose {
+			return c, &FilterErr{pos: t.pos, msg: "unbalanced parenthesis"}
+		}
+		p.i++
+		return c, nil
+	case tokTerm:
+		return compileTerm(*t)
+	default:
+		return cond{}, &FilterErr{pos: t.pos, msg: fmt.Sprintf("unexpected \"%s\"", t.raw)}
+	}
+}
+
+func compileTerm(t filterToken) (cond, error) {
+	fail := func(format string, a ...any) (cond, error) {
+		return cond{}, &FilterErr{pos: t.pos, msg: fmt.Sprintf(format, a...)}
+	}
+	if t.op != ":" {
+		switch t.field {
+		case "due", "start", "created", "updated", "priority":
+		default:
+			return fail("operator %s is not supported for \"%s\"", t.op, t.field)
+		}
+	}
+
+	switch t.field {
+	case "", "text":
+		return cond{sql: "todo_items.text LIKE ? ESCAPE '\\'", args: []any{"%" + escapeLike(t.value) + "%"}}, nil
+	case "done":
+		done, err := strconv.ParseBool(t.value)
+		if err != nil {
+			return fail("done must be true or false")
+		}
+		return cond{sql: "todo_items.done = ?", args: []any{done}}, nil
+	case "important", "urgent":
+		v, err := strconv.ParseBool(t.value)
+		if err != nil {
+			return fail("%s must be true or false", t.field)
+		}
+		return cond{sql: "todo_items." + t.field + " = ?", args: []any{v}}, nil
+	case "blocked":
+		v, err := strconv.ParseBool(t.value)
+		if err != nil {
+			return fail("blocked must be true or false")
+		}
+		return blockedCond(v), nil
+	case "priority":
+		p, err := ParsePriority(t.value)
+		if err != nil {
+			return fail("%s", err.Error())
+		}
+		op := t.op
+		if op == ":" {
+			op = "="
+		}
+		return cond{sql: "todo_items.priority " + op + " ?", args: []any{p}}, nil
+	case "tag":
+		return cond{sql: "todo_items.id IN (SELECT " + tagJoinTable + ".todo_item_id FROM " + tagJoinTable +
+			" JOIN tags ON tags.id = " + tagJoinTable + ".tag_id WHERE tags.name = ?)", args: []any{t.value}}, nil
+	case "status":
+		return cond{sql: "todo_items.status_id IN (SELECT statuses.id FROM statuses WHERE statuses.name = ? AND statuses.deleted_at IS NULL)",
+			args: []any{t.value}}, nil
+	case "list":
+		return cond{sql: "todo_items.list_id = ?", args: []any{t.value}}, nil
+	case "due", "start":
+		if t.value == "none" && t.op == ":" {
+			return cond{sql: "todo_items." + t.field + "_date IS NULL"}, nil
+		}
+		from, to, err := filterRange(t.value)
+		if err != nil {
+			return fail("%s", err.Error())
+		}
+		switch t.op {
+		case "<":
+			return dateBefore(t.field, from), nil
+		case "<=":
+			return dateBefore(t.field, to), nil
+		case ">":
+			return dateAfter(t.field, to), nil
+		case ">=":
+			return dateAfter(t.field, from), nil
+		}
+		a, b := dateAfter(t.field, from), dateBefore(t.field, to)
+		return cond{sql: "(" + a.sql + " AND " + b.sql + ")", args: append(a.args, b.args...)}, nil
+	case "created", "updated":
+		from, to, err := filterRange(t.value)
+		if err != nil {
+			return fail("%s", err.Error())
+		}
+		column := "todo_items." + t.field + "_at"
+		switch t.op {
+		case "<":
+			return cond{sql: column + " < ?", args: []any{from.UTC()}}, nil
+		case "<=":
+			return cond{sql: column + " < ?", args: []any{to.UTC()}}, nil
+		case ">":
+			return cond{sql: column + " >= ?", args: []any{to.UTC()}}, nil
+		case ">=":
+			return cond{sql: column + " >= ?", args: []any{from.UTC()}}, nil
+		}
+		return cond{sql: "(" + column + " >= ? AND " + column + " < ?)", args: []any{from.UTC(), to.UTC()}}, nil
+	default:
+		return fail("unknown filter field \"%s\"", t.field)
+	}
+}
+
+// filterRange returns the time span covered by a date value: a whole day for dates and a single
+// instant for timestamps, "to" is exclusive
+func filterRange(value string) (from, to time.Time, err error) {
+	d, err := ParseDate(value, time.UTC)
+	if err != nil {
+		return from, to, err
+	}
+	from = *d.Time
+	if d.HasTime {
+		return from, from.Add(time.Nanosecond), nil
+	}
+	return from, from.AddDate(0, 0, 1), nil
+}
@@ -0,0 +1,115 @@
+package todolist_test
+
+import (
+	"github.com/go-bumbu/todo-app/internal/model/todolist"
+	"github.com/google/go-cmp/cmp"
+	"testing"
+	"time"
+)
+
+func TestFilters(t *testing.T) {
+	mngr := testManager(t)
+	day := func(d int) *time.Time {
+		t := time.Date(2024, 5, d, 0, 0, 0, 0, time.UTC)
+		return &t
+	}
+	items := []todolist.TodoItem{
+		{Text: "write report", DueDate: day(10), Tags: []todolist.Tag{{Name: "work"}}},
+		{Text: "call boss", Done: true, DueDate: day(3), Tags: []todolist.Tag{{Name: "work"}}},
+		{Text: "buy milk", DueDate: day(12), Tags: []todolist.Tag{{Name: "errand"}}},
+		{Text: "read book"},
+	}
+	for i := range items {
+		items[i].OwnerId = "u1"
+		if _, err := mngr.Create(&items[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sortDue, err := todolist.SortBy("due", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sortDueDesc, err := todolist.SortBy("due", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tcs := []struct {
+		name   string
+		scopes []todolist.Scope
+		expect []string
+	}{
+		{name: "pending", scopes: []todolist.Scope{todolist.WithDone(false)}, expect: []string{"write report", "buy milk", "read book"}},
+		{name: "sort by due", scopes: []todolist.Scope{sortDue}, expect: []string{"call boss", "write report", "buy milk", "read book"}},
+		{name: "sort by due desc", scopes: []todolist.Scope{sortDueDesc}, expect: []string{"buy milk", "write report", "call boss", "read book"}},
+		{name: "created after", scopes: []todolist.Scope{todolist.CreatedAfter(time.Now().Add(time.Hour))}, expect: []string{}},
+	}
+
+	filters := []struct {
+		expr   string
+		expect []string
+	}{
+		{expr: "tag:work", expect: []string{"write report", "call boss"}},
+		{expr: "tag:work done:false", expect: []string{"write report"}},
+		{expr: "tag:errand OR due:none", expect: []string{"buy milk", "read book"}},
+		{expr: "NOT (tag:work OR tag:errand)", expect: []string{"read book"}},
+		{expr: "due>=2024-05-10 AND due<=2024-05-12", expect: []string{"write report", "buy milk"}},
+		{expr: "due>2024-05-10", expect: []string{"buy milk"}},
+		{expr: "due:2024-05-03", expect: []string{"call boss"}},
+		{expr: `"read b" OR text:milk`, expect: []string{"buy milk", "read book"}},
+		{expr: "created>2024-01-01 report", expect: []string{"write report"}},
+	}
+	for _, f := range filters {
+		scope, err := todolist.ParseFilter(f.expr)
+		if err != nil {
+			t.Fatalf("%s: %v", f.expr, err)
+		}
+		tcs = append(tcs, struct {
+			name   string
+			scopes []todolist.Scope
+			expect []string
+		}{name: f.expr, scopes: []todolist.Scope{scope}, expect: f.expect})
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			got := taskTexts(t, mngr, "u1", tc.scopes...)
+			if diff := cmp.Diff(got, tc.expect); diff != "" {
+				t.Errorf("unexpected value (-got +want)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	tcs := []struct {
+		expr    string
+		wantErr string
+	}{
+		{expr: "color:red", wantErr: "unknown filter field \"color\" at position 0"},
+		{expr: "tag:work AND", wantErr: "unexpected end of filter at position 12"},
+		{expr: "(tag:work", wantErr: "unbalanced parenthesis at position 0"},
+		{expr: "tag:work)", wantErr: "unexpected \")\" at position 8"},
+		{expr: "done:maybe", wantErr: "done must be true or false at position 0"},
+		{expr: "tag<work", wantErr: "operator < is not supported for \"tag\" at position 0"},
+		{expr: "due<tomorrow", wantErr: "unable to parse date \"tomorrow\", use YYYY-MM-DD or RFC3339 at position 0"},
+		{expr: `text:"open`, wantErr: "unterminated quote at position 5"},
+		{expr: "due:", wantErr: "missing value for \"due\" at position 4"},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.expr, func(t *testing.T) {
+			_, err := todolist.ParseFilter(tc.expr)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if err.Error() != tc.wantErr {
+				t.Errorf("unexpected error: got \"%s\" want \"%s\"", err.Error(), tc.wantErr)
+			}
+		})
+	}
+
+	if _, err := todolist.SortBy("color", false); err == nil {
+		t.Error("expected error for unknown sort key")
+	}
+}
@@ -0,0 +1,398 @@
+package todolist
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"strconv"
+	"time"
+)
+
+// Audit identifies who made a change, it is stored in the history of the changed tasks
+type Audit struct {
+	Actor     string // user that made the change, the owner of the task if empty
+	RequestId string // id of the http request, used to correlate the history with the logs
+}
+
+// WithAudit returns a copy of the manager that records the changes it makes as done by the actor of a request
+func (m Manager) WithAudit(a Audit) Manager {
+	m.audit = a
+	return m
+}
+
+// Actions stored in the history
+const (
+	ActionCreate  = "create"
+	ActionUpdate  = "update"
+	ActionDelete  = "delete"
+	ActionRestore = "restore"
+	ActionMove    = "move"
+	ActionRevert  = "revert"
+)
+
+// HistoryEntry is the record of a change to one field of a task, all the entries written by the same operation
+// share the ChangeId. Entries are never modified, they are only removed when the task is purged.
+// Changes caused by editing lists, statuses or tags are not recorded.
+type HistoryEntry struct {
+	ID        uint   `gorm:"primaryKey"`
+	ChangeId  string `gorm:"index"`
+	TaskId    string `gorm:"index"`
+	OwnerId   string `gorm:"index"`
+	Action    string
+	Field     string
+	OldValue  string
+	NewValue  string
+	Actor     string
+	RequestId string
+	CreatedAt time.Time
+}
+
+// ErrHistoryImmutable is returned when trying to modify a history entry
+var ErrHistoryImmutable = errors.New("history entries cannot be changed")
+
+func (h *HistoryEntry) BeforeUpdate(tx *gorm.DB) error {
+	return ErrHistoryImmutable
+}
+
+type ChangeNotFoundErr struct {
+	id     string
+	taskId string
+}
+
+func (m *ChangeNotFoundErr) Error() string {
+	return fmt.Sprintf("change with id: %s not found in the history of task %s", m.id, m.taskId)
+}
+
+// ErrRevertConflict is returned when a task cannot be reverted because the version references
+// a status, parent or blocker that no longer exists, or an assignee that is no longer a collaborator of the list
+var ErrRevertConflict = errors.New("the task cannot be reverted to this version")
+
+// historyFields are the tracked fields of a task, in the order they are written to the history
+var historyFields = []string{
+	"text", "notes", "statusId", "done", "priority", "important", "urgent", "dueDate", "startDate",
+	"timeZone", "recurrence", "tags", "parentId", "listId", "assigneeId", "position", "blockedBy", "deleted",
+}
+
+// taskState holds the tracked fields of a task formatted as text
+type taskState struct {
+	owner  string
+	fields map[string]string
+}
+
+func stateOf(t TodoItem, blockers []string) taskState {
+	tags := make([]string, len(t.Tags))
+	for i := range t.Tags {
+		tags[i] = t.Tags[i].Name
+	}
+	return taskState{
+		owner: t.OwnerId,
+		fields: map[string]string{
+			"text":       t.Text,
+			"notes":      t.Notes,
+			"statusId":   t.StatusId,
+			"done":       strconv.FormatBool(t.Done),
+			"priority":   t.Priority.String(),
+			"important":  strconv.FormatBool(t.Important),
+			"urgent":     strconv.FormatBool(t.Urgent),
+			"dueDate":    FormatDate(t.DueDate, t.DueHasTime, time.UTC),
+			"startDate":  FormatDate(t.StartDate, t.StartHasTime, time.UTC),
+			"timeZone":   t.TimeZone,
+			"recurrence": t.Recurrence,
+			"tags":       formatList(tags),
+			"parentId":   t.ParentId,
+			"listId":     t.ListId,
+			"assigneeId": t.AssigneeId,
+			"position":   t.Position,
+			"blockedBy":  formatList(blockers),
+			"deleted":    strconv.FormatBool(t.DeletedAt.Valid),
+		},
+	}
+}
+
+// zeroState are the values of a new empty task, they are left out of the history of created tasks
+var zeroState = stateOf(TodoItem{}, nil)
+
+// formatList encodes a list as json array, an empty list is an empty string
+func formatList(items []string) string {
+	if len(items) == 0 {
+		return ""
+	}
+	b, _ := json.Marshal(items)
+	return string(b)
+}
+
+func parseList(in string) ([]string, error) {
+	items := []string{}
+	if in == "" {
+		return items, nil
+	}
+	err := json.Unmarshal([]byte(in), &items)
+	return items, err
+}
+
+// snapshot reads the tracked fields of the tasks, including deleted ones
+func snapshot(tx *gorm.DB, ids []string) (map[string]taskState, error) {
+	states := map[string]taskState{}
+	if len(ids) == 0 {
+		return states, nil
+	}
+	tasks := []TodoItem{}
+	err := tx.Unscoped().Scopes(preloadTags).Where("id IN ?", ids).Find(&tasks).Error
+	if err != nil {
+		return nil, err
+	}
+	deps := []Dependency{}
+	err = tx.Where("task_id IN ?", ids).Order("blocker_id").Find(&deps).Error
+	if err != nil {
+		return nil, err
+	}
+	blockers := map[string][]string{}
+	for _, d := range deps {
+		blockers[d.TaskId] = append(blockers[d.TaskId], d.BlockerId)
+	}
+	for _, t := range tasks {
+		states[t.ID] = stateOf(t, blockers[t.ID])
+	}
+	return states, nil
+}
+
+// track records in the history the changes that mutate makes to the tasks with the given ids, mutate returns
+// the ids of the tasks it created. Tasks that are deleted or restored are recorded with the matching action,
+// the other changed tasks with action.
+func (m Manager) track(tx *gorm.DB, action string, ids []string, mutate func() ([]string, error)) error {
+	before, err := snapshot(tx, ids)
+	if err != nil {
+		return err
+	}
+	created, err := mutate()
+	if err != nil {
+		return err
+	}
+	all := append(append([]string{}, ids...), created...)
+	after, err := snapshot(tx, all)
+	if err != nil {
+		return err
+	}
+
+	change := uuid.NewString()
+	entries := []HistoryEntry{}
+	seen := map[string]bool{}
+	for _, id := range all {
+		now, ok := after[id]
+		if !ok || seen[id] {
+			continue
+		}
+		seen[id] = true
+		prev, existed := before[id]
+		taskAction := action
+		switch {
+		case !existed:
+			taskAction = ActionCreate
+			prev = zeroState
+		case prev.fields["deleted"] != now.fields["deleted"] && now.fields["deleted"] == "true":
+			taskAction = ActionDelete
+		case prev.fields["deleted"] != now.fields["deleted"]:
+			taskAction = ActionRestore
+		}
+		actor := m.audit.Actor
+		if actor == "" {
+			actor = now.owner
+		}
+		for _, field := range historyFields {
+			if prev.fields[field] == now.fields[field] {
+				continue
+			}
+			entries = append(entries, HistoryEntry{
+				ChangeId:  change,
+				TaskId:    id,
+				OwnerId:   now.owner,
+				Action:    taskAction,
+				Field:     field,
+				OldValue:  prev.fields[field],
+				NewValue:  now.fields[field],
+				Actor:     actor,
+				RequestId: m.audit.RequestId,
+			})
+		}
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	return tx.Create(&entries).Error
+}
+
+// FieldChange is the change of a single field, see HistoryEntry
+type FieldChange struct {
+	Field    string
+	OldValue string
+	NewValue string
+}
+
+// Change groups the history entries written by one operation on a task
+type Change struct {
+	ID        string
+	TaskId    string
+	Action    string
+	Actor     string
+	RequestId string
+	CreatedAt time.Time
+	Fields    []FieldChange
+}
+
+// History returns the changes of the task, the most recent first. Deleted tasks keep their history until they are purged.
+func (m Manager) History(taskId, user string) ([]Change, error) {
+	owner, err := authorize(m.db.Unscoped().Session(&gorm.Session{}), taskId, user, RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []HistoryEntry{}
+	err = m.db.Where("task_id = ? AND owner_id = ?", taskId, owner).Order("id DESC").Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+	changes := []Change{}
+	for i := len(entries) - 1; i >= 0; i-- {
+		// entries are iterated in insert order, so that the fields keep the order they were written in
+		e := entries[i]
+		if len(changes) == 0 || changes[0].ID != e.ChangeId {
+			changes = append([]Change{{
+				ID:        e.ChangeId,
+				TaskId:    e.TaskId,
+				Action:    e.Action,
+				Actor:     e.Actor,
+				RequestId: e.RequestId,
+				CreatedAt: e.CreatedAt,
+			}}, changes...)
+		}
+		changes[0].Fields = append(changes[0].Fields, FieldChange{Field: e.Field, OldValue: e.OldValue, NewValue: e.NewValue})
+	}
+	return changes, nil
+}
+
+// Revert restores the fields of the task to the values they had right after the change, the revert
+// is recorded in the history as a new change. The position, list and deletion state are not reverted.
+func (m Manager) Revert(taskId, user, changeId string) error {
+	owner, err := authorize(m.db, taskId, user, RoleEditor)
+	if err != nil {
+		return err
+	}
+	current, err := snapshot(m.db, []string{taskId})
+	if err != nil {
+		return err
+	}
+	state := current[taskId]
+
+	entries := []HistoryEntry{}
+	err = m.db.Where("task_id = ? AND owner_id = ?", taskId, owner).Order("id").Find(&entries).Error
+	if err != nil {
+		return err
+	}
+	last := -1
+	for i := range entries {
+		if entries[i].ChangeId == changeId {
+			last = i
+		}
+	}
+	if last == -1 {
+		return &ChangeNotFoundErr{id: changeId, taskId: taskId}
+	}
+
+	// the value of a field at the version is the new value of its last change up to the version, fields
+	// that were not changed until then keep the old value of their first later change
+	version := map[string]string{}
+	for i, e := range entries {
+		if i <= last {
+			version[e.Field] = e.NewValue
+		} else if _, ok := version[e.Field]; !ok {
+			version[e.Field] = e.OldValue
+		}
+	}
+	changed := func(field string) (string, bool) {
+		v, ok := version[field]
+		return v, ok && v != state.fields[field]
+	}
+
+	upd := TaskUpdate{}
+	if v, ok := changed("text"); ok {
+		upd.Text = &v
+	}
+	if v, ok := changed("notes"); ok {
+		upd.Notes = &v
+	}
+	if v, ok := changed("statusId"); ok && v != "" {
+		upd.StatusId = &v
+	} else if v, ok := changed("done"); ok {
+		done := v == "true"
+		upd.Done = &done
+	}
+	if v, ok := changed("priority"); ok {
+		p, err := ParsePriority(v)
+		if err != nil {
+			return err
+		}
+		upd.Priority = &p
+	}
+	if v, ok := changed("important"); ok {
+		b := v == "true"
+		upd.Important = &b
+	}
+	if v, ok := changed("urgent"); ok {
+		b := v == "true"
+		upd.Urgent = &b
+	}
+	if v, ok := changed("dueDate"); ok {
+		d, err := ParseDate(v, time.UTC)
+		if err != nil {
+			return err
+		}
+		upd.Due = &d
+	}
+	if v, ok := changed("startDate"); ok {
+		d, err := ParseDate(v, time.UTC)
+		if err != nil {
+			return err
+		}
+		upd.Start = &d
+	}
+	if v, ok := changed("timeZone"); ok {
+		upd.TimeZone = &v
+	}
+	if v, ok := changed("recurrence"); ok {
+		upd.Recurrence = &v
+	}
+	if v, ok := changed("tags"); ok {
+		tags, err := parseList(v)
+		if err != nil {
+			return err
+		}
+		upd.Tags = &tags
+	}
+	if v, ok := changed("parentId"); ok {
+		upd.ParentId = &v
+	}
+	if v, ok := changed("blockedBy"); ok {
+		blockers, err := parseList(v)
+		if err != nil {
+			return err
+		}
+		upd.BlockedBy = &blockers
+	}
+	if v, ok := changed("assigneeId"); ok {
+		upd.AssigneeId = &v
+	}
+
+	err = authorizeTargets(m.db, taskId, user, owner, upd)
+	if err == nil {
+		err = m.update(taskId, owner, upd, ActionRevert)
+	}
+	taskErr := &ItemNotFountErr{}
+	statusErr := &StatusNotFoundErr{}
+	if errors.As(err, &taskErr) || errors.As(err, &statusErr) || errors.Is(err, ErrUnknownBlocker) ||
+		errors.Is(err, ErrDependencyCycle) || errors.Is(err, ErrTaskCycle) || errors.Is(err, ErrInvalidAssignee) {
+		return fmt.Errorf("%w: %w", ErrRevertConflict, err)
+	}
+	return err
+}
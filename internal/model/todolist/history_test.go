@@ -0,0 +1,200 @@
+package todolist_test
+
+import (
+	"errors"
+	"github.com/go-bumbu/todo-app/internal/model/todolist"
+	"github.com/google/go-cmp/cmp"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"path/filepath"
+	"testing"
+)
+
+// changeSummary returns the action and the changed fields of every change, the most recent first
+func changeSummary(t *testing.T, mngr *todolist.Manager, id, owner string) []string {
+	t.Helper()
+	changes, err := mngr.History(id, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, c := range changes {
+		s := c.Action + ":"
+		for _, f := range c.Fields {
+			s += " " + f.Field
+		}
+		got = append(got, s)
+	}
+	return got
+}
+
+func TestHistory(t *testing.T) {
+	t.Run("changes are recorded", func(t *testing.T) {
+		mngr := testManager(t)
+		id := createTask(t, mngr, "report", "u1")
+		setText(t, mngr, id, "u1", "final report", "")
+		important := true
+		audited := mngr.WithAudit(todolist.Audit{Actor: "admin", RequestId: "req-1"})
+		err := audited.Update(id, "u1", todolist.TaskUpdate{Important: &important, Tags: &[]string{"work"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		deleteTask(t, mngr, id, "u1", "")
+
+		want := []string{
+			"delete: deleted",
+			"update: important tags",
+			"update: text",
+			"create: text statusId listId position",
+		}
+		if diff := cmp.Diff(changeSummary(t, mngr, id, "u1"), want); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+
+		changes, err := mngr.History(id, "u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if changes[1].Actor != "admin" || changes[1].RequestId != "req-1" || changes[2].Actor != "u1" {
+			t.Errorf("unexpected actors: %+v", changes)
+		}
+		wantField := todolist.FieldChange{Field: "text", OldValue: "report", NewValue: "final report"}
+		if diff := cmp.Diff(changes[2].Fields[0], wantField); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+
+		_, err = mngr.History(id, "u2")
+		target := &todolist.ItemNotFountErr{}
+		if !errors.As(err, &target) {
+			t.Errorf("expected not found error, got: %v", err)
+		}
+	})
+
+	t.Run("side effects on other tasks", func(t *testing.T) {
+		mngr := testManager(t)
+		parent := createTask(t, mngr, "parent", "u1")
+		child := createSubtask(t, mngr, "child", "u1", parent)
+		done := true
+		err := mngr.Update(parent, "u1", todolist.TaskUpdate{Done: &done, CompleteSubtasks: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []string{"update: statusId done", "create: text statusId parentId listId position"}
+		if diff := cmp.Diff(changeSummary(t, mngr, child, "u1"), want); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+
+		daily := createTask(t, mngr, "water plants", "u1")
+		rule := "freq=daily"
+		err = mngr.Update(daily, "u1", todolist.TaskUpdate{Recurrence: &rule})
+		if err != nil {
+			t.Fatal(err)
+		}
+		setDone(t, mngr, daily, "u1", true, "")
+		tasks, err := mngr.List("u1", 0, 0, todolist.WithDone(false))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tasks) != 1 {
+			t.Fatalf("expected the next occurrence to be created, got %d tasks", len(tasks))
+		}
+		got := changeSummary(t, mngr, tasks[0].ID, "u1")
+		if len(got) != 1 || got[0][:7] != "create:" {
+			t.Errorf("expected the next occurrence to be recorded as created, got: %v", got)
+		}
+	})
+
+	t.Run("entries are immutable", func(t *testing.T) {
+		db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+			Logger: logger.Default.LogMode(logger.Silent),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		mngr, err := todolist.New(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		createTask(t, mngr, "report", "u1")
+
+		entry := todolist.HistoryEntry{}
+		err = db.First(&entry).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = db.Model(&entry).Update("new_value", "changed").Error
+		if !errors.Is(err, todolist.ErrHistoryImmutable) {
+			t.Errorf("expected immutable error, got: %v", err)
+		}
+	})
+}
+
+func TestRevert(t *testing.T) {
+	mngr := testManager(t)
+	id := createTask(t, mngr, "draft", "u1")
+	high := todolist.PriorityHigh
+	err := mngr.Update(id, "u1", todolist.TaskUpdate{Priority: &high, Tags: &[]string{"work", "q3"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes, err := mngr.History(id, "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	version := changes[0].ID
+
+	setText(t, mngr, id, "u1", "final", "")
+	low := todolist.PriorityLow
+	err = mngr.Update(id, "u1", todolist.TaskUpdate{Priority: &low, Tags: &[]string{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = mngr.Revert(id, "u1", version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	task, err := mngr.Get(id, "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{task.Text, task.Priority.String()}
+	for _, tag := range task.Tags {
+		got = append(got, tag.Name)
+	}
+	if diff := cmp.Diff(got, []string{"draft", "high", "q3", "work"}); diff != "" {
+		t.Errorf("unexpected value (-got +want)\n%s", diff)
+	}
+	if summary := changeSummary(t, mngr, id, "u1"); summary[0] != "revert: text priority tags" {
+		t.Errorf("unexpected history: %v", summary)
+	}
+
+	t.Run("unknown change", func(t *testing.T) {
+		err := mngr.Revert(id, "u1", "b4a5e3a8-0c36-4a53-a0d5-0a8a4b0ddd1e")
+		target := &todolist.ChangeNotFoundErr{}
+		if !errors.As(err, &target) {
+			t.Errorf("expected change not found error, got: %v", err)
+		}
+	})
+
+	t.Run("parent no longer exists", func(t *testing.T) {
+		parent := createTask(t, mngr, "parent", "u1")
+		child := createSubtask(t, mngr, "child", "u1", parent)
+		changes, err := mngr.History(child, "u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		empty := ""
+		err = mngr.Update(child, "u1", todolist.TaskUpdate{ParentId: &empty})
+		if err != nil {
+			t.Fatal(err)
+		}
+		deleteTask(t, mngr, parent, "u1", "")
+
+		err = mngr.Revert(child, "u1", changes[0].ID)
+		if !errors.Is(err, todolist.ErrRevertConflict) {
+			t.Errorf("expected revert conflict, got: %v", err)
+		}
+	})
+}
@@ -0,0 +1,217 @@
+package todolist
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+const InboxName = "Inbox"
+
+// TodoList groups tasks of a user into named projects, every user has one default Inbox list
+type TodoList struct {
+	ID       string `gorm:"primaryKey,index"`
+	OwnerId  string `gorm:"index"`
+	Name     string
+	Color    string
+	Position int
+	Archived bool
+	IsInbox  bool
+
+	// WorkspaceId is the workspace the list belongs to, it is set by the manager, see Manager.InWorkspace
+	WorkspaceId string `gorm:"index;not null;default:''"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+func (list *TodoList) BeforeCreate(db *gorm.DB) (err error) {
+	// UUID version 4
+	list.ID = uuid.NewString()
+	return
+}
+
+type ListNotFoundErr struct {
+	id    string
+	owner string
+}
+
+func (m *ListNotFoundErr) Error() string {
+	return fmt.Sprintf("list with id: %s and owner %s not found", m.id, m.owner)
+}
+
+// ErrInboxList is returned when trying to delete or archive the default inbox list
+var ErrInboxList = errors.New("the inbox list cannot be deleted or archived")
+
+// Inbox returns the default list of the owner, creating it if it does not exist yet.
+func (m Manager) Inbox(owner string) (TodoList, error) {
+	inbox := TodoList{}
+	result := m.db.Where("owner_id = ? AND is_inbox = ?", owner, true).Limit(1).Find(&inbox)
+	if result.Error != nil {
+		return TodoList{}, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return inbox, nil
+	}
+
+	inbox = TodoList{OwnerId: owner, Name: InboxName, IsInbox: true}
+	err := m.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Create(&inbox).Error
+		if err != nil {
+			return err
+		}
+		// tasks created before lists existed are moved into the new inbox
+		return tx.Model(&TodoItem{}).
+			Where("owner_id = ? AND list_id = ?", owner, "").
+			Update("list_id", inbox.ID).Error
+	})
+	if err != nil {
+		return TodoList{}, err
+	}
+	return inbox, nil
+}
+
+// Lists returns all the lists of the owner ordered by position, archived lists are only included if requested
+func (m Manager) Lists(owner string, archived bool) ([]TodoList, error) {
+	// make sure the inbox is always part of the response
+	_, err := m.Inbox(owner)
+	if err != nil {
+		return nil, err
+	}
+
+	lists := []TodoList{}
+	db := m.db.Where("owner_id = ?", owner)
+	if !archived {
+		db = db.Where("archived = ?", false)
+	}
+	result := db.Order("is_inbox desc").Order("position").Order("created_at").Find(&lists)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return lists, nil
+}
+
+// CreateList stores a new list, if no position is set it is appended after the existing lists
+func (m Manager) CreateList(list *TodoList) (string, error) {
+	list.IsInbox = false
+	// the inbox is created first, so that it always takes the first position
+	_, err := m.Inbox(list.OwnerId)
+	if err != nil {
+		return "", err
+	}
+	if list.Position == 0 {
+		var maxPos *int
+		err := m.db.Model(&TodoList{}).Where("owner_id = ?", list.OwnerId).
+			Select("MAX(position)").Scan(&maxPos).Error
+		if err != nil {
+			return "", err
+		}
+		if maxPos != nil {
+			list.Position = *maxPos + 1
+		}
+	}
+
+	result := m.db.Create(list)
+	if result.Error != nil {
+		return "", result.Error
+	}
+	return list.ID, nil
+}
+
+func (m Manager) GetList(id, owner string) (TodoList, error) {
+	l := TodoList{}
+	result := m.db.First(&l, "ID = ? AND owner_id = ?", id, owner)
+	if result.RowsAffected == 0 {
+		return l, &ListNotFoundErr{id: id, owner: owner}
+	}
+	return l, nil
+}
+
+// ListUpdate holds the fields of a list that can be changed, nil values are left untouched
+type ListUpdate struct {
+	Name     *string
+	Color    *string
+	Position *int
+	Archived *bool
+}
+
+func (m Manager) UpdateList(id, user string, upd ListUpdate) error {
+	list, role, err := listRole(m.db, id, user)
+	if err != nil {
+		return err
+	}
+	if !role.allows(RoleOwner) {
+		return ErrPermissionDenied
+	}
+
+	fieldMap := map[string]any{}
+	if upd.Name != nil {
+		fieldMap["name"] = *upd.Name
+	}
+	if upd.Color != nil {
+		fieldMap["color"] = *upd.Color
+	}
+	if upd.Position != nil {
+		fieldMap["position"] = *upd.Position
+	}
+	if upd.Archived != nil {
+		if list.IsInbox && *upd.Archived {
+			return ErrInboxList
+		}
+		fieldMap["archived"] = *upd.Archived
+	}
+	if len(fieldMap) == 0 {
+		return nil
+	}
+
+	result := m.db.Model(&TodoList{}).
+		Where("ID = ? AND owner_id = ?", id, list.OwnerId).
+		Updates(fieldMap)
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+// DeleteList removes a list, if cascade is true the tasks of the list are deleted as well,
+// otherwise they are moved into the owner's inbox.
+func (m Manager) DeleteList(id, owner string, cascade bool) error {
+	list, err := m.GetList(id, owner)
+	if err != nil {
+		return err
+	}
+	if list.IsInbox {
+		return ErrInboxList
+	}
+
+	var inbox TodoList
+	if !cascade {
+		inbox, err = m.Inbox(owner)
+		if err != nil {
+			return err
+		}
+	}
+
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		tasks := tx.Where("list_id = ? AND owner_id = ?", id, owner)
+		if cascade {
+			err := tasks.Delete(&TodoItem{}).Error
+			if err != nil {
+				return err
+			}
+		} else {
+			err := tasks.Model(&TodoItem{}).Update("list_id", inbox.ID).Error
+			if err != nil {
+				return err
+			}
+		}
+		err := tx.Where("list_id = ?", id).Delete(&ListMember{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("ID = ? AND owner_id = ?", id, owner).Delete(&TodoList{}).Error
+	})
+}
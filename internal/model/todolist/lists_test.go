@@ -0,0 +1,229 @@
+package todolist_test
+
+import (
+	"errors"
+	"github.com/go-bumbu/todo-app/internal/model/todolist"
+	"github.com/google/go-cmp/cmp"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"path/filepath"
+	"testing"
+)
+
+// testManager returns a manager backed by a new sqlite DB in a temporary directory
+func testManager(t *testing.T) *todolist.Manager {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mngr, err := todolist.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mngr
+}
+
+func createList(t *testing.T, mngr *todolist.Manager, name, owner string) string {
+	t.Helper()
+	list := todolist.TodoList{
+		Name:    name,
+		OwnerId: owner,
+	}
+	id, err := mngr.CreateList(&list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func listNames(t *testing.T, mngr *todolist.Manager, owner string, archived bool) []string {
+	t.Helper()
+	lists, err := mngr.Lists(owner, archived)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, l := range lists {
+		got = append(got, l.Name)
+	}
+	return got
+}
+
+func taskTexts(t *testing.T, mngr *todolist.Manager, owner string, scopes ...todolist.Scope) []string {
+	t.Helper()
+	items, err := mngr.List(owner, 50, 0, scopes...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, item := range items {
+		got = append(got, item.Text)
+	}
+	return got
+}
+
+func TestCrudList(t *testing.T) {
+	mngr := testManager(t)
+
+	work := createList(t, mngr, "work", "u1")
+	_ = createList(t, mngr, "home", "u1")
+	_ = createList(t, mngr, "other", "u2")
+
+	t.Run("lists are sorted with the inbox first", func(t *testing.T) {
+		want := []string{todolist.InboxName, "work", "home"}
+		if diff := cmp.Diff(listNames(t, mngr, "u1", false), want); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+	})
+
+	t.Run("ownership is conserved", func(t *testing.T) {
+		_, err := mngr.GetList(work, "u2")
+		target := &todolist.ListNotFoundErr{}
+		if !errors.As(err, &target) {
+			t.Errorf("expected list not found error, got: %v", err)
+		}
+	})
+
+	t.Run("rename and archive", func(t *testing.T) {
+		name := "office"
+		archived := true
+		err := mngr.UpdateList(work, "u1", todolist.ListUpdate{Name: &name, Archived: &archived})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(listNames(t, mngr, "u1", false), []string{todolist.InboxName, "home"}); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+		if diff := cmp.Diff(listNames(t, mngr, "u1", true), []string{todolist.InboxName, "office", "home"}); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+	})
+
+	t.Run("inbox cannot be archived or deleted", func(t *testing.T) {
+		inbox, err := mngr.Inbox("u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		archived := true
+		err = mngr.UpdateList(inbox.ID, "u1", todolist.ListUpdate{Archived: &archived})
+		if !errors.Is(err, todolist.ErrInboxList) {
+			t.Errorf("expected inbox error, got: %v", err)
+		}
+		err = mngr.DeleteList(inbox.ID, "u1", true)
+		if !errors.Is(err, todolist.ErrInboxList) {
+			t.Errorf("expected inbox error, got: %v", err)
+		}
+	})
+}
+
+func TestDeleteList(t *testing.T) {
+	mngr := testManager(t)
+
+	createInList := func(text, listId string) {
+		task := todolist.TodoItem{Text: text, OwnerId: "u1", ListId: listId}
+		_, err := mngr.Create(&task)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	inbox, err := mngr.Inbox("u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	move := createList(t, mngr, "move", "u1")
+	cascade := createList(t, mngr, "cascade", "u1")
+	createInList("inbox task", "")
+	createInList("moved task", move)
+	createInList("deleted task", cascade)
+
+	t.Run("move tasks to inbox", func(t *testing.T) {
+		err := mngr.DeleteList(move, "u1", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []string{"inbox task", "moved task"}
+		if diff := cmp.Diff(taskTexts(t, mngr, "u1", todolist.InList(inbox.ID)), want); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+	})
+
+	t.Run("cascade delete tasks", func(t *testing.T) {
+		err := mngr.DeleteList(cascade, "u1", true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []string{"inbox task", "moved task"}
+		if diff := cmp.Diff(taskTexts(t, mngr, "u1"), want); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+	})
+
+	t.Run("create task in a list of another user", func(t *testing.T) {
+		other := createList(t, mngr, "other", "u2")
+		task := todolist.TodoItem{Text: "intruder", OwnerId: "u1", ListId: other}
+		_, err := mngr.Create(&task)
+		target := &todolist.ListNotFoundErr{}
+		if !errors.As(err, &target) {
+			t.Errorf("expected list not found error, got: %v", err)
+		}
+	})
+}
+
+func TestMoveTaskToList(t *testing.T) {
+	mngr := testManager(t)
+	inbox, err := mngr.Inbox("u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	work := createList(t, mngr, "work", "u1")
+	parent := createTask(t, mngr, "report", "u1")
+	child := createSubtask(t, mngr, "charts", "u1", parent)
+
+	err = mngr.Update(parent, "u1", todolist.TaskUpdate{ListId: &work})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"report", "charts"}
+	if diff := cmp.Diff(taskTexts(t, mngr, "u1", todolist.InList(work)), want); diff != "" {
+		t.Errorf("unexpected value (-got +want)\n%s", diff)
+	}
+
+	t.Run("subtasks stay in the list of their parent", func(t *testing.T) {
+		err := mngr.Update(child, "u1", todolist.TaskUpdate{ListId: &inbox.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		task, err := mngr.Get(child, "u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if task.ListId != work {
+			t.Errorf("unexpected list: got %s want %s", task.ListId, work)
+		}
+	})
+
+	t.Run("empty list moves to the inbox", func(t *testing.T) {
+		empty := ""
+		err := mngr.Update(parent, "u1", todolist.TaskUpdate{ListId: &empty})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(taskTexts(t, mngr, "u1", todolist.InList(inbox.ID)), want); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+	})
+
+	t.Run("list of another user", func(t *testing.T) {
+		other := createList(t, mngr, "other", "u2")
+		err := mngr.Update(parent, "u1", todolist.TaskUpdate{ListId: &other})
+		target := &todolist.ListNotFoundErr{}
+		if !errors.As(err, &target) {
+			t.Errorf("expected list not found error, got: %v", err)
+		}
+	})
+}
@@ -0,0 +1,35 @@
+package todolist
+
+import (
+	"github.com/go-bumbu/todo-app/internal/markdown"
+	"gorm.io/gorm"
+)
+
+// SetCheckbox checks or unchecks the task list item with the given index in the notes of the task,
+// the notes are rewritten keeping the rest of the text untouched, see markdown.SetCheckbox
+func (m Manager) SetCheckbox(id, user string, index int, checked bool) error {
+	owner, err := authorize(m.db, id, user, RoleEditor)
+	if err != nil {
+		return err
+	}
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		t := TodoItem{}
+		result := tx.Where("ID = ? AND owner_id = ?", id, owner).Limit(1).Find(&t)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return &ItemNotFountErr{id: id, owner: owner}
+		}
+		notes, err := markdown.SetCheckbox(t.Notes, index, checked)
+		if err != nil {
+			return err
+		}
+		if notes == t.Notes {
+			return nil
+		}
+		return m.track(tx, ActionUpdate, []string{id}, func() ([]string, error) {
+			return nil, tx.Model(&t).Updates(map[string]any{"notes": notes, "version": gorm.Expr("version + 1")}).Error
+		})
+	})
+}
@@ -0,0 +1,126 @@
+package todolist_test
+
+import (
+	"errors"
+	glebarez "github.com/glebarez/sqlite"
+	"github.com/go-bumbu/todo-app/internal/markdown"
+	"github.com/go-bumbu/todo-app/internal/model/todolist"
+	"github.com/google/go-cmp/cmp"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"path/filepath"
+	"testing"
+)
+
+func TestNotes(t *testing.T) {
+	t.Run("toggle checkbox", func(t *testing.T) {
+		mngr := testManager(t)
+		task := todolist.TodoItem{Text: "trip", OwnerId: "u1", Notes: "- [ ] passport\n- [ ] tickets"}
+		_, err := mngr.Create(&task)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = mngr.SetCheckbox(task.ID, "u1", 1, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := mngr.Get(task.ID, "u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(got.Notes, "- [ ] passport\n- [x] tickets"); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+
+		err = mngr.SetCheckbox(task.ID, "u1", 2, true)
+		if !errors.Is(err, markdown.ErrNoCheckbox) {
+			t.Errorf("expected missing checkbox error, got: %v", err)
+		}
+		err = mngr.SetCheckbox(task.ID, "u2", 0, true)
+		target := &todolist.ItemNotFountErr{}
+		if !errors.As(err, &target) {
+			t.Errorf("expected not found error, got: %v", err)
+		}
+	})
+
+	t.Run("recurring checklist is reset", func(t *testing.T) {
+		mngr := testManager(t)
+		task := todolist.TodoItem{Text: "weekly review", OwnerId: "u1", Recurrence: "freq=weekly", Notes: "- [x] inbox zero"}
+		_, err := mngr.Create(&task)
+		if err != nil {
+			t.Fatal(err)
+		}
+		setDone(t, mngr, task.ID, "u1", true, "")
+		tasks, err := mngr.List("u1", 10, 1, todolist.WithDone(false))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tasks) != 1 || tasks[0].Notes != "- [ ] inbox zero" {
+			t.Errorf("unexpected next occurrence: %+v", tasks)
+		}
+	})
+
+	managers := map[string]func(t *testing.T) *todolist.Manager{
+		"fts5":     ftsManager,
+		"fallback": testManager,
+	}
+	for name, newManager := range managers {
+		t.Run("search notes "+name, func(t *testing.T) {
+			mngr := newManager(t)
+			task := todolist.TodoItem{Text: "trip", OwnerId: "u1", Notes: "remember the passport"}
+			_, err := mngr.Create(&task)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = createTask(t, mngr, "passport photos", "u1")
+			notes := "renew the passport"
+			err = mngr.Update(createTask(t, mngr, "errands", "u1"), "u1", todolist.TaskUpdate{Notes: &notes})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			results, err := mngr.Search("u1", "passport", 10, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := map[string]bool{}
+			for _, r := range results {
+				got[r.Text] = true
+			}
+			want := map[string]bool{"trip": true, "passport photos": true, "errands": true}
+			if diff := cmp.Diff(got, want); diff != "" {
+				t.Errorf("unexpected value (-got +want)\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("outdated search index is rebuilt", func(t *testing.T) {
+		db, err := gorm.Open(glebarez.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+			Logger: logger.Default.LogMode(logger.Silent),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// index without notes as created by previous versions
+		err = db.Exec("CREATE VIRTUAL TABLE todo_items_fts USING fts5(id UNINDEXED, text)").Error
+		if err != nil {
+			t.Fatal(err)
+		}
+		mngr, err := todolist.New(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		task := todolist.TodoItem{Text: "trip", OwnerId: "u1", Notes: "passport"}
+		_, err = mngr.Create(&task)
+		if err != nil {
+			t.Fatal(err)
+		}
+		results, err := mngr.Search("u1", "passport", 10, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 1 || results[0].Snippet != todolist.SnippetStart+"passport"+todolist.SnippetEnd {
+			t.Errorf("unexpected results: %+v", results)
+		}
+	})
+}
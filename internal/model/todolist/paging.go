@@ -0,0 +1,338 @@
+package todolist
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCursor is returned when a page cursor cannot be decoded
+var ErrInvalidCursor = errors.New("invalid page cursor")
+
+// DefaultSort is the sort key used when none is requested
+const DefaultSort = "position"
+
+// SortKeys are the values accepted by SortBy and PageRequest.Sort
+var SortKeys = []string{"position", "created", "updated", "due", "priority"}
+
+type columnKind int
+
+const (
+	kindString columnKind = iota
+	kindTime
+	kindBool
+	kindInt
+)
+
+// sortColumn is one of the expressions a sort key orders by, together they need to be unique
+// so that every task has a stable place that can be used as cursor
+type sortColumn struct {
+	expr    string
+	kind    columnKind
+	follows bool // follows the requested direction, otherwise it is always ascending
+	value   func(t TodoItem) any
+}
+
+var (
+	createdColumn = sortColumn{expr: "created_at", kind: kindTime, value: func(t TodoItem) any { return t.CreatedAt }}
+	idColumn      = sortColumn{expr: "id", kind: kindString, value: func(t TodoItem) any { return t.ID }}
+)
+
+var sortSpecs = map[string][]sortColumn{
+	"position": {
+		{expr: "position", kind: kindString, follows: true, value: func(t TodoItem) any { return t.Position }},
+		withDirection(idColumn),
+	},
+	"created": {withDirection(createdColumn), withDirection(idColumn)},
+	"updated": {
+		{expr: "updated_at", kind: kindTime, follows: true, value: func(t TodoItem) any { return t.UpdatedAt }},
+		withDirection(idColumn),
+	},
+	"due": {
+		// tasks without due date are sorted last in both directions
+		{expr: "due_date IS NULL", kind: kindBool, value: func(t TodoItem) any { return t.DueDate == nil }},
+		{expr: "due_date", kind: kindTime, follows: true, value: func(t TodoItem) any { return t.DueDate }},
+		createdColumn,
+		idColumn,
+	},
+	"priority": {
+		{expr: "priority", kind: kindInt, follows: true, value: func(t TodoItem) any { return int(t.Priority) }},
+		{expr: "position", kind: kindString, value: func(t TodoItem) any { return t.Position }},
+		idColumn,
+	},
+}
+
+func withDirection(c sortColumn) sortColumn {
+	c.follows = true
+	return c
+}
+
+func sortSpec(key string) ([]sortColumn, error) {
+	cols, ok := sortSpecs[key]
+	if !ok {
+		return nil, fmt.Errorf("unknown sort key \"%s\", use one of: %s", key, strings.Join(SortKeys, ", "))
+	}
+	return cols, nil
+}
+
+// SortBy orders the tasks by the given key, tasks without a value for the key are always sorted last
+func SortBy(key string, desc bool) (Scope, error) {
+	cols, err := sortSpec(key)
+	if err != nil {
+		return nil, err
+	}
+	return orderBy(cols, desc, false), nil
+}
+
+// orderBy sorts by the columns in the requested direction, reverse inverts the whole order
+func orderBy(cols []sortColumn, desc, reverse bool) Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		for _, c := range cols {
+			if (c.follows && desc) != reverse {
+				db = db.Order(c.expr + " DESC")
+			} else {
+				db = db.Order(c.expr + " ASC")
+			}
+		}
+		return db
+	}
+}
+
+// PageRequest selects a page of tasks, pages are either selected by number or by a cursor
+// returned in a previous TaskPage, in that case Page, Sort and Desc are ignored.
+type PageRequest struct {
+	Size   int
+	Page   int
+	Cursor string
+	Sort   string // one of SortKeys, defaults to DefaultSort
+	Desc   bool
+}
+
+// TaskPage is a page of tasks together with the cursors to the adjacent pages,
+// empty cursors mean there are no more tasks in that direction.
+type TaskPage struct {
+	Tasks []TodoItem
+	Total int64 // number of tasks matching the scopes on all pages
+	Next  string
+	Prev  string
+}
+
+// cursor points to the boundary of a page by the values of its sort columns, so paging is not
+// affected by tasks added or removed before it
+type cursor struct {
+	Sort   string `json:"s"`
+	Desc   bool   `json:"d,omitempty"`
+	Before bool   `json:"b,omitempty"` // the page ends before the boundary instead of starting after it
+	Values []any  `json:"v"`
+}
+
+func newCursor(sort string, desc, before bool, cols []sortColumn, t TodoItem) string {
+	c := cursor{Sort: sort, Desc: desc, Before: before, Values: make([]any, len(cols))}
+	for i, col := range cols {
+		v := col.value(t)
+		switch tv := v.(type) {
+		case time.Time:
+			v = tv.Format(time.RFC3339Nano)
+		case *time.Time:
+			if tv == nil {
+				v = nil
+			} else {
+				v = tv.Format(time.RFC3339Nano)
+			}
+		}
+		c.Values[i] = v
+	}
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(in string) (cursor, []sortColumn, error) {
+	c := cursor{}
+	data, err := base64.RawURLEncoding.DecodeString(in)
+	if err != nil {
+		return c, nil, ErrInvalidCursor
+	}
+	if json.Unmarshal(data, &c) != nil {
+		return c, nil, ErrInvalidCursor
+	}
+	cols, ok := sortSpecs[c.Sort]
+	if !ok || len(cols) != len(c.Values) {
+		return c, nil, ErrInvalidCursor
+	}
+	for i, col := range cols {
+		v := c.Values[i]
+		if v == nil {
+			continue
+		}
+		var ok bool
+		switch col.kind {
+		case kindTime:
+			var s string
+			if s, ok = v.(string); ok {
+				t, err := time.Parse(time.RFC3339Nano, s)
+				ok = err == nil
+				c.Values[i] = t
+			}
+		case kindBool:
+			_, ok = v.(bool)
+		case kindInt:
+			// json numbers are decoded as float64
+			var f float64
+			if f, ok = v.(float64); ok {
+				c.Values[i] = int(f)
+			}
+		default:
+			_, ok = v.(string)
+		}
+		if !ok {
+			return c, nil, ErrInvalidCursor
+		}
+	}
+	return c, cols, nil
+}
+
+// keyset returns the condition that selects the tasks sorted after the cursor, or before it
+// if the cursor points backwards
+func (c cursor) keyset(cols []sortColumn) cond {
+	ors := []string{}
+	args := []any{}
+	for i, col := range cols {
+		if c.Values[i] == nil {
+			// no value is sorted after NULL within its group, the group is split by the previous columns
+			continue
+		}
+		ands := []string{}
+		for j := 0; j < i; j++ {
+			if c.Values[j] == nil {
+				ands = append(ands, "("+cols[j].expr+") IS NULL")
+			} else {
+				ands = append(ands, "("+cols[j].expr+") = ?")
+				args = append(args, c.Values[j])
+			}
+		}
+		op := ">"
+		if (col.follows && c.Desc) != c.Before {
+			op = "<"
+		}
+		ands = append(ands, "("+col.expr+") "+op+" ?")
+		args = append(args, c.Values[i])
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+	}
+	if len(ors) == 0 {
+		return cond{sql: "1 = 0"}
+	}
+	return cond{sql: "(" + strings.Join(ors, " OR ") + ")", args: args}
+}
+
+// pageSize limits the number of tasks returned in a single page
+func pageSize(size int) int {
+	if size <= 0 {
+		return 20
+	}
+	if size >= 50 {
+		return 50
+	}
+	return size
+}
+
+// ListPage returns a page of the owner's tasks matching the scopes together with the total count
+// and the cursors to the previous and next pages. The scopes should not change the order.
+func (m Manager) ListPage(owner string, req PageRequest, scopes ...Scope) (TaskPage, error) {
+	return m.page(func(db *gorm.DB) *gorm.DB {
+		return db.Where("owner_id = ?", owner)
+	}, req, scopes)
+}
+
+// assignedSort is the default order of the assigned tasks, positions are ranked per list and owner and have
+// no meaning across the lists of different users
+const assignedSort = "created"
+
+// AssignedPage returns a page of the tasks assigned to the user like ListPage, including the tasks of the
+// lists shared with the user. Without sort key the tasks are sorted by creation instead of by position.
+func (m Manager) AssignedPage(user string, req PageRequest, scopes ...Scope) (TaskPage, error) {
+	if req.Sort == "" {
+		req.Sort = assignedSort
+	}
+	return m.page(func(db *gorm.DB) *gorm.DB {
+		shared := m.db.Model(&ListMember{}).Select("list_id").Where("user_id = ? AND accepted = ?", user, true)
+		return db.Where("assignee_id = ?", user).Where("owner_id = ? OR list_id IN (?)", user, shared)
+	}, req, scopes)
+}
+
+// page returns a page of the tasks visible through the base scope that match the scopes
+func (m Manager) page(base Scope, req PageRequest, scopes []Scope) (TaskPage, error) {
+	page := TaskPage{Tasks: []TodoItem{}}
+	size := pageSize(req.Size)
+	query := func() *gorm.DB {
+		db := m.db.Model(&TodoItem{}).Scopes(base)
+		for _, scope := range scopes {
+			db = db.Scopes(scope)
+		}
+		return db
+	}
+
+	err := query().Count(&page.Total).Error
+	if err != nil {
+		return page, err
+	}
+
+	c := cursor{Sort: req.Sort, Desc: req.Desc}
+	var cols []sortColumn
+	db := query()
+	offset := 0
+	if req.Cursor != "" {
+		c, cols, err = decodeCursor(req.Cursor)
+		if err != nil {
+			return page, err
+		}
+		k := c.keyset(cols)
+		db = db.Where(k.sql, k.args...)
+	} else {
+		if c.Sort == "" {
+			c.Sort = DefaultSort
+		}
+		cols, err = sortSpec(c.Sort)
+		if err != nil {
+			return page, err
+		}
+		offset = max(size*(req.Page-1), 0)
+	}
+
+	// one more task than needed is loaded to know if there is a following page,
+	// backward pages are loaded in reverse order
+	tasks := []TodoItem{}
+	err = db.Scopes(orderBy(cols, c.Desc, c.Before), preloadTags).Offset(offset).Limit(size + 1).Find(&tasks).Error
+	if err != nil {
+		return page, err
+	}
+	more := len(tasks) > size
+	if more {
+		tasks = tasks[:size]
+	}
+	if c.Before {
+		for i, j := 0, len(tasks)-1; i < j; i, j = i+1, j-1 {
+			tasks[i], tasks[j] = tasks[j], tasks[i]
+		}
+	}
+	page.Tasks = tasks
+	if len(tasks) == 0 {
+		return page, nil
+	}
+
+	first, last := tasks[0], tasks[len(tasks)-1]
+	hasPrev, hasNext := req.Cursor != "" || offset > 0, more
+	if c.Before {
+		hasPrev, hasNext = more, true
+	}
+	if hasPrev {
+		page.Prev = newCursor(c.Sort, c.Desc, true, cols, first)
+	}
+	if hasNext {
+		page.Next = newCursor(c.Sort, c.Desc, false, cols, last)
+	}
+	return page, nil
+}
@@ -0,0 +1,140 @@
+package todolist_test
+
+import (
+	"errors"
+	"github.com/go-bumbu/todo-app/internal/model/todolist"
+	"github.com/google/go-cmp/cmp"
+	"testing"
+	"time"
+)
+
+func pageTexts(page todolist.TaskPage) []string {
+	got := []string{}
+	for _, item := range page.Tasks {
+		got = append(got, item.Text)
+	}
+	return got
+}
+
+func TestListPage(t *testing.T) {
+	mngr := testManager(t)
+	day := func(d int) *time.Time {
+		t := time.Date(2024, 5, d, 0, 0, 0, 0, time.UTC)
+		return &t
+	}
+	dues := map[string]*time.Time{"t1": day(5), "t2": nil, "t3": day(1), "t4": day(5), "t5": nil, "t6": day(3), "t7": day(9)}
+	for _, text := range []string{"t1", "t2", "t3", "t4", "t5", "t6", "t7"} {
+		item := todolist.TodoItem{Text: text, OwnerId: "u1", DueDate: dues[text]}
+		if _, err := mngr.Create(&item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = createTask(t, mngr, "other", "u2")
+
+	// walk forward until the last page and back to the first one
+	walk := func(t *testing.T, req todolist.PageRequest) (forward, backward [][]string) {
+		page, err := mngr.ListPage("u1", req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if page.Total != 7 {
+			t.Errorf("expected total of 7, got %d", page.Total)
+		}
+		if page.Prev != "" {
+			t.Error("first page should not have a previous cursor")
+		}
+		forward = append(forward, pageTexts(page))
+		for page.Next != "" {
+			page, err = mngr.ListPage("u1", todolist.PageRequest{Size: req.Size, Cursor: page.Next})
+			if err != nil {
+				t.Fatal(err)
+			}
+			forward = append(forward, pageTexts(page))
+		}
+		for page.Prev != "" {
+			page, err = mngr.ListPage("u1", todolist.PageRequest{Size: req.Size, Cursor: page.Prev})
+			if err != nil {
+				t.Fatal(err)
+			}
+			backward = append(backward, pageTexts(page))
+		}
+		return forward, backward
+	}
+
+	tcs := []struct {
+		name     string
+		req      todolist.PageRequest
+		forward  [][]string
+		backward [][]string
+	}{
+		{
+			name:     "created",
+			req:      todolist.PageRequest{Size: 3},
+			forward:  [][]string{{"t1", "t2", "t3"}, {"t4", "t5", "t6"}, {"t7"}},
+			backward: [][]string{{"t4", "t5", "t6"}, {"t1", "t2", "t3"}},
+		},
+		{
+			name:     "due with nulls last",
+			req:      todolist.PageRequest{Size: 3, Sort: "due"},
+			forward:  [][]string{{"t3", "t6", "t1"}, {"t4", "t7", "t2"}, {"t5"}},
+			backward: [][]string{{"t4", "t7", "t2"}, {"t3", "t6", "t1"}},
+		},
+		{
+			name:     "due descending",
+			req:      todolist.PageRequest{Size: 2, Sort: "due", Desc: true},
+			forward:  [][]string{{"t7", "t1"}, {"t4", "t6"}, {"t3", "t2"}, {"t5"}},
+			backward: [][]string{{"t3", "t2"}, {"t4", "t6"}, {"t7", "t1"}},
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			forward, backward := walk(t, tc.req)
+			if diff := cmp.Diff(forward, tc.forward); diff != "" {
+				t.Errorf("unexpected forward pages (-got +want)\n%s", diff)
+			}
+			if diff := cmp.Diff(backward, tc.backward); diff != "" {
+				t.Errorf("unexpected backward pages (-got +want)\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("page numbers", func(t *testing.T) {
+		page, err := mngr.ListPage("u1", todolist.PageRequest{Size: 3, Page: 3})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(pageTexts(page), []string{"t7"}); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+		if page.Prev == "" || page.Next != "" {
+			t.Errorf("expected only a previous cursor")
+		}
+	})
+
+	t.Run("cursor is stable on deletes", func(t *testing.T) {
+		page, err := mngr.ListPage("u1", todolist.PageRequest{Size: 3})
+		if err != nil {
+			t.Fatal(err)
+		}
+		deleteTask(t, mngr, page.Tasks[0].ID, "u1", "")
+		page, err = mngr.ListPage("u1", todolist.PageRequest{Size: 3, Cursor: page.Next})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(pageTexts(page), []string{"t4", "t5", "t6"}); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+		if page.Total != 6 {
+			t.Errorf("expected total of 6, got %d", page.Total)
+		}
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		for _, c := range []string{"not a cursor", "eyJzIjoiY29sb3IiLCJ2IjpbXX0"} {
+			_, err := mngr.ListPage("u1", todolist.PageRequest{Cursor: c})
+			if !errors.Is(err, todolist.ErrInvalidCursor) {
+				t.Errorf("expected invalid cursor error, got %v", err)
+			}
+		}
+	})
+}
@@ -0,0 +1,248 @@
+package todolist
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"strings"
+)
+
+// Positions are strings compared lexicographically that represent base 36 fractions between 0 and 1,
+// e.g. "i" is 0.5. There is always room for a new position between two different ones, so moving a task
+// only updates the moved task. Positions never end with "0", that would make two of them equal.
+const rankDigits = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+// maxRankLen is the length after which the positions of an owner are rebalanced
+const maxRankLen = 32
+
+// ErrMoveToSelf is returned when a task is moved before or after itself
+var ErrMoveToSelf = errors.New("a task cannot be moved relative to itself")
+
+// errRankOrder is returned when two positions have no room between them, this only happens
+// if they are equal which requires a rebalance
+var errRankOrder = errors.New("positions are not in order")
+
+func rankDigit(s string, i int) int {
+	if i >= len(s) {
+		return 0
+	}
+	return strings.IndexByte(rankDigits, s[i])
+}
+
+func rankSuffix(s string, i int) string {
+	if i >= len(s) {
+		return ""
+	}
+	return s[i:]
+}
+
+// rankBetween returns a position sorted between a and b, an empty a means the start
+// and an empty b the end of the list
+func rankBetween(a, b string) (string, error) {
+	if b == "" {
+		return rankAfter(a), nil
+	}
+	if a >= b {
+		return "", errRankOrder
+	}
+	return rankMidpoint(a, b), nil
+}
+
+// rankAfter returns the shortest position after a, it is used to append tasks so that
+// consecutive appends only grow the position every 35 tasks
+func rankAfter(a string) string {
+	for i := 0; i < len(a); i++ {
+		if d := rankDigit(a, i); d < len(rankDigits)-1 {
+			return a[:i] + string(rankDigits[d+1])
+		}
+	}
+	return a + rankMidpoint("", "")
+}
+
+// rankMidpoint returns a position between a and b, a has to be sorted before b
+func rankMidpoint(a, b string) string {
+	if b != "" {
+		// keep the common prefix, a is padded with zeros
+		n := 0
+		for n < len(b) && rankDigit(a, n) == rankDigit(b, n) {
+			n++
+		}
+		if n > 0 {
+			return b[:n] + rankMidpoint(rankSuffix(a, n), b[n:])
+		}
+	}
+	da := rankDigit(a, 0)
+	db := len(rankDigits)
+	if b != "" {
+		db = rankDigit(b, 0)
+	}
+	if db-da > 1 {
+		return string(rankDigits[(da+db+1)/2])
+	}
+	// the first digits are consecutive
+	if len(b) > 1 {
+		return b[:1]
+	}
+	return string(rankDigits[da]) + rankMidpoint(rankSuffix(a, 1), "")
+}
+
+// evenRanks returns n positions of the same length evenly spread between 0 and 1
+func evenRanks(n int) []string {
+	base := len(rankDigits)
+	width, space := 1, base
+	// leave room for at least one digit of inserts between every two positions
+	for space < base*(n+1) {
+		width++
+		space *= base
+	}
+	step := space / (n + 1)
+	ranks := make([]string, n)
+	digits := make([]byte, width)
+	for i := range ranks {
+		v := (i + 1) * step
+		for j := width - 1; j >= 0; j-- {
+			digits[j] = rankDigits[v%base]
+			v /= base
+		}
+		ranks[i] = strings.TrimRight(string(digits), "0")
+	}
+	return ranks
+}
+
+// rebalance assigns evenly spread positions to all the tasks of the owner keeping their order,
+// tasks without position are sorted last by creation. Deleted tasks are included so that they
+// keep their place when restored.
+func rebalance(tx *gorm.DB, owner string) error {
+	ids := []string{}
+	err := tx.Unscoped().Model(&TodoItem{}).Where("owner_id = ?", owner).
+		Order("position = ''").Order("position").Order("created_at").Order("id").
+		Pluck("id", &ids).Error
+	if err != nil {
+		return err
+	}
+	for i, rank := range evenRanks(len(ids)) {
+		err = tx.Unscoped().Model(&TodoItem{}).Where("id = ?", ids[i]).UpdateColumn("position", rank).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rebalanceUnranked assigns positions to the tasks stored before positions existed
+func rebalanceUnranked(db *gorm.DB) error {
+	owners := []TodoItem{}
+	err := db.Unscoped().Model(&TodoItem{}).Where("position = ''").Distinct("owner_id", "workspace_id").Find(&owners).Error
+	if err != nil {
+		return err
+	}
+	for _, owner := range owners {
+		err = withWorkspace(db, owner.WorkspaceId).Transaction(func(tx *gorm.DB) error {
+			return rebalance(tx, owner.OwnerId)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lastPosition returns the position of the last task of the owner
+func lastPosition(tx *gorm.DB, owner string) (string, error) {
+	positions := []string{}
+	err := tx.Unscoped().Model(&TodoItem{}).Where("owner_id = ?", owner).
+		Order("position DESC").Limit(1).Pluck("position", &positions).Error
+	if err != nil || len(positions) == 0 {
+		return "", err
+	}
+	return positions[0], nil
+}
+
+// appendPosition returns the position after all the tasks of the owner
+func appendPosition(tx *gorm.DB, owner string) (string, error) {
+	last, err := lastPosition(tx, owner)
+	if err != nil {
+		return "", err
+	}
+	if rank := rankAfter(last); len(rank) <= maxRankLen {
+		return rank, nil
+	}
+	err = rebalance(tx, owner)
+	if err != nil {
+		return "", err
+	}
+	last, err = lastPosition(tx, owner)
+	if err != nil {
+		return "", err
+	}
+	return rankAfter(last), nil
+}
+
+// Move places the task right before the anchor task, or right after it if after is true
+func (m Manager) Move(id, user, anchorId string, after bool) error {
+	if id == anchorId {
+		return ErrMoveToSelf
+	}
+	owner, err := authorize(m.db, id, user, RoleEditor)
+	if err != nil {
+		return err
+	}
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		err := tx.Model(&TodoItem{}).Where("ID = ? AND owner_id = ?", id, owner).Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return &ItemNotFountErr{id: id, owner: owner}
+		}
+
+		rank, err := moveRank(tx, id, owner, anchorId, after)
+		if errors.Is(err, errRankOrder) || (err == nil && len(rank) > maxRankLen) {
+			err = rebalance(tx, owner)
+			if err != nil {
+				return err
+			}
+			rank, err = moveRank(tx, id, owner, anchorId, after)
+		}
+		if err != nil {
+			return err
+		}
+		// only the moved task is recorded, the positions changed by a rebalance are not part of the history
+		return m.track(tx, ActionMove, []string{id}, func() ([]string, error) {
+			return nil, tx.Model(&TodoItem{}).Where("ID = ?", id).
+				Updates(map[string]any{"position": rank, "version": gorm.Expr("version + 1")}).Error
+		})
+	})
+}
+
+// moveRank calculates the position between the anchor and its neighbour, ignoring the moved task
+func moveRank(tx *gorm.DB, id, owner, anchorId string, after bool) (string, error) {
+	anchor := TodoItem{}
+	result := tx.Where("ID = ? AND owner_id = ?", anchorId, owner).Limit(1).Find(&anchor)
+	if result.Error != nil {
+		return "", result.Error
+	}
+	if result.RowsAffected == 0 {
+		return "", &ItemNotFountErr{id: anchorId, owner: owner}
+	}
+
+	neighbours := []string{}
+	query := tx.Unscoped().Model(&TodoItem{}).Where("owner_id = ? AND id NOT IN ?", owner, []string{id, anchorId})
+	if after {
+		query = query.Where("position >= ?", anchor.Position).Order("position")
+	} else {
+		query = query.Where("position <= ?", anchor.Position).Order("position DESC")
+	}
+	err := query.Limit(1).Pluck("position", &neighbours).Error
+	if err != nil {
+		return "", err
+	}
+	neighbour := ""
+	if len(neighbours) > 0 {
+		neighbour = neighbours[0]
+	}
+	if after {
+		return rankBetween(anchor.Position, neighbour)
+	}
+	return rankBetween(neighbour, anchor.Position)
+}
@@ -0,0 +1,112 @@
+package todolist_test
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-bumbu/todo-app/internal/model/todolist"
+	"github.com/google/go-cmp/cmp"
+	"testing"
+)
+
+func moveTask(t *testing.T, mngr *todolist.Manager, id, anchor string, after bool) {
+	t.Helper()
+	err := mngr.Move(id, "u1", anchor, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMoveTask(t *testing.T) {
+	mngr := testManager(t)
+	ids := map[string]string{}
+	for _, text := range []string{"a", "b", "c", "d", "e"} {
+		ids[text] = createTask(t, mngr, text, "u1")
+	}
+
+	tcs := []struct {
+		name   string
+		id     string
+		anchor string
+		after  bool
+		want   []string
+	}{
+		{name: "move to the top", id: "e", anchor: "a", want: []string{"e", "a", "b", "c", "d"}},
+		{name: "move after a task", id: "e", anchor: "c", after: true, want: []string{"a", "b", "c", "e", "d"}},
+		{name: "move to the bottom", id: "a", anchor: "d", after: true, want: []string{"b", "c", "e", "d", "a"}},
+		{name: "move before the next task", id: "c", anchor: "d", want: []string{"b", "e", "c", "d", "a"}},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			moveTask(t, mngr, ids[tc.id], ids[tc.anchor], tc.after)
+			if diff := cmp.Diff(taskTexts(t, mngr, "u1"), tc.want); diff != "" {
+				t.Errorf("unexpected value (-got +want)\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("new tasks are appended", func(t *testing.T) {
+		_ = createTask(t, mngr, "f", "u1")
+		want := []string{"b", "e", "c", "d", "a", "f"}
+		if diff := cmp.Diff(taskTexts(t, mngr, "u1"), want); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+	})
+
+	t.Run("move relative to itself", func(t *testing.T) {
+		err := mngr.Move(ids["a"], "u1", ids["a"], false)
+		if !errors.Is(err, todolist.ErrMoveToSelf) {
+			t.Errorf("expected move to self error, got: %v", err)
+		}
+	})
+
+	t.Run("anchor of other owner", func(t *testing.T) {
+		other := createTask(t, mngr, "other", "u2")
+		err := mngr.Move(ids["a"], "u1", other, false)
+		target := &todolist.ItemNotFountErr{}
+		if !errors.As(err, &target) {
+			t.Errorf("expected item not found error, got: %v", err)
+		}
+	})
+}
+
+func TestPositionRebalance(t *testing.T) {
+	mngr := testManager(t)
+	first := createTask(t, mngr, "first", "u1")
+	_ = createTask(t, mngr, "last", "u1")
+
+	// every task is inserted right after the first one, which halves the same gap over and over
+	want := []string{"first"}
+	for i := 0; i < 200; i++ {
+		text := fmt.Sprintf("task %d", i)
+		moveTask(t, mngr, createTask(t, mngr, text, "u1"), first, true)
+		want = append([]string{"first", text}, want[1:]...)
+	}
+	want = append(want, "last")
+
+	tasks, err := mngr.List("u1", 50, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, task := range tasks {
+		if len(task.Position) > 32 {
+			t.Errorf("expected positions to be rebalanced, got %q", task.Position)
+		}
+	}
+
+	got := []string{}
+	req := todolist.PageRequest{Size: 50}
+	for {
+		page, err := mngr.ListPage("u1", req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, pageTexts(page)...)
+		if page.Next == "" {
+			break
+		}
+		req.Cursor = page.Next
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("unexpected value (-got +want)\n%s", diff)
+	}
+}
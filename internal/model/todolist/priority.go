@@ -0,0 +1,109 @@
+package todolist
+
+import (
+	"fmt"
+	"gorm.io/gorm/clause"
+	"strings"
+	"time"
+)
+
+// Priority of a task, higher values are more important
+type Priority int
+
+const (
+	PriorityNone Priority = iota
+	PriorityLow
+	PriorityMedium
+	PriorityHigh
+	PriorityUrgent
+)
+
+var priorityNames = []string{"none", "low", "medium", "high", "urgent"}
+
+func (p Priority) String() string {
+	if p < PriorityNone || p > PriorityUrgent {
+		return fmt.Sprintf("Priority(%d)", int(p))
+	}
+	return priorityNames[p]
+}
+
+// ParsePriority reads a priority by name, an empty string is PriorityNone
+func ParsePriority(in string) (Priority, error) {
+	if in == "" {
+		return PriorityNone, nil
+	}
+	for i, name := range priorityNames {
+		if strings.EqualFold(in, name) {
+			return Priority(i), nil
+		}
+	}
+	return PriorityNone, fmt.Errorf("unknown priority \"%s\", use one of: %s", in, strings.Join(priorityNames, ", "))
+}
+
+func (p Priority) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+func (p *Priority) UnmarshalText(text []byte) error {
+	v, err := ParsePriority(string(text))
+	if err != nil {
+		return err
+	}
+	*p = v
+	return nil
+}
+
+// focusScore ranks the pending tasks for the focus view: every priority level adds 10 points,
+// being important adds 15 and urgent 10, overdue tasks get 40 points, tasks due today 30,
+// in the next 3 days 20 and in the next week 10.
+func focusScore(now time.Time) cond {
+	y, mo, d := now.Date()
+	today := time.Date(y, mo, d, 0, 0, 0, 0, now.Location())
+	sql := "(todo_items.priority * 10" +
+		" + CASE WHEN todo_items.important THEN 15 ELSE 0 END" +
+		" + CASE WHEN todo_items.urgent THEN 10 ELSE 0 END" +
+		" + CASE"
+	args := []any{}
+	for _, step := range []struct {
+		before time.Time
+		points int
+	}{
+		{before: now, points: 40},
+		{before: today.AddDate(0, 0, 1), points: 30},
+		{before: today.AddDate(0, 0, 4), points: 20},
+		{before: today.AddDate(0, 0, 8), points: 10},
+	} {
+		c := dateBefore("todo_items.due", step.before)
+		sql += fmt.Sprintf(" WHEN %s THEN %d", c.sql, step.points)
+		args = append(args, c.args...)
+	}
+	return cond{sql: sql + " ELSE 0 END)", args: args}
+}
+
+// Focus returns the pending tasks of the owner that need attention ranked by priority, importance,
+// urgency and due date, tasks that start after today or have no score at all are left out.
+func (m Manager) Focus(owner string, size int, now time.Time, scopes ...Scope) ([]TodoItem, error) {
+	y, mo, d := now.Date()
+	tomorrow := time.Date(y, mo, d+1, 0, 0, 0, 0, now.Location())
+	started := dateBefore("todo_items.start", tomorrow)
+	score := focusScore(now)
+
+	tasks := []TodoItem{}
+	db := m.db.Model(&TodoItem{}).Where("owner_id = ? AND done = ?", owner, false)
+	for _, scope := range scopes {
+		db = db.Scopes(scope)
+	}
+	result := db.Scopes(preloadTags).
+		Where("(todo_items.start_date IS NULL OR "+started.sql+")", started.args...).
+		Where(score.sql+" > 0", score.args...).
+		Clauses(clause.OrderBy{Expression: clause.Expr{
+			SQL:                score.sql + " DESC, todo_items.due_date IS NULL, todo_items.due_date, todo_items.position, todo_items.id",
+			Vars:               score.args,
+			WithoutParentheses: true,
+		}}).
+		Limit(pageSize(size)).Find(&tasks)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return tasks, nil
+}
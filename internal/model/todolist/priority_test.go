@@ -0,0 +1,117 @@
+package todolist_test
+
+import (
+	"encoding/json"
+	"github.com/go-bumbu/todo-app/internal/model/todolist"
+	"github.com/google/go-cmp/cmp"
+	"testing"
+	"time"
+)
+
+func TestParsePriority(t *testing.T) {
+	for in, want := range map[string]todolist.Priority{
+		"":       todolist.PriorityNone,
+		"low":    todolist.PriorityLow,
+		"Medium": todolist.PriorityMedium,
+		"HIGH":   todolist.PriorityHigh,
+		"urgent": todolist.PriorityUrgent,
+	} {
+		got, err := todolist.ParsePriority(in)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", in, err)
+		}
+		if got != want {
+			t.Errorf("%s: got %v want %v", in, got, want)
+		}
+	}
+
+	_, err := todolist.ParsePriority("asap")
+	want := `unknown priority "asap", use one of: none, low, medium, high, urgent`
+	if err == nil || err.Error() != want {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	data, err := json.Marshal(struct{ P todolist.Priority }{P: todolist.PriorityHigh})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"P":"high"}` {
+		t.Errorf("unexpected json: %s", data)
+	}
+}
+
+func TestPriorities(t *testing.T) {
+	mngr := testManager(t)
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	day := func(d int) *time.Time {
+		t := time.Date(2024, 5, d, 0, 0, 0, 0, time.UTC)
+		return &t
+	}
+	items := []todolist.TodoItem{
+		{Text: "someday"},
+		{Text: "low", Priority: todolist.PriorityLow},
+		{Text: "urgent", Priority: todolist.PriorityUrgent},
+		{Text: "overdue", DueDate: day(8)},
+		{Text: "due today", Priority: todolist.PriorityLow, DueDate: day(10)},
+		{Text: "important and urgent", Important: true, Urgent: true},
+		{Text: "done", Priority: todolist.PriorityUrgent, Done: true},
+		{Text: "not started", Priority: todolist.PriorityHigh, StartDate: day(12)},
+		{Text: "started", Priority: todolist.PriorityHigh, StartDate: day(10)},
+		{Text: "due next week", DueDate: day(16)},
+		{Text: "due next month", DueDate: day(30)},
+	}
+	for i := range items {
+		items[i].OwnerId = "u1"
+		if _, err := mngr.Create(&items[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("focus", func(t *testing.T) {
+		tasks, err := mngr.Focus("u1", 0, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []string{}
+		for _, task := range tasks {
+			got = append(got, task.Text)
+		}
+		// equal scores are sorted by due date
+		want := []string{"overdue", "due today", "urgent", "started", "important and urgent", "due next week", "low"}
+		if diff := cmp.Diff(got, want); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+	})
+
+	t.Run("update priority", func(t *testing.T) {
+		high := todolist.PriorityHigh
+		important := true
+		err := mngr.Update(items[0].ID, "u1", todolist.TaskUpdate{Priority: &high, Important: &important})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := mngr.Get(items[0].ID, "u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Priority != todolist.PriorityHigh || !got.Important || got.Urgent {
+			t.Errorf("unexpected priority values: %v %v %v", got.Priority, got.Important, got.Urgent)
+		}
+	})
+
+	t.Run("filter and sort", func(t *testing.T) {
+		filter, err := todolist.ParseFilter("priority>=high urgent:false done:false")
+		if err != nil {
+			t.Fatal(err)
+		}
+		sort, err := todolist.SortBy("priority", true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := taskTexts(t, mngr, "u1", filter, sort)
+		want := []string{"urgent", "someday", "not started", "started"}
+		if diff := cmp.Diff(got, want); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+	})
+}
@@ -0,0 +1,269 @@
+package todolist
+
+import (
+	"fmt"
+	"github.com/go-bumbu/todo-app/internal/markdown"
+	"gorm.io/gorm"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+)
+
+// Recurrence is a subset of the iCalendar RRULE used to repeat tasks, it is stored in the task as string e.g.
+// "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH". The non-standard part FROM=COMPLETION counts the interval from
+// the moment the task is completed instead of from its due date.
+type Recurrence struct {
+	Freq           Frequency
+	Interval       int            // repeat every Interval days, weeks or months, defaults to 1
+	ByDay          []time.Weekday // only for weekly rules
+	ByMonthDay     int            // only for monthly rules, clamped to the last day on shorter months
+	FromCompletion bool           // only for daily rules
+}
+
+var weekdays = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// ParseRecurrence reads a rule in the format written by Recurrence.String, an optional "RRULE:" prefix is ignored
+func ParseRecurrence(in string) (Recurrence, error) {
+	r := Recurrence{Interval: 1}
+	in = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(in)), "RRULE:")
+	if in == "" {
+		return r, fmt.Errorf("empty recurrence rule")
+	}
+
+	for _, part := range strings.Split(in, ";") {
+		key, val, ok := strings.Cut(part, "=")
+		if !ok || val == "" {
+			return r, fmt.Errorf("malformed recurrence part \"%s\"", part)
+		}
+		switch key {
+		case "FREQ":
+			r.Freq = Frequency(val)
+		case "INTERVAL":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return r, fmt.Errorf("INTERVAL must be a positive number")
+			}
+			r.Interval = n
+		case "BYDAY":
+			for _, day := range strings.Split(val, ",") {
+				wd := indexOf(weekdays, day)
+				if wd < 0 {
+					return r, fmt.Errorf("unknown week day \"%s\"", day)
+				}
+				r.ByDay = append(r.ByDay, time.Weekday(wd))
+			}
+		case "BYMONTHDAY":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 || n > 31 {
+				return r, fmt.Errorf("BYMONTHDAY must be a number between 1 and 31")
+			}
+			r.ByMonthDay = n
+		case "FROM":
+			if val != "COMPLETION" {
+				return r, fmt.Errorf("FROM only supports the value COMPLETION")
+			}
+			r.FromCompletion = true
+		default:
+			return r, fmt.Errorf("unsupported recurrence part \"%s\"", key)
+		}
+	}
+
+	switch r.Freq {
+	case Daily, Weekly, Monthly:
+	case "":
+		return r, fmt.Errorf("FREQ is required")
+	default:
+		return r, fmt.Errorf("FREQ must be one of: %s, %s, %s", Daily, Weekly, Monthly)
+	}
+	if len(r.ByDay) > 0 && r.Freq != Weekly {
+		return r, fmt.Errorf("BYDAY is only allowed for %s rules", Weekly)
+	}
+	if r.ByMonthDay > 0 && r.Freq != Monthly {
+		return r, fmt.Errorf("BYMONTHDAY is only allowed for %s rules", Monthly)
+	}
+	if r.FromCompletion && r.Freq != Daily {
+		return r, fmt.Errorf("FROM=COMPLETION is only allowed for %s rules", Daily)
+	}
+	return r, nil
+}
+
+// String returns the rule in its canonical form
+func (r Recurrence) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, d := range r.ByDay {
+			days[i] = weekdays[d]
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if r.ByMonthDay > 0 {
+		parts = append(parts, "BYMONTHDAY="+strconv.Itoa(r.ByMonthDay))
+	}
+	if r.FromCompletion {
+		parts = append(parts, "FROM=COMPLETION")
+	}
+	return strings.Join(parts, ";")
+}
+
+// Next returns the first occurrence after t, the calendar is evaluated in the location of t
+// and the time of day of t is kept.
+func (r Recurrence) Next(t time.Time) time.Time {
+	interval := r.Interval
+	if interval < 1 {
+		interval = 1
+	}
+	switch r.Freq {
+	case Weekly:
+		if len(r.ByDay) == 0 {
+			return t.AddDate(0, 0, 7*interval)
+		}
+		// only the weeks that are a multiple of the interval away from the week of t are eligible
+		week := startOfWeek(t)
+		for i := 1; i <= 7*(interval+1); i++ {
+			d := t.AddDate(0, 0, i)
+			weeks := daysBetween(week, startOfWeek(d)) / 7
+			if weeks%interval == 0 && containsWeekday(r.ByDay, d.Weekday()) {
+				return d
+			}
+		}
+		return t.AddDate(0, 0, 7*interval)
+	case Monthly:
+		day := r.ByMonthDay
+		if day == 0 {
+			day = t.Day()
+		}
+		for k := 0; ; k++ {
+			first := time.Date(t.Year(), t.Month()+time.Month(k*interval), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+			last := first.AddDate(0, 1, -1).Day()
+			c := first.AddDate(0, 0, min(day, last)-1)
+			if c.After(t) {
+				return c
+			}
+		}
+	default:
+		return t.AddDate(0, 0, interval)
+	}
+}
+
+// startOfWeek returns the monday of the week of t
+func startOfWeek(t time.Time) time.Time {
+	return t.AddDate(0, 0, -((int(t.Weekday()) + 6) % 7))
+}
+
+func daysBetween(a, b time.Time) int {
+	return int(floatingDate(b).Sub(floatingDate(a)).Hours() / 24)
+}
+
+func containsWeekday(days []time.Weekday, d time.Weekday) bool {
+	for _, wd := range days {
+		if wd == d {
+			return true
+		}
+	}
+	return false
+}
+
+func indexOf(items []string, s string) int {
+	for i, item := range items {
+		if item == s {
+			return i
+		}
+	}
+	return -1
+}
+
+// canonicalRecurrence validates a stored rule and returns it in canonical form, an empty rule is kept empty
+func canonicalRecurrence(in string) (string, error) {
+	if in == "" {
+		return "", nil
+	}
+	r, err := ParseRecurrence(in)
+	if err != nil {
+		return "", err
+	}
+	return r.String(), nil
+}
+
+// scheduleNext creates the next occurrence of a recurring task that was completed at now and returns its id,
+// the new task is a copy of t with the dates moved forward and linked to the same series, subtasks are not copied.
+func scheduleNext(tx *gorm.DB, t TodoItem, now time.Time) (string, error) {
+	r, err := ParseRecurrence(t.Recurrence)
+	if err != nil {
+		return "", err
+	}
+	loc, err := time.LoadLocation(t.TimeZone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	// all day dates are floating, they are calculated in UTC to keep the calendar day
+	var base time.Time
+	hasTime := t.DueDate != nil && t.DueHasTime
+	switch {
+	case t.DueDate == nil:
+		base = floatingDate(now.In(loc))
+	case hasTime && r.FromCompletion:
+		due := t.DueDate.In(loc)
+		n := now.In(loc)
+		base = time.Date(n.Year(), n.Month(), n.Day(), due.Hour(), due.Minute(), due.Second(), 0, loc)
+	case hasTime:
+		base = t.DueDate.In(loc)
+	case r.FromCompletion:
+		base = floatingDate(now.In(loc))
+	default:
+		base = *t.DueDate
+	}
+	due := r.Next(base)
+
+	series := t.SeriesId
+	if series == "" {
+		series = t.ID
+		err = tx.Model(&TodoItem{}).Where("ID = ?", t.ID).Update("series_id", series).Error
+		if err != nil {
+			return "", err
+		}
+	}
+
+	next := TodoItem{
+		OwnerId:      t.OwnerId,
+		ListId:       t.ListId,
+		ParentId:     t.ParentId,
+		Text:         t.Text,
+		Notes:        markdown.ClearCheckboxes(t.Notes),
+		Priority:     t.Priority,
+		Important:    t.Important,
+		Urgent:       t.Urgent,
+		AssigneeId:   t.AssigneeId,
+		DueDate:      normalizeDate(&due, hasTime),
+		DueHasTime:   hasTime,
+		StartHasTime: t.StartHasTime,
+		TimeZone:     t.TimeZone,
+		Recurrence:   t.Recurrence,
+		SeriesId:     series,
+		Tags:         t.Tags,
+	}
+	if t.StartDate != nil && t.DueDate != nil {
+		// the start date keeps the same distance to the due date
+		start := t.StartDate.Add(next.DueDate.Sub(*t.DueDate))
+		next.StartDate = &start
+	}
+	open, err := defaultStatus(tx, t.OwnerId, false)
+	if err != nil {
+		return "", err
+	}
+	next.StatusId = open.ID
+	err = tx.Omit("Tags.*").Create(&next).Error
+	return next.ID, err
+}
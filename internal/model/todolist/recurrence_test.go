@@ -0,0 +1,131 @@
+package todolist_test
+
+import (
+	"github.com/go-bumbu/todo-app/internal/model/todolist"
+	"github.com/google/go-cmp/cmp"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+func TestRecurrenceNext(t *testing.T) {
+	day := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, 9, 30, 0, 0, time.UTC)
+	}
+	tcs := []struct {
+		name   string
+		rule   string
+		from   time.Time
+		expect time.Time
+		canon  string
+	}{
+		{name: "every 3 days", rule: "FREQ=DAILY;INTERVAL=3", from: day(2024, 5, 10), expect: day(2024, 5, 13)},
+		{name: "weekly on days", rule: "rrule:freq=weekly;byday=mo,th", from: day(2024, 5, 10), expect: day(2024, 5, 13), canon: "FREQ=WEEKLY;BYDAY=MO,TH"},
+		{name: "every 2 weeks skips a week", rule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH", from: day(2024, 5, 9), expect: day(2024, 5, 20)},
+		{name: "weekly without days", rule: "FREQ=WEEKLY", from: day(2024, 5, 9), expect: day(2024, 5, 16)},
+		{name: "monthly later in month", rule: "FREQ=MONTHLY;BYMONTHDAY=15", from: day(2024, 5, 10), expect: day(2024, 5, 15)},
+		{name: "monthly clamps to month end", rule: "FREQ=MONTHLY;BYMONTHDAY=31", from: day(2024, 1, 31), expect: day(2024, 2, 29)},
+		{name: "monthly same day", rule: "FREQ=MONTHLY;INTERVAL=3", from: day(2024, 11, 5), expect: day(2025, 2, 5)},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := todolist.ParseRecurrence(tc.rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := r.Next(tc.from); !got.Equal(tc.expect) {
+				t.Errorf("unexpected next occurrence: got %s want %s", got, tc.expect)
+			}
+			canon := tc.canon
+			if canon == "" {
+				canon = tc.rule
+			}
+			if r.String() != canon {
+				t.Errorf("unexpected canonical rule: got %s want %s", r.String(), canon)
+			}
+		})
+	}
+
+	for _, rule := range []string{"", "FREQ=YEARLY", "FREQ=DAILY;BYDAY=MO", "FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=MONTHLY;BYMONTHDAY=32", "FREQ=WEEKLY;FROM=COMPLETION", "FREQ=DAILY;INTERVAL=0", "INTERVAL=2"} {
+		if _, err := todolist.ParseRecurrence(rule); err == nil {
+			t.Errorf("expected error for rule \"%s\"", rule)
+		}
+	}
+}
+
+func TestRecurringTask(t *testing.T) {
+	mngr := testManager(t)
+	done := true
+
+	due := time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC)
+	start := time.Date(2024, 5, 8, 0, 0, 0, 0, time.UTC)
+	task := todolist.TodoItem{Text: "water plants", OwnerId: "u1", DueDate: &due, StartDate: &start,
+		Recurrence: "freq=weekly;byday=fr", Tags: []todolist.Tag{{Name: "home"}}}
+	id, err := mngr.Create(&task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.Recurrence != "FREQ=WEEKLY;BYDAY=FR" {
+		t.Errorf("expected canonical rule, got %s", task.Recurrence)
+	}
+
+	err = mngr.Update(id, "u1", todolist.TaskUpdate{Done: &done})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// completing a task twice does not create a second occurrence
+	err = mngr.Update(id, "u1", todolist.TaskUpdate{Done: &done})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tasks, err := mngr.List("u1", 10, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 2 {
+		t.Fatalf("expected 2 occurrences, got %d", len(tasks))
+	}
+	first, next := tasks[0], tasks[1]
+	if next.ID == id {
+		first, next = next, first
+	}
+
+	type occurrence struct {
+		Text, Due, Start, Series, Rule string
+		Done                           bool
+		Tags                           []string
+	}
+	format := func(t todolist.TodoItem) occurrence {
+		return occurrence{Text: t.Text, Done: t.Done, Series: t.SeriesId, Rule: t.Recurrence, Tags: t.TagNames(),
+			Due: todolist.FormatDate(t.DueDate, t.DueHasTime, nil), Start: todolist.FormatDate(t.StartDate, t.StartHasTime, nil)}
+	}
+	want := occurrence{Text: "water plants", Due: "2024-05-17", Start: "2024-05-15", Series: id,
+		Rule: "FREQ=WEEKLY;BYDAY=FR", Tags: []string{"home"}}
+	if diff := cmp.Diff(format(next), want); diff != "" {
+		t.Errorf("unexpected next occurrence (-got +want)\n%s", diff)
+	}
+	if first.SeriesId != id || !first.Done {
+		t.Errorf("expected the completed task to be done and linked to the series")
+	}
+
+	t.Run("after completion", func(t *testing.T) {
+		rule := "FREQ=DAILY;INTERVAL=2;FROM=COMPLETION"
+		err = mngr.Update(next.ID, "u1", todolist.TaskUpdate{Recurrence: &rule, Done: &done})
+		if err != nil {
+			t.Fatal(err)
+		}
+		tasks, err := mngr.List("u1", 10, 1, func(db *gorm.DB) *gorm.DB { return db.Where("done = ?", false) })
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tasks) != 1 {
+			t.Fatalf("expected 1 pending occurrence, got %d", len(tasks))
+		}
+		expect := time.Now().UTC().AddDate(0, 0, 2).Format("2006-01-02")
+		if got := todolist.FormatDate(tasks[0].DueDate, false, nil); got != expect || tasks[0].SeriesId != id {
+			t.Errorf("unexpected occurrence: due %s, series %s", got, tasks[0].SeriesId)
+		}
+	})
+}
@@ -0,0 +1,416 @@
+package todolist
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
+
+// FilterSchemaVersion is the version of FilterDefinition, it has to be increased and a migration added to
+// filterMigrations every time a field changes its meaning or is removed
+const FilterSchemaVersion = 1
+
+// FilterDefinition is the structured query of a saved filter, all the set fields have to match
+type FilterDefinition struct {
+	// Version of the schema the definition was written for, 0 is the current version
+	Version int `json:"version"`
+
+	// Text matches tasks containing the text
+	Text string `json:"text,omitempty"`
+	Done *bool  `json:"done,omitempty"`
+	// Tags matches tasks with any of the tags, or with all of them if TagMatch is FilterTagsAll
+	Tags     []string `json:"tags,omitempty"`
+	TagMatch string   `json:"tagMatch,omitempty"`
+	ListId   string   `json:"listId,omitempty"`
+	// Status is the name of the status of the tasks
+	Status      string    `json:"status,omitempty"`
+	MinPriority *Priority `json:"minPriority,omitempty"`
+	Important   *bool     `json:"important,omitempty"`
+	Urgent      *bool     `json:"urgent,omitempty"`
+	Blocked     *bool     `json:"blocked,omitempty"`
+	// Due is relative to the day the filter is evaluated, one of: overdue, today, week, none
+	Due string `json:"due,omitempty"`
+	// Expression is a filter expression as accepted by ParseFilter for the conditions not covered by the fields
+	Expression string `json:"expression,omitempty"`
+}
+
+// relative due dates of a FilterDefinition
+const (
+	FilterDueOverdue = "overdue"
+	FilterDueToday   = "today"
+	// FilterDueWeek matches the tasks due today and in the next 6 days
+	FilterDueWeek = "week"
+	FilterDueNone = "none"
+)
+
+// tag matching of a FilterDefinition, empty matches any of the tags
+const (
+	FilterTagsAny = "any"
+	FilterTagsAll = "all"
+)
+
+// ErrInvalidFilter is returned when a filter definition is not valid, stored definitions can become invalid
+// when the schema evolves or the lists they refer to are deleted
+var ErrInvalidFilter = errors.New("invalid filter definition")
+
+// ErrEmptyFilterName is returned when saving a filter without name
+var ErrEmptyFilterName = errors.New("filter name cannot be empty")
+
+// filterMigrations upgrade stored definitions from the version of the key to the next one, definitions are
+// migrated in memory every time they are read
+var filterMigrations = map[int]func(def map[string]any){}
+
+// ParseFilterDefinition reads a json filter definition, migrates it to the current schema version and
+// validates it. Unknown fields are rejected so that definitions relying on removed fields are reported.
+func ParseFilterDefinition(data []byte) (FilterDefinition, error) {
+	raw := map[string]any{}
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return FilterDefinition{}, fmt.Errorf("%w: %s", ErrInvalidFilter, err.Error())
+	}
+	version := FilterSchemaVersion
+	if v, ok := raw["version"].(float64); ok && v != 0 {
+		version = int(v)
+	}
+	if version > FilterSchemaVersion || version < 1 {
+		return FilterDefinition{}, fmt.Errorf("%w: unsupported version %d", ErrInvalidFilter, version)
+	}
+	for ; version < FilterSchemaVersion; version++ {
+		migrate, ok := filterMigrations[version]
+		if !ok {
+			return FilterDefinition{}, fmt.Errorf("%w: no migration from version %d", ErrInvalidFilter, version)
+		}
+		migrate(raw)
+	}
+	raw["version"] = FilterSchemaVersion
+
+	data, err = json.Marshal(raw)
+	if err != nil {
+		return FilterDefinition{}, err
+	}
+	def := FilterDefinition{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	err = dec.Decode(&def)
+	if err != nil {
+		return FilterDefinition{}, fmt.Errorf("%w: %s", ErrInvalidFilter, err.Error())
+	}
+	_, err = def.scopes(time.Now())
+	if err != nil {
+		return FilterDefinition{}, err
+	}
+	return def, nil
+}
+
+// scopes compiles the definition into the scopes that select its tasks, relative due dates use the day of now
+func (def FilterDefinition) scopes(now time.Time) ([]Scope, error) {
+	fail := func(format string, a ...any) ([]Scope, error) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidFilter, fmt.Sprintf(format, a...))
+	}
+	if def.Version != 0 && def.Version != FilterSchemaVersion {
+		return fail("unsupported version %d", def.Version)
+	}
+
+	conds := []cond{}
+	term := func(field, value string) error {
+		c, err := compileTerm(filterToken{kind: tokTerm, field: field, op: ":", value: value})
+		if err != nil {
+			return err
+		}
+		conds = append(conds, c)
+		return nil
+	}
+	scopes := []Scope{}
+
+	if def.Text != "" {
+		if err := term("text", def.Text); err != nil {
+			return fail("%s", err.Error())
+		}
+	}
+	if def.Done != nil {
+		scopes = append(scopes, WithDone(*def.Done))
+	}
+	switch def.TagMatch {
+	case "", FilterTagsAny, FilterTagsAll:
+	default:
+		return fail("tagMatch must be one of: %s, %s", FilterTagsAny, FilterTagsAll)
+	}
+	if len(def.Tags) > 0 {
+		scopes = append(scopes, WithTags(def.Tags, def.TagMatch == FilterTagsAll))
+	}
+	if def.ListId != "" {
+		if uuid.Validate(def.ListId) != nil {
+			return fail("listId is not a UUID")
+		}
+		scopes = append(scopes, InList(def.ListId))
+	}
+	if def.Status != "" {
+		if err := term("status", def.Status); err != nil {
+			return fail("%s", err.Error())
+		}
+	}
+	if def.MinPriority != nil {
+		if *def.MinPriority < PriorityNone || *def.MinPriority > PriorityUrgent {
+			return fail("unknown priority %s", def.MinPriority.String())
+		}
+		conds = append(conds, cond{sql: "todo_items.priority >= ?", args: []any{*def.MinPriority}})
+	}
+	if def.Important != nil {
+		conds = append(conds, cond{sql: "todo_items.important = ?", args: []any{*def.Important}})
+	}
+	if def.Urgent != nil {
+		conds = append(conds, cond{sql: "todo_items.urgent = ?", args: []any{*def.Urgent}})
+	}
+	if def.Blocked != nil {
+		conds = append(conds, blockedCond(*def.Blocked))
+	}
+
+	switch def.Due {
+	case "":
+	case FilterDueOverdue:
+		scopes = append(scopes, Overdue(now))
+	case FilterDueToday:
+		scopes = append(scopes, DueWithin(now, 0))
+	case FilterDueWeek:
+		scopes = append(scopes, DueWithin(now, 6))
+	case FilterDueNone:
+		conds = append(conds, cond{sql: "todo_items.due_date IS NULL"})
+	default:
+		return fail("due must be one of: %s, %s, %s, %s", FilterDueOverdue, FilterDueToday, FilterDueWeek, FilterDueNone)
+	}
+
+	if def.Expression != "" {
+		scope, err := ParseFilter(def.Expression)
+		if err != nil {
+			return fail("expression: %s", err.Error())
+		}
+		scopes = append(scopes, scope)
+	}
+
+	for _, c := range conds {
+		scopes = append(scopes, func(db *gorm.DB) *gorm.DB {
+			return db.Where(c.sql, c.args...)
+		})
+	}
+	return scopes, nil
+}
+
+// SavedFilter is a named filter of a user that is shown like a list, its tasks are evaluated on every read
+type SavedFilter struct {
+	ID      string `gorm:"primaryKey"`
+	OwnerId string `gorm:"index"`
+	// WorkspaceId is the workspace the filter is evaluated in, it is set by the manager, see Manager.InWorkspace
+	WorkspaceId string `gorm:"index;not null;default:''"`
+	Name        string
+	// Definition is the json of the FilterDefinition, it is validated again every time it is evaluated
+	Definition string
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (f *SavedFilter) BeforeCreate(db *gorm.DB) (err error) {
+	// UUID version 4
+	f.ID = uuid.NewString()
+	return
+}
+
+// Filter returns the definition of the saved filter migrated to the current schema version
+func (f SavedFilter) Filter() (FilterDefinition, error) {
+	return ParseFilterDefinition([]byte(f.Definition))
+}
+
+type SavedFilterNotFoundErr struct {
+	id    string
+	owner string
+}
+
+func (m *SavedFilterNotFoundErr) Error() string {
+	return fmt.Sprintf("saved filter with id: %s and owner %s not found", m.id, m.owner)
+}
+
+// SavedFilterUpdate holds the fields of a saved filter to change, nil fields are left untouched
+type SavedFilterUpdate struct {
+	Name       *string
+	Definition *FilterDefinition
+}
+
+// SavedFilterCount is a saved filter together with the amount of tasks it matches, Err is set instead of the
+// count when the stored definition is no longer valid
+type SavedFilterCount struct {
+	Filter SavedFilter
+	Count  int64
+	Err    error
+}
+
+// encodeFilter validates the definition for the owner and returns its json, the version is set to the current one
+func (m Manager) encodeFilter(owner string, def FilterDefinition) (string, error) {
+	def.Version = FilterSchemaVersion
+	_, err := def.scopes(time.Now())
+	if err != nil {
+		return "", err
+	}
+	err = m.checkFilterList(owner, def)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(def)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// checkFilterList makes sure the list of the definition, if any, is a list of the owner
+func (m Manager) checkFilterList(owner string, def FilterDefinition) error {
+	if def.ListId == "" {
+		return nil
+	}
+	var count int64
+	err := m.db.Model(&TodoList{}).Where("ID = ? AND owner_id = ?", def.ListId, owner).Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("%w: list %s not found", ErrInvalidFilter, def.ListId)
+	}
+	return nil
+}
+
+// CreateSavedFilter validates and stores a new saved filter of the owner
+func (m Manager) CreateSavedFilter(owner, name string, def FilterDefinition) (SavedFilter, error) {
+	f := SavedFilter{OwnerId: owner, Name: strings.TrimSpace(name)}
+	if f.Name == "" {
+		return SavedFilter{}, ErrEmptyFilterName
+	}
+	var err error
+	f.Definition, err = m.encodeFilter(owner, def)
+	if err != nil {
+		return SavedFilter{}, err
+	}
+	err = m.db.Create(&f).Error
+	if err != nil {
+		return SavedFilter{}, err
+	}
+	return f, nil
+}
+
+// GetSavedFilter returns a saved filter of the owner
+func (m Manager) GetSavedFilter(id, owner string) (SavedFilter, error) {
+	f := SavedFilter{}
+	result := m.db.Where("ID = ? AND owner_id = ?", id, owner).Limit(1).Find(&f)
+	if result.Error != nil {
+		return f, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return f, &SavedFilterNotFoundErr{id: id, owner: owner}
+	}
+	return f, nil
+}
+
+// SavedFilters returns the saved filters of the owner sorted by creation
+func (m Manager) SavedFilters(owner string) ([]SavedFilter, error) {
+	filters := []SavedFilter{}
+	err := m.db.Where("owner_id = ?", owner).Order("created_at").Order("id").Find(&filters).Error
+	if err != nil {
+		return nil, err
+	}
+	return filters, nil
+}
+
+// UpdateSavedFilter changes the name or the definition of a saved filter, a new definition replaces the
+// stored one completely
+func (m Manager) UpdateSavedFilter(id, owner string, upd SavedFilterUpdate) error {
+	f, err := m.GetSavedFilter(id, owner)
+	if err != nil {
+		return err
+	}
+	if upd.Name != nil {
+		f.Name = strings.TrimSpace(*upd.Name)
+		if f.Name == "" {
+			return ErrEmptyFilterName
+		}
+	}
+	if upd.Definition != nil {
+		f.Definition, err = m.encodeFilter(owner, *upd.Definition)
+		if err != nil {
+			return err
+		}
+	}
+	return m.db.Model(&SavedFilter{}).Where("ID = ? AND owner_id = ?", id, owner).
+		Updates(map[string]any{"name": f.Name, "definition": f.Definition}).Error
+}
+
+// DeleteSavedFilter deletes a saved filter, the tasks it matches are not affected
+func (m Manager) DeleteSavedFilter(id, owner string) error {
+	result := m.db.Where("ID = ? AND owner_id = ?", id, owner).Delete(&SavedFilter{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return &SavedFilterNotFoundErr{id: id, owner: owner}
+	}
+	return nil
+}
+
+// savedFilterScopes validates the stored definition again and compiles it, now is used for relative due dates
+func (m Manager) savedFilterScopes(f SavedFilter, now time.Time) ([]Scope, error) {
+	def, err := f.Filter()
+	if err != nil {
+		return nil, err
+	}
+	err = m.checkFilterList(f.OwnerId, def)
+	if err != nil {
+		return nil, err
+	}
+	return def.scopes(now)
+}
+
+// SavedFilterPage returns a page of the tasks matching the saved filter like ListPage, the extra scopes are
+// combined with the filter. Definitions that are no longer valid return ErrInvalidFilter.
+func (m Manager) SavedFilterPage(id, owner string, now time.Time, req PageRequest, scopes ...Scope) (TaskPage, error) {
+	f, err := m.GetSavedFilter(id, owner)
+	if err != nil {
+		return TaskPage{}, err
+	}
+	filterScopes, err := m.savedFilterScopes(f, now)
+	if err != nil {
+		return TaskPage{}, err
+	}
+	return m.ListPage(owner, req, append(filterScopes, scopes...)...)
+}
+
+// SavedFilterCounts returns the saved filters of the owner with the amount of tasks each one matches.
+// Filters that are no longer valid are returned with the validation error instead of failing the whole call.
+func (m Manager) SavedFilterCounts(owner string, now time.Time) ([]SavedFilterCount, error) {
+	filters, err := m.SavedFilters(owner)
+	if err != nil {
+		return nil, err
+	}
+	counts := make([]SavedFilterCount, len(filters))
+	for i, f := range filters {
+		counts[i].Filter = f
+		scopes, err := m.savedFilterScopes(f, now)
+		if errors.Is(err, ErrInvalidFilter) {
+			counts[i].Err = err
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		db := m.db.Model(&TodoItem{}).Where("owner_id = ?", owner)
+		for _, scope := range scopes {
+			db = db.Scopes(scope)
+		}
+		err = db.Count(&counts[i].Count).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+	return counts, nil
+}
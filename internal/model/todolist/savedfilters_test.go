@@ -0,0 +1,148 @@
+package todolist_test
+
+import (
+	"errors"
+	"github.com/go-bumbu/todo-app/internal/model/todolist"
+	"github.com/google/go-cmp/cmp"
+	"testing"
+	"time"
+)
+
+func TestParseFilterDefinition(t *testing.T) {
+	tcs := []struct {
+		name    string
+		in      string
+		wantErr bool
+	}{
+		{name: "empty", in: `{}`},
+		{name: "current version", in: `{"version":1,"done":false,"minPriority":"high","due":"week","tagMatch":"all"}`},
+		{name: "future version", in: `{"version":2}`, wantErr: true},
+		{name: "unknown field", in: `{"version":1,"dueBefore":"2024-01-01"}`, wantErr: true},
+		{name: "unknown priority", in: `{"minPriority":"critical"}`, wantErr: true},
+		{name: "unknown due", in: `{"due":"yesterday"}`, wantErr: true},
+		{name: "unknown tag match", in: `{"tagMatch":"some"}`, wantErr: true},
+		{name: "invalid expression", in: `{"expression":"(tag:work"}`, wantErr: true},
+		{name: "invalid list", in: `{"listId":"work"}`, wantErr: true},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			def, err := todolist.ParseFilterDefinition([]byte(tc.in))
+			if tc.wantErr {
+				if !errors.Is(err, todolist.ErrInvalidFilter) {
+					t.Errorf("expected invalid filter, got: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if def.Version != todolist.FilterSchemaVersion {
+				t.Errorf("expected the current version, got: %d", def.Version)
+			}
+		})
+	}
+}
+
+func TestSavedFilters(t *testing.T) {
+	mngr := testManager(t)
+	now := time.Now()
+	work := createList(t, mngr, "work", "u1")
+	tomorrow := now.AddDate(0, 0, 1)
+	tasks := []todolist.TodoItem{
+		{Text: "report", OwnerId: "u1", ListId: work, Priority: todolist.PriorityHigh, Tags: []todolist.Tag{{Name: "work"}},
+			DueDate: &tomorrow},
+		{Text: "slides", OwnerId: "u1", ListId: work, Priority: todolist.PriorityUrgent},
+		{Text: "groceries", OwnerId: "u1", Priority: todolist.PriorityLow, Tags: []todolist.Tag{{Name: "work"}}},
+		{Text: "other", OwnerId: "u2", Priority: todolist.PriorityHigh},
+	}
+	for i := range tasks {
+		if _, err := mngr.Create(&tasks[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	setDone(t, mngr, tasks[1].ID, "u1", true, "")
+
+	notDone := false
+	high := todolist.PriorityHigh
+	important, err := mngr.CreateSavedFilter("u1", "High priority not done", todolist.FilterDefinition{Done: &notDone, MinPriority: &high})
+	if err != nil {
+		t.Fatal(err)
+	}
+	week, err := mngr.CreateSavedFilter("u1", "Due this week and tagged work", todolist.FilterDefinition{Due: todolist.FilterDueWeek, Tags: []string{"work"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	inWork, err := mngr.CreateSavedFilter("u1", "Work", todolist.FilterDefinition{ListId: work})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("tasks of a filter", func(t *testing.T) {
+		page, err := mngr.SavedFilterPage(important.ID, "u1", now, todolist.PageRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(pageTexts(page), []string{"report"}); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+		_, err = mngr.SavedFilterPage(important.ID, "u2", now, todolist.PageRequest{})
+		fErr := &todolist.SavedFilterNotFoundErr{}
+		if !errors.As(err, &fErr) {
+			t.Errorf("expected saved filter not found, got: %v", err)
+		}
+	})
+
+	t.Run("invalid definitions are rejected", func(t *testing.T) {
+		_, err := mngr.CreateSavedFilter("u1", " ", todolist.FilterDefinition{})
+		if !errors.Is(err, todolist.ErrEmptyFilterName) {
+			t.Errorf("expected empty name, got: %v", err)
+		}
+		_, err = mngr.CreateSavedFilter("u2", "Work", todolist.FilterDefinition{ListId: work})
+		if !errors.Is(err, todolist.ErrInvalidFilter) {
+			t.Errorf("expected invalid filter, got: %v", err)
+		}
+		err = mngr.UpdateSavedFilter(week.ID, "u1", todolist.SavedFilterUpdate{Definition: &todolist.FilterDefinition{Due: "later"}})
+		if !errors.Is(err, todolist.ErrInvalidFilter) {
+			t.Errorf("expected invalid filter, got: %v", err)
+		}
+	})
+
+	t.Run("counts", func(t *testing.T) {
+		if err := mngr.DeleteList(work, "u1", false); err != nil {
+			t.Fatal(err)
+		}
+		counts, err := mngr.SavedFilterCounts("u1", now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := map[string]int64{}
+		for _, c := range counts {
+			got[c.Filter.Name] = c.Count
+			if c.Filter.ID == inWork.ID && !errors.Is(c.Err, todolist.ErrInvalidFilter) {
+				t.Errorf("expected the filter of a deleted list to be invalid, got: %v", c.Err)
+			}
+		}
+		// the tasks of the deleted list are moved to the inbox
+		want := map[string]int64{"High priority not done": 1, "Due this week and tagged work": 1, "Work": 0}
+		if diff := cmp.Diff(got, want); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+		_, err = mngr.SavedFilterPage(inWork.ID, "u1", now, todolist.PageRequest{})
+		if !errors.Is(err, todolist.ErrInvalidFilter) {
+			t.Errorf("expected invalid filter, got: %v", err)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		if err := mngr.DeleteSavedFilter(inWork.ID, "u1"); err != nil {
+			t.Fatal(err)
+		}
+		filters, err := mngr.SavedFilters("u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(filters) != 2 {
+			t.Errorf("unexpected filters: %+v", filters)
+		}
+	})
+}
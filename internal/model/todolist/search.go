@@ -0,0 +1,161 @@
+package todolist
+
+import (
+	"gorm.io/gorm"
+	"slices"
+	"strings"
+)
+
+// SnippetStart and SnippetEnd delimit the matched terms in SearchResult.Snippet, private use characters
+// are used so that the snippet can be escaped safely before replacing them with any markup.
+const (
+	SnippetStart = "\uE000"
+	SnippetEnd   = "\uE001"
+)
+
+const searchTable = "todo_items_fts"
+
+// searchSchema creates the FTS5 index for the task text and notes, the index is kept in sync with triggers
+// so every write to todo_items, including the ones that don't use the manager, is reflected.
+// An index created by a previous version is dropped and built again.
+var searchSchema = []string{
+	`DROP TRIGGER IF EXISTS todo_items_fts_insert`,
+	`DROP TRIGGER IF EXISTS todo_items_fts_update`,
+	`DROP TRIGGER IF EXISTS todo_items_fts_delete`,
+	`DROP TABLE IF EXISTS ` + searchTable,
+	`CREATE VIRTUAL TABLE ` + searchTable + ` USING fts5(id UNINDEXED, text, notes, tokenize = 'unicode61 remove_diacritics 2')`,
+	`CREATE TRIGGER todo_items_fts_insert AFTER INSERT ON todo_items BEGIN
+		INSERT INTO ` + searchTable + `(id, text, notes) VALUES (new.id, new.text, new.notes);
+	END`,
+	`CREATE TRIGGER todo_items_fts_update AFTER UPDATE OF text, notes ON todo_items BEGIN
+		UPDATE ` + searchTable + ` SET text = new.text, notes = new.notes WHERE id = old.id;
+	END`,
+	`CREATE TRIGGER todo_items_fts_delete AFTER DELETE ON todo_items BEGIN
+		DELETE FROM ` + searchTable + ` WHERE id = old.id;
+	END`,
+	`INSERT INTO ` + searchTable + `(id, text, notes) SELECT id, text, notes FROM todo_items`,
+}
+
+// searchColumns are the indexed columns, an index without all of them is outdated
+var searchColumns = []string{"text", "notes"}
+
+// setupSearch creates the full text index if it does not exist yet or is outdated, it returns false if
+// the DB does not support FTS5, in that case the search falls back to a plain LIKE query.
+func setupSearch(db *gorm.DB) (bool, error) {
+	if db.Dialector.Name() != "sqlite" {
+		return false, nil
+	}
+	var columns []string
+	err := db.Raw("SELECT name FROM pragma_table_info(?)", searchTable).Scan(&columns).Error
+	if err != nil {
+		return false, err
+	}
+	upToDate := len(columns) > 0
+	for _, c := range searchColumns {
+		upToDate = upToDate && slices.Contains(columns, c)
+	}
+	if upToDate {
+		return true, nil
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for _, stmt := range searchSchema {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "no such module: fts5") {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// SearchResult is a task matching a search query
+type SearchResult struct {
+	TodoItem
+	// Snippet is the fragment of text around the matches, matched terms are wrapped in SnippetStart and SnippetEnd
+	Snippet string
+}
+
+// Search returns the tasks of the user and of the lists shared with the user which text or notes match all the
+// terms of the query ordered by relevance, every term also matches words that start with it, e.g. "gro" matches
+// "groceries".
+func (m Manager) Search(user, query string, size, page int) ([]SearchResult, error) {
+	terms := strings.Fields(query)
+	results := []SearchResult{}
+	if len(terms) == 0 {
+		return results, nil
+	}
+	size = pageSize(size)
+	offset := size * (page - 1)
+	if offset <= 0 {
+		offset = 0
+	}
+
+	hits := []struct {
+		ID      string
+		Snippet string
+	}{}
+	shared := m.db.Model(&ListMember{}).Select("list_id").Where("user_id = ? AND accepted = ?", user, true)
+	db := m.db.Model(&TodoItem{}).Where("(todo_items.owner_id = ? OR todo_items.list_id IN (?))", user, shared)
+	if m.fts {
+		db = db.Select("todo_items.id, snippet("+searchTable+", -1, ?, ?, '…', 12) AS snippet", SnippetStart, SnippetEnd).
+			Joins("JOIN "+searchTable+" ON "+searchTable+".id = todo_items.id").
+			Where(searchTable+" MATCH ?", matchQuery(terms)).
+			Order(searchTable + ".rank")
+	} else {
+		db = db.Select("todo_items.id, todo_items.text AS snippet")
+		for _, term := range terms {
+			like := "%" + escapeLike(term) + "%"
+			db = db.Where("(todo_items.text LIKE ? ESCAPE '\\' OR todo_items.notes LIKE ? ESCAPE '\\')", like, like)
+		}
+		db = db.Order("todo_items.updated_at DESC")
+	}
+	err := db.Offset(offset).Limit(size).Scan(&hits).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(hits) == 0 {
+		return results, nil
+	}
+
+	ids := make([]string, len(hits))
+	for i, h := range hits {
+		ids[i] = h.ID
+	}
+	tasks := []TodoItem{}
+	err = m.db.Scopes(preloadTags).Where("id IN ?", ids).Find(&tasks).Error
+	if err != nil {
+		return nil, err
+	}
+	byId := make(map[string]TodoItem, len(tasks))
+	for _, t := range tasks {
+		byId[t.ID] = t
+	}
+	// keep the order of relevance
+	for _, h := range hits {
+		if t, ok := byId[h.ID]; ok {
+			results = append(results, SearchResult{TodoItem: t, Snippet: h.Snippet})
+		}
+	}
+	return results, nil
+}
+
+// matchQuery converts user input into an FTS5 query, every term is quoted to disable the query
+// syntax and marked as prefix, all the terms need to match
+func matchQuery(terms []string) string {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"*`
+	}
+	return strings.Join(quoted, " ")
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
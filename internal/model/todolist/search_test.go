@@ -0,0 +1,116 @@
+package todolist_test
+
+import (
+	glebarez "github.com/glebarez/sqlite"
+	"github.com/go-bumbu/todo-app/internal/model/todolist"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"path/filepath"
+	"testing"
+)
+
+// ftsManager returns a manager backed by the pure go sqlite driver that ships with FTS5,
+// the cgo driver used by testManager needs the sqlite_fts5 build tag for it.
+func ftsManager(t *testing.T) *todolist.Manager {
+	t.Helper()
+	db, err := gorm.Open(glebarez.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mngr, err := todolist.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mngr
+}
+
+func TestSearch(t *testing.T) {
+	managers := map[string]func(t *testing.T) *todolist.Manager{
+		"fts5":     ftsManager,
+		"fallback": testManager,
+	}
+	for name, newManager := range managers {
+		t.Run(name, func(t *testing.T) {
+			mngr := newManager(t)
+			_ = createTask(t, mngr, "buy groceries for the week", "u1")
+			renamed := createTask(t, mngr, "call the bank", "u1")
+			deleted := createTask(t, mngr, "buy a new bike", "u1")
+			_ = createTask(t, mngr, "buy groceries", "u2")
+			_ = createTask(t, mngr, "100% done_ish", "u1")
+
+			// tasks of lists shared with the user are found once the invitation is accepted
+			home := createList(t, mngr, "home", "u2")
+			market := todolist.TodoItem{Text: "groceries at the market", OwnerId: "u2", ListId: home}
+			if _, err := mngr.Create(&market); err != nil {
+				t.Fatal(err)
+			}
+			garden := createList(t, mngr, "garden", "u2")
+			seeds := todolist.TodoItem{Text: "groceries for the garden", OwnerId: "u2", ListId: garden}
+			if _, err := mngr.Create(&seeds); err != nil {
+				t.Fatal(err)
+			}
+			for _, l := range []string{home, garden} {
+				if err := mngr.Share(l, "u2", "u1", todolist.RoleViewer); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := mngr.AcceptInvitation(home, "u1"); err != nil {
+				t.Fatal(err)
+			}
+
+			text := "call the grocer"
+			err := mngr.Update(renamed, "u1", todolist.TaskUpdate{Text: &text})
+			if err != nil {
+				t.Fatal(err)
+			}
+			deleteTask(t, mngr, deleted, "u1", "")
+
+			tcs := []struct {
+				query  string
+				expect []string
+			}{
+				{query: "groceries", expect: []string{"buy groceries for the week", "groceries at the market"}},
+				{query: "gro", expect: []string{"buy groceries for the week", "call the grocer", "groceries at the market"}},
+				{query: "buy week", expect: []string{"buy groceries for the week"}},
+				{query: "bank", expect: []string{}},
+				{query: "bike", expect: []string{}},
+				{query: `"unbalanced OR`, expect: []string{}},
+			}
+			for _, tc := range tcs {
+				results, err := mngr.Search("u1", tc.query, 10, 1)
+				if err != nil {
+					t.Fatalf("%s: %v", tc.query, err)
+				}
+				got := []string{}
+				for _, r := range results {
+					got = append(got, r.Text)
+				}
+				if diff := cmp.Diff(got, tc.expect, cmpopts.SortSlices(func(a, b string) bool { return a < b })); diff != "" {
+					t.Errorf("%s: unexpected value (-got +want)\n%s", tc.query, diff)
+				}
+			}
+		})
+	}
+
+	t.Run("ranking and snippet", func(t *testing.T) {
+		mngr := ftsManager(t)
+		_ = createTask(t, mngr, "plan the trip, book flights and check the trip budget", "u1")
+		_ = createTask(t, mngr, "trip", "u1")
+		results, err := mngr.Search("u1", "trip", 10, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 2 || results[0].Text != "trip" {
+			t.Fatalf("expected the shortest match first, got %v", results)
+		}
+		want := "plan the " + todolist.SnippetStart + "trip" + todolist.SnippetEnd + ", book flights and check the " +
+			todolist.SnippetStart + "trip" + todolist.SnippetEnd + " budget"
+		if results[1].Snippet != want {
+			t.Errorf("unexpected snippet: %q", results[1].Snippet)
+		}
+	})
+}
@@ -0,0 +1,155 @@
+package todolist
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ShareLink gives read only access to a list to anyone that knows the token, without an account.
+// Links can be revoked at any time and optionally expire.
+type ShareLink struct {
+	Token     string `gorm:"primaryKey"`
+	ListId    string `gorm:"index"`
+	CreatedBy string
+	// ExpiresAt is nil for links that do not expire
+	ExpiresAt *time.Time
+
+	CreatedAt time.Time
+}
+
+// expired reports whether the link can no longer be used at the given time
+func (l ShareLink) expired(now time.Time) bool {
+	return l.ExpiresAt != nil && !now.Before(*l.ExpiresAt)
+}
+
+// ShareLinkNotFoundErr does not include the token, so that it does not end up in logs
+type ShareLinkNotFoundErr struct{}
+
+func (m *ShareLinkNotFoundErr) Error() string {
+	return "share link not found"
+}
+
+// ErrLinkExpired is returned when creating a share link with an expiry date in the past
+var ErrLinkExpired = errors.New("the expiry date of the share link must be in the future")
+
+// shareTokenBytes is the amount of random bytes of a share token, 256 bits are not guessable
+const shareTokenBytes = 32
+
+func newShareToken() (string, error) {
+	b := make([]byte, shareTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("unable to generate share token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// CreateShareLink creates a read only public link for the list, only members with the owner role can create them.
+// A nil expiresAt creates a link that is valid until it is revoked.
+func (m Manager) CreateShareLink(listId, user string, expiresAt *time.Time) (ShareLink, error) {
+	l, r, err := listRole(m.db, listId, user)
+	if err != nil {
+		return ShareLink{}, err
+	}
+	if !r.allows(RoleOwner) {
+		return ShareLink{}, ErrPermissionDenied
+	}
+	if l.IsInbox {
+		return ShareLink{}, ErrShareInbox
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return ShareLink{}, ErrLinkExpired
+	}
+
+	token, err := newShareToken()
+	if err != nil {
+		return ShareLink{}, err
+	}
+	link := ShareLink{Token: token, ListId: listId, CreatedBy: user, ExpiresAt: expiresAt}
+	err = m.db.Create(&link).Error
+	if err != nil {
+		return ShareLink{}, err
+	}
+	return link, nil
+}
+
+// ShareLinks returns the public links of the list including the expired ones, only members with the owner role
+// can see them
+func (m Manager) ShareLinks(listId, user string) ([]ShareLink, error) {
+	_, r, err := listRole(m.db, listId, user)
+	if err != nil {
+		return nil, err
+	}
+	if !r.allows(RoleOwner) {
+		return nil, ErrPermissionDenied
+	}
+	links := []ShareLink{}
+	err = m.db.Where("list_id = ?", listId).Order("created_at").Find(&links).Error
+	if err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// RevokeShareLink deletes a public link of the list, the link stops working immediately
+func (m Manager) RevokeShareLink(listId, user, token string) error {
+	_, r, err := listRole(m.db, listId, user)
+	if err != nil {
+		return err
+	}
+	if !r.allows(RoleOwner) {
+		return ErrPermissionDenied
+	}
+	result := m.db.Where("list_id = ? AND token = ?", listId, token).Delete(&ShareLink{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return &ShareLinkNotFoundErr{}
+	}
+	return nil
+}
+
+// SharedLinkList returns the list of a public link. Unknown, revoked and expired links, as well as links of
+// deleted lists, are reported as not found. A link only works as long as its creator has the owner role on the
+// list and is a member of its workspace, it stops working when the role is changed or the access removed. The tasks of the list belong to the list owner in the workspace of
+// the list, see InList and InWorkspace.
+func (m Manager) SharedLinkList(token string) (TodoList, error) {
+	link := ShareLink{}
+	result := m.db.Where("token = ?", token).Limit(1).Find(&link)
+	if result.Error != nil {
+		return TodoList{}, result.Error
+	}
+	if result.RowsAffected == 0 || link.expired(time.Now()) {
+		return TodoList{}, &ShareLinkNotFoundErr{}
+	}
+	// the token is the only credential, the list is looked up in all the workspaces
+	l := TodoList{}
+	result = allWorkspaces(m.db).Where("ID = ?", link.ListId).Limit(1).Find(&l)
+	if result.Error != nil {
+		return TodoList{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return TodoList{}, &ShareLinkNotFoundErr{}
+	}
+
+	ws := withWorkspace(m.db, l.WorkspaceId)
+	_, role, err := listRole(ws, l.ID, link.CreatedBy)
+	lErr := &ListNotFoundErr{}
+	if errors.As(err, &lErr) {
+		return TodoList{}, &ShareLinkNotFoundErr{}
+	}
+	if err != nil {
+		return TodoList{}, err
+	}
+	err = sameWorkspace(ws, link.CreatedBy)
+	if !role.allows(RoleOwner) || errors.Is(err, ErrOtherWorkspace) {
+		return TodoList{}, &ShareLinkNotFoundErr{}
+	}
+	if err != nil {
+		return TodoList{}, err
+	}
+	return l, nil
+}
@@ -0,0 +1,108 @@
+package todolist_test
+
+import (
+	"errors"
+	"github.com/go-bumbu/todo-app/internal/model/todolist"
+	"testing"
+	"time"
+)
+
+func TestShareLinks(t *testing.T) {
+	mngr := testManager(t)
+	work := createList(t, mngr, "work", "u1")
+	if err := mngr.Share(work, "u1", "u2", todolist.RoleEditor); err != nil {
+		t.Fatal(err)
+	}
+	if err := mngr.AcceptInvitation(work, "u2"); err != nil {
+		t.Fatal(err)
+	}
+
+	link, err := mngr.CreateShareLink(work, "u1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := mngr.CreateShareLink(work, "u1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(link.Token) < 40 || link.Token == other.Token {
+		t.Errorf("expected long unique tokens, got: %s and %s", link.Token, other.Token)
+	}
+
+	t.Run("the token reads the list", func(t *testing.T) {
+		l, err := mngr.SharedLinkList(link.Token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if l.ID != work || l.OwnerId != "u1" {
+			t.Errorf("unexpected list: %+v", l)
+		}
+	})
+
+	t.Run("only owners manage links", func(t *testing.T) {
+		_, err := mngr.CreateShareLink(work, "u2", nil)
+		if !errors.Is(err, todolist.ErrPermissionDenied) {
+			t.Errorf("expected permission denied, got: %v", err)
+		}
+		_, err = mngr.ShareLinks(work, "u2")
+		if !errors.Is(err, todolist.ErrPermissionDenied) {
+			t.Errorf("expected permission denied, got: %v", err)
+		}
+		inbox, err := mngr.Inbox("u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = mngr.CreateShareLink(inbox.ID, "u1", nil)
+		if !errors.Is(err, todolist.ErrShareInbox) {
+			t.Errorf("expected share inbox, got: %v", err)
+		}
+	})
+
+	t.Run("expired links are not found", func(t *testing.T) {
+		past := time.Now().Add(-time.Minute)
+		_, err := mngr.CreateShareLink(work, "u1", &past)
+		if !errors.Is(err, todolist.ErrLinkExpired) {
+			t.Errorf("expected link expired, got: %v", err)
+		}
+		soon := time.Now().Add(50 * time.Millisecond)
+		expiring, err := mngr.CreateShareLink(work, "u1", &soon)
+		if err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(100 * time.Millisecond)
+		_, err = mngr.SharedLinkList(expiring.Token)
+		sErr := &todolist.ShareLinkNotFoundErr{}
+		if !errors.As(err, &sErr) {
+			t.Errorf("expected share link not found, got: %v", err)
+		}
+	})
+
+	t.Run("revoked links are not found", func(t *testing.T) {
+		if err := mngr.RevokeShareLink(work, "u1", link.Token); err != nil {
+			t.Fatal(err)
+		}
+		_, err := mngr.SharedLinkList(link.Token)
+		sErr := &todolist.ShareLinkNotFoundErr{}
+		if !errors.As(err, &sErr) {
+			t.Errorf("expected share link not found, got: %v", err)
+		}
+		links, err := mngr.ShareLinks(work, "u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(links) != 2 || links[0].Token != other.Token {
+			t.Errorf("unexpected links: %+v", links)
+		}
+	})
+
+	t.Run("links of deleted lists are not found", func(t *testing.T) {
+		if err := mngr.DeleteList(work, "u1", false); err != nil {
+			t.Fatal(err)
+		}
+		_, err := mngr.SharedLinkList(other.Token)
+		sErr := &todolist.ShareLinkNotFoundErr{}
+		if !errors.As(err, &sErr) {
+			t.Errorf("expected share link not found, got: %v", err)
+		}
+	})
+}
@@ -0,0 +1,400 @@
+package todolist
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"slices"
+	"time"
+)
+
+// Role is the access a user has on a list, every role includes the access of the previous ones
+type Role string
+
+const (
+	// RoleViewer can read the list and its tasks
+	RoleViewer Role = "viewer"
+	// RoleEditor can also create, change and delete the tasks of the list
+	RoleEditor Role = "editor"
+	// RoleOwner can also change the list and manage its members, only the user that created the list can delete it
+	RoleOwner Role = "owner"
+)
+
+var roleRanks = map[Role]int{RoleViewer: 1, RoleEditor: 2, RoleOwner: 3}
+
+// ParseRole validates the name of a role
+func ParseRole(s string) (Role, error) {
+	r := Role(s)
+	if _, ok := roleRanks[r]; !ok {
+		return "", fmt.Errorf("%w: %s", ErrInvalidRole, s)
+	}
+	return r, nil
+}
+
+// allows reports whether the role includes the access of need
+func (r Role) allows(need Role) bool {
+	return roleRanks[r] >= roleRanks[need]
+}
+
+// ErrInvalidRole is returned when a role is not one of viewer, editor or owner
+var ErrInvalidRole = errors.New("role must be one of: viewer, editor, owner")
+
+// ErrPermissionDenied is returned when the role of a user on a shared list does not allow the change
+var ErrPermissionDenied = errors.New("the role on the list does not allow this action")
+
+// ErrShareInbox is returned when sharing the inbox, that is always personal
+var ErrShareInbox = errors.New("the inbox list cannot be shared")
+
+// ErrShareWithOwner is returned when the list is shared with the user that created it
+var ErrShareWithOwner = errors.New("the list cannot be shared with its owner")
+
+// ErrInvalidAssignee is returned when a task is assigned to a user that is not a collaborator of its list
+var ErrInvalidAssignee = errors.New("the assignee is not a collaborator of the list")
+
+// ListMember gives a user access to the list of another user, the access is granted once the invitation is accepted
+type ListMember struct {
+	ListId    string `gorm:"primaryKey"`
+	UserId    string `gorm:"primaryKey;index"`
+	Role      Role
+	InvitedBy string
+	Accepted  bool
+
+	// WorkspaceId is the workspace of the list, it is set by the manager, see Manager.InWorkspace
+	WorkspaceId string `gorm:"index;not null;default:''"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type MemberNotFoundErr struct {
+	listId string
+	user   string
+}
+
+func (m *MemberNotFoundErr) Error() string {
+	return fmt.Sprintf("user %s is not a member of the list with id: %s", m.user, m.listId)
+}
+
+// SharedList is a list of another user together with the role of the member
+type SharedList struct {
+	List     TodoList
+	Role     Role
+	Accepted bool
+}
+
+// listRole returns the list and the role of the user on it, the user that created the list is always the owner.
+// Lists that are not shared with the user are reported as not found.
+func listRole(db *gorm.DB, listId, user string) (TodoList, Role, error) {
+	l := TodoList{}
+	result := db.Where("ID = ?", listId).Limit(1).Find(&l)
+	if result.Error != nil {
+		return l, "", result.Error
+	}
+	if result.RowsAffected == 0 {
+		return l, "", &ListNotFoundErr{id: listId, owner: user}
+	}
+	if l.OwnerId == user {
+		return l, RoleOwner, nil
+	}
+	role, err := memberRole(db, listId, user)
+	if err != nil {
+		return l, "", err
+	}
+	if role == "" {
+		return TodoList{}, "", &ListNotFoundErr{id: listId, owner: user}
+	}
+	return l, role, nil
+}
+
+// memberRole returns the role of an accepted member of the list, an empty role if the user is not a member
+func memberRole(db *gorm.DB, listId, user string) (Role, error) {
+	member := ListMember{}
+	result := db.Where("list_id = ? AND user_id = ? AND accepted = ?", listId, user, true).Limit(1).Find(&member)
+	if result.Error != nil {
+		return "", result.Error
+	}
+	return member.Role, nil
+}
+
+// authorize checks that the user has at least the role on the task and returns the owner of the task.
+// The tasks of the user are always accessible, the tasks of others only through the lists shared with the user,
+// the access is checked on every call so that revoking it takes effect immediately.
+func authorize(db *gorm.DB, id, user string, need Role) (string, error) {
+	t := TodoItem{}
+	result := db.Select("owner_id", "list_id").Where("ID = ?", id).Limit(1).Find(&t)
+	if result.Error != nil {
+		return "", result.Error
+	}
+	if result.RowsAffected == 0 {
+		return "", &ItemNotFountErr{id: id, owner: user}
+	}
+	if t.OwnerId == user {
+		return user, nil
+	}
+	role, err := memberRole(db, t.ListId, user)
+	if err != nil {
+		return "", err
+	}
+	if role == "" {
+		return "", &ItemNotFountErr{id: id, owner: user}
+	}
+	if !role.allows(need) {
+		return "", ErrPermissionDenied
+	}
+	return t.OwnerId, nil
+}
+
+// authorizeUpdate checks that the user can apply the update to the task and returns the owner of the task.
+// Editors can apply any update, the assignee of the task can also complete and reopen it with any role.
+func authorizeUpdate(db *gorm.DB, id, user string, upd TaskUpdate) (string, error) {
+	owner, err := authorize(db, id, user, RoleEditor)
+	if !errors.Is(err, ErrPermissionDenied) || !completesOnly(upd) {
+		return owner, err
+	}
+	owner, err = authorize(db, id, user, RoleViewer)
+	if err != nil {
+		return "", err
+	}
+	t := TodoItem{}
+	err = db.Select("assignee_id").Where("ID = ?", id).Limit(1).Find(&t).Error
+	if err != nil {
+		return "", err
+	}
+	if t.AssigneeId != user {
+		return "", ErrPermissionDenied
+	}
+	return owner, nil
+}
+
+// authorizeTargets checks that the user can edit the list, parent and blockers the update moves or links the
+// task to, so that the editors of a shared list cannot move its tasks into the other lists of the owner or link
+// them to tasks they cannot see. The inbox is personal, only the owner can move tasks into it. Targets the task
+// already has are not checked again.
+func authorizeTargets(db *gorm.DB, id, user, owner string, upd TaskUpdate) error {
+	t := TodoItem{}
+	err := db.Select("list_id", "parent_id").Where("ID = ?", id).Limit(1).Find(&t).Error
+	if err != nil {
+		return err
+	}
+	if upd.ListId != nil && *upd.ListId == "" && user != owner {
+		return ErrPermissionDenied
+	}
+	if upd.ListId != nil && *upd.ListId != "" && *upd.ListId != t.ListId {
+		_, role, err := listRole(db, *upd.ListId, user)
+		if err != nil {
+			return err
+		}
+		if !role.allows(RoleEditor) {
+			return ErrPermissionDenied
+		}
+	}
+	if upd.ParentId != nil && *upd.ParentId != "" && *upd.ParentId != t.ParentId {
+		_, err := authorize(db, *upd.ParentId, user, RoleEditor)
+		if err != nil {
+			return err
+		}
+	}
+	if upd.BlockedBy != nil {
+		current := []string{}
+		err := db.Model(&Dependency{}).Where("task_id = ?", id).Pluck("blocker_id", &current).Error
+		if err != nil {
+			return err
+		}
+		return authorizeBlockers(db, user, *upd.BlockedBy, current)
+	}
+	return nil
+}
+
+// authorizeBlockers checks that the user can edit the blockers that are not in known, blockers the user cannot
+// see are reported as unknown
+func authorizeBlockers(db *gorm.DB, user string, ids, known []string) error {
+	for _, id := range ids {
+		if slices.Contains(known, id) {
+			continue
+		}
+		_, err := authorize(db, id, user, RoleEditor)
+		tErr := &ItemNotFountErr{}
+		if errors.As(err, &tErr) {
+			return fmt.Errorf("%w: %s", ErrUnknownBlocker, id)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// completesOnly reports whether the update does nothing else than changing the status of the task
+func completesOnly(upd TaskUpdate) bool {
+	upd.Done, upd.StatusId, upd.IgnoreBlockers, upd.IfVersion = nil, nil, false, nil
+	return upd == TaskUpdate{}
+}
+
+// checkAssignee returns ErrInvalidAssignee unless the user is the owner of the tasks or an accepted member of the list
+func checkAssignee(db *gorm.DB, listId, owner, assignee string) error {
+	if assignee == owner {
+		return nil
+	}
+	role, err := memberRole(db, listId, assignee)
+	if err != nil {
+		return err
+	}
+	if role == "" {
+		return ErrInvalidAssignee
+	}
+	return nil
+}
+
+// AssignedTo matches the tasks assigned to the user
+func AssignedTo(user string) Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("assignee_id = ?", user)
+	}
+}
+
+// ListAccess returns a list the user owns or that is shared with the user, together with the role of the user
+func (m Manager) ListAccess(id, user string) (TodoList, Role, error) {
+	return listRole(m.db, id, user)
+}
+
+// Share invites a user to the list with the given role, or changes the role of an existing member.
+// Only members with the owner role can share a list.
+func (m Manager) Share(listId, user, invitee string, role Role) error {
+	return m.share(listId, user, invitee, role, true)
+}
+
+// SetRole changes the role of an existing member of the list or of a pending invitation
+func (m Manager) SetRole(listId, user, member string, role Role) error {
+	return m.share(listId, user, member, role, false)
+}
+
+func (m Manager) share(listId, user, invitee string, role Role, invite bool) error {
+	if _, err := ParseRole(string(role)); err != nil {
+		return err
+	}
+	l, r, err := listRole(m.db, listId, user)
+	if err != nil {
+		return err
+	}
+	if !r.allows(RoleOwner) {
+		return ErrPermissionDenied
+	}
+	if l.IsInbox {
+		return ErrShareInbox
+	}
+	if invitee == l.OwnerId {
+		return ErrShareWithOwner
+	}
+	if err := sameWorkspace(m.db, invitee); err != nil {
+		return err
+	}
+
+	member := ListMember{}
+	result := m.db.Where("list_id = ? AND user_id = ?", listId, invitee).Limit(1).Find(&member)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		return m.db.Model(&member).Update("role", role).Error
+	}
+	if !invite {
+		return &MemberNotFoundErr{listId: listId, user: invitee}
+	}
+	return m.db.Create(&ListMember{ListId: listId, UserId: invitee, Role: role, InvitedBy: user}).Error
+}
+
+// Members returns the members of the list including pending invitations, every member can see them
+func (m Manager) Members(listId, user string) ([]ListMember, error) {
+	_, _, err := listRole(m.db, listId, user)
+	if err != nil {
+		return nil, err
+	}
+	members := []ListMember{}
+	err = m.db.Where("list_id = ?", listId).Order("created_at").Order("user_id").Find(&members).Error
+	if err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// Revoke removes the member from the list and unassigns the tasks of the list assigned to the member.
+// Members with the owner role can remove anyone and every member can leave the list or decline an invitation.
+func (m Manager) Revoke(listId, user, member string) error {
+	if member != user {
+		_, r, err := listRole(m.db, listId, user)
+		if err != nil {
+			return err
+		}
+		if !r.allows(RoleOwner) {
+			return ErrPermissionDenied
+		}
+	}
+
+	return m.revoke(listId, member)
+}
+
+// revoke removes the member from the list and unassigns the tasks assigned to the member
+func (m Manager) revoke(listId, member string) error {
+	// the tasks assigned to the member are unassigned, they are read before the transaction that only writes
+	assigned := []TodoItem{}
+	err := m.db.Select("id", "owner_id").Where("list_id = ? AND assignee_id = ?", listId, member).Find(&assigned).Error
+	if err != nil {
+		return err
+	}
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("list_id = ? AND user_id = ?", listId, member).Delete(&ListMember{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return &MemberNotFoundErr{listId: listId, user: member}
+		}
+		for _, t := range assigned {
+			err := bumpVersion(tx, t.ID, t.OwnerId, nil)
+			if err != nil {
+				return err
+			}
+			err = m.track(tx, ActionUpdate, []string{t.ID}, func() ([]string, error) {
+				return nil, tx.Model(&TodoItem{}).Where("ID = ?", t.ID).Update("assignee_id", "").Error
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// AcceptInvitation grants the user access to the list it was invited to
+func (m Manager) AcceptInvitation(listId, user string) error {
+	result := m.db.Model(&ListMember{}).Where("list_id = ? AND user_id = ?", listId, user).Update("accepted", true)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return &MemberNotFoundErr{listId: listId, user: user}
+	}
+	return nil
+}
+
+// SharedWithMe returns the lists of other users the user is a member of, including pending invitations
+func (m Manager) SharedWithMe(user string) ([]SharedList, error) {
+	members := []ListMember{}
+	err := m.db.Where("user_id = ?", user).Order("created_at").Find(&members).Error
+	if err != nil {
+		return nil, err
+	}
+	shared := []SharedList{}
+	for _, member := range members {
+		l := TodoList{}
+		result := m.db.Where("ID = ?", member.ListId).Limit(1).Find(&l)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+		shared = append(shared, SharedList{List: l, Role: member.Role, Accepted: member.Accepted})
+	}
+	return shared, nil
+}
@@ -0,0 +1,372 @@
+package todolist_test
+
+import (
+	"errors"
+	"github.com/go-bumbu/todo-app/internal/model/todolist"
+	"github.com/google/go-cmp/cmp"
+	"testing"
+)
+
+func TestShareList(t *testing.T) {
+	mngr := testManager(t)
+	work := createList(t, mngr, "work", "u1")
+	task := todolist.TodoItem{Text: "report", OwnerId: "u1", ListId: work}
+	if _, err := mngr.Create(&task); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mngr.Share(work, "u1", "u2", todolist.RoleViewer); err != nil {
+		t.Fatal(err)
+	}
+	// pending invitations do not grant access
+	readTask(t, mngr, task.ID, "u2", "", "task with id: "+task.ID+" and owner u2 not found")
+	if err := mngr.AcceptInvitation(work, "u2"); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("viewer can read but not change", func(t *testing.T) {
+		readTask(t, mngr, task.ID, "u2", "report", "")
+		setText(t, mngr, task.ID, "u2", "changed", todolist.ErrPermissionDenied.Error())
+		deleteTask(t, mngr, task.ID, "u2", todolist.ErrPermissionDenied.Error())
+		err := mngr.Share(work, "u2", "u3", todolist.RoleViewer)
+		if !errors.Is(err, todolist.ErrPermissionDenied) {
+			t.Errorf("expected permission denied, got: %v", err)
+		}
+	})
+
+	t.Run("editor can change and create tasks", func(t *testing.T) {
+		if err := mngr.SetRole(work, "u1", "u2", todolist.RoleEditor); err != nil {
+			t.Fatal(err)
+		}
+		setText(t, mngr, task.ID, "u2", "report v2", "")
+		created := todolist.TodoItem{Text: "slides", OwnerId: "u2", ListId: work}
+		if _, err := mngr.Create(&created); err != nil {
+			t.Fatal(err)
+		}
+		if created.OwnerId != "u1" {
+			t.Errorf("expected tasks of a shared list to belong to the list owner, got: %s", created.OwnerId)
+		}
+		err := mngr.UpdateList(work, "u2", todolist.ListUpdate{})
+		if !errors.Is(err, todolist.ErrPermissionDenied) {
+			t.Errorf("expected permission denied, got: %v", err)
+		}
+	})
+
+	t.Run("editor cannot move tasks to private lists and tasks", func(t *testing.T) {
+		private := createList(t, mngr, "private", "u1")
+		secret := todolist.TodoItem{Text: "secret", OwnerId: "u1", ListId: private}
+		if _, err := mngr.Create(&secret); err != nil {
+			t.Fatal(err)
+		}
+		inbox := ""
+		lErr := &todolist.ListNotFoundErr{}
+		err := mngr.Update(task.ID, "u2", todolist.TaskUpdate{ListId: &private})
+		if !errors.As(err, &lErr) {
+			t.Errorf("expected list not found, got: %v", err)
+		}
+		err = mngr.Update(task.ID, "u2", todolist.TaskUpdate{ListId: &inbox})
+		if !errors.Is(err, todolist.ErrPermissionDenied) {
+			t.Errorf("expected permission denied, got: %v", err)
+		}
+		tErr := &todolist.ItemNotFountErr{}
+		err = mngr.Update(task.ID, "u2", todolist.TaskUpdate{ParentId: &secret.ID})
+		if !errors.As(err, &tErr) {
+			t.Errorf("expected task not found, got: %v", err)
+		}
+		err = mngr.Update(task.ID, "u2", todolist.TaskUpdate{BlockedBy: &[]string{secret.ID}})
+		if !errors.Is(err, todolist.ErrUnknownBlocker) {
+			t.Errorf("expected unknown blocker, got: %v", err)
+		}
+		created := todolist.TodoItem{Text: "blocked", OwnerId: "u2", ListId: work, BlockedBy: []string{secret.ID}}
+		if _, err = mngr.Create(&created); !errors.Is(err, todolist.ErrUnknownBlocker) {
+			t.Errorf("expected unknown blocker, got: %v", err)
+		}
+		got, err := mngr.Get(task.ID, "u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.ListId != work || got.ParentId != "" {
+			t.Errorf("expected the task to stay in the shared list, got: %+v", got)
+		}
+
+		// blockers set by the owner are kept when the editor changes the task
+		if err = mngr.Update(task.ID, "u1", todolist.TaskUpdate{BlockedBy: &[]string{secret.ID}}); err != nil {
+			t.Fatal(err)
+		}
+		if err = mngr.Update(task.ID, "u2", todolist.TaskUpdate{BlockedBy: &[]string{secret.ID}, ListId: &work}); err != nil {
+			t.Errorf("expected the current blockers to be accepted, got: %v", err)
+		}
+		if err = mngr.Update(task.ID, "u1", todolist.TaskUpdate{BlockedBy: &[]string{}}); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("shared with me", func(t *testing.T) {
+		shared, err := mngr.SharedWithMe("u2")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []string{}
+		for _, s := range shared {
+			got = append(got, s.List.Name+":"+string(s.Role))
+		}
+		if diff := cmp.Diff(got, []string{"work:editor"}); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+		members, err := mngr.Members(work, "u2")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(members) != 1 || members[0].UserId != "u2" || !members[0].Accepted {
+			t.Errorf("unexpected members: %+v", members)
+		}
+	})
+
+	t.Run("revoke takes effect immediately", func(t *testing.T) {
+		if err := mngr.Revoke(work, "u1", "u2"); err != nil {
+			t.Fatal(err)
+		}
+		readTask(t, mngr, task.ID, "u2", "", "task with id: "+task.ID+" and owner u2 not found")
+		_, _, err := mngr.ListAccess(work, "u2")
+		lErr := &todolist.ListNotFoundErr{}
+		if !errors.As(err, &lErr) {
+			t.Errorf("expected list not found, got: %v", err)
+		}
+		err = mngr.Revoke(work, "u1", "u2")
+		mErr := &todolist.MemberNotFoundErr{}
+		if !errors.As(err, &mErr) {
+			t.Errorf("expected member not found, got: %v", err)
+		}
+	})
+
+	t.Run("invalid shares", func(t *testing.T) {
+		inbox, err := mngr.Inbox("u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		tcs := []struct {
+			name    string
+			listId  string
+			invitee string
+			role    todolist.Role
+			wantErr error
+		}{
+			{name: "unknown role", listId: work, invitee: "u3", role: "admin", wantErr: todolist.ErrInvalidRole},
+			{name: "inbox", listId: inbox.ID, invitee: "u3", role: todolist.RoleViewer, wantErr: todolist.ErrShareInbox},
+			{name: "owner", listId: work, invitee: "u1", role: todolist.RoleViewer, wantErr: todolist.ErrShareWithOwner},
+		}
+		for _, tc := range tcs {
+			t.Run(tc.name, func(t *testing.T) {
+				err := mngr.Share(tc.listId, "u1", tc.invitee, tc.role)
+				if !errors.Is(err, tc.wantErr) {
+					t.Errorf("expected error %v, got: %v", tc.wantErr, err)
+				}
+			})
+		}
+	})
+}
+
+func TestAssignTask(t *testing.T) {
+	mngr := testManager(t)
+	work := createList(t, mngr, "work", "u1")
+	task := todolist.TodoItem{Text: "report", OwnerId: "u1", ListId: work}
+	if _, err := mngr.Create(&task); err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range []string{"u2", "u3"} {
+		if err := mngr.Share(work, "u1", u, todolist.RoleViewer); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := mngr.AcceptInvitation(work, "u2"); err != nil {
+		t.Fatal(err)
+	}
+	assign := func(user, assignee string) error {
+		return mngr.Update(task.ID, user, todolist.TaskUpdate{AssigneeId: &assignee})
+	}
+
+	t.Run("only collaborators can be assigned", func(t *testing.T) {
+		for _, assignee := range []string{"u3", "u4"} {
+			if err := assign("u1", assignee); !errors.Is(err, todolist.ErrInvalidAssignee) {
+				t.Errorf("expected invalid assignee for %s, got: %v", assignee, err)
+			}
+		}
+		inboxTask := todolist.TodoItem{Text: "private", OwnerId: "u1", AssigneeId: "u2"}
+		if _, err := mngr.Create(&inboxTask); !errors.Is(err, todolist.ErrInvalidAssignee) {
+			t.Errorf("expected invalid assignee, got: %v", err)
+		}
+		if err := assign("u1", "u2"); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("assignee can complete the task", func(t *testing.T) {
+		setDone(t, mngr, task.ID, "u2", true, "")
+		setText(t, mngr, task.ID, "u2", "changed", todolist.ErrPermissionDenied.Error())
+		if err := assign("u2", "u1"); !errors.Is(err, todolist.ErrPermissionDenied) {
+			t.Errorf("expected permission denied, got: %v", err)
+		}
+	})
+
+	t.Run("assigned to me", func(t *testing.T) {
+		own := todolist.TodoItem{Text: "own", OwnerId: "u2"}
+		if _, err := mngr.Create(&own); err != nil {
+			t.Fatal(err)
+		}
+		self := "u2"
+		if err := mngr.Update(own.ID, "u2", todolist.TaskUpdate{AssigneeId: &self}); err != nil {
+			t.Fatal(err)
+		}
+		page, err := mngr.AssignedPage("u2", todolist.PageRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(pageTexts(page), []string{"report", "own"}); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+	})
+
+	t.Run("revoking unassigns the tasks", func(t *testing.T) {
+		if err := mngr.Revoke(work, "u1", "u2"); err != nil {
+			t.Fatal(err)
+		}
+		got, err := mngr.Get(task.ID, "u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.AssigneeId != "" {
+			t.Errorf("expected the task to be unassigned, got: %s", got.AssigneeId)
+		}
+		want := []string{"update: assigneeId", "update: statusId done", "update: assigneeId", "create: text statusId listId position"}
+		if diff := cmp.Diff(changeSummary(t, mngr, task.ID, "u1"), want); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+		page, err := mngr.AssignedPage("u2", todolist.PageRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(pageTexts(page), []string{"own"}); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+	})
+}
+
+func TestSharedTaskResources(t *testing.T) {
+	mngr := testManager(t)
+	work := createList(t, mngr, "work", "u1")
+	private := createList(t, mngr, "private", "u1")
+	task := todolist.TodoItem{Text: "report", OwnerId: "u1", ListId: work, Notes: "- [ ] draft"}
+	if _, err := mngr.Create(&task); err != nil {
+		t.Fatal(err)
+	}
+	secret := todolist.TodoItem{Text: "secret", OwnerId: "u1", ListId: private}
+	if _, err := mngr.Create(&secret); err != nil {
+		t.Fatal(err)
+	}
+	if err := mngr.Share(work, "u1", "u2", todolist.RoleEditor); err != nil {
+		t.Fatal(err)
+	}
+	if err := mngr.AcceptInvitation(work, "u2"); err != nil {
+		t.Fatal(err)
+	}
+	setRole := func(t *testing.T, role todolist.Role) {
+		t.Helper()
+		if err := mngr.SetRole(work, "u1", "u2", role); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("comments", func(t *testing.T) {
+		c := todolist.Comment{TaskId: task.ID, AuthorId: "u2", Body: "looks good"}
+		if _, err := mngr.AddComment(&c); err != nil {
+			t.Fatal(err)
+		}
+		setRole(t, todolist.RoleViewer)
+		defer setRole(t, todolist.RoleEditor)
+
+		comments, err := mngr.Comments(task.ID, "u2")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(comments) != 1 {
+			t.Errorf("expected the viewer to read the comments, got: %+v", comments)
+		}
+		_, err = mngr.AddComment(&todolist.Comment{TaskId: task.ID, AuthorId: "u2", Body: "again"})
+		if !errors.Is(err, todolist.ErrPermissionDenied) {
+			t.Errorf("expected permission denied, got: %v", err)
+		}
+		_, err = mngr.UpdateComment(task.ID, c.ID, "u2", "changed")
+		if !errors.Is(err, todolist.ErrPermissionDenied) {
+			t.Errorf("expected permission denied, got: %v", err)
+		}
+		err = mngr.DeleteComment(task.ID, c.ID, "u2")
+		if !errors.Is(err, todolist.ErrPermissionDenied) {
+			t.Errorf("expected permission denied, got: %v", err)
+		}
+	})
+
+	t.Run("notes checkboxes", func(t *testing.T) {
+		setRole(t, todolist.RoleViewer)
+		err := mngr.SetCheckbox(task.ID, "u2", 0, true)
+		if !errors.Is(err, todolist.ErrPermissionDenied) {
+			t.Errorf("expected permission denied, got: %v", err)
+		}
+		setRole(t, todolist.RoleEditor)
+		if err = mngr.SetCheckbox(task.ID, "u2", 0, true); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("revert", func(t *testing.T) {
+		for _, blockers := range [][]string{{secret.ID}, {}} {
+			if err := mngr.Update(task.ID, "u1", todolist.TaskUpdate{BlockedBy: &blockers}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		changes, err := mngr.History(task.ID, "u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		blocked := changes[1].ID
+
+		setRole(t, todolist.RoleViewer)
+		err = mngr.Revert(task.ID, "u2", blocked)
+		if !errors.Is(err, todolist.ErrPermissionDenied) {
+			t.Errorf("expected permission denied, got: %v", err)
+		}
+		setRole(t, todolist.RoleEditor)
+		// the editor cannot restore a blocker in a list that is not shared
+		err = mngr.Revert(task.ID, "u2", blocked)
+		if !errors.Is(err, todolist.ErrRevertConflict) || !errors.Is(err, todolist.ErrUnknownBlocker) {
+			t.Errorf("expected revert conflict, got: %v", err)
+		}
+		if err = mngr.Revert(task.ID, "u1", blocked); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("dependency graph", func(t *testing.T) {
+		texts := func(graph todolist.DependencyGraph) []string {
+			got := []string{}
+			for _, task := range graph.Tasks {
+				got = append(got, task.Text)
+			}
+			return got
+		}
+		graph, err := mngr.DependencyGraph("u1", work)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(texts(graph), []string{"report", "secret"}); diff != "" || len(graph.Edges) != 1 {
+			t.Errorf("unexpected graph of the owner (-got +want)\n%s", diff)
+		}
+		// the tasks of the lists that are not shared are left out
+		graph, err = mngr.DependencyGraph("u2", work)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(texts(graph), []string{"report"}); diff != "" || len(graph.Edges) != 0 {
+			t.Errorf("unexpected graph of the member (-got +want)\n%s", diff)
+		}
+	})
+}
@@ -0,0 +1,281 @@
+package todolist
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+// Status is a column of the owner's kanban board, every task is in exactly one status.
+// Tasks in a status marked as Done are completed, this keeps TodoItem.Done in sync with the board.
+type Status struct {
+	ID       string `gorm:"primaryKey,index"`
+	OwnerId  string `gorm:"index"`
+	Name     string
+	Position int
+	Done     bool
+
+	// WorkspaceId is the workspace of the board, it is set by the manager, see Manager.InWorkspace
+	WorkspaceId string `gorm:"index;not null;default:''"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+func (s *Status) BeforeCreate(db *gorm.DB) (err error) {
+	// UUID version 4
+	s.ID = uuid.NewString()
+	return
+}
+
+// defaultStatuses are created for every owner the first time the statuses are needed
+var defaultStatuses = []Status{
+	{Name: "To do"},
+	{Name: "In progress"},
+	{Name: "Blocked"},
+	{Name: "Done", Done: true},
+}
+
+type StatusNotFoundErr struct {
+	id    string
+	owner string
+}
+
+func (m *StatusNotFoundErr) Error() string {
+	return fmt.Sprintf("status with id: %s and owner %s not found", m.id, m.owner)
+}
+
+// ErrLastStatus is returned when a change would leave the owner without an open or without a done status
+var ErrLastStatus = errors.New("at least one open and one done status are required")
+
+// ErrEmptyStatusName is returned when a status is created or renamed with an empty name
+var ErrEmptyStatusName = errors.New("status name cannot be empty")
+
+// ensureStatuses returns the statuses of the owner ordered by position, creating the default ones if
+// the owner has none yet. Tasks created before statuses existed are assigned to the first open or done status.
+func ensureStatuses(db *gorm.DB, owner string) ([]Status, error) {
+	statuses := []Status{}
+	err := db.Where("owner_id = ?", owner).Order("position").Order("created_at").Find(&statuses).Error
+	if err != nil || len(statuses) > 0 {
+		return statuses, err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		statuses = make([]Status, len(defaultStatuses))
+		ids := map[bool]string{}
+		for i, s := range defaultStatuses {
+			s.OwnerId = owner
+			s.Position = i
+			err := tx.Create(&s).Error
+			if err != nil {
+				return err
+			}
+			statuses[i] = s
+			if _, ok := ids[s.Done]; !ok {
+				ids[s.Done] = s.ID
+			}
+		}
+		return tx.Unscoped().Model(&TodoItem{}).
+			Where("owner_id = ? AND status_id = ?", owner, "").
+			Update("status_id", gorm.Expr("CASE WHEN done THEN ? ELSE ? END", ids[true], ids[false])).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return statuses, nil
+}
+
+// defaultStatus returns the first open or done status of the owner, it is used when a task
+// is completed or reopened without choosing a status
+func defaultStatus(db *gorm.DB, owner string, done bool) (Status, error) {
+	statuses, err := ensureStatuses(db, owner)
+	if err != nil {
+		return Status{}, err
+	}
+	for _, s := range statuses {
+		if s.Done == done {
+			return s, nil
+		}
+	}
+	return Status{}, ErrLastStatus
+}
+
+func getStatus(db *gorm.DB, id, owner string) (Status, error) {
+	s := Status{}
+	result := db.Where("ID = ? AND owner_id = ?", id, owner).Limit(1).Find(&s)
+	if result.Error != nil {
+		return s, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return s, &StatusNotFoundErr{id: id, owner: owner}
+	}
+	return s, nil
+}
+
+// Statuses returns the statuses of the owner ordered by position
+func (m Manager) Statuses(owner string) ([]Status, error) {
+	return ensureStatuses(m.db, owner)
+}
+
+func (m Manager) GetStatus(id, owner string) (Status, error) {
+	return getStatus(m.db, id, owner)
+}
+
+// CreateStatus stores a new status, if no position is set it is appended after the existing statuses
+func (m Manager) CreateStatus(s *Status) (string, error) {
+	if s.Name == "" {
+		return "", ErrEmptyStatusName
+	}
+	statuses, err := ensureStatuses(m.db, s.OwnerId)
+	if err != nil {
+		return "", err
+	}
+	if s.Position == 0 {
+		for _, existing := range statuses {
+			s.Position = max(s.Position, existing.Position+1)
+		}
+	}
+	result := m.db.Create(s)
+	if result.Error != nil {
+		return "", result.Error
+	}
+	return s.ID, nil
+}
+
+// StatusUpdate holds the fields of a status that can be changed, nil values are left untouched
+type StatusUpdate struct {
+	Name     *string
+	Position *int
+	// Done changes the done value of all the tasks in the status as well
+	Done *bool
+}
+
+func (m Manager) UpdateStatus(id, owner string, upd StatusUpdate) error {
+	if upd.Name != nil && *upd.Name == "" {
+		return ErrEmptyStatusName
+	}
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		s, err := getStatus(tx, id, owner)
+		if err != nil {
+			return err
+		}
+
+		fieldMap := map[string]any{}
+		if upd.Name != nil {
+			fieldMap["name"] = *upd.Name
+		}
+		if upd.Position != nil {
+			fieldMap["position"] = *upd.Position
+		}
+		if upd.Done != nil && *upd.Done != s.Done {
+			err = keepsStatusKinds(tx, id, owner, s.Done)
+			if err != nil {
+				return err
+			}
+			fieldMap["done"] = *upd.Done
+			err = tx.Unscoped().Model(&TodoItem{}).Where("owner_id = ? AND status_id = ?", owner, id).
+				Update("done", *upd.Done).Error
+			if err != nil {
+				return err
+			}
+		}
+		if len(fieldMap) == 0 {
+			return nil
+		}
+		return tx.Model(&s).Updates(fieldMap).Error
+	})
+}
+
+// keepsStatusKinds verifies that there is another status with the same done value as the status
+// that is removed or changed
+func keepsStatusKinds(tx *gorm.DB, id, owner string, done bool) error {
+	var others int64
+	err := tx.Model(&Status{}).Where("owner_id = ? AND done = ? AND id != ?", owner, done, id).Count(&others).Error
+	if err != nil {
+		return err
+	}
+	if others == 0 {
+		return ErrLastStatus
+	}
+	return nil
+}
+
+// DeleteStatus removes a status moving its tasks into the status moveTo, if moveTo is empty
+// the tasks are moved into the first status with the same done value
+func (m Manager) DeleteStatus(id, owner, moveTo string) error {
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		s, err := getStatus(tx, id, owner)
+		if err != nil {
+			return err
+		}
+		err = keepsStatusKinds(tx, id, owner, s.Done)
+		if err != nil {
+			return err
+		}
+
+		target := Status{}
+		if moveTo != "" && moveTo != id {
+			target, err = getStatus(tx, moveTo, owner)
+		} else {
+			err = tx.Where("owner_id = ? AND done = ? AND id != ?", owner, s.Done, id).
+				Order("position").Order("created_at").First(&target).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		// deleted tasks are moved as well so that they have a valid status when restored
+		err = tx.Unscoped().Model(&TodoItem{}).Where("owner_id = ? AND status_id = ?", owner, id).
+			Updates(map[string]any{"status_id": target.ID, "done": target.Done}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Delete(&s).Error
+	})
+}
+
+// InStatus limits the tasks to the ones in the status with the given id
+func InStatus(statusId string) Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("status_id = ?", statusId)
+	}
+}
+
+// BoardColumn is a status together with the first tasks in it
+type BoardColumn struct {
+	Status Status
+	Tasks  []TodoItem
+	Total  int64 // number of tasks in the status matching the scopes
+}
+
+// Board returns the tasks of the owner grouped by status, every column contains at most size tasks
+// ordered by position
+func (m Manager) Board(owner string, size int, scopes ...Scope) ([]BoardColumn, error) {
+	statuses, err := ensureStatuses(m.db, owner)
+	if err != nil {
+		return nil, err
+	}
+	columns := make([]BoardColumn, len(statuses))
+	for i, s := range statuses {
+		query := func() *gorm.DB {
+			db := m.db.Model(&TodoItem{}).Where("owner_id = ? AND status_id = ?", owner, s.ID)
+			for _, scope := range scopes {
+				db = db.Scopes(scope)
+			}
+			return db
+		}
+		columns[i] = BoardColumn{Status: s, Tasks: []TodoItem{}}
+		err = query().Count(&columns[i].Total).Error
+		if err != nil {
+			return nil, err
+		}
+		err = query().Scopes(preloadTags, defaultOrder).Limit(pageSize(size)).Find(&columns[i].Tasks).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+	return columns, nil
+}
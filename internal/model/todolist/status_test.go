@@ -0,0 +1,186 @@
+package todolist_test
+
+import (
+	"errors"
+	"github.com/go-bumbu/todo-app/internal/model/todolist"
+	"github.com/google/go-cmp/cmp"
+	"testing"
+)
+
+func statusByName(t *testing.T, mngr *todolist.Manager, owner, name string) todolist.Status {
+	t.Helper()
+	statuses, err := mngr.Statuses(owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range statuses {
+		if s.Name == name {
+			return s
+		}
+	}
+	t.Fatalf("status %s not found", name)
+	return todolist.Status{}
+}
+
+func setStatus(t *testing.T, mngr *todolist.Manager, id, owner, statusId string) {
+	t.Helper()
+	err := mngr.Update(id, owner, todolist.TaskUpdate{StatusId: &statusId})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func boardTexts(t *testing.T, mngr *todolist.Manager, owner string, scopes ...todolist.Scope) map[string][]string {
+	t.Helper()
+	columns, err := mngr.Board(owner, 0, scopes...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string][]string{}
+	for _, c := range columns {
+		texts := []string{}
+		for _, task := range c.Tasks {
+			texts = append(texts, task.Text)
+		}
+		got[c.Status.Name] = texts
+		if c.Total != int64(len(texts)) {
+			t.Errorf("unexpected total for %s: %d", c.Status.Name, c.Total)
+		}
+	}
+	return got
+}
+
+func TestStatuses(t *testing.T) {
+	t.Run("default statuses", func(t *testing.T) {
+		mngr := testManager(t)
+		statuses, err := mngr.Statuses("u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := map[string]bool{}
+		names := []string{}
+		for _, s := range statuses {
+			got[s.Name] = s.Done
+			names = append(names, s.Name)
+		}
+		if diff := cmp.Diff(names, []string{"To do", "In progress", "Blocked", "Done"}); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+		if !got["Done"] || got["To do"] {
+			t.Errorf("unexpected done values: %v", got)
+		}
+	})
+
+	t.Run("done follows the status", func(t *testing.T) {
+		mngr := testManager(t)
+		id := createTask(t, mngr, "task", "u1")
+		readDone := func() todolist.TodoItem {
+			task, err := mngr.Get(id, "u1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			return task
+		}
+		if task := readDone(); task.StatusId != statusByName(t, mngr, "u1", "To do").ID {
+			t.Errorf("expected new task to be in the first status")
+		}
+
+		setStatus(t, mngr, id, "u1", statusByName(t, mngr, "u1", "In progress").ID)
+		if readDone().Done {
+			t.Errorf("expected task in progress to be pending")
+		}
+		setStatus(t, mngr, id, "u1", statusByName(t, mngr, "u1", "Done").ID)
+		if !readDone().Done {
+			t.Errorf("expected task in done status to be done")
+		}
+
+		setDone(t, mngr, id, "u1", false, "")
+		if task := readDone(); task.StatusId != statusByName(t, mngr, "u1", "To do").ID {
+			t.Errorf("expected reopened task to be moved to the first open status")
+		}
+
+		setStatus(t, mngr, id, "u1", statusByName(t, mngr, "u1", "In progress").ID)
+		setStatus(t, mngr, id, "u1", "")
+		if task := readDone(); task.StatusId != statusByName(t, mngr, "u1", "To do").ID {
+			t.Errorf("expected an empty status to move the task to the first open status")
+		}
+	})
+
+	t.Run("status of other owner", func(t *testing.T) {
+		mngr := testManager(t)
+		id := createTask(t, mngr, "task", "u1")
+		other := statusByName(t, mngr, "u2", "Done").ID
+		err := mngr.Update(id, "u1", todolist.TaskUpdate{StatusId: &other})
+		target := &todolist.StatusNotFoundErr{}
+		if !errors.As(err, &target) {
+			t.Errorf("expected status not found error, got: %v", err)
+		}
+	})
+
+	t.Run("board", func(t *testing.T) {
+		mngr := testManager(t)
+		a := createTask(t, mngr, "a", "u1")
+		_ = createTask(t, mngr, "b", "u1")
+		c := createTask(t, mngr, "c", "u1")
+		_ = createTask(t, mngr, "other", "u2")
+		setStatus(t, mngr, a, "u1", statusByName(t, mngr, "u1", "Blocked").ID)
+		setDone(t, mngr, c, "u1", true, "")
+
+		want := map[string][]string{"To do": {"b"}, "In progress": {}, "Blocked": {"a"}, "Done": {"c"}}
+		if diff := cmp.Diff(boardTexts(t, mngr, "u1"), want); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+
+		filter, err := todolist.ParseFilter(`status:Blocked OR status:"To do"`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(taskTexts(t, mngr, "u1", filter), []string{"a", "b"}); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+	})
+
+	t.Run("create, update and delete statuses", func(t *testing.T) {
+		mngr := testManager(t)
+		a := createTask(t, mngr, "a", "u1")
+		review := todolist.Status{Name: "Review", OwnerId: "u1"}
+		_, err := mngr.CreateStatus(&review)
+		if err != nil {
+			t.Fatal(err)
+		}
+		setStatus(t, mngr, a, "u1", review.ID)
+
+		done := true
+		err = mngr.UpdateStatus(review.ID, "u1", todolist.StatusUpdate{Done: &done})
+		if err != nil {
+			t.Fatal(err)
+		}
+		task, err := mngr.Get(a, "u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !task.Done {
+			t.Errorf("expected task to follow the done value of its status")
+		}
+
+		// the task is moved to the remaining done status
+		err = mngr.DeleteStatus(review.ID, "u1", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := map[string][]string{"To do": {}, "In progress": {}, "Blocked": {}, "Done": {"a"}}
+		if diff := cmp.Diff(boardTexts(t, mngr, "u1"), want); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+
+		err = mngr.DeleteStatus(statusByName(t, mngr, "u1", "Done").ID, "u1", "")
+		if !errors.Is(err, todolist.ErrLastStatus) {
+			t.Errorf("expected last status error, got: %v", err)
+		}
+
+		_, err = mngr.CreateStatus(&todolist.Status{OwnerId: "u1"})
+		if !errors.Is(err, todolist.ErrEmptyStatusName) {
+			t.Errorf("expected empty name error, got: %v", err)
+		}
+	})
+}
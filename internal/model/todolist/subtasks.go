@@ -0,0 +1,200 @@
+package todolist
+
+import (
+	"errors"
+	"gorm.io/gorm"
+)
+
+// ErrTaskCycle is returned when setting the parent of a task would create a loop in the hierarchy
+var ErrTaskCycle = errors.New("a task cannot be a subtask of itself or of one of its subtasks")
+
+// Progress counts the completed subtasks of a task, including all nested levels
+type Progress struct {
+	Done  int
+	Total int
+}
+
+// descendantsCte is a recursive query returning the root id, id and done status of all
+// non deleted descendants of the tasks passed as parameter
+const descendantsCte = `WITH RECURSIVE sub(root, id, done) AS (
+	SELECT parent_id, id, done FROM todo_items WHERE parent_id IN ? AND owner_id = ? AND deleted_at IS NULL
+	UNION ALL
+	SELECT sub.root, t.id, t.done FROM todo_items t JOIN sub ON t.parent_id = sub.id WHERE t.deleted_at IS NULL
+) `
+
+// descendantIds returns the ids of all the subtasks of the task, on any level
+func descendantIds(db *gorm.DB, id, owner string) ([]string, error) {
+	ids := []string{}
+	err := db.Raw(descendantsCte+"SELECT id FROM sub", []string{id}, owner).Scan(&ids).Error
+	return ids, err
+}
+
+// Subtasks returns all the descendants of the given tasks as a flat list, use ParentId to build the tree
+func (m Manager) Subtasks(owner string, ids ...string) ([]TodoItem, error) {
+	tasks := []TodoItem{}
+	if len(ids) == 0 {
+		return tasks, nil
+	}
+	sub := m.db.Raw(descendantsCte+"SELECT id FROM sub", ids, owner)
+	result := m.db.Scopes(preloadTags, defaultOrder).
+		Where("owner_id = ? AND id IN (?)", owner, sub).
+		Find(&tasks)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return tasks, nil
+}
+
+// Progress returns the done/total count of subtasks for the given tasks, tasks without subtasks are not included
+func (m Manager) Progress(owner string, ids ...string) (map[string]Progress, error) {
+	progress := map[string]Progress{}
+	if len(ids) == 0 {
+		return progress, nil
+	}
+	rows := []struct {
+		Root  string
+		Done  int
+		Total int
+	}{}
+	err := m.db.Raw(descendantsCte+
+		"SELECT root, SUM(CASE WHEN done THEN 1 ELSE 0 END) AS done, COUNT(*) AS total FROM sub GROUP BY root",
+		ids, owner).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		progress[r.Root] = Progress{Done: r.Done, Total: r.Total}
+	}
+	return progress, nil
+}
+
+// RootTasks limits the tasks to the ones that are not a subtask of another task
+func RootTasks() Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("parent_id = ?", "")
+	}
+}
+
+// ChildrenOf limits the tasks to the direct subtasks of the given task
+func ChildrenOf(parentId string) Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("parent_id = ?", parentId)
+	}
+}
+
+// setParent validates and applies a new parent to the task, the subtree is moved into the list of the parent
+func setParent(tx *gorm.DB, id, owner, parentId string) error {
+	if parentId == "" {
+		return tx.Model(&TodoItem{}).Where("ID = ? AND owner_id = ?", id, owner).Update("parent_id", "").Error
+	}
+	if parentId == id {
+		return ErrTaskCycle
+	}
+	parent := TodoItem{}
+	result := tx.Where("ID = ? AND owner_id = ?", parentId, owner).Limit(1).Find(&parent)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return &ItemNotFountErr{id: parentId, owner: owner}
+	}
+
+	descendants, err := descendantIds(tx, id, owner)
+	if err != nil {
+		return err
+	}
+	for _, d := range descendants {
+		if d == parentId {
+			return ErrTaskCycle
+		}
+	}
+
+	err = tx.Model(&TodoItem{}).Where("ID = ? AND owner_id = ?", id, owner).
+		Updates(map[string]any{"parent_id": parentId, "list_id": parent.ListId}).Error
+	if err != nil {
+		return err
+	}
+	if len(descendants) == 0 {
+		return nil
+	}
+	return tx.Model(&TodoItem{}).Where("ID IN ? AND owner_id = ?", descendants, owner).
+		Updates(map[string]any{"list_id": parent.ListId, "version": gorm.Expr("version + 1")}).Error
+}
+
+// moveToList moves a top level task and all its descendants into the list, subtasks are left untouched
+// as they always follow the list of their parent
+func moveToList(tx *gorm.DB, id, owner, listId string) error {
+	t := TodoItem{}
+	err := tx.Where("ID = ? AND owner_id = ?", id, owner).First(&t).Error
+	if err != nil {
+		return err
+	}
+	if t.ParentId != "" || t.ListId == listId {
+		return nil
+	}
+	descendants, err := descendantIds(tx, id, owner)
+	if err != nil {
+		return err
+	}
+	err = tx.Model(&TodoItem{}).Where("ID = ? AND owner_id = ?", id, owner).Update("list_id", listId).Error
+	if err != nil {
+		return err
+	}
+	if len(descendants) == 0 {
+		return nil
+	}
+	return tx.Model(&TodoItem{}).Where("ID IN ? AND owner_id = ?", descendants, owner).
+		Updates(map[string]any{"list_id": listId, "version": gorm.Expr("version + 1")}).Error
+}
+
+// completeSubtasks marks all the descendants of the task as done, the pending ones are moved into status
+func completeSubtasks(tx *gorm.DB, id, owner string, status Status) error {
+	descendants, err := descendantIds(tx, id, owner)
+	if err != nil {
+		return err
+	}
+	if len(descendants) == 0 {
+		return nil
+	}
+	return tx.Model(&TodoItem{}).Where("ID IN ? AND owner_id = ? AND done = ?", descendants, owner, false).
+		Updates(map[string]any{"done": true, "status_id": status.ID, "version": gorm.Expr("version + 1")}).Error
+}
+
+// DeleteKeepSubtasks deletes a task but keeps its subtasks, they are moved one level up in the hierarchy
+func (m Manager) DeleteKeepSubtasks(id, user string) error {
+	owner, err := authorize(m.db, id, user, RoleEditor)
+	if err != nil {
+		return err
+	}
+	return m.deleteKeepSubtasks(id, owner, nil)
+}
+
+func (m Manager) deleteKeepSubtasks(id, owner string, version *int64) error {
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		err := bumpVersion(tx, id, owner, version)
+		if err != nil {
+			return err
+		}
+		t := TodoItem{}
+		result := tx.Where("ID = ? AND owner_id = ?", id, owner).Limit(1).Find(&t)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return &ItemNotFountErr{id: id, owner: owner}
+		}
+		children := []string{}
+		err = tx.Model(&TodoItem{}).Where("parent_id = ? AND owner_id = ?", id, owner).Pluck("id", &children).Error
+		if err != nil {
+			return err
+		}
+		return m.track(tx, ActionUpdate, append([]string{id}, children...), func() ([]string, error) {
+			err := tx.Model(&TodoItem{}).Where("parent_id = ? AND owner_id = ?", id, owner).
+				Updates(map[string]any{"parent_id": t.ParentId, "version": gorm.Expr("version + 1")}).Error
+			if err != nil {
+				return nil, err
+			}
+			return nil, tx.Where("ID = ? AND owner_id = ?", id, owner).Delete(&TodoItem{}).Error
+		})
+	})
+}
@@ -0,0 +1,149 @@
+package todolist_test
+
+import (
+	"errors"
+	"github.com/go-bumbu/todo-app/internal/model/todolist"
+	"github.com/google/go-cmp/cmp"
+	"testing"
+)
+
+func createSubtask(t *testing.T, mngr *todolist.Manager, text, owner, parent string) string {
+	t.Helper()
+	task := todolist.TodoItem{Text: text, OwnerId: owner, ParentId: parent}
+	id, err := mngr.Create(&task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+// hierarchy creates: trip -> (pack -> (clothes, passport), book hotel)
+func hierarchy(t *testing.T, mngr *todolist.Manager) map[string]string {
+	t.Helper()
+	ids := map[string]string{}
+	ids["trip"] = createSubtask(t, mngr, "trip", "u1", "")
+	ids["pack"] = createSubtask(t, mngr, "pack", "u1", ids["trip"])
+	ids["clothes"] = createSubtask(t, mngr, "clothes", "u1", ids["pack"])
+	ids["passport"] = createSubtask(t, mngr, "passport", "u1", ids["pack"])
+	ids["hotel"] = createSubtask(t, mngr, "book hotel", "u1", ids["trip"])
+	return ids
+}
+
+func TestSubtasks(t *testing.T) {
+	mngr := testManager(t)
+	ids := hierarchy(t, mngr)
+	_ = createTask(t, mngr, "unrelated", "u1")
+
+	t.Run("root tasks", func(t *testing.T) {
+		got := taskTexts(t, mngr, "u1", todolist.RootTasks())
+		if diff := cmp.Diff(got, []string{"trip", "unrelated"}); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+	})
+
+	t.Run("all descendants", func(t *testing.T) {
+		subtasks, err := mngr.Subtasks("u1", ids["trip"])
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []string{}
+		for _, s := range subtasks {
+			got = append(got, s.Text)
+		}
+		if diff := cmp.Diff(got, []string{"pack", "clothes", "passport", "book hotel"}); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+	})
+
+	t.Run("subtasks of other owner", func(t *testing.T) {
+		subtasks, err := mngr.Subtasks("u2", ids["trip"])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(subtasks) != 0 {
+			t.Errorf("expected no subtasks, got %d", len(subtasks))
+		}
+	})
+
+	t.Run("progress", func(t *testing.T) {
+		setDone(t, mngr, ids["clothes"], "u1", true, "")
+		got, err := mngr.Progress("u1", ids["trip"], ids["pack"], ids["hotel"])
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := map[string]todolist.Progress{
+			ids["trip"]: {Done: 1, Total: 4},
+			ids["pack"]: {Done: 1, Total: 2},
+		}
+		if diff := cmp.Diff(got, want); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+	})
+
+	t.Run("prevent cycles", func(t *testing.T) {
+		for _, parent := range []string{ids["pack"], ids["clothes"]} {
+			p := parent
+			err := mngr.Update(ids["pack"], "u1", todolist.TaskUpdate{ParentId: &p})
+			if !errors.Is(err, todolist.ErrTaskCycle) {
+				t.Errorf("expected cycle error, got: %v", err)
+			}
+		}
+	})
+
+	t.Run("complete with subtasks", func(t *testing.T) {
+		done := true
+		err := mngr.Update(ids["pack"], "u1", todolist.TaskUpdate{Done: &done, CompleteSubtasks: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := mngr.Progress("u1", ids["trip"])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(got[ids["trip"]], todolist.Progress{Done: 3, Total: 4}); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+	})
+
+	t.Run("move subtree into another list", func(t *testing.T) {
+		listId := createList(t, mngr, "other", "u1")
+		other := todolist.TodoItem{Text: "other root", OwnerId: "u1", ListId: listId}
+		otherId, err := mngr.Create(&other)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = mngr.Update(ids["pack"], "u1", todolist.TaskUpdate{ParentId: &otherId})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := taskTexts(t, mngr, "u1", todolist.InList(listId))
+		if diff := cmp.Diff(got, []string{"pack", "clothes", "passport", "other root"}); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+	})
+}
+
+func TestDeleteSubtasks(t *testing.T) {
+	t.Run("delete cascades to descendants", func(t *testing.T) {
+		mngr := testManager(t)
+		ids := hierarchy(t, mngr)
+		deleteTask(t, mngr, ids["pack"], "u1", "")
+		got := taskTexts(t, mngr, "u1")
+		if diff := cmp.Diff(got, []string{"trip", "book hotel"}); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+	})
+
+	t.Run("delete keeping subtasks", func(t *testing.T) {
+		mngr := testManager(t)
+		ids := hierarchy(t, mngr)
+		err := mngr.DeleteKeepSubtasks(ids["pack"], "u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := taskTexts(t, mngr, "u1", todolist.ChildrenOf(ids["trip"]))
+		if diff := cmp.Diff(got, []string{"clothes", "passport", "book hotel"}); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+	})
+}
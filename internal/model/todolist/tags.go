@@ -0,0 +1,250 @@
+package todolist
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
+
+// Tag is a label a user can attach to any of their tasks, names are unique per user and workspace
+type Tag struct {
+	ID          string `gorm:"primaryKey,index"`
+	WorkspaceId string `gorm:"uniqueIndex:idx_tag_workspace_owner_name;not null;default:''"`
+	OwnerId     string `gorm:"uniqueIndex:idx_tag_workspace_owner_name"`
+	Name        string `gorm:"uniqueIndex:idx_tag_workspace_owner_name"`
+	Color       string
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (tag *Tag) BeforeCreate(db *gorm.DB) (err error) {
+	// existing tags are upserted when saving the associations of a task, keep their id
+	if tag.ID == "" {
+		// UUID version 4
+		tag.ID = uuid.NewString()
+	}
+	return
+}
+
+const tagJoinTable = "task_tags"
+
+type TagNotFoundErr struct {
+	id    string
+	owner string
+}
+
+func (m *TagNotFoundErr) Error() string {
+	return fmt.Sprintf("tag with id: %s and owner %s not found", m.id, m.owner)
+}
+
+// ErrTagExists is returned when creating or renaming a tag to a name that is already in use, use MergeTags instead
+var ErrTagExists = errors.New("a tag with the same name already exists")
+
+// ErrEmptyTagName is returned when a tag name is empty
+var ErrEmptyTagName = errors.New("tag name cannot be empty")
+
+// Tags returns all the tags of the owner sorted by name
+func (m Manager) Tags(owner string) ([]Tag, error) {
+	tags := []Tag{}
+	result := m.db.Where("owner_id = ?", owner).Order("name").Find(&tags)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return tags, nil
+}
+
+func (m Manager) CreateTag(tag *Tag) (string, error) {
+	tag.Name = strings.TrimSpace(tag.Name)
+	if tag.Name == "" {
+		return "", ErrEmptyTagName
+	}
+	err := m.db.Transaction(func(tx *gorm.DB) error {
+		exists, err := tagExists(tx, tag.OwnerId, tag.Name, "")
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrTagExists
+		}
+		return tx.Create(tag).Error
+	})
+	if err != nil {
+		return "", err
+	}
+	return tag.ID, nil
+}
+
+func (m Manager) GetTag(id, owner string) (Tag, error) {
+	t := Tag{}
+	result := m.db.First(&t, "ID = ? AND owner_id = ?", id, owner)
+	if result.RowsAffected == 0 {
+		return t, &TagNotFoundErr{id: id, owner: owner}
+	}
+	return t, nil
+}
+
+// UpdateTag renames or changes the color of a tag, since tasks reference the tag by id
+// the change is visible on all tasks at once.
+func (m Manager) UpdateTag(id, owner string, name, color *string) error {
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		fieldMap := map[string]any{}
+		if name != nil {
+			n := strings.TrimSpace(*name)
+			if n == "" {
+				return ErrEmptyTagName
+			}
+			exists, err := tagExists(tx, owner, n, id)
+			if err != nil {
+				return err
+			}
+			if exists {
+				return ErrTagExists
+			}
+			fieldMap["name"] = n
+		}
+		if color != nil {
+			fieldMap["color"] = *color
+		}
+
+		result := tx.Model(&Tag{}).Where("ID = ? AND owner_id = ?", id, owner)
+		if len(fieldMap) > 0 {
+			result = result.Updates(fieldMap)
+		} else {
+			result = result.Find(&Tag{})
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return &TagNotFoundErr{id: id, owner: owner}
+		}
+		return nil
+	})
+}
+
+// DeleteTag removes the tag and detaches it from all tasks
+func (m Manager) DeleteTag(id, owner string) error {
+	_, err := m.GetTag(id, owner)
+	if err != nil {
+		return err
+	}
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec("DELETE FROM "+tagJoinTable+" WHERE tag_id = ?", id).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("ID = ? AND owner_id = ?", id, owner).Delete(&Tag{}).Error
+	})
+}
+
+// MergeTags moves all tasks tagged with source to target and removes source, in a single transaction
+func (m Manager) MergeTags(sourceId, targetId, owner string) error {
+	if sourceId == targetId {
+		return nil
+	}
+	_, err := m.GetTag(sourceId, owner)
+	if err != nil {
+		return err
+	}
+	_, err = m.GetTag(targetId, owner)
+	if err != nil {
+		return err
+	}
+
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec("INSERT INTO "+tagJoinTable+" (todo_item_id, tag_id) "+
+			"SELECT todo_item_id, ? FROM "+tagJoinTable+" WHERE tag_id = ? "+
+			"AND todo_item_id NOT IN (SELECT todo_item_id FROM "+tagJoinTable+" WHERE tag_id = ?)",
+			targetId, sourceId, targetId).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Exec("DELETE FROM "+tagJoinTable+" WHERE tag_id = ?", sourceId).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("ID = ? AND owner_id = ?", sourceId, owner).Delete(&Tag{}).Error
+	})
+}
+
+func tagExists(db *gorm.DB, owner, name, ignoreId string) (bool, error) {
+	var count int64
+	err := db.Model(&Tag{}).Where("owner_id = ? AND name = ? AND ID <> ?", owner, name, ignoreId).Count(&count).Error
+	return count > 0, err
+}
+
+// resolveTags returns the tags of the owner matching the names, tags that do not exist yet are created
+func resolveTags(db *gorm.DB, owner string, names []string) ([]Tag, error) {
+	tags := []Tag{}
+	seen := map[string]bool{}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil, ErrEmptyTagName
+		}
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		tag := Tag{}
+		result := db.Where("owner_id = ? AND name = ?", owner, name).Limit(1).Find(&tag)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 0 {
+			tag = Tag{OwnerId: owner, Name: name}
+			err := db.Create(&tag).Error
+			if err != nil {
+				return nil, err
+			}
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// TagNames returns the names of the tags attached to the task
+func (task TodoItem) TagNames() []string {
+	names := make([]string, len(task.Tags))
+	for i, tag := range task.Tags {
+		names[i] = tag.Name
+	}
+	return names
+}
+
+// WithTags limits the tasks to the ones tagged with the given names, if all is true
+// the task needs to have all the tags, otherwise any of them.
+func WithTags(names []string, all bool) Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		if len(names) == 0 {
+			return db
+		}
+		sub := db.Session(&gorm.Session{NewDB: true}).
+			Table(tagJoinTable).
+			Select(tagJoinTable+".todo_item_id").
+			Joins("JOIN tags ON tags.id = "+tagJoinTable+".tag_id").
+			Where("tags.name IN ?", names)
+		if all {
+			sub = sub.Group(tagJoinTable+".todo_item_id").
+				Having("COUNT(DISTINCT tags.id) = ?", len(uniqueStrings(names)))
+		}
+		return db.Where("todo_items.id IN (?)", sub)
+	}
+}
+
+func uniqueStrings(in []string) []string {
+	seen := map[string]bool{}
+	out := []string{}
+	for _, s := range in {
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,171 @@
+package todolist_test
+
+import (
+	"errors"
+	"github.com/go-bumbu/todo-app/internal/model/todolist"
+	"github.com/google/go-cmp/cmp"
+	"testing"
+)
+
+func createTagged(t *testing.T, mngr *todolist.Manager, text, owner string, tags ...string) string {
+	t.Helper()
+	task := todolist.TodoItem{Text: text, OwnerId: owner}
+	for _, tag := range tags {
+		task.Tags = append(task.Tags, todolist.Tag{Name: tag})
+	}
+	id, err := mngr.Create(&task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func tagNames(t *testing.T, mngr *todolist.Manager, owner string) []string {
+	t.Helper()
+	tags, err := mngr.Tags(owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, tag := range tags {
+		got = append(got, tag.Name)
+	}
+	return got
+}
+
+func tagByName(t *testing.T, mngr *todolist.Manager, owner, name string) todolist.Tag {
+	t.Helper()
+	tags, err := mngr.Tags(owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tag := range tags {
+		if tag.Name == name {
+			return tag
+		}
+	}
+	t.Fatalf("tag %s not found", name)
+	return todolist.Tag{}
+}
+
+func TestTaskTags(t *testing.T) {
+	mngr := testManager(t)
+
+	t1 := createTagged(t, mngr, "report", "u1", "work", "urgent")
+	_ = createTagged(t, mngr, "groceries", "u1", "errand")
+	_ = createTagged(t, mngr, "call boss", "u1", "work")
+	_ = createTagged(t, mngr, "other user", "u2", "work")
+
+	t.Run("tags are created per user", func(t *testing.T) {
+		if diff := cmp.Diff(tagNames(t, mngr, "u1"), []string{"errand", "urgent", "work"}); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+		if diff := cmp.Diff(tagNames(t, mngr, "u2"), []string{"work"}); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+	})
+
+	t.Run("tags are loaded with the task", func(t *testing.T) {
+		task, err := mngr.Get(t1, "u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(task.TagNames(), []string{"urgent", "work"}); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+	})
+
+	t.Run("filter any tag", func(t *testing.T) {
+		got := taskTexts(t, mngr, "u1", todolist.WithTags([]string{"errand", "urgent"}, false))
+		if diff := cmp.Diff(got, []string{"report", "groceries"}); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+	})
+
+	t.Run("filter all tags", func(t *testing.T) {
+		got := taskTexts(t, mngr, "u1", todolist.WithTags([]string{"work", "urgent"}, true))
+		if diff := cmp.Diff(got, []string{"report"}); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+	})
+
+	t.Run("replace tags of a task", func(t *testing.T) {
+		tags := []string{"errand", "home"}
+		err := mngr.Update(t1, "u1", todolist.TaskUpdate{Tags: &tags})
+		if err != nil {
+			t.Fatal(err)
+		}
+		task, err := mngr.Get(t1, "u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(task.TagNames(), tags); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+		got := taskTexts(t, mngr, "u1", todolist.WithTags([]string{"errand"}, false))
+		if diff := cmp.Diff(got, []string{"report", "groceries"}); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+	})
+}
+
+func TestRenameMergeTags(t *testing.T) {
+	mngr := testManager(t)
+
+	_ = createTagged(t, mngr, "t1", "u1", "job")
+	_ = createTagged(t, mngr, "t2", "u1", "job", "work")
+	_ = createTagged(t, mngr, "t3", "u1", "work")
+
+	job := tagByName(t, mngr, "u1", "job")
+	work := tagByName(t, mngr, "u1", "work")
+
+	t.Run("rename to existing name", func(t *testing.T) {
+		name := "work"
+		err := mngr.UpdateTag(job.ID, "u1", &name, nil)
+		if !errors.Is(err, todolist.ErrTagExists) {
+			t.Errorf("expected tag exists error, got: %v", err)
+		}
+	})
+
+	t.Run("rename", func(t *testing.T) {
+		name := "office"
+		err := mngr.UpdateTag(job.ID, "u1", &name, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := taskTexts(t, mngr, "u1", todolist.WithTags([]string{"office"}, false))
+		if diff := cmp.Diff(got, []string{"t1", "t2"}); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+	})
+
+	t.Run("merge", func(t *testing.T) {
+		err := mngr.MergeTags(job.ID, work.ID, "u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(tagNames(t, mngr, "u1"), []string{"work"}); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+		got := taskTexts(t, mngr, "u1", todolist.WithTags([]string{"work"}, false))
+		if diff := cmp.Diff(got, []string{"t1", "t2", "t3"}); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		err := mngr.DeleteTag(work.ID, "u2")
+		target := &todolist.TagNotFoundErr{}
+		if !errors.As(err, &target) {
+			t.Errorf("expected tag not found error, got: %v", err)
+		}
+		err = mngr.DeleteTag(work.ID, "u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := taskTexts(t, mngr, "u1", todolist.WithTags([]string{"work"}, false))
+		if len(got) != 0 {
+			t.Errorf("expected no tagged tasks, got %v", got)
+		}
+	})
+}
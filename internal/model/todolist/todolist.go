@@ -1,25 +1,58 @@
 package todolist
 
 import (
+	"errors"
 	"fmt"
+	"github.com/go-bumbu/todo-app/internal/blobstore"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 	"time"
 )
 
 type Manager struct {
-	db *gorm.DB
+	db  *gorm.DB
+	fts bool // full text search is available
+
+	blobs blobstore.Store // content of the attachments, nil if attachments are disabled
+	quota int64           // bytes every owner can store in attachments, 0 is unlimited
+
+	audit Audit // actor of the changes recorded in the history, see WithAudit
 }
 
 func New(db *gorm.DB) (*Manager, error) {
 	// Migrate the schema
-	err := db.AutoMigrate(&TodoItem{})
+	err := db.AutoMigrate(&TodoItem{}, &TodoList{}, &Tag{}, &Status{}, &Dependency{}, &Attachment{}, &Comment{},
+		&HistoryEntry{}, &ListMember{}, &Workspace{}, &WorkspaceMember{}, &ShareLink{}, &SavedFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	// tags were unique per owner before workspaces existed
+	if db.Migrator().HasIndex(&Tag{}, "idx_tag_owner_name") {
+		err = db.Migrator().DropIndex(&Tag{}, "idx_tag_owner_name")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = registerWorkspaceScope(db)
+	if err != nil {
+		return nil, err
+	}
+
+	err = rebalanceUnranked(db)
+	if err != nil {
+		return nil, err
+	}
+
+	fts, err := setupSearch(db)
 	if err != nil {
 		return nil, err
 	}
 
 	m := Manager{
-		db: db,
+		db:  withWorkspace(db, ""),
+		fts: fts,
 	}
 	return &m, nil
 }
@@ -27,8 +60,49 @@ func New(db *gorm.DB) (*Manager, error) {
 type TodoItem struct {
 	ID      string `gorm:"primaryKey,index"`
 	OwnerId string `gorm:"index"`
-	Text    string
-	Done    bool
+	// WorkspaceId is the workspace the task belongs to, it is set by the manager, see Manager.InWorkspace
+	WorkspaceId string `gorm:"index;not null;default:''"`
+	ListId      string `gorm:"index"`
+	Text        string
+	// Notes is the long form description of the task in markdown, see the markdown package
+	Notes string
+	// Done is derived from the status, it is true if the task is in a done status
+	Done     bool
+	StatusId string `gorm:"index"`
+
+	Priority Priority `gorm:"index"`
+	// Important and Urgent place the task in one of the quadrants of the Eisenhower matrix
+	Important bool
+	Urgent    bool
+
+	// ParentId links a subtask to its parent task, it is empty for top level tasks
+	ParentId string `gorm:"index"`
+
+	// AssigneeId is the user responsible for the task, either the owner of the list or one of its members
+	AssigneeId string `gorm:"index"`
+
+	// DueDate and StartDate are stored in UTC, dates without time of day are stored as midnight UTC
+	DueDate      *time.Time `gorm:"index"`
+	DueHasTime   bool
+	StartDate    *time.Time
+	StartHasTime bool
+	TimeZone     string // IANA name of the time zone the user created the dates in
+
+	// Recurrence is the rule used to create the next occurrence when the task is completed, see Recurrence
+	Recurrence string
+	// SeriesId links all the occurrences of a recurring task, it is the id of the first occurrence
+	SeriesId string `gorm:"index"`
+
+	// Position is the rank of the task in the owner's manual order, see rankBetween
+	Position string `gorm:"index"`
+
+	Tags []Tag `gorm:"many2many:task_tags;"`
+	// BlockedBy are the ids of the tasks that have to be done first, it is only read by Create,
+	// use Manager.Dependencies to load them
+	BlockedBy []string `gorm:"-"`
+
+	// Version is increased with every change to the task, it is used to detect concurrent updates
+	Version int64 `gorm:"not null;default:1"`
 
 	CreatedAt time.Time
 	UpdatedAt time.Time
@@ -38,9 +112,24 @@ type TodoItem struct {
 func (user *TodoItem) BeforeCreate(db *gorm.DB) (err error) {
 	// UUID version 4
 	user.ID = uuid.NewString()
+	user.Version = 1
 	return
 }
 
+func (user *TodoItem) AfterCreate(db *gorm.DB) (err error) {
+	if user.Position != "" {
+		return nil
+	}
+	// new tasks are added at the end of the manual order, this happens after the insert so that
+	// the transaction already holds the write lock when reading the other positions
+	tx := db.Session(&gorm.Session{NewDB: true})
+	user.Position, err = appendPosition(tx, user.OwnerId)
+	if err != nil {
+		return err
+	}
+	return tx.Model(&TodoItem{}).Where("id = ?", user.ID).UpdateColumn("position", user.Position).Error
+}
+
 type ItemNotFountErr struct {
 	id    string
 	owner string
@@ -50,74 +139,512 @@ func (m *ItemNotFountErr) Error() string {
 	return fmt.Sprintf("task with id: %s and owner %s not found", m.id, m.owner)
 }
 
-func (m Manager) List(owner string, size, page int) ([]TodoItem, error) {
-	if size <= 0 {
-		size = 20
+// ErrVersionMismatch is returned when a task was changed since the version passed to an update or delete
+var ErrVersionMismatch = errors.New("the task was modified by another request")
+
+// bumpVersion increases the version of the task, if version is not nil the task is only changed if it still has
+// that version. It is the first write of the mutations so that the transaction takes the write lock early.
+func bumpVersion(tx *gorm.DB, id, owner string, version *int64) error {
+	q := tx.Model(&TodoItem{}).Where("ID = ? AND owner_id = ?", id, owner)
+	if version != nil {
+		q = q.Where("version = ?", *version)
+	}
+	result := q.UpdateColumn("version", gorm.Expr("version + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		return nil
 	}
-	if size >= 50 {
-		size = 50
+	if version != nil {
+		var count int64
+		err := tx.Model(&TodoItem{}).Where("ID = ? AND owner_id = ?", id, owner).Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrVersionMismatch
+		}
 	}
+	return &ItemNotFountErr{id: id, owner: owner}
+}
 
+func preloadTags(db *gorm.DB) *gorm.DB {
+	return db.Preload("Tags", func(db *gorm.DB) *gorm.DB {
+		return db.Order("name")
+	})
+}
+
+// defaultOrder sorts the tasks by their manual position, it has to be the last scope so that sort scopes
+// take precedence and it is only used as tiebreaker
+func defaultOrder(db *gorm.DB) *gorm.DB {
+	return db.Order("position").Order("id")
+}
+
+// Scope is a composable query condition that can be passed to List to narrow down the returned tasks
+type Scope func(db *gorm.DB) *gorm.DB
+
+// InList limits the tasks to the ones belonging to the list with the given id
+func InList(listId string) Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("list_id = ?", listId)
+	}
+}
+
+func (m Manager) List(owner string, size, page int, scopes ...Scope) ([]TodoItem, error) {
+	size = pageSize(size)
 	offset := size * (page - 1)
 	if offset <= 0 {
 		offset = 0
 	}
-	tasks := make([]TodoItem, size)
-	result := m.db.Where("owner_id = ?", owner).Model(&TodoItem{}).Offset(offset).Limit(size).Find(&tasks)
+	tasks := []TodoItem{}
+	db := m.db.Where("owner_id = ?", owner).Model(&TodoItem{})
+	for _, scope := range scopes {
+		db = db.Scopes(scope)
+	}
+	result := db.Scopes(preloadTags, defaultOrder).Offset(offset).Limit(size).Find(&tasks)
 	if result.Error != nil {
 		return nil, result.Error
 	}
 	return tasks, nil
 }
 
+// Create stores a new task, if no list is set the task is added to the owner's inbox,
+// subtasks are always stored in the list of their parent. Tasks created by an editor in a shared list
+// belong to the owner of the list.
 func (m Manager) Create(task *TodoItem) (string, error) {
+	err := authorizeBlockers(m.db, task.OwnerId, task.BlockedBy, nil)
+	if err != nil {
+		return "", err
+	}
+	if task.ParentId != "" {
+		owner, err := authorize(m.db, task.ParentId, task.OwnerId, RoleEditor)
+		if err != nil {
+			return "", err
+		}
+		task.OwnerId = owner
+		parent, err := m.Get(task.ParentId, task.OwnerId)
+		if err != nil {
+			return "", err
+		}
+		task.ListId = parent.ListId
+	} else if task.ListId == "" {
+		inbox, err := m.Inbox(task.OwnerId)
+		if err != nil {
+			return "", err
+		}
+		task.ListId = inbox.ID
+	} else {
+		list, role, err := listRole(m.db, task.ListId, task.OwnerId)
+		if err != nil {
+			return "", err
+		}
+		if !role.allows(RoleEditor) {
+			return "", ErrPermissionDenied
+		}
+		task.OwnerId = list.OwnerId
+	}
+	if task.AssigneeId != "" {
+		err := checkAssignee(m.db, task.ListId, task.OwnerId, task.AssigneeId)
+		if err != nil {
+			return "", err
+		}
+	}
 
-	result := m.db.Create(task)
-	if result.Error != nil {
-		return "", result.Error
+	recurrence, err := canonicalRecurrence(task.Recurrence)
+	if err != nil {
+		return "", err
+	}
+	task.Recurrence = recurrence
+
+	// the status is resolved before the transaction, that only writes, to avoid lock upgrades in sqlite
+	var status Status
+	if task.StatusId != "" {
+		status, err = getStatus(m.db, task.StatusId, task.OwnerId)
+	} else {
+		status, err = defaultStatus(m.db, task.OwnerId, task.Done)
+	}
+	if err != nil {
+		return "", err
+	}
+	task.StatusId, task.Done = status.ID, status.Done
+
+	task.DueDate = normalizeDate(task.DueDate, task.DueHasTime)
+	task.StartDate = normalizeDate(task.StartDate, task.StartHasTime)
+	err = m.db.Transaction(func(tx *gorm.DB) error {
+		return m.track(tx, ActionCreate, nil, func() ([]string, error) {
+			// tags are matched by name, the ones that don't exist yet are created for the owner
+			tags, err := resolveTags(tx, task.OwnerId, task.TagNames())
+			if err != nil {
+				return nil, err
+			}
+			task.Tags = tags
+			err = tx.Omit("Tags.*").Create(task).Error
+			if err != nil {
+				return nil, err
+			}
+			if len(task.BlockedBy) == 0 {
+				return []string{task.ID}, nil
+			}
+			return []string{task.ID}, setDependencies(tx, task.ID, task.OwnerId, task.BlockedBy)
+		})
+	})
+	if err != nil {
+		return "", err
 	}
 	return task.ID, nil
 }
 
-func (m Manager) Get(id, owner string) (TodoItem, error) {
+// Get returns a task of the user or a task of a list shared with the user
+func (m Manager) Get(id, user string) (TodoItem, error) {
 	t := TodoItem{}
-	result := m.db.First(&t, "ID = ? AND owner_id = ?", id, owner)
+	owner, err := authorize(m.db, id, user, RoleViewer)
+	if err != nil {
+		return t, err
+	}
+	result := m.db.Scopes(preloadTags).First(&t, "ID = ? AND owner_id = ?", id, owner)
 	if result.RowsAffected == 0 {
 		return t, &ItemNotFountErr{id: id, owner: owner}
 	}
 	return t, nil
 }
 
-func (m Manager) Update(id, owner, text string, done *bool) error {
+// TaskUpdate holds the fields of a task that can be changed, nil values are left untouched
+type TaskUpdate struct {
+	Text  *string
+	Notes *string
+	// Done moves the task into the first open or done status, unless StatusId is set as well
+	Done *bool
+	// StatusId moves the task into the status, an empty string moves it into the first open or done status
+	StatusId  *string
+	Priority  *Priority
+	Important *bool
+	Urgent    *bool
+	Due       *Date
+	Start     *Date
+	TimeZone  *string
+	Tags      *[]string // replaces all the tags of the task
+	ParentId  *string   // an empty string makes the task a top level task
+	// ListId moves a top level task together with its subtasks into the list, an empty string moves it
+	// into the inbox. Subtasks always stay in the list of their parent.
+	ListId *string
+	// Recurrence replaces the recurrence rule, an empty string stops the recurrence
+	Recurrence *string
+	BlockedBy  *[]string // replaces all the blockers of the task
+	// AssigneeId assigns the task to a collaborator of its list, an empty string removes the assignee
+	AssigneeId *string
 
-	fieldMap := map[string]any{}
-	if text != "" {
-		fieldMap["text"] = text
+	// CompleteSubtasks marks all the subtasks as done as well when Done is set to true
+	CompleteSubtasks bool
+	// IgnoreBlockers allows completing a task that is waiting for blockers which are not done,
+	// without it such an update fails with ErrTaskBlocked
+	IgnoreBlockers bool
+	// IfVersion makes the update fail with ErrVersionMismatch unless the task still has this version
+	IfVersion *int64
+}
+
+// Update applies the changes to the task, completing a recurring task creates its next occurrence.
+// Tasks of shared lists can be changed by members with the editor role, the assignee of a task can
+// complete and reopen it with any role.
+func (m Manager) Update(id, user string, upd TaskUpdate) error {
+	owner, err := authorizeUpdate(m.db, id, user, upd)
+	if err != nil {
+		return err
 	}
-	if done != nil {
-		fieldMap["done"] = *done
+	err = authorizeTargets(m.db, id, user, owner, upd)
+	if err != nil {
+		return err
 	}
+	return m.update(id, owner, upd, ActionUpdate)
+}
 
-	t := TodoItem{}
-	result := m.db.Model(&t).
-		Where("ID = ? AND owner_id = ?", id, owner).
-		Updates(fieldMap)
+// update applies the changes to the task and records them in the history with the given action
+func (m Manager) update(id, owner string, upd TaskUpdate, action string) error {
+	fieldMap, err := updateFields(upd)
+	if err != nil {
+		return err
+	}
+	// the list is resolved before the transaction, as the inbox might have to be created
+	if upd.ListId != nil {
+		listId, err := m.resolveList(*upd.ListId, owner)
+		if err != nil {
+			return err
+		}
+		upd.ListId = &listId
+	}
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		return m.updateTx(tx, id, owner, upd, fieldMap, action)
+	})
+}
 
-	if result.RowsAffected == 0 {
-		return &ItemNotFountErr{id: id, owner: owner}
+// updateFields maps the changes to the columns that are written as they are
+func updateFields(upd TaskUpdate) (map[string]any, error) {
+	fieldMap := map[string]any{}
+	if upd.Text != nil {
+		fieldMap["text"] = *upd.Text
+	}
+	if upd.Notes != nil {
+		fieldMap["notes"] = *upd.Notes
+	}
+	if upd.Priority != nil {
+		fieldMap["priority"] = *upd.Priority
+	}
+	if upd.Important != nil {
+		fieldMap["important"] = *upd.Important
+	}
+	if upd.Urgent != nil {
+		fieldMap["urgent"] = *upd.Urgent
+	}
+	if upd.Due != nil {
+		fieldMap["due_date"] = upd.Due.value()
+		fieldMap["due_has_time"] = upd.Due.hasTime()
+	}
+	if upd.Start != nil {
+		fieldMap["start_date"] = upd.Start.value()
+		fieldMap["start_has_time"] = upd.Start.hasTime()
+	}
+	if upd.TimeZone != nil {
+		fieldMap["time_zone"] = *upd.TimeZone
 	}
-	return nil
+	if upd.Recurrence != nil {
+		recurrence, err := canonicalRecurrence(*upd.Recurrence)
+		if err != nil {
+			return nil, err
+		}
+		fieldMap["recurrence"] = recurrence
+	}
+	return fieldMap, nil
 }
 
-func (m Manager) Delete(id, owner string) error {
+// resolveList returns the id of the list a task is moved to, an empty id is the inbox of the owner
+func (m Manager) resolveList(listId, owner string) (string, error) {
+	if listId == "" {
+		inbox, err := m.Inbox(owner)
+		if err != nil {
+			return "", err
+		}
+		return inbox.ID, nil
+	}
+	_, err := m.GetList(listId, owner)
+	if err != nil {
+		return "", err
+	}
+	return listId, nil
+}
 
-	t := TodoItem{}
-	result := m.db.Where("ID = ? AND owner_id = ?", id, owner).Delete(&t)
+// updateTx applies the changes to the task within the transaction and records them in the history
+func (m Manager) updateTx(tx *gorm.DB, id, owner string, upd TaskUpdate, fieldMap map[string]any, action string) error {
+	// subtasks are changed as well when they are completed together with the task or moved to another list
+	ids := []string{id}
+	if upd.CompleteSubtasks || upd.ParentId != nil || upd.ListId != nil {
+		descendants, err := descendantIds(tx, id, owner)
+		if err != nil {
+			return err
+		}
+		ids = append(ids, descendants...)
+	}
+	return m.track(tx, action, ids, func() ([]string, error) {
+		return applyUpdate(tx, id, owner, upd, fieldMap)
+	})
+}
 
+// applyUpdate writes the changes to the task, it returns the id of the next occurrence if one was created
+func applyUpdate(tx *gorm.DB, id, owner string, upd TaskUpdate, fieldMap map[string]any) ([]string, error) {
+	err := bumpVersion(tx, id, owner, upd.IfVersion)
+	if err != nil {
+		return nil, err
+	}
+	t := TodoItem{}
+	result := tx.Where("ID = ? AND owner_id = ?", id, owner).Limit(1).Find(&t)
+	if result.Error != nil {
+		return nil, result.Error
+	}
 	if result.RowsAffected == 0 {
-		return &ItemNotFountErr{id: id, owner: owner}
+		return nil, &ItemNotFountErr{id: id, owner: owner}
+	}
+	wasDone := t.Done
+
+	// the done value always follows the status
+	var status *Status
+	if upd.StatusId != nil && *upd.StatusId == "" {
+		done := t.Done
+		if upd.Done != nil {
+			done = *upd.Done
+		}
+		s, err := defaultStatus(tx, owner, done)
+		if err != nil {
+			return nil, err
+		}
+		status = &s
+	} else if upd.StatusId != nil {
+		s, err := getStatus(tx, *upd.StatusId, owner)
+		if err != nil {
+			return nil, err
+		}
+		status = &s
+	} else if upd.Done != nil && (*upd.Done != t.Done || t.StatusId == "") {
+		s, err := defaultStatus(tx, owner, *upd.Done)
+		if err != nil {
+			return nil, err
+		}
+		status = &s
+	}
+	if status != nil {
+		fieldMap["status_id"] = status.ID
+		fieldMap["done"] = status.Done
+	}
+	isDone := t.Done
+	if status != nil {
+		isDone = status.Done
+	}
+
+	if len(fieldMap) > 0 {
+		err := tx.Model(&t).Updates(fieldMap).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if upd.Tags != nil {
+		tags, err := resolveTags(tx, owner, *upd.Tags)
+		if err != nil {
+			return nil, err
+		}
+		err = tx.Model(&t).Omit("Tags.*").Association("Tags").Replace(tags)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if upd.ParentId != nil && *upd.ParentId != t.ParentId {
+		err := setParent(tx, id, owner, *upd.ParentId)
+		if err != nil {
+			return nil, err
+		}
 	}
-	return nil
+
+	if upd.ListId != nil {
+		err := moveToList(tx, id, owner, *upd.ListId)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if upd.BlockedBy != nil {
+		err := setDependencies(tx, id, owner, *upd.BlockedBy)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if upd.AssigneeId != nil && *upd.AssigneeId != t.AssigneeId {
+		// the assignee is checked against the list the task is in after the update
+		listId := t.ListId
+		if upd.ListId != nil {
+			listId = *upd.ListId
+		}
+		if *upd.AssigneeId != "" {
+			err := checkAssignee(tx, listId, owner, *upd.AssigneeId)
+			if err != nil {
+				return nil, err
+			}
+		}
+		err := tx.Model(&t).Update("assignee_id", *upd.AssigneeId).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+	if isDone && !wasDone && !upd.IgnoreBlockers {
+		err := checkBlockers(tx, id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if isDone && upd.CompleteSubtasks && (upd.Done != nil || upd.StatusId != nil) {
+		s, err := defaultStatus(tx, owner, true)
+		if err != nil {
+			return nil, err
+		}
+		err = completeSubtasks(tx, id, owner, s)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if isDone && !wasDone {
+		// reload to generate the next occurrence from the updated values
+		completed := TodoItem{}
+		err := tx.Scopes(preloadTags).First(&completed, "ID = ?", id).Error
+		if err != nil {
+			return nil, err
+		}
+		if completed.Recurrence != "" {
+			next, err := scheduleNext(tx, completed, time.Now())
+			return []string{next}, err
+		}
+	}
+	return nil, nil
+}
+
+// Delete removes the task together with all its subtasks
+func (m Manager) Delete(id, user string) error {
+	owner, err := authorize(m.db, id, user, RoleEditor)
+	if err != nil {
+		return err
+	}
+	return m.delete(id, owner, nil)
+}
+
+// DeleteVersion deletes the task like Delete, or like DeleteKeepSubtasks if keepSubtasks is true, but fails
+// with ErrVersionMismatch if the task was changed since the given version
+func (m Manager) DeleteVersion(id, user string, version int64, keepSubtasks bool) error {
+	owner, err := authorize(m.db, id, user, RoleEditor)
+	if err != nil {
+		return err
+	}
+	if keepSubtasks {
+		return m.deleteKeepSubtasks(id, owner, &version)
+	}
+	return m.delete(id, owner, &version)
+}
+
+func (m Manager) delete(id, owner string, version *int64) error {
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		_, err := m.deleteTx(tx, id, owner, version)
+		return err
+	})
 }
 
-// TODO, hard delete
+// deleteTx removes the task and its subtasks within the transaction, it returns the ids of the removed tasks
+func (m Manager) deleteTx(tx *gorm.DB, id, owner string, version *int64) ([]string, error) {
+	err := bumpVersion(tx, id, owner, version)
+	if err != nil {
+		return nil, err
+	}
+	descendants, err := descendantIds(tx, id, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := append([]string{id}, descendants...)
+	err = m.track(tx, ActionDelete, ids, func() ([]string, error) {
+		t := TodoItem{}
+		result := tx.Where("ID = ? AND owner_id = ?", id, owner).Delete(&t)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil, &ItemNotFountErr{id: id, owner: owner}
+		}
+
+		if len(descendants) == 0 {
+			return nil, nil
+		}
+		return nil, tx.Where("ID IN ? AND owner_id = ?", descendants, owner).Delete(&TodoItem{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
@@ -140,6 +140,77 @@ func TestCrudTask(t *testing.T) {
 
 }
 
+func TestTaskVersion(t *testing.T) {
+	mngr := testManager(t)
+	id := createTask(t, mngr, "report", "u1")
+	version := func(t *testing.T) int64 {
+		t.Helper()
+		task, err := mngr.Get(id, "u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return task.Version
+	}
+	if got := version(t); got != 1 {
+		t.Fatalf("unexpected version of a new task: %d", got)
+	}
+
+	text := "final report"
+	v := int64(1)
+	err := mngr.Update(id, "u1", todolist.TaskUpdate{Text: &text, IfVersion: &v})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := version(t); got != 2 {
+		t.Errorf("unexpected version after update: %d", got)
+	}
+
+	t.Run("stale version", func(t *testing.T) {
+		other := "other"
+		err := mngr.Update(id, "u1", todolist.TaskUpdate{Text: &other, IfVersion: &v})
+		if !errors.Is(err, todolist.ErrVersionMismatch) {
+			t.Errorf("expected version mismatch, got: %v", err)
+		}
+		readTask(t, mngr, id, "u1", "final report", "")
+
+		err = mngr.DeleteVersion(id, "u1", v, false)
+		if !errors.Is(err, todolist.ErrVersionMismatch) {
+			t.Errorf("expected version mismatch, got: %v", err)
+		}
+	})
+
+	t.Run("other owner", func(t *testing.T) {
+		err := mngr.Update(id, "u2", todolist.TaskUpdate{Text: &text, IfVersion: &v})
+		target := &todolist.ItemNotFountErr{}
+		if !errors.As(err, &target) {
+			t.Errorf("expected not found error, got: %v", err)
+		}
+	})
+
+	t.Run("other changes increase the version", func(t *testing.T) {
+		before := version(t)
+		setDone(t, mngr, id, "u1", true, "")
+		notes := "- [ ] draft"
+		err := mngr.Update(id, "u1", todolist.TaskUpdate{Notes: &notes})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = mngr.SetCheckbox(id, "u1", 0, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := version(t); got != before+3 {
+			t.Errorf("unexpected version: got %d want %d", got, before+3)
+		}
+	})
+
+	err = mngr.DeleteVersion(id, "u1", version(t), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	readTask(t, mngr, id, "u1", "", fmt.Sprintf("task with id: %s and owner u1 not found", id))
+}
+
 func createTask(t *testing.T, mngr *todolist.Manager, content, owner string) string {
 	task := todolist.TodoItem{
 		Text:    content,
@@ -170,7 +241,7 @@ func readTask(t *testing.T, mngr *todolist.Manager, taskId, owner, want string,
 
 func setDone(t *testing.T, mngr *todolist.Manager, taskId, owner string, val bool, wantErr string) {
 	var err error
-	err = mngr.Update(taskId, owner, "", &val)
+	err = mngr.Update(taskId, owner, todolist.TaskUpdate{Done: &val})
 	if err != nil {
 		if wantErr != err.Error() {
 			t.Errorf("wanted error:\"%s\", but got: \"%s\"", wantErr, err.Error())
@@ -187,7 +258,7 @@ func setDone(t *testing.T, mngr *todolist.Manager, taskId, owner string, val boo
 }
 
 func setText(t *testing.T, mngr *todolist.Manager, taskId, owner, text, wantErr string) {
-	err := mngr.Update(taskId, owner, text, nil)
+	err := mngr.Update(taskId, owner, todolist.TaskUpdate{Text: &text})
 	if err != nil {
 		if wantErr != err.Error() {
 			t.Errorf("wanted error:\"%s\", but got: \"%s\"", wantErr, err.Error())
@@ -204,7 +275,7 @@ func setText(t *testing.T, mngr *todolist.Manager, taskId, owner, text, wantErr
 }
 
 func setMultiple(t *testing.T, mngr *todolist.Manager, taskId, owner string, input todolist.TodoItem, wantErr string) {
-	err := mngr.Update(taskId, owner, input.Text, &input.Done)
+	err := mngr.Update(taskId, owner, todolist.TaskUpdate{Text: &input.Text, Done: &input.Done})
 	if err != nil {
 		if wantErr != err.Error() {
 			t.Errorf("wanted error:\"%s\", but got: \"%s\"", wantErr, err.Error())
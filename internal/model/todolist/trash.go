@@ -0,0 +1,212 @@
+package todolist
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// deletedTreeCte is a recursive query returning the ids of the task and of its descendants that were
+// deleted together with it or later, subtasks deleted on their own before the task are not included
+const deletedTreeCte = `WITH RECURSIVE tree(id, deleted_at) AS (
+	SELECT id, deleted_at FROM todo_items WHERE id = ? AND owner_id = ? AND deleted_at IS NOT NULL
+	UNION ALL
+	SELECT t.id, t.deleted_at FROM todo_items t JOIN tree ON t.parent_id = tree.id WHERE t.deleted_at >= ?
+) `
+
+// Trash returns the deleted tasks of the owner, the most recently deleted first
+func (m Manager) Trash(owner string, size, page int) ([]TodoItem, error) {
+	size = pageSize(size)
+	offset := size * (page - 1)
+	if offset <= 0 {
+		offset = 0
+	}
+	tasks := []TodoItem{}
+	result := m.db.Unscoped().Scopes(preloadTags).
+		Where("owner_id = ? AND deleted_at IS NOT NULL", owner).
+		Order("deleted_at DESC").Order("id").
+		Offset(offset).Limit(size).Find(&tasks)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return tasks, nil
+}
+
+// trashedTree returns the deleted task and its descendants that were deleted with it
+func trashedTree(tx *gorm.DB, id, owner string) (TodoItem, []string, error) {
+	t := TodoItem{}
+	result := tx.Unscoped().Where("ID = ? AND owner_id = ? AND deleted_at IS NOT NULL", id, owner).Limit(1).Find(&t)
+	if result.Error != nil {
+		return t, nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return t, nil, &ItemNotFountErr{id: id, owner: owner}
+	}
+	ids := []string{}
+	err := tx.Raw(deletedTreeCte+"SELECT id FROM tree", id, owner, t.DeletedAt.Time).Scan(&ids).Error
+	return t, ids, err
+}
+
+// Restore recovers a deleted task together with the subtasks that were deleted with it. If the parent
+// task is still deleted the task is restored as top level task, if its list was deleted it is moved to the inbox.
+func (m Manager) Restore(id, owner string) error {
+	inbox, err := m.Inbox(owner)
+	if err != nil {
+		return err
+	}
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		t, ids, err := trashedTree(tx, id, owner)
+		if err != nil {
+			return err
+		}
+		return m.track(tx, ActionRestore, ids, func() ([]string, error) {
+			err = tx.Unscoped().Model(&TodoItem{}).Where("id IN ?", ids).
+				Updates(map[string]any{"deleted_at": nil, "version": gorm.Expr("version + 1")}).Error
+			if err != nil {
+				return nil, err
+			}
+
+			if t.ParentId != "" {
+				var parents int64
+				err = tx.Model(&TodoItem{}).Where("ID = ? AND owner_id = ?", t.ParentId, owner).Count(&parents).Error
+				if err != nil {
+					return nil, err
+				}
+				if parents == 0 {
+					err = tx.Model(&TodoItem{}).Where("ID = ?", id).Update("parent_id", "").Error
+					if err != nil {
+						return nil, err
+					}
+				}
+			}
+			return nil, moveOrphansToInbox(tx, owner, inbox.ID)
+		})
+	})
+}
+
+// RestoreAll recovers all the deleted tasks of the owner
+func (m Manager) RestoreAll(owner string) error {
+	inbox, err := m.Inbox(owner)
+	if err != nil {
+		return err
+	}
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		ids := []string{}
+		err := tx.Unscoped().Model(&TodoItem{}).
+			Where("owner_id = ? AND deleted_at IS NOT NULL", owner).
+			Pluck("id", &ids).Error
+		if err != nil {
+			return err
+		}
+		return m.track(tx, ActionRestore, ids, func() ([]string, error) {
+			err := tx.Unscoped().Model(&TodoItem{}).Where("id IN ?", ids).
+				Updates(map[string]any{"deleted_at": nil, "version": gorm.Expr("version + 1")}).Error
+			if err != nil {
+				return nil, err
+			}
+			// subtasks of tasks purged in the meantime become top level tasks
+			err = tx.Model(&TodoItem{}).
+				Where("owner_id = ? AND parent_id != '' AND parent_id NOT IN (?)", owner,
+					tx.Model(&TodoItem{}).Select("id").Where("owner_id = ? AND deleted_at IS NULL", owner)).
+				Update("parent_id", "").Error
+			if err != nil {
+				return nil, err
+			}
+			return nil, moveOrphansToInbox(tx, owner, inbox.ID)
+		})
+	})
+}
+
+// moveOrphansToInbox moves the tasks that belong to a deleted list to the inbox
+func moveOrphansToInbox(tx *gorm.DB, owner, inboxId string) error {
+	return tx.Model(&TodoItem{}).
+		Where("owner_id = ? AND list_id NOT IN (?)", owner,
+			tx.Model(&TodoList{}).Select("id").Where("owner_id = ? AND deleted_at IS NULL", owner)).
+		Update("list_id", inboxId).Error
+}
+
+// Purge permanently deletes a task from the trash together with the subtasks deleted with it
+func (m Manager) Purge(id, owner string) error {
+	_, err := m.purgeTasks(func(tx *gorm.DB) ([]string, error) {
+		_, ids, err := trashedTree(tx, id, owner)
+		return ids, err
+	})
+	return err
+}
+
+// EmptyTrash permanently deletes all the deleted tasks of the owner
+func (m Manager) EmptyTrash(owner string) error {
+	_, err := m.purgeTasks(func(tx *gorm.DB) ([]string, error) {
+		ids := []string{}
+		err := tx.Unscoped().Model(&TodoItem{}).
+			Where("owner_id = ? AND deleted_at IS NOT NULL", owner).
+			Pluck("id", &ids).Error
+		return ids, err
+	})
+	return err
+}
+
+// PurgeTrash permanently deletes the tasks of all users and workspaces that were deleted before t,
+// it returns the amount of removed tasks
+func (m Manager) PurgeTrash(before time.Time) (int, error) {
+	m.db = allWorkspaces(m.db)
+	return m.purgeTasks(func(tx *gorm.DB) ([]string, error) {
+		ids := []string{}
+		err := tx.Unscoped().Model(&TodoItem{}).
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", before).
+			Pluck("id", &ids).Error
+		return ids, err
+	})
+}
+
+// purgeTasks permanently deletes the tasks returned by find in a single transaction, the content of their
+// attachments is removed once the transaction is committed as it cannot be rolled back
+func (m Manager) purgeTasks(find func(tx *gorm.DB) ([]string, error)) (int, error) {
+	var ids, blobs []string
+	err := m.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		ids, err = find(tx)
+		if err != nil {
+			return err
+		}
+		blobs, err = hardDelete(tx, ids)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(ids), m.deleteBlobs(blobs)
+}
+
+// hardDelete removes the tasks together with their tag associations, dependencies, comments, history and attachments from
+// the DB, it returns the ids of the attachments whose content has to be removed from the blob store
+func hardDelete(tx *gorm.DB, ids []string) ([]string, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	err := tx.Exec("DELETE FROM "+tagJoinTable+" WHERE todo_item_id IN ?", ids).Error
+	if err != nil {
+		return nil, err
+	}
+	err = tx.Where("task_id IN ? OR blocker_id IN ?", ids, ids).Delete(&Dependency{}).Error
+	if err != nil {
+		return nil, err
+	}
+	err = tx.Where("task_id IN ?", ids).Delete(&Comment{}).Error
+	if err != nil {
+		return nil, err
+	}
+	err = tx.Where("task_id IN ?", ids).Delete(&HistoryEntry{}).Error
+	if err != nil {
+		return nil, err
+	}
+	blobs := []string{}
+	err = tx.Model(&Attachment{}).Where("task_id IN ?", ids).Pluck("id", &blobs).Error
+	if err != nil {
+		return nil, err
+	}
+	err = tx.Where("task_id IN ?", ids).Delete(&Attachment{}).Error
+	if err != nil {
+		return nil, err
+	}
+	return blobs, tx.Unscoped().Where("id IN ?", ids).Delete(&TodoItem{}).Error
+}
@@ -0,0 +1,183 @@
+package todolist_test
+
+import (
+	"errors"
+	"github.com/go-bumbu/todo-app/internal/model/todolist"
+	"github.com/google/go-cmp/cmp"
+	"testing"
+	"time"
+)
+
+func trashTexts(t *testing.T, mngr *todolist.Manager, owner string) []string {
+	t.Helper()
+	items, err := mngr.Trash(owner, 50, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, item := range items {
+		got = append(got, item.Text)
+	}
+	return got
+}
+
+func TestTrash(t *testing.T) {
+	t.Run("list deleted tasks", func(t *testing.T) {
+		mngr := testManager(t)
+		ids := hierarchy(t, mngr)
+		other := createTask(t, mngr, "other user", "u2")
+		deleteTask(t, mngr, ids["hotel"], "u1", "")
+		deleteTask(t, mngr, other, "u2", "")
+
+		if diff := cmp.Diff(trashTexts(t, mngr, "u1"), []string{"book hotel"}); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+	})
+
+	t.Run("restore task with subtasks", func(t *testing.T) {
+		mngr := testManager(t)
+		ids := hierarchy(t, mngr)
+		deleteTask(t, mngr, ids["passport"], "u1", "")
+		deleteTask(t, mngr, ids["pack"], "u1", "")
+
+		err := mngr.Restore(ids["pack"], "u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// passport was deleted on its own before pack, it stays in the trash
+		got := taskTexts(t, mngr, "u1", todolist.ChildrenOf(ids["pack"]))
+		if diff := cmp.Diff(got, []string{"clothes"}); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+		if diff := cmp.Diff(trashTexts(t, mngr, "u1"), []string{"passport"}); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+	})
+
+	t.Run("restore subtask of deleted task", func(t *testing.T) {
+		mngr := testManager(t)
+		ids := hierarchy(t, mngr)
+		deleteTask(t, mngr, ids["trip"], "u1", "")
+
+		err := mngr.Restore(ids["pack"], "u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := taskTexts(t, mngr, "u1", todolist.RootTasks())
+		if diff := cmp.Diff(got, []string{"pack"}); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+	})
+
+	t.Run("restore task of deleted list", func(t *testing.T) {
+		mngr := testManager(t)
+		inbox, err := mngr.Inbox("u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		list := createList(t, mngr, "groceries", "u1")
+		task := todolist.TodoItem{Text: "milk", OwnerId: "u1", ListId: list}
+		id, err := mngr.Create(&task)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = mngr.DeleteList(list, "u1", true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = mngr.Restore(id, "u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := taskTexts(t, mngr, "u1", todolist.InList(inbox.ID))
+		if diff := cmp.Diff(got, []string{"milk"}); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+	})
+
+	t.Run("restore all", func(t *testing.T) {
+		mngr := testManager(t)
+		ids := hierarchy(t, mngr)
+		deleteTask(t, mngr, ids["clothes"], "u1", "")
+		deleteTask(t, mngr, ids["hotel"], "u1", "")
+
+		err := mngr.RestoreAll("u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := taskTexts(t, mngr, "u1")
+		if diff := cmp.Diff(got, []string{"trip", "pack", "clothes", "passport", "book hotel"}); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+	})
+
+	t.Run("task not in trash", func(t *testing.T) {
+		mngr := testManager(t)
+		id := createTask(t, mngr, "active", "u1")
+		for _, fn := range []func(string, string) error{mngr.Restore, mngr.Purge} {
+			err := fn(id, "u1")
+			target := &todolist.ItemNotFountErr{}
+			if !errors.As(err, &target) {
+				t.Errorf("expected item not found error, got: %v", err)
+			}
+		}
+	})
+
+	t.Run("purge and empty", func(t *testing.T) {
+		mngr := testManager(t)
+		ids := hierarchy(t, mngr)
+		deleteTask(t, mngr, ids["pack"], "u1", "")
+		deleteTask(t, mngr, ids["hotel"], "u1", "")
+
+		err := mngr.Purge(ids["pack"], "u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(trashTexts(t, mngr, "u1"), []string{"book hotel"}); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+
+		err = mngr.EmptyTrash("u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := trashTexts(t, mngr, "u1"); len(got) != 0 {
+			t.Errorf("expected empty trash, got %v", got)
+		}
+		if diff := cmp.Diff(taskTexts(t, mngr, "u1"), []string{"trip"}); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+	})
+
+	t.Run("purge expired tasks", func(t *testing.T) {
+		mngr := testManager(t)
+		old := createTask(t, mngr, "old", "u1")
+		other := createTask(t, mngr, "other user", "u2")
+		deleteTask(t, mngr, old, "u1", "")
+		deleteTask(t, mngr, other, "u2", "")
+		// the retention applies to all the workspaces
+		team := mngr.InWorkspace("8a3c7a52-0d4b-4a47-9a4c-8f0e1f4f8a10")
+		shared := createTask(t, &team, "team", "u1")
+		deleteTask(t, &team, shared, "u1", "")
+
+		n, err := mngr.PurgeTrash(time.Now().Add(-time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 0 {
+			t.Errorf("expected no purged tasks, got %d", n)
+		}
+
+		n, err = mngr.PurgeTrash(time.Now().Add(time.Second))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 3 {
+			t.Errorf("expected 3 purged tasks, got %d", n)
+		}
+		if got := trashTexts(t, mngr, "u1"); len(got) != 0 {
+			t.Errorf("expected empty trash, got %v", got)
+		}
+	})
+}
@@ -0,0 +1,389 @@
+package todolist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Workspace is shared by the users of a team. The lists, tasks, tags and statuses of a user in a workspace are
+// kept apart from the ones in other workspaces, see InWorkspace. Every user also has a personal workspace, it is
+// not stored and has an empty id.
+type Workspace struct {
+	ID        string `gorm:"primaryKey"`
+	Name      string
+	CreatedBy string
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (w *Workspace) BeforeCreate(db *gorm.DB) (err error) {
+	// UUID version 4
+	w.ID = uuid.NewString()
+	return
+}
+
+// WorkspaceRole is the role of a user in a workspace
+type WorkspaceRole string
+
+const (
+	// RoleWorkspaceMember can use the workspace and share lists with the other members
+	RoleWorkspaceMember WorkspaceRole = "member"
+	// RoleWorkspaceAdmin can also invite and remove members
+	RoleWorkspaceAdmin WorkspaceRole = "admin"
+)
+
+// ParseWorkspaceRole validates the name of a workspace role
+func ParseWorkspaceRole(s string) (WorkspaceRole, error) {
+	r := WorkspaceRole(s)
+	if r != RoleWorkspaceMember && r != RoleWorkspaceAdmin {
+		return "", fmt.Errorf("%w: %s", ErrInvalidWorkspaceRole, s)
+	}
+	return r, nil
+}
+
+// WorkspaceMember is the membership of a user in a workspace
+type WorkspaceMember struct {
+	WorkspaceId string `gorm:"primaryKey"`
+	UserId      string `gorm:"primaryKey;index"`
+	Role        WorkspaceRole
+	InvitedBy   string
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// UserWorkspace is a workspace together with the role of the user in it
+type UserWorkspace struct {
+	Workspace Workspace
+	Role      WorkspaceRole
+}
+
+type WorkspaceNotFoundErr struct {
+	id   string
+	user string
+}
+
+func (m *WorkspaceNotFoundErr) Error() string {
+	return fmt.Sprintf("workspace with id: %s and member %s not found", m.id, m.user)
+}
+
+type WorkspaceMemberNotFoundErr struct {
+	id   string
+	user string
+}
+
+func (m *WorkspaceMemberNotFoundErr) Error() string {
+	return fmt.Sprintf("user %s is not a member of the workspace with id: %s", m.user, m.id)
+}
+
+// ErrInvalidWorkspaceRole is returned when a workspace role is not one of member or admin
+var ErrInvalidWorkspaceRole = errors.New("workspace role must be one of: member, admin")
+
+// ErrNotWorkspaceAdmin is returned when a member that is not an admin tries to manage the members of a workspace
+var ErrNotWorkspaceAdmin = errors.New("only workspace admins can manage members")
+
+// ErrLastWorkspaceAdmin is returned when the last admin of a workspace would be removed or demoted
+var ErrLastWorkspaceAdmin = errors.New("a workspace needs at least one admin")
+
+// ErrEmptyWorkspaceName is returned when creating a workspace without name
+var ErrEmptyWorkspaceName = errors.New("workspace name cannot be empty")
+
+// ErrOtherWorkspace is returned when sharing a list with or assigning a task to a user of another workspace
+var ErrOtherWorkspace = errors.New("the user is not a member of the workspace of the list")
+
+type workspaceCtxKey struct{}
+
+// workspaceModels are the models whose rows belong to a workspace, the statements on their tables are limited to
+// the workspace of the manager by scopeWorkspace
+var workspaceModels = map[reflect.Type]bool{
+	reflect.TypeOf(TodoItem{}):    true,
+	reflect.TypeOf(TodoList{}):    true,
+	reflect.TypeOf(ListMember{}):  true,
+	reflect.TypeOf(Tag{}):         true,
+	reflect.TypeOf(Status{}):      true,
+	reflect.TypeOf(SavedFilter{}): true,
+}
+
+// InWorkspace returns a manager that reads and writes the data of the workspace, the personal workspace has an
+// empty id. The lists, tasks, tags, statuses and saved filters of a user are stored in the workspace they are
+// created in and are only visible through a manager of that workspace.
+func (m Manager) InWorkspace(id string) Manager {
+	m.db = withWorkspace(m.db, id)
+	return m
+}
+
+// withWorkspace returns a session whose statements are limited to the workspace
+func withWorkspace(db *gorm.DB, id string) *gorm.DB {
+	return db.WithContext(context.WithValue(db.Statement.Context, workspaceCtxKey{}, id))
+}
+
+// allWorkspaces returns a session whose statements are not limited to a workspace, it is only used for lookups
+// that are not done on behalf of a user, e.g. the public share links
+func allWorkspaces(db *gorm.DB) *gorm.DB {
+	return db.WithContext(context.Background())
+}
+
+// workspaceOf returns the workspace of the session, ok is false for sessions of all the workspaces
+func workspaceOf(db *gorm.DB) (id string, ok bool) {
+	id, ok = db.Statement.Context.Value(workspaceCtxKey{}).(string)
+	return id, ok
+}
+
+// registerWorkspaceScope adds the callbacks that keep the workspaces apart to all the statements of the db
+func registerWorkspaceScope(db *gorm.DB) error {
+	cb := db.Callback()
+	if cb.Query().Get("todolist:workspace") != nil {
+		return nil
+	}
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register("todolist:workspace", setWorkspace),
+		cb.Query().Before("gorm:query").Register("todolist:workspace", scopeWorkspace),
+		cb.Row().Before("gorm:row").Register("todolist:workspace", scopeWorkspace),
+		cb.Update().Before("gorm:update").Register("todolist:workspace", scopeWorkspace),
+		cb.Delete().Before("gorm:delete").Register("todolist:workspace", scopeWorkspace),
+	)
+}
+
+// inWorkspace reports whether the statement works on the rows of a workspace and returns the workspace
+func inWorkspace(db *gorm.DB) (string, bool) {
+	stmt := db.Statement
+	if db.Error != nil || stmt.Schema == nil || !workspaceModels[stmt.Schema.ModelType] {
+		return "", false
+	}
+	return workspaceOf(db)
+}
+
+// scopeWorkspace limits the statement to the rows of the workspace of the session, raw sql has to do it itself
+func scopeWorkspace(db *gorm.DB) {
+	ws, ok := inWorkspace(db)
+	if !ok || db.Statement.SQL.Len() > 0 {
+		return
+	}
+	col := clause.Column{Table: clause.CurrentTable, Name: "workspace_id"}
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{clause.Eq{Column: col, Value: ws}}})
+}
+
+// setWorkspace stores new rows in the workspace of the session
+func setWorkspace(db *gorm.DB) {
+	ws, ok := inWorkspace(db)
+	if !ok {
+		return
+	}
+	field := db.Statement.Schema.LookUpField("WorkspaceId")
+	rv := db.Statement.ReflectValue
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			db.AddError(field.Set(db.Statement.Context, reflect.Indirect(rv.Index(i)), ws))
+		}
+	case reflect.Struct:
+		db.AddError(field.Set(db.Statement.Context, rv, ws))
+	}
+}
+
+// CreateWorkspace stores a new workspace, the user creating it becomes its first admin
+func (m Manager) CreateWorkspace(name, user string) (Workspace, error) {
+	w := Workspace{Name: strings.TrimSpace(name), CreatedBy: user}
+	if w.Name == "" {
+		return w, ErrEmptyWorkspaceName
+	}
+	err := m.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Create(&w).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(&WorkspaceMember{WorkspaceId: w.ID, UserId: user, Role: RoleWorkspaceAdmin, InvitedBy: user}).Error
+	})
+	if err != nil {
+		return Workspace{}, err
+	}
+	return w, nil
+}
+
+// Workspaces returns the workspaces the user is a member of, the personal workspace is not included
+func (m Manager) Workspaces(user string) ([]UserWorkspace, error) {
+	memberships := []WorkspaceMember{}
+	err := m.db.Where("user_id = ?", user).Order("created_at").Find(&memberships).Error
+	if err != nil {
+		return nil, err
+	}
+	workspaces := []UserWorkspace{}
+	for _, membership := range memberships {
+		w := Workspace{}
+		result := m.db.Where("ID = ?", membership.WorkspaceId).Limit(1).Find(&w)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+		workspaces = append(workspaces, UserWorkspace{Workspace: w, Role: membership.Role})
+	}
+	return workspaces, nil
+}
+
+// GetWorkspace returns a workspace the user is a member of together with the role of the user.
+// The membership is checked on every call, so that removing a member takes effect immediately.
+func (m Manager) GetWorkspace(id, user string) (Workspace, WorkspaceRole, error) {
+	w := Workspace{}
+	role, err := workspaceRole(m.db, id, user)
+	if err != nil {
+		return w, "", err
+	}
+	if role == "" {
+		return w, "", &WorkspaceNotFoundErr{id: id, user: user}
+	}
+	result := m.db.Where("ID = ?", id).Limit(1).Find(&w)
+	if result.Error != nil {
+		return w, "", result.Error
+	}
+	if result.RowsAffected == 0 {
+		return w, "", &WorkspaceNotFoundErr{id: id, user: user}
+	}
+	return w, role, nil
+}
+
+// workspaceRole returns the role of the user in the workspace, an empty role if the user is not a member
+func workspaceRole(db *gorm.DB, id, user string) (WorkspaceRole, error) {
+	member := WorkspaceMember{}
+	err := db.Where("workspace_id = ? AND user_id = ?", id, user).Limit(1).Find(&member).Error
+	if err != nil {
+		return "", err
+	}
+	return member.Role, nil
+}
+
+// WorkspaceMembers returns the members of the workspace, every member can see them
+func (m Manager) WorkspaceMembers(id, user string) ([]WorkspaceMember, error) {
+	_, _, err := m.GetWorkspace(id, user)
+	if err != nil {
+		return nil, err
+	}
+	members := []WorkspaceMember{}
+	err = m.db.Where("workspace_id = ?", id).Order("created_at").Order("user_id").Find(&members).Error
+	if err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// AddWorkspaceMember adds a user to the workspace with the given role, or changes the role of an existing member.
+// Only admins can add members.
+func (m Manager) AddWorkspaceMember(id, user, invitee string, role WorkspaceRole) error {
+	if _, err := ParseWorkspaceRole(string(role)); err != nil {
+		return err
+	}
+	_, r, err := m.GetWorkspace(id, user)
+	if err != nil {
+		return err
+	}
+	if r != RoleWorkspaceAdmin {
+		return ErrNotWorkspaceAdmin
+	}
+
+	current, err := workspaceRole(m.db, id, invitee)
+	if err != nil {
+		return err
+	}
+	if current == "" {
+		return m.db.Create(&WorkspaceMember{WorkspaceId: id, UserId: invitee, Role: role, InvitedBy: user}).Error
+	}
+	if current == RoleWorkspaceAdmin && role != RoleWorkspaceAdmin {
+		if err := lastAdmin(m.db, id); err != nil {
+			return err
+		}
+	}
+	return m.db.Model(&WorkspaceMember{}).Where("workspace_id = ? AND user_id = ?", id, invitee).Update("role", role).Error
+}
+
+// lastAdmin returns ErrLastWorkspaceAdmin if the workspace has only one admin left
+func lastAdmin(db *gorm.DB, id string) error {
+	var admins int64
+	err := db.Model(&WorkspaceMember{}).Where("workspace_id = ? AND role = ?", id, RoleWorkspaceAdmin).Count(&admins).Error
+	if err != nil {
+		return err
+	}
+	if admins <= 1 {
+		return ErrLastWorkspaceAdmin
+	}
+	return nil
+}
+
+// RemoveWorkspaceMember removes a member from the workspace, admins can remove anyone and every member can leave.
+// The access of the member ends with it: the lists of the workspace shared with the member are revoked and the
+// tasks assigned to the member unassigned. The lists of the member stop being shared as well and their public
+// links are revoked together with the links the member created, nobody could manage them anymore. The lists and
+// tasks of the member stay in the workspace and are available again if the user is added back.
+func (m Manager) RemoveWorkspaceMember(id, user, member string) error {
+	_, r, err := m.GetWorkspace(id, user)
+	if err != nil {
+		return err
+	}
+	if member != user && r != RoleWorkspaceAdmin {
+		return ErrNotWorkspaceAdmin
+	}
+	current, err := workspaceRole(m.db, id, member)
+	if err != nil {
+		return err
+	}
+	if current == "" {
+		return &WorkspaceMemberNotFoundErr{id: id, user: member}
+	}
+	if current == RoleWorkspaceAdmin {
+		if err := lastAdmin(m.db, id); err != nil {
+			return err
+		}
+	}
+
+	err = m.db.Where("workspace_id = ? AND user_id = ?", id, member).Delete(&WorkspaceMember{}).Error
+	if err != nil {
+		return err
+	}
+	return m.InWorkspace(id).revokeMember(member)
+}
+
+// revokeMember removes the access of the user to the lists of the workspace and the access of the others to the
+// lists of the user
+func (m Manager) revokeMember(member string) error {
+	owned := m.db.Model(&TodoList{}).Select("id").Where("owner_id = ?", member)
+	memberships := []ListMember{}
+	err := m.db.Where("user_id = ? OR list_id IN (?)", member, owned).Find(&memberships).Error
+	if err != nil {
+		return err
+	}
+	for _, membership := range memberships {
+		err = m.revoke(membership.ListId, membership.UserId)
+		if err != nil {
+			return err
+		}
+	}
+	// share links are not stored per workspace, the lists of the workspace select them
+	lists := m.db.Model(&TodoList{}).Select("id")
+	return m.db.Where("list_id IN (?) OR (created_by = ? AND list_id IN (?))", owned, member, lists).
+		Delete(&ShareLink{}).Error
+}
+
+// sameWorkspace returns ErrOtherWorkspace unless the user is a member of the workspace of the session, every user
+// can share the lists of the personal workspace
+func sameWorkspace(db *gorm.DB, user string) error {
+	ws, _ := workspaceOf(db)
+	if ws == "" {
+		return nil
+	}
+	role, err := workspaceRole(db, ws, user)
+	if err != nil {
+		return err
+	}
+	if role == "" {
+		return ErrOtherWorkspace
+	}
+	return nil
+}
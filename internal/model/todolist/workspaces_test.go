@@ -0,0 +1,190 @@
+package todolist_test
+
+import (
+	"errors"
+	"github.com/go-bumbu/todo-app/internal/model/todolist"
+	"github.com/google/go-cmp/cmp"
+	"testing"
+)
+
+func TestWorkspaces(t *testing.T) {
+	mngr := testManager(t)
+	ws, err := mngr.CreateWorkspace("acme", "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	inWs := mngr.InWorkspace(ws.ID)
+	team := &inWs
+
+	t.Run("data is kept apart from the personal workspace", func(t *testing.T) {
+		personal := createTask(t, mngr, "personal", "u1")
+		shared := createTask(t, team, "team", "u1")
+		readTask(t, team, personal, "u1", "", "task with id: "+personal+" and owner u1 not found")
+		readTask(t, mngr, shared, "u1", "", "task with id: "+shared+" and owner u1 not found")
+		readTask(t, team, shared, "u1", "team", "")
+
+		// every workspace has its own inbox and tags
+		personalInbox, err := mngr.Inbox("u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		teamInbox, err := team.Inbox("u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if personalInbox.ID == teamInbox.ID || teamInbox.WorkspaceId != ws.ID {
+			t.Errorf("expected an inbox per workspace, got: %+v and %+v", personalInbox, teamInbox)
+		}
+		for _, m := range []*todolist.Manager{mngr, team} {
+			if _, err := m.CreateTag(&todolist.Tag{Name: "work", OwnerId: "u1"}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		tags, err := team.Tags("u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tags) != 1 || tags[0].WorkspaceId != ws.ID {
+			t.Errorf("unexpected tags: %+v", tags)
+		}
+	})
+
+	t.Run("user ids are not parsed", func(t *testing.T) {
+		id := createTask(t, mngr, "slash", ws.ID+"/u1")
+		readTask(t, mngr, id, ws.ID+"/u1", "slash", "")
+		readTask(t, team, id, "u1", "", "task with id: "+id+" and owner u1 not found")
+	})
+
+	t.Run("only admins manage members", func(t *testing.T) {
+		if err := mngr.AddWorkspaceMember(ws.ID, "u1", "u2", todolist.RoleWorkspaceMember); err != nil {
+			t.Fatal(err)
+		}
+		err := mngr.AddWorkspaceMember(ws.ID, "u2", "u3", todolist.RoleWorkspaceMember)
+		if !errors.Is(err, todolist.ErrNotWorkspaceAdmin) {
+			t.Errorf("expected not admin, got: %v", err)
+		}
+		err = mngr.RemoveWorkspaceMember(ws.ID, "u1", "u1")
+		if !errors.Is(err, todolist.ErrLastWorkspaceAdmin) {
+			t.Errorf("expected last admin, got: %v", err)
+		}
+		members, err := mngr.WorkspaceMembers(ws.ID, "u2")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []string{}
+		for _, m := range members {
+			got = append(got, m.UserId+":"+string(m.Role))
+		}
+		if diff := cmp.Diff(got, []string{"u1:admin", "u2:member"}); diff != "" {
+			t.Errorf("unexpected value (-got +want)\n%s", diff)
+		}
+	})
+
+	work := createList(t, team, "work", "u1")
+	t.Run("lists are shared within the workspace", func(t *testing.T) {
+		err := team.Share(work, "u1", "u3", todolist.RoleEditor)
+		if !errors.Is(err, todolist.ErrOtherWorkspace) {
+			t.Errorf("expected other workspace, got: %v", err)
+		}
+		if err = team.Share(work, "u1", "u2", todolist.RoleOwner); err != nil {
+			t.Fatal(err)
+		}
+		if err = team.AcceptInvitation(work, "u2"); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err = team.ListAccess(work, "u2"); err != nil {
+			t.Fatal(err)
+		}
+		// the membership belongs to the workspace of the list
+		shared, err := mngr.SharedWithMe("u2")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(shared) != 0 {
+			t.Errorf("expected no shared lists in the personal workspace, got: %+v", shared)
+		}
+	})
+
+	t.Run("links stop working when the creator is no longer owner", func(t *testing.T) {
+		link, err := team.CreateShareLink(work, "u2", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = team.SetRole(work, "u1", "u2", todolist.RoleViewer); err != nil {
+			t.Fatal(err)
+		}
+		sErr := &todolist.ShareLinkNotFoundErr{}
+		if _, err = mngr.SharedLinkList(link.Token); !errors.As(err, &sErr) {
+			t.Errorf("expected share link not found, got: %v", err)
+		}
+		if err = team.SetRole(work, "u1", "u2", todolist.RoleOwner); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = mngr.SharedLinkList(link.Token); err != nil {
+			t.Errorf("expected the link to work again, got: %v", err)
+		}
+	})
+
+	t.Run("removing a member revokes the shared lists and links", func(t *testing.T) {
+		ownLink, err := team.CreateShareLink(work, "u1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		memberLink, err := team.CreateShareLink(work, "u2", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		plans := createList(t, team, "plans", "u2")
+		if err = team.Share(plans, "u2", "u1", todolist.RoleEditor); err != nil {
+			t.Fatal(err)
+		}
+		if err = team.AcceptInvitation(plans, "u1"); err != nil {
+			t.Fatal(err)
+		}
+		plansLink, err := team.CreateShareLink(plans, "u2", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err = mngr.RemoveWorkspaceMember(ws.ID, "u1", "u2"); err != nil {
+			t.Fatal(err)
+		}
+		lErr := &todolist.ListNotFoundErr{}
+		if _, _, err = team.ListAccess(work, "u2"); !errors.As(err, &lErr) {
+			t.Errorf("expected list not found, got: %v", err)
+		}
+		if _, _, err = team.ListAccess(plans, "u1"); !errors.As(err, &lErr) {
+			t.Errorf("expected the lists of the member to be unshared, got: %v", err)
+		}
+		sErr := &todolist.ShareLinkNotFoundErr{}
+		for _, token := range []string{memberLink.Token, plansLink.Token} {
+			if _, err = mngr.SharedLinkList(token); !errors.As(err, &sErr) {
+				t.Errorf("expected the link to be revoked, got: %v", err)
+			}
+		}
+		if _, err = mngr.SharedLinkList(ownLink.Token); err != nil {
+			t.Errorf("expected the links of the other members to work, got: %v", err)
+		}
+
+		_, _, err = mngr.GetWorkspace(ws.ID, "u2")
+		wErr := &todolist.WorkspaceNotFoundErr{}
+		if !errors.As(err, &wErr) {
+			t.Errorf("expected workspace not found, got: %v", err)
+		}
+		workspaces, err := mngr.Workspaces("u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(workspaces) != 1 || workspaces[0].Workspace.Name != "acme" || workspaces[0].Role != todolist.RoleWorkspaceAdmin {
+			t.Errorf("unexpected workspaces: %+v", workspaces)
+		}
+
+		// the lists of the member are kept for when the member is added back
+		if err = mngr.AddWorkspaceMember(ws.ID, "u1", "u2", todolist.RoleWorkspaceMember); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = team.GetList(plans, "u2"); err != nil {
+			t.Errorf("expected the list to be kept, got: %v", err)
+		}
+	})
+}